@@ -184,6 +184,21 @@ func main() {
 	noticeSyncService.StartSyncScheduler(ctx)
 	log.Info("通知同步调度器启动成功")
 
+	// 启动设备离线检测
+	deviceStatusService := serviceContainer.GetService("deviceStatus").(base_services.InterfaceDeviceStatusService)
+	deviceStatusService.StartStatusDetector(ctx)
+
+	// 启动告警检测
+	alertService := serviceContainer.GetService("alert").(base_services.InterfaceAlertService)
+	alertService.StartAlertScheduler(ctx)
+
+	// 迁移旧的明文打印密码并启动定时轮换
+	printPasswordService := serviceContainer.GetService("printPassword").(base_services.InterfacePrintPasswordService)
+	if err := printPasswordService.MigrateLegacyPasswords(); err != nil {
+		log.Error("迁移打印密码失败: %v", err)
+	}
+	printPasswordService.StartRotationScheduler(ctx)
+
 	// 启动服务器
 	serverAddr := "0.0.0.0:10031"
 	log.Info("启动HTTP服务器，监听地址: %s...", serverAddr)
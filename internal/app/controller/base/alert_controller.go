@@ -0,0 +1,365 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceAlertController interface {
+	GetRules()
+	CreateRule()
+	UpdateRule()
+	DeleteRules()
+	GetAlerts()
+	GetAlert()
+	Evaluate()
+}
+
+type AlertController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewAlertController(ctx *gin.Context, container *container.ServiceContainer) *AlertController {
+	return &AlertController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncAlert returns a gin.HandlerFunc for the specified method
+func HandleFuncAlert(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getRules":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.GetRules()
+		}
+	case "createRule":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.CreateRule()
+		}
+	case "updateRule":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.UpdateRule()
+		}
+	case "deleteRules":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.DeleteRules()
+		}
+	case "getAlerts":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.GetAlerts()
+		}
+	case "getAlert":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.GetAlert()
+		}
+	case "evaluate":
+		return func(ctx *gin.Context) {
+			controller := NewAlertController(ctx, container)
+			controller.Evaluate()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// GetRules 获取告警规则
+// @Summary      获取告警规则
+// @Tags         Alert
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/alert_rule [get]
+// @Security     JWT
+func (c *AlertController) GetRules() {
+	rules, err := c.Container.GetService("alert").(base_services.InterfaceAlertService).GetRules()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get alert rules success",
+		"data":    rules,
+	})
+}
+
+// CreateRule 创建告警规则
+// @Summary      创建告警规则
+// @Description  type: device_offline（threshold 为分钟）, orangepi_offline, printer_offline, ink_low（threshold 为百分比）
+// @Tags         Alert
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "name, type, threshold, buildingId, cooldownMinutes, notifyRecovery, enabled"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/alert_rule [post]
+// @Security     JWT
+func (c *AlertController) CreateRule() {
+	var form struct {
+		Name            string  `json:"name" binding:"required"`
+		Type            string  `json:"type" binding:"required"`
+		Threshold       float64 `json:"threshold"`
+		BuildingID      *uint   `json:"buildingId"`
+		CooldownMinutes *int    `json:"cooldownMinutes"`
+		NotifyRecovery  *bool   `json:"notifyRecovery"`
+		Enabled         *bool   `json:"enabled"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	rule := &models.AlertRule{
+		Name:            form.Name,
+		Type:            field.AlertRuleType(form.Type),
+		Threshold:       form.Threshold,
+		BuildingID:      form.BuildingID,
+		CooldownMinutes: base_services.DefaultAlertCooldownMinutes,
+		NotifyRecovery:  form.NotifyRecovery == nil || *form.NotifyRecovery,
+		Enabled:         form.Enabled == nil || *form.Enabled,
+	}
+	if form.CooldownMinutes != nil {
+		rule.CooldownMinutes = *form.CooldownMinutes
+	}
+
+	if err := c.Container.GetService("alert").(base_services.InterfaceAlertService).CreateRule(rule); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "create alert rule failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "create alert rule success",
+		"data":    rule,
+	})
+}
+
+// UpdateRule 更新告警规则
+// @Summary      更新告警规则
+// @Tags         Alert
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "id, name, type, threshold, buildingId, cooldownMinutes, notifyRecovery, enabled"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/alert_rule [put]
+// @Security     JWT
+func (c *AlertController) UpdateRule() {
+	var form struct {
+		ID              uint     `json:"id" binding:"required"`
+		Name            *string  `json:"name"`
+		Type            *string  `json:"type"`
+		Threshold       *float64 `json:"threshold"`
+		BuildingID      *uint    `json:"buildingId"`
+		CooldownMinutes *int     `json:"cooldownMinutes"`
+		NotifyRecovery  *bool    `json:"notifyRecovery"`
+		Enabled         *bool    `json:"enabled"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if form.Name != nil {
+		updates["name"] = *form.Name
+	}
+	if form.Type != nil {
+		updates["type"] = *form.Type
+	}
+	if form.Threshold != nil {
+		updates["threshold"] = *form.Threshold
+	}
+	if form.BuildingID != nil {
+		// buildingId 为 0 表示适用于所有建筑
+		if *form.BuildingID == 0 {
+			updates["building_id"] = nil
+		} else {
+			updates["building_id"] = *form.BuildingID
+		}
+	}
+	if form.CooldownMinutes != nil {
+		updates["cooldown_minutes"] = *form.CooldownMinutes
+	}
+	if form.NotifyRecovery != nil {
+		updates["notify_recovery"] = *form.NotifyRecovery
+	}
+	if form.Enabled != nil {
+		updates["enabled"] = *form.Enabled
+	}
+
+	rule, err := c.Container.GetService("alert").(base_services.InterfaceAlertService).UpdateRule(form.ID, updates)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "update alert rule success",
+		"data":    rule,
+	})
+}
+
+// DeleteRules 删除告警规则
+// @Summary      删除告警规则
+// @Description  删除规则时其未恢复的告警会被关闭，不发送恢复通知
+// @Tags         Alert
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "ids"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/alert_rule [delete]
+// @Security     JWT
+func (c *AlertController) DeleteRules() {
+	var form struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("alert").(base_services.InterfaceAlertService).DeleteRules(form.IDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "delete alert rules success"})
+}
+
+// GetAlerts 获取告警列表
+// @Summary      获取告警列表
+// @Tags         Alert
+// @Produce      json
+// @Param        status query string false "状态: open, resolved"
+// @Param        buildingId query int false "建筑ID"
+// @Param        deviceId query int false "设备ID"
+// @Param        ruleId query int false "规则ID"
+// @Param        pageSize query int false "每页数量" default(10)
+// @Param        pageNum query int false "页码" default(1)
+// @Param        desc query bool false "是否降序" default(true)
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/alert [get]
+// @Security     JWT
+func (c *AlertController) GetAlerts() {
+	var searchQuery struct {
+		Status     string `form:"status"`
+		BuildingID uint   `form:"buildingId"`
+		DeviceID   uint   `form:"deviceId"`
+		RuleID     uint   `form:"ruleId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"status":     searchQuery.Status,
+		"buildingId": searchQuery.BuildingID,
+		"deviceId":   searchQuery.DeviceID,
+		"ruleId":     searchQuery.RuleID,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	alerts, paginationResult, err := c.Container.GetService("alert").(base_services.InterfaceAlertService).GetAlerts(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       alerts,
+		"pagination": paginationResult,
+	})
+}
+
+// GetAlert 获取告警详情
+// @Summary      获取告警详情
+// @Tags         Alert
+// @Produce      json
+// @Param        id path int true "告警ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /admin/alert/{id} [get]
+// @Security     JWT
+func (c *AlertController) GetAlert() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid alert ID"})
+		return
+	}
+
+	alert, err := c.Container.GetService("alert").(base_services.InterfaceAlertService).GetAlertByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get alert success",
+		"data":    alert,
+	})
+}
+
+// Evaluate 立即执行一次告警检测
+// @Summary      立即执行告警检测
+// @Tags         Alert
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/alert/evaluate [post]
+// @Security     JWT
+func (c *AlertController) Evaluate() {
+	opened, resolved, err := c.Container.GetService("alert").(base_services.InterfaceAlertService).Evaluate()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Evaluate alerts success",
+		"data": gin.H{
+			"opened":   opened,
+			"resolved": resolved,
+		},
+	})
+}
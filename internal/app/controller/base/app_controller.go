@@ -1,10 +1,14 @@
 package http_base_controller
 
 import (
+	"strconv"
+	"strings"
+
 	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
 	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
 	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // AppController 应用版本控制器
@@ -27,6 +31,9 @@ func HandleFuncApp(container *container.ServiceContainer, method string) gin.Han
 	// 更新应用版本配置
 	case "update":
 		return func(ctx *gin.Context) { NewAppController(ctx, container).Update() }
+	// 获取版本签名公钥
+	case "getSigningKey":
+		return func(ctx *gin.Context) { NewAppController(ctx, container).GetSigningKey() }
 	default:
 		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
 	}
@@ -34,10 +41,13 @@ func HandleFuncApp(container *container.ServiceContainer, method string) gin.Han
 
 // Get 获取应用版本配置
 // @Summary      获取应用版本配置
-// @Description  获取当前应用版本配置信息，包括当前使用的版本详情
+// @Description  获取当前应用版本配置信息，包括当前使用的版本详情；携带设备 Token 时 currentVersion 为该设备应安装的版本（含发布渠道、灰度发布与固定版本）
 // @Tags         App
 // @Accept       json
 // @Produce      json
+// @Param        Authorization header string false "设备 Token（可选）"
+// @Param        currentVersion query string false "设备已安装的版本号，低于最低支持版本时 forceUpdate 为 true"
+// @Param        sdkInt query int false "设备 Android API 级别，低于版本 minSdkVersion 时 compatible 为 false"
 // @Success      200  {object}  map[string]interface{} "返回应用版本配置信息"
 // @Failure      500  {object}  map[string]interface{} "错误信息"
 // @Router       /api/app/version [get]
@@ -48,18 +58,43 @@ func (c *AppController) Get() {
 		return
 	}
 
+	resolution, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).Resolve(c.optionalDevice(), c.Ctx.Query("currentVersion"))
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentVersionID := app.CurrentVersionID
+	compatible := true
+	if resolution.Version != nil {
+		currentVersionID = resolution.Version.ID
+		if sdkInt, err := strconv.Atoi(c.Ctx.Query("sdkInt")); err == nil && sdkInt < resolution.Version.MinSdkVersion {
+			compatible = false
+		}
+	}
+
 	// 确保返回的数据结构清晰
 	response := gin.H{
 		"data": gin.H{
-			"id":               app.ID,
-			"currentVersionId": app.CurrentVersionID,
-			"currentVersion":   app.CurrentVersion,
-			"lastCheckTime":    app.LastCheckTime,
-			"updateInterval":   app.UpdateInterval,
-			"autoUpdate":       app.AutoUpdate,
-			"status":           app.Status,
-			"createdAt":        app.CreatedAt,
-			"updatedAt":        app.UpdatedAt,
+			"id":                 app.ID,
+			"currentVersionId":   currentVersionID,
+			"currentVersion":     resolution.Version,
+			"source":             resolution.Source,
+			"rolloutId":          resolution.RolloutID,
+			"rollback":           resolution.Rollback,
+			"channel":            resolution.Channel,
+			"allowDowngrade":     resolution.AllowDowngrade,
+			"minVersionId":       app.MinVersionID,
+			"minVersion":         resolution.MinVersion,
+			"forceUpdate":        resolution.ForceUpdate,
+			"compatible":         compatible,
+			"signatureAlgorithm": base_services.VersionSignatureAlgorithm,
+			"lastCheckTime":      app.LastCheckTime,
+			"updateInterval":     app.UpdateInterval,
+			"autoUpdate":         app.AutoUpdate,
+			"status":             app.Status,
+			"createdAt":          app.CreatedAt,
+			"updatedAt":          app.UpdatedAt,
 		},
 		"message": "Get app version config success",
 	}
@@ -67,9 +102,38 @@ func (c *AppController) Get() {
 	c.Ctx.JSON(200, response)
 }
 
+// optionalDevice 从可选的设备 Token 中获取设备，未携带或无效时返回 nil
+func (c *AppController) optionalDevice() *models.Device {
+	authHeader := c.Ctx.GetHeader("Authorization")
+	if authHeader == "" {
+		return nil
+	}
+
+	token, err := base_services.NewJWTService().ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if err != nil || !token.Valid {
+		return nil
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["isDevice"] != true {
+		return nil
+	}
+
+	deviceID, ok := claims["deviceId"].(string)
+	if !ok {
+		return nil
+	}
+
+	device, err := c.Container.GetService("device").(base_services.InterfaceDeviceService).GetByDeviceID(deviceID)
+	if err != nil {
+		return nil
+	}
+	return device
+}
+
 // Update 更新应用版本配置
 // @Summary      更新应用版本配置
-// @Description  更新应用版本配置信息，包括设置当前使用的版本
+// @Description  更新应用版本配置信息，包括设置当前使用的版本与最低支持版本（minVersionId 为 0 时清除）
 // @Tags         App
 // @Accept       json
 // @Produce      json
@@ -92,3 +156,27 @@ func (c *AppController) Update() {
 	}
 	c.Ctx.JSON(200, gin.H{"message": "Update app version config success", "data": updatedApp})
 }
+
+// GetSigningKey 获取版本签名公钥
+// @Summary      获取版本签名公钥
+// @Description  返回校验版本元数据签名的 Ed25519 公钥；设备应内置该公钥，此接口仅用于初始配置与核对
+// @Tags         App
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "返回算法与 base64 公钥"
+// @Failure      404  {object}  map[string]interface{} "未配置签名私钥"
+// @Router       /api/app/signing_key [get]
+func (c *AppController) GetSigningKey() {
+	publicKey, ok := base_services.AppSigningPublicKey()
+	if !ok {
+		c.Ctx.JSON(404, gin.H{"error": "signing key not configured"})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data": gin.H{
+			"algorithm": base_services.VersionSignatureAlgorithm,
+			"publicKey": publicKey,
+		},
+		"message": "Get signing key success",
+	})
+}
@@ -0,0 +1,147 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/gin-gonic/gin"
+)
+
+// AppFleetController 设备 App 版本统计控制器
+type AppFleetController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewAppFleetController 创建控制器
+func NewAppFleetController(ctx *gin.Context, container *container.ServiceContainer) *AppFleetController {
+	return &AppFleetController{Ctx: ctx, Container: container}
+}
+
+// HandleFuncAppFleet 根据方法返回处理函数
+func HandleFuncAppFleet(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getDistribution":
+		return func(ctx *gin.Context) { NewAppFleetController(ctx, container).GetDistribution() }
+	case "getOutdated":
+		return func(ctx *gin.Context) { NewAppFleetController(ctx, container).GetOutdated() }
+	case "getDeviceHistory":
+		return func(ctx *gin.Context) { NewAppFleetController(ctx, container).GetDeviceHistory() }
+	default:
+		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
+	}
+}
+
+// GetDistribution 获取设备 App 版本分布
+// @Summary      获取设备 App 版本分布
+// @Description  按设备登录与心跳上报的已安装版本统计，未上报的设备计为 unknown
+// @Tags         AppFleet
+// @Produce      json
+// @Param        buildingId query int false "建筑ID"
+// @Success      200  {object}  map[string]interface{} "返回全部与各建筑的版本分布"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/versions/distribution [get]
+// @Security     BearerAuth
+func (c *AppFleetController) GetDistribution() {
+	var searchQuery struct {
+		BuildingID uint `form:"buildingId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	distribution, err := c.Container.GetService("appFleet").(base_services.InterfaceAppFleetService).GetDistribution(searchQuery.BuildingID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": distribution, "message": "Get version distribution success"})
+}
+
+// GetOutdated 获取版本落后的设备
+// @Summary      获取版本落后的设备
+// @Description  已安装版本低于应安装版本（含渠道、灰度、固定版本与最低支持版本）的设备；固定版本或灰度回滚时版本不一致即视为落后
+// @Tags         AppFleet
+// @Produce      json
+// @Param        buildingId query int false "建筑ID"
+// @Param        includeUnknown query bool false "包含未上报版本的设备"
+// @Param        forceOnly query bool false "只返回低于最低支持版本的设备"
+// @Success      200  {object}  map[string]interface{} "返回设备列表"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/versions/outdated [get]
+// @Security     BearerAuth
+func (c *AppFleetController) GetOutdated() {
+	var searchQuery struct {
+		BuildingID     uint `form:"buildingId"`
+		IncludeUnknown bool `form:"includeUnknown"`
+		ForceOnly      bool `form:"forceOnly"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int `form:"pageSize"`
+		PageNum  int `form:"pageNum"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+	}
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if pagination.PageSize <= 0 || pagination.PageNum <= 0 {
+		c.Ctx.JSON(400, gin.H{"error": "invalid pagination"})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"buildingId":     searchQuery.BuildingID,
+		"includeUnknown": searchQuery.IncludeUnknown,
+		"forceOnly":      searchQuery.ForceOnly,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+	}
+
+	devices, paginationResult, err := c.Container.GetService("appFleet").(base_services.InterfaceAppFleetService).GetOutdated(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": devices, "pagination": paginationResult})
+}
+
+// GetDeviceHistory 获取设备版本与更新记录
+// @Summary      获取设备版本与更新记录
+// @Tags         AppFleet
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Param        limit query int false "更新记录条数，默认 50"
+// @Success      200  {object}  map[string]interface{} "返回已安装版本、应安装版本与更新记录"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/device/{id}/update_history [get]
+// @Security     BearerAuth
+func (c *AppFleetController) GetDeviceHistory() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Ctx.DefaultQuery("limit", "50"))
+
+	history, err := c.Container.GetService("appFleet").(base_services.InterfaceAppFleetService).GetDeviceHistory(uint(id), limit)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": history, "message": "Get device update history success"})
+}
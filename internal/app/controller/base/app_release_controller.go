@@ -0,0 +1,152 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+// AppReleaseController 发布渠道与版本固定控制器
+type AppReleaseController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewAppReleaseController 创建控制器
+func NewAppReleaseController(ctx *gin.Context, container *container.ServiceContainer) *AppReleaseController {
+	return &AppReleaseController{Ctx: ctx, Container: container}
+}
+
+// HandleFuncAppRelease 根据方法返回处理函数
+func HandleFuncAppRelease(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "setDeviceChannel":
+		return func(ctx *gin.Context) { NewAppReleaseController(ctx, container).SetDeviceChannel() }
+	case "setBuildingChannel":
+		return func(ctx *gin.Context) { NewAppReleaseController(ctx, container).SetBuildingChannel() }
+	case "pin":
+		return func(ctx *gin.Context) { NewAppReleaseController(ctx, container).Pin() }
+	case "getDeviceRelease":
+		return func(ctx *gin.Context) { NewAppReleaseController(ctx, container).GetDeviceRelease() }
+	default:
+		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
+	}
+}
+
+// SetDeviceChannel 设置设备发布渠道
+// @Summary      设置设备发布渠道
+// @Description  channel 为空字符串时设备跟随所在建筑的渠道
+// @Tags         AppRelease
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "deviceIds, channel"
+// @Success      200  {object}  map[string]interface{} "设置成功"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/channel/device [put]
+// @Security     BearerAuth
+func (c *AppReleaseController) SetDeviceChannel() {
+	var form struct {
+		DeviceIDs []uint `json:"deviceIds" binding:"required"`
+		Channel   string `json:"channel"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
+
+	if err := c.Container.GetService("appRelease").(base_services.InterfaceAppReleaseService).SetDeviceChannel(form.DeviceIDs, field.ReleaseChannel(form.Channel)); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Set device channel success"})
+}
+
+// SetBuildingChannel 设置建筑发布渠道
+// @Summary      设置建筑发布渠道
+// @Description  建筑内未单独设置渠道的设备使用建筑的渠道；channel 为空字符串时为 stable
+// @Tags         AppRelease
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "buildingIds, channel"
+// @Success      200  {object}  map[string]interface{} "设置成功"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/channel/building [put]
+// @Security     BearerAuth
+func (c *AppReleaseController) SetBuildingChannel() {
+	var form struct {
+		BuildingIDs []uint `json:"buildingIds" binding:"required"`
+		Channel     string `json:"channel"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
+
+	if err := c.Container.GetService("appRelease").(base_services.InterfaceAppReleaseService).SetBuildingChannel(form.BuildingIDs, field.ReleaseChannel(form.Channel)); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Set building channel success"})
+}
+
+// Pin 固定设备版本
+// @Summary      固定设备版本
+// @Description  固定后设备始终安装该版本（允许降级），不受渠道与灰度影响，但不会低于最低支持版本；versionId 为空或 0 时取消固定
+// @Tags         AppRelease
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "deviceIds, versionId"
+// @Success      200  {object}  map[string]interface{} "设置成功"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/pin [put]
+// @Security     BearerAuth
+func (c *AppReleaseController) Pin() {
+	var form struct {
+		DeviceIDs []uint `json:"deviceIds" binding:"required"`
+		VersionID *uint  `json:"versionId"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
+	if form.VersionID != nil && *form.VersionID == 0 {
+		form.VersionID = nil
+	}
+
+	if err := c.Container.GetService("appRelease").(base_services.InterfaceAppReleaseService).PinDevices(form.DeviceIDs, form.VersionID); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Pin device version success"})
+}
+
+// GetDeviceRelease 获取设备的发布渠道与应安装版本
+// @Summary      获取设备的发布渠道与应安装版本
+// @Tags         AppRelease
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Success      200  {object}  map[string]interface{} "返回渠道、固定版本与解析结果"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/device/{id}/release [get]
+// @Security     BearerAuth
+func (c *AppReleaseController) GetDeviceRelease() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	release, err := c.Container.GetService("appRelease").(base_services.InterfaceAppReleaseService).GetDeviceRelease(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": release, "message": "Get device release success"})
+}
@@ -0,0 +1,355 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+// AppRolloutController 应用灰度发布控制器
+type AppRolloutController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewAppRolloutController 创建控制器
+func NewAppRolloutController(ctx *gin.Context, container *container.ServiceContainer) *AppRolloutController {
+	return &AppRolloutController{Ctx: ctx, Container: container}
+}
+
+// HandleFuncAppRollout 根据方法返回处理函数
+func HandleFuncAppRollout(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "create":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).Create() }
+	case "get":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).Get() }
+	case "getOne":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).GetOne() }
+	case "update":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).Update() }
+	case "pause":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).SetStatus(field.AppRolloutPaused) }
+	case "resume":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).SetStatus(field.AppRolloutActive) }
+	case "rollback":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).SetStatus(field.AppRolloutRolledBack) }
+	case "cancel":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).SetStatus(field.AppRolloutCanceled) }
+	case "complete":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).Complete() }
+	case "getAttempts":
+		return func(ctx *gin.Context) { NewAppRolloutController(ctx, container).GetAttempts() }
+	default:
+		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
+	}
+}
+
+// appRolloutForm 创建或修改灰度发布的请求参数
+type appRolloutForm struct {
+	Name           *string  `json:"name"`
+	VersionID      *uint    `json:"versionId"`
+	Percentage     *int     `json:"percentage"`
+	BuildingIDs    []uint   `json:"buildingIds"`
+	DeviceGroupIDs []uint   `json:"deviceGroupIds"`
+	MaxFailures    *int     `json:"maxFailures"`
+	MaxFailureRate *float64 `json:"maxFailureRate"`
+	MinReports     *int     `json:"minReports"`
+	FailureAction  *string  `json:"failureAction"`
+}
+
+func (f appRolloutForm) toInput() base_services.AppRolloutInput {
+	input := base_services.AppRolloutInput{
+		Name:           f.Name,
+		VersionID:      f.VersionID,
+		Percentage:     f.Percentage,
+		BuildingIDs:    f.BuildingIDs,
+		DeviceGroupIDs: f.DeviceGroupIDs,
+		MaxFailures:    f.MaxFailures,
+		MaxFailureRate: f.MaxFailureRate,
+		MinReports:     f.MinReports,
+	}
+	if f.FailureAction != nil {
+		action := field.AppRolloutFailureAction(*f.FailureAction)
+		input.FailureAction = &action
+	}
+	return input
+}
+
+// Create 创建灰度发布
+// @Summary      创建灰度发布
+// @Description  按比例、建筑或设备分组灰度发布版本；失败次数或失败率超过限制时自动暂停或回滚
+// @Tags         AppRollout
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "versionId, percentage, buildingIds, deviceGroupIds, maxFailures, maxFailureRate, minReports, failureAction"
+// @Success      200  {object}  map[string]interface{} "返回创建的灰度发布"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout [post]
+// @Security     BearerAuth
+func (c *AppRolloutController) Create() {
+	var form appRolloutForm
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
+
+	createdBy, _ := c.Ctx.Value("email").(string)
+
+	rollout, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).Create(form.toInput(), createdBy)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Create rollout success", "data": rollout})
+}
+
+// Get 获取灰度发布列表
+// @Summary      获取灰度发布列表
+// @Tags         AppRollout
+// @Produce      json
+// @Param        versionId query int false "版本ID"
+// @Param        status query string false "状态: active, paused, completed, rolled_back, canceled"
+// @Success      200  {object}  map[string]interface{} "返回灰度发布列表"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout [get]
+// @Security     BearerAuth
+func (c *AppRolloutController) Get() {
+	var searchQuery struct {
+		VersionID uint   `form:"versionId"`
+		Status    string `form:"status"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"versionId": searchQuery.VersionID,
+		"status":    searchQuery.Status,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	rollouts, paginationResult, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).Get(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": rollouts, "pagination": paginationResult})
+}
+
+// GetOne 获取灰度发布详情
+// @Summary      获取灰度发布详情
+// @Description  包含命中设备数与更新成功、失败次数
+// @Tags         AppRollout
+// @Produce      json
+// @Param        id path int true "灰度ID"
+// @Success      200  {object}  map[string]interface{} "返回灰度发布详情"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout/{id} [get]
+// @Security     BearerAuth
+func (c *AppRolloutController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	detail, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": detail, "message": "Get rollout success"})
+}
+
+// Update 修改灰度发布
+// @Summary      修改灰度发布
+// @Description  调整比例、范围或失败限制，未传的字段保持不变
+// @Tags         AppRollout
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "灰度ID"
+// @Param        data body object true "与创建参数相同"
+// @Success      200  {object}  map[string]interface{} "返回修改后的灰度发布"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout/{id} [put]
+// @Security     BearerAuth
+func (c *AppRolloutController) Update() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var form appRolloutForm
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
+
+	rollout, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).Update(uint(id), form.toInput())
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Update rollout success", "data": rollout})
+}
+
+// SetStatus 暂停、恢复、回滚或取消灰度发布
+// @Summary      变更灰度发布状态
+// @Tags         AppRollout
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "灰度ID"
+// @Param        data body object false "reason"
+// @Success      200  {object}  map[string]interface{} "返回修改后的灰度发布"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout/{id}/pause [post]
+// @Router       /admin/app/rollout/{id}/resume [post]
+// @Router       /admin/app/rollout/{id}/rollback [post]
+// @Router       /admin/app/rollout/{id}/cancel [post]
+// @Security     BearerAuth
+func (c *AppRolloutController) SetStatus(status field.AppRolloutStatus) {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var form struct {
+		Reason string `json:"reason"`
+	}
+	if c.Ctx.Request.ContentLength > 0 {
+		if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+			return
+		}
+	}
+	if form.Reason == "" {
+		if email, ok := c.Ctx.Value("email").(string); ok {
+			form.Reason = "manual by " + email
+		}
+	}
+
+	rollout, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).SetStatus(uint(id), status, form.Reason)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Update rollout status success", "data": rollout})
+}
+
+// Complete 全量发布
+// @Summary      全量发布
+// @Description  将灰度版本设为 App 当前版本，并通知所有设备检查更新
+// @Tags         AppRollout
+// @Produce      json
+// @Param        id path int true "灰度ID"
+// @Success      200  {object}  map[string]interface{} "返回修改后的灰度发布"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/rollout/{id}/complete [post]
+// @Security     BearerAuth
+func (c *AppRolloutController) Complete() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rollout, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).Complete(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Complete rollout success", "data": rollout})
+}
+
+// GetAttempts 获取设备更新记录
+// @Summary      获取设备更新记录
+// @Tags         AppRollout
+// @Produce      json
+// @Param        deviceId query int false "设备ID"
+// @Param        buildingId query int false "建筑ID"
+// @Param        rolloutId query int false "灰度ID"
+// @Param        versionId query int false "版本ID"
+// @Param        success query bool false "是否成功"
+// @Success      200  {object}  map[string]interface{} "返回更新记录"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/app/update_attempts [get]
+// @Security     BearerAuth
+func (c *AppRolloutController) GetAttempts() {
+	var searchQuery struct {
+		DeviceID   uint  `form:"deviceId"`
+		BuildingID uint  `form:"buildingId"`
+		RolloutID  uint  `form:"rolloutId"`
+		VersionID  uint  `form:"versionId"`
+		Success    *bool `form:"success"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"deviceId":   searchQuery.DeviceID,
+		"buildingId": searchQuery.BuildingID,
+		"rolloutId":  searchQuery.RolloutID,
+		"versionId":  searchQuery.VersionID,
+		"success":    searchQuery.Success,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	attempts, paginationResult, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).GetAttempts(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": attempts, "pagination": paginationResult})
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -21,6 +22,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -32,6 +34,9 @@ type InterfaceBuildingController interface {
 	GetOne()
 	SyncNotice()
 	ManualSyncNotice()
+	GetUpstream()
+	CheckUpstream()
+	ImportUpstream()
 }
 
 type BuildingController struct {
@@ -84,6 +89,21 @@ func HandleFuncBuilding(container *container.ServiceContainer, method string) gi
 			controller := NewBuildingController(ctx, container)
 			controller.ManualSyncNotice()
 		}
+	case "getUpstream":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingController(ctx, container)
+			controller.GetUpstream()
+		}
+	case "checkUpstream":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingController(ctx, container)
+			controller.CheckUpstream()
+		}
+	case "importUpstream":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingController(ctx, container)
+			controller.ImportUpstream()
+		}
 	default:
 		return func(ctx *gin.Context) {
 			ctx.JSON(400, gin.H{"error": "invalid method"})
@@ -102,16 +122,25 @@ func HandleFuncBuilding(container *container.ServiceContainer, method string) gi
 // @Param        ismartId formData string true "智慧建筑ID" example:"BLG1001"
 // @Param        remark formData string false "备注" example:"位于中心城区的甲级写字楼"
 // @Param        location formData string false "位置" example:"广州市天河区珠江东路28号"
+// @Param        noticeSources formData []string false "通知来源(ismart/local)，为空时使用默认来源" example:"[\"ismart\"]"
+// @Param        noticeSyncEnabled formData bool false "是否参与定时同步，默认 true" example:"true"
+// @Param        noticeSyncInterval formData int false "定时同步间隔(分钟)，0 时使用 NOTICE_SYNC_INTERVAL" example:"10"
+// @Param        noticeSyncQuietStart formData string false "免同步时段开始(HH:MM，北京时间)" example:"23:00"
+// @Param        noticeSyncQuietEnd formData string false "免同步时段结束(HH:MM，北京时间)" example:"06:00"
+// @Param        noticeTypeMapping formData object false "来源通知类型到通知类型的映射" example:"{\"io\":\"normal\"}"
+// @Param        noticeSyncEndTime formData string false "同步创建的通知的结束时间(RFC3339)" example:"2030-01-01T00:00:00+08:00"
 // @Success      200  {object}  map[string]interface{} "返回创建的建筑信息"
 // @Failure      400  {object}  map[string]interface{} "错误信息"
 // @Router       /admin/building [post]
 // @Security     BearerAuth
 func (c *BuildingController) Create() {
 	var form struct {
-		Name     string `json:"name" binding:"required" example:"星汇中心"`
-		IsmartID string `json:"ismartId" binding:"required" example:"BLG1001"`
-		Remark   string `json:"remark" example:"位于中心城区的甲级写字楼"`
-		Location string `json:"location" example:"广州市天河区珠江东路28号"`
+		Name          string   `json:"name" binding:"required" example:"星汇中心"`
+		IsmartID      string   `json:"ismartId" binding:"required" example:"BLG1001"`
+		Remark        string   `json:"remark" example:"位于中心城区的甲级写字楼"`
+		Location      string   `json:"location" example:"广州市天河区珠江东路28号"`
+		NoticeSources []string `json:"noticeSources" example:"ismart"`
+		noticeSyncSettingsForm
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -121,6 +150,11 @@ func (c *BuildingController) Create() {
 		})
 		return
 	}
+	syncSettings, err := form.noticeSyncSettingsForm.updates()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+		return
+	}
 
 	building := &base_models.Building{
 		Name:     form.Name,
@@ -128,6 +162,15 @@ func (c *BuildingController) Create() {
 		Remark:   form.Remark,
 		Location: form.Location,
 	}
+	if form.NoticeSources != nil {
+		sources, err := noticeSourcesJSON(form.NoticeSources)
+		if err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error(), "message": "invalid form"})
+			return
+		}
+		building.NoticeSources = sources
+	}
+	applyNoticeSyncSettings(building, syncSettings)
 
 	if err := c.Container.GetService("building").(base_services.InterfaceBuildingService).Create(building); err != nil {
 		c.Ctx.JSON(400, gin.H{
@@ -219,17 +262,26 @@ func (c *BuildingController) Get() {
 // @Param        ismartId formData string false "智慧建筑ID" example:"BLG1001-A"
 // @Param        remark formData string false "备注" example:"位于中心城区的甲级写字楼，2023年重新装修"
 // @Param        location formData string false "位置" example:"广州市天河区珠江东路28号"
+// @Param        noticeSources formData []string false "通知来源(ismart/local)，传空数组时恢复默认来源" example:"[\"ismart\"]"
+// @Param        noticeSyncEnabled formData bool false "是否参与定时同步" example:"true"
+// @Param        noticeSyncInterval formData int false "定时同步间隔(分钟)，0 时使用 NOTICE_SYNC_INTERVAL" example:"10"
+// @Param        noticeSyncQuietStart formData string false "免同步时段开始(HH:MM，北京时间)，需与结束同时传，都传空字符串时取消" example:"23:00"
+// @Param        noticeSyncQuietEnd formData string false "免同步时段结束(HH:MM，北京时间)" example:"06:00"
+// @Param        noticeTypeMapping formData object false "来源通知类型到通知类型的映射，传空对象时恢复默认映射" example:"{\"io\":\"normal\"}"
+// @Param        noticeSyncEndTime formData string false "同步创建的通知的结束时间(RFC3339)，传空字符串时恢复默认" example:"2030-01-01T00:00:00+08:00"
 // @Success      200  {object}  map[string]interface{} "更新成功消息"
 // @Failure      400  {object}  map[string]interface{} "错误信息"
 // @Router       /admin/building [put]
 // @Security     BearerAuth
 func (c *BuildingController) Update() {
 	var form struct {
-		ID       uint   `json:"id" binding:"required" example:"1"`
-		Name     string `json:"name" example:"星汇国际中心"`
-		IsmartID string `json:"ismartId" example:"BLG1001-A"`
-		Remark   string `json:"remark" example:"位于中心城区的甲级写字楼，2023年重新装修"`
-		Location string `json:"location" example:"广州市天河区珠江东路28号"`
+		ID            uint     `json:"id" binding:"required" example:"1"`
+		Name          string   `json:"name" example:"星汇国际中心"`
+		IsmartID      string   `json:"ismartId" example:"BLG1001-A"`
+		Remark        string   `json:"remark" example:"位于中心城区的甲级写字楼，2023年重新装修"`
+		Location      string   `json:"location" example:"广州市天河区珠江东路28号"`
+		NoticeSources []string `json:"noticeSources" example:"ismart"`
+		noticeSyncSettingsForm
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -237,17 +289,31 @@ func (c *BuildingController) Update() {
 		return
 	}
 
-	updates := map[string]interface{}{}
+	updates, err := form.noticeSyncSettingsForm.updates()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 	if form.Name != "" {
 		updates["name"] = form.Name
 	}
 	if form.IsmartID != "" {
 		updates["ismart_id"] = form.IsmartID
+		// 来源检查结果待下次比对时更新
+		updates["upstream_missing"] = nil
 	}
 	if form.Remark != "" {
 		updates["remark"] = form.Remark
 	}
 	updates["location"] = form.Location
+	if form.NoticeSources != nil {
+		sources, err := noticeSourcesJSON(form.NoticeSources)
+		if err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		updates["notice_sources"] = sources
+	}
 
 	if err := c.Container.GetService("building").(base_services.InterfaceBuildingService).Update(form.ID, updates); err != nil {
 		c.Ctx.JSON(400, gin.H{"error": err.Error()})
@@ -257,6 +323,95 @@ func (c *BuildingController) Update() {
 	c.Ctx.JSON(200, gin.H{"message": "update building success"})
 }
 
+// noticeSyncSettingsForm 建筑的通知同步设置，未传的字段保持不变
+type noticeSyncSettingsForm struct {
+	NoticeSyncEnabled    *bool             `json:"noticeSyncEnabled" example:"true"`
+	NoticeSyncInterval   *int              `json:"noticeSyncInterval" example:"10"`
+	NoticeSyncQuietStart *string           `json:"noticeSyncQuietStart" example:"23:00"`
+	NoticeSyncQuietEnd   *string           `json:"noticeSyncQuietEnd" example:"06:00"`
+	NoticeTypeMapping    map[string]string `json:"noticeTypeMapping"`
+	NoticeSyncEndTime    *string           `json:"noticeSyncEndTime" example:"2030-01-01T00:00:00+08:00"`
+}
+
+// updates 校验同步设置并转换为建筑的更新字段
+func (f noticeSyncSettingsForm) updates() (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+	if f.NoticeSyncEnabled != nil {
+		updates["notice_sync_enabled"] = *f.NoticeSyncEnabled
+	}
+	if f.NoticeSyncInterval != nil {
+		if *f.NoticeSyncInterval < 0 {
+			return nil, errors.New("noticeSyncInterval must not be negative")
+		}
+		updates["notice_sync_interval"] = *f.NoticeSyncInterval
+	}
+	if f.NoticeSyncQuietStart != nil || f.NoticeSyncQuietEnd != nil {
+		if f.NoticeSyncQuietStart == nil || f.NoticeSyncQuietEnd == nil {
+			return nil, errors.New("noticeSyncQuietStart and noticeSyncQuietEnd must be set together")
+		}
+		if err := base_services.ParseQuietHours(*f.NoticeSyncQuietStart, *f.NoticeSyncQuietEnd); err != nil {
+			return nil, err
+		}
+		updates["notice_sync_quiet_start"] = *f.NoticeSyncQuietStart
+		updates["notice_sync_quiet_end"] = *f.NoticeSyncQuietEnd
+	}
+	if f.NoticeTypeMapping != nil {
+		mapping, err := base_services.ParseNoticeTypeMapping(f.NoticeTypeMapping)
+		if err != nil {
+			return nil, err
+		}
+		updates["notice_type_mapping"] = mapping
+	}
+	if f.NoticeSyncEndTime != nil {
+		if *f.NoticeSyncEndTime == "" {
+			updates["notice_sync_end_time"] = nil
+		} else {
+			endTime, err := time.Parse(time.RFC3339, *f.NoticeSyncEndTime)
+			if err != nil {
+				return nil, errors.New("noticeSyncEndTime must be RFC3339")
+			}
+			updates["notice_sync_end_time"] = endTime
+		}
+	}
+	return updates, nil
+}
+
+// applyNoticeSyncSettings 将校验后的同步设置写入新建的建筑
+func applyNoticeSyncSettings(building *base_models.Building, updates map[string]interface{}) {
+	if enabled, ok := updates["notice_sync_enabled"].(bool); ok {
+		building.NoticeSyncEnabled = &enabled
+	}
+	if interval, ok := updates["notice_sync_interval"].(int); ok {
+		building.NoticeSyncInterval = interval
+	}
+	if start, ok := updates["notice_sync_quiet_start"].(string); ok {
+		building.NoticeSyncQuietStart = start
+		building.NoticeSyncQuietEnd = updates["notice_sync_quiet_end"].(string)
+	}
+	if mapping, ok := updates["notice_type_mapping"].(datatypes.JSON); ok {
+		building.NoticeTypeMapping = mapping
+	}
+	if endTime, ok := updates["notice_sync_end_time"].(time.Time); ok {
+		building.NoticeSyncEndTime = &endTime
+	}
+}
+
+// noticeSourcesJSON 校验通知来源并转换为 JSON，空列表表示使用默认来源
+func noticeSourcesJSON(names []string) (datatypes.JSON, error) {
+	sources, err := base_services.ParseNoticeSources(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(sources)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
 // 4.Delete 删除建筑
 // @Summary      删除建筑
 // @Description  删除一个或多个建筑
@@ -871,13 +1026,15 @@ func (c *BuildingController) SyncNotice() {
 
 // 7.ManualSyncNotice 手动同步建筑通知
 // @Summary      手动同步建筑通知
-// @Description  手动触发建筑通知同步过程
+// @Description  手动触发建筑通知同步过程，dryRun=true 时只返回计划的变更及原因，不写入任何数据
 // @Tags         Building
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "建筑ID" example:"1"
-// @Success      200  {object}  map[string]interface{} "同步结果详情"
+// @Param        dryRun query bool false "是否试运行" default(false)
+// @Success      200  {object}  map[string]interface{} "同步结果详情或试运行计划"
 // @Failure      400  {object}  map[string]interface{} "无效的建筑ID"
+// @Failure      409  {object}  map[string]interface{} "该建筑正在同步（定时同步或其他实例）"
 // @Failure      500  {object}  map[string]interface{} "服务器内部错误"
 // @Router       /admin/building/{id}/sync_notice [post]
 // @Security     BearerAuth
@@ -888,8 +1045,18 @@ func (c *BuildingController) ManualSyncNotice() {
 		return
 	}
 
+	dryRun, err := strconv.ParseBool(c.Ctx.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		c.Ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid dryRun"})
+		return
+	}
+
 	claims := c.Ctx.MustGet("claims").(jwt.MapClaims)
-	result, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).ManualSyncBuildingNotices(uint(id), claims)
+	result, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).ManualSyncBuildingNotices(uint(id), claims, dryRun)
+	if errors.Is(err, base_services.ErrNoticeSyncInProgress) {
+		c.Ctx.JSON(http.StatusConflict, gin.H{"error": err.Error(), "message": "building notices are being synced, please retry later"})
+		return
+	}
 	if err != nil {
 		c.Ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -897,3 +1064,77 @@ func (c *BuildingController) ManualSyncNotice() {
 
 	c.Ctx.JSON(http.StatusOK, result)
 }
+
+// GetUpstream 比对通知来源的建筑列表与本地建筑
+// @Summary      比对来源建筑
+// @Description  获取所有通知来源的建筑列表，返回尚未导入的来源建筑与 IsmartID 在其通知来源中不存在的本地建筑，不更新建筑的标记；获取失败的来源见 sourceErrors
+// @Tags         Building
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "未导入的来源建筑与来源中不存在的本地建筑"
+// @Failure      500  {object}  map[string]interface{} "服务器内部错误"
+// @Router       /admin/building_upstream [get]
+// @Security     BearerAuth
+func (c *BuildingController) GetUpstream() {
+	result, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).DiscoverBuildings()
+	if err != nil {
+		c.Ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(http.StatusOK, gin.H{"data": result, "message": "Get upstream buildings success"})
+}
+
+// CheckUpstream 比对通知来源的建筑列表并更新本地建筑的标记
+// @Summary      更新来源缺失标记
+// @Description  与比对来源建筑相同，并将 IsmartID 在其通知来源中不存在的本地建筑保存到建筑的 upstreamMissing，只更新标记有变化的建筑；获取失败的来源沿用原标记
+// @Tags         Building
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "比对结果与标记有变化的建筑数 updatedCount"
+// @Failure      500  {object}  map[string]interface{} "服务器内部错误"
+// @Router       /admin/building_upstream/check [post]
+// @Security     BearerAuth
+func (c *BuildingController) CheckUpstream() {
+	result, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).RefreshUpstreamMissing()
+	if err != nil {
+		c.Ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(http.StatusOK, gin.H{"data": result, "message": "Check upstream buildings success"})
+}
+
+// ImportUpstream 从通知来源批量导入建筑
+// @Summary      导入来源建筑
+// @Description  为通知来源中尚无本地建筑的建筑创建建筑，名称与位置取自来源；不传 ismartIds 时导入该来源的全部未导入建筑
+// @Tags         Building
+// @Accept       json
+// @Produce      json
+// @Param        source body string true "通知来源(ismart/local)" example:"ismart"
+// @Param        ismartIds body []string false "要导入的来源建筑ID" example:"[\"0314100\"]"
+// @Success      200  {object}  map[string]interface{} "创建的建筑，以及已存在、来源中不存在与失败的建筑ID"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Failure      500  {object}  map[string]interface{} "来源不可用或服务器内部错误"
+// @Router       /admin/building_upstream/import [post]
+// @Security     BearerAuth
+func (c *BuildingController) ImportUpstream() {
+	var form struct {
+		Source    string   `json:"source" binding:"required" example:"ismart"`
+		IsmartIDs []string `json:"ismartIds" example:"0314100"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !field.IsValidNoticeSource(form.Source) {
+		c.Ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid notice source: %s", form.Source)})
+		return
+	}
+
+	result, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).ImportBuildings(field.NoticeSource(form.Source), form.IsmartIDs)
+	if err != nil {
+		c.Ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(http.StatusOK, gin.H{"data": result, "message": "Import upstream buildings completed"})
+}
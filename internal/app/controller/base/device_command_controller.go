@@ -0,0 +1,214 @@
+package http_base_controller
+
+import (
+	"strconv"
+	"time"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceDeviceCommandController interface {
+	Enqueue()
+	Get()
+	GetOne()
+}
+
+type DeviceCommandController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewDeviceCommandController(ctx *gin.Context, container *container.ServiceContainer) *DeviceCommandController {
+	return &DeviceCommandController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncDeviceCommand returns a gin.HandlerFunc for the specified method
+func HandleFuncDeviceCommand(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "enqueue":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceCommandController(ctx, container)
+			controller.Enqueue()
+		}
+	case "get":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceCommandController(ctx, container)
+			controller.Get()
+		}
+	case "getOne":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceCommandController(ctx, container)
+			controller.GetOne()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// Enqueue 下发设备命令
+// @Summary      下发设备命令
+// @Description  向单个设备、设备分组或建筑下的所有设备下发命令（reboot, refresh, screenshot, clear_cache）
+// @Tags         DeviceCommand
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "targetType, targetId, command, payload, ttlSeconds"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_command [post]
+// @Security     JWT
+func (c *DeviceCommandController) Enqueue() {
+	var form struct {
+		TargetType string                 `json:"targetType" binding:"required"`
+		TargetID   uint                   `json:"targetId" binding:"required"`
+		Command    string                 `json:"command" binding:"required"`
+		Payload    map[string]interface{} `json:"payload"`
+		TTLSeconds int                    `json:"ttlSeconds"`
+	}
+
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	if !field.IsValidCommandTarget(form.TargetType) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid targetType"})
+		return
+	}
+	if !field.IsValidDeviceCommandType(form.Command) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid command"})
+		return
+	}
+
+	issuedBy, _ := c.Ctx.Value("email").(string)
+
+	commands, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).Enqueue(
+		field.CommandTarget(form.TargetType),
+		form.TargetID,
+		field.DeviceCommandType(form.Command),
+		form.Payload,
+		time.Duration(form.TTLSeconds)*time.Second,
+		issuedBy,
+	)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "enqueue command failed",
+		})
+		return
+	}
+
+	batchID := ""
+	if len(commands) > 0 {
+		batchID = commands[0].BatchID
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "enqueue command success",
+		"data": gin.H{
+			"batchId":  batchID,
+			"count":    len(commands),
+			"commands": commands,
+		},
+	})
+}
+
+// Get 获取命令历史
+// @Summary      获取设备命令历史
+// @Tags         DeviceCommand
+// @Produce      json
+// @Param        deviceId query int false "设备ID"
+// @Param        batchId query string false "批次ID"
+// @Param        status query string false "状态: queued, delivered, succeeded, failed, expired"
+// @Param        command query string false "命令"
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/device_command [get]
+// @Security     JWT
+func (c *DeviceCommandController) Get() {
+	var searchQuery struct {
+		DeviceID uint   `form:"deviceId"`
+		BatchID  string `form:"batchId"`
+		Status   string `form:"status"`
+		Command  string `form:"command"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"deviceId": searchQuery.DeviceID,
+		"batchId":  searchQuery.BatchID,
+		"status":   searchQuery.Status,
+		"command":  searchQuery.Command,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	commands, paginationResult, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).Get(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       commands,
+		"pagination": paginationResult,
+	})
+}
+
+// GetOne 获取单条命令
+// @Summary      获取设备命令详情
+// @Tags         DeviceCommand
+// @Produce      json
+// @Param        id path int true "命令ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /admin/device_command/{id} [get]
+// @Security     JWT
+func (c *DeviceCommandController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid command ID"})
+		return
+	}
+
+	command, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get command success",
+		"data":    command,
+	})
+}
@@ -1,12 +1,17 @@
 package http_base_controller
 
 import (
+	"errors"
+	"io"
 	"strconv"
+	"time"
 
 	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
 	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
 	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
 	"github.com/The-Healthist/iboard_http_service/pkg/utils"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,6 +30,17 @@ type InterfaceDeviceController interface {
 	HealthTest()
 	PrintersHealthCheck()
 	PrintersCallback()
+	SubscribeEvents()
+	GetSettings()
+	GetEffectiveSettings()
+	FetchCommands()
+	ReportCommandResult()
+	UploadScreenshot()
+	GetScreenshots()
+	ReportPrintJobs()
+	VerifyPrintPassword()
+	FetchPrintDispatches()
+	ReportAppUpdate()
 }
 
 type DeviceController struct {
@@ -112,6 +128,61 @@ func HandleFuncDevice(container *container.ServiceContainer, method string) gin.
 			controller := NewDeviceController(ctx, container)
 			controller.PrintersCallback()
 		}
+	case "subscribeEvents":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.SubscribeEvents()
+		}
+	case "getSettings":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.GetSettings()
+		}
+	case "getEffectiveSettings":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.GetEffectiveSettings()
+		}
+	case "reportAppUpdate":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.ReportAppUpdate()
+		}
+	case "fetchPrintDispatches":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.FetchPrintDispatches()
+		}
+	case "fetchCommands":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.FetchCommands()
+		}
+	case "reportCommandResult":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.ReportCommandResult()
+		}
+	case "uploadScreenshot":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.UploadScreenshot()
+		}
+	case "verifyPrintPassword":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.VerifyPrintPassword()
+		}
+	case "reportPrintJobs":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.ReportPrintJobs()
+		}
+	case "getScreenshots":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceController(ctx, container)
+			controller.GetScreenshots()
+		}
 	case "getTopAdCarousel":
 		return func(ctx *gin.Context) { NewDeviceController(ctx, container).GetTopAdCarousel() }
 	case "updateTopAdCarousel":
@@ -151,7 +222,7 @@ func HandleFuncDevice(container *container.ServiceContainer, method string) gin.
 // @Param        settings.appUpdateDuration formData int false "应用更新间隔(秒)" example:"600"
 // @Param        settings.advertisementPlayDuration formData int false "广告播放时长(秒)" example:"30"
 // @Param        settings.noticeStayDuration formData int false "通知停留时长(秒)" example:"5"
-// @Param        settings.printPassWord formData string false "打印密码" example:"1090119"
+// @Param        settings.printPassWord formData string false "打印密码，4-16 位数字，保存为设备级哈希密码，不会在响应中返回"
 // @Param        settings.bottomCarouselDuration formData int false "底部轮播切换时间(秒)" example:"10"
 // @Param        settings.paymentTableOnePageDuration formData int false "缴费表格单页停留时间(秒)" example:"5"
 // @Param        settings.normalToAnnouncementCarouselDuration formData int false "正常播放到公告轮播时间(秒)" example:"10"
@@ -162,9 +233,9 @@ func HandleFuncDevice(container *container.ServiceContainer, method string) gin.
 // @Security     BearerAuth
 func (c *DeviceController) Create() {
 	var form struct {
-		DeviceID   string                `json:"deviceId" binding:"required" example:"DEV1001"`
-		BuildingID uint                  `json:"buildingId" binding:"required" example:"1"`
-		Settings   models.DeviceSettings `json:"settings"`
+		DeviceID   string             `json:"deviceId" binding:"required" example:"DEV1001"`
+		BuildingID uint               `json:"buildingId" binding:"required" example:"1"`
+		Settings   deviceSettingsForm `json:"settings"`
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -174,11 +245,17 @@ func (c *DeviceController) Create() {
 		})
 		return
 	}
+	if form.Settings.PrintPassWord != "" {
+		if err := base_services.ValidatePrintPassword(form.Settings.PrintPassWord); err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	device := &models.Device{
 		DeviceID:   form.DeviceID,
 		BuildingID: form.BuildingID,
-		Settings:   form.Settings,
+		Settings:   form.Settings.DeviceSettings,
 	}
 
 	if err := c.Container.GetService("device").(base_services.InterfaceDeviceService).Create(device); err != nil {
@@ -189,6 +266,16 @@ func (c *DeviceController) Create() {
 		return
 	}
 
+	if form.Settings.PrintPassWord != "" {
+		if err := c.setDevicePrintPassword(device.ID, form.Settings.PrintPassWord); err != nil {
+			c.Ctx.JSON(400, gin.H{
+				"error":   err.Error(),
+				"message": "device created but failed to set print password",
+			})
+			return
+		}
+	}
+
 	c.Ctx.JSON(200, gin.H{
 		"message": "create device success",
 		"data":    device,
@@ -202,6 +289,7 @@ func (c *DeviceController) Create() {
 // @Accept       json
 // @Produce      json
 // @Param        search query string false "搜索关键词" example:"DEV"
+// @Param        health query string false "健康状态: healthy, unhealthy, unknown"
 // @Param        pageSize query int false "每页数量" default(10)
 // @Param        pageNum query int false "页码" default(1)
 // @Param        desc query bool false "是否降序" default(true)
@@ -212,6 +300,7 @@ func (c *DeviceController) Create() {
 func (c *DeviceController) Get() {
 	var searchQuery struct {
 		Search string `form:"search" example:"DEV"`
+		Health string `form:"health"`
 	}
 	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
 		c.Ctx.JSON(400, gin.H{"error": err.Error()})
@@ -267,7 +356,7 @@ func (c *DeviceController) Get() {
 // @Param        settings.appUpdateDuration formData int false "应用更新间隔(秒)" example:"600"
 // @Param        settings.advertisementPlayDuration formData int false "广告播放时长(秒)" example:"30"
 // @Param        settings.noticeStayDuration formData int false "通知停留时长(秒)" example:"5"
-// @Param        settings.printPassWord formData string false "打印密码" example:"1090119"
+// @Param        settings.printPassWord formData string false "打印密码，4-16 位数字，保存为设备级哈希密码，不会在响应中返回"
 // @Param        settings.bottomCarouselDuration formData int false "底部轮播切换时间(秒)" example:"10"
 // @Param        settings.paymentTableOnePageDuration formData int false "缴费表格单页停留时间(秒)" example:"5"
 // @Param        settings.normalToAnnouncementCarouselDuration formData int false "正常播放到公告轮播时间(秒)" example:"10"
@@ -278,11 +367,11 @@ func (c *DeviceController) Get() {
 // @Security     BearerAuth
 func (c *DeviceController) Update() {
 	var form struct {
-		ID         uint                   `json:"id" binding:"required" example:"1"`
-		DeviceID   string                 `json:"deviceId" example:"DEV1001-UPDATED"`
-		BuildingID uint                   `json:"buildingId" example:"2"`
-		Settings   *models.DeviceSettings `json:"settings"`
-		Status     string                 `json:"status"` // 忽略status字段，设备状态由服务器自动管理
+		ID         uint                `json:"id" binding:"required" example:"1"`
+		DeviceID   string              `json:"deviceId" example:"DEV1001-UPDATED"`
+		BuildingID uint                `json:"buildingId" example:"2"`
+		Settings   *deviceSettingsForm `json:"settings"`
+		Status     string              `json:"status"` // 忽略status字段，设备状态由服务器自动管理
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -290,6 +379,14 @@ func (c *DeviceController) Update() {
 		return
 	}
 
+	// 打印密码改为设备级哈希密码，不再写入设备表
+	if form.Settings != nil && form.Settings.PrintPassWord != "" {
+		if err := c.setDevicePrintPassword(form.ID, form.Settings.PrintPassWord); err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 创建更新映射
 	updates := map[string]interface{}{}
 
@@ -332,10 +429,6 @@ func (c *DeviceController) Update() {
 			updates["advertisement_play_duration"] = 30 // 默认值
 		}
 
-		if form.Settings.PrintPassWord != "" {
-			updates["print_pass_word"] = form.Settings.PrintPassWord
-		}
-
 		if form.Settings.NoticeStayDuration > 0 {
 			updates["notice_stay_duration"] = form.Settings.NoticeStayDuration
 		} else if form.Settings.NoticeStayDuration == 0 {
@@ -506,6 +599,8 @@ func (c *DeviceController) CreateMany() {
 // @Produce      json
 // @Param        login body object true "登录信息"
 // @Param        deviceId formData string true "设备ID" example:"DEV1001"
+// @Param        appVersion formData string false "已安装的 App 版本" example:"1.3.0"
+// @Param        buildNumber formData string false "已安装的 App 构建号" example:"130"
 // @Success      200  {object}  map[string]interface{} "返回登录令牌和设备信息"
 // @Failure      400  {object}  map[string]interface{} "错误信息"
 // @Failure      401  {object}  map[string]interface{} "认证失败"
@@ -513,7 +608,9 @@ func (c *DeviceController) CreateMany() {
 // @Security     None
 func (c *DeviceController) Login() {
 	var form struct {
-		DeviceID string `json:"deviceId" binding:"required" example:"DEV1001"`
+		DeviceID    string `json:"deviceId" binding:"required" example:"DEV1001"`
+		AppVersion  string `json:"appVersion" example:"1.3.0"` // 已安装的 App 版本（可选）
+		BuildNumber string `json:"buildNumber" example:"130"`
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -534,6 +631,10 @@ func (c *DeviceController) Login() {
 		return
 	}
 
+	if err := c.Container.GetService("appFleet").(base_services.InterfaceAppFleetService).ReportInstalled(device, form.AppVersion, form.BuildNumber); err != nil {
+		log.Warn("记录设备已安装版本失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+	}
+
 	// Generate JWT token
 	token, err := c.Container.GetService("jwt").(base_services.IJWTService).GenerateDeviceToken(device)
 	if err != nil {
@@ -544,10 +645,22 @@ func (c *DeviceController) Login() {
 		return
 	}
 
+	// 使用按层级合并后的生效设置
+	effective, err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).ResolveEffectiveSettings(device)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{
+			"error":   err.Error(),
+			"message": "Failed to resolve device settings",
+		})
+		return
+	}
+	device.Settings = effective.Settings
+
 	c.Ctx.JSON(200, gin.H{
-		"message": "Login success",
-		"data":    device,
-		"token":   token,
+		"message":         "Login success",
+		"data":            device,
+		"token":           token,
+		"settingsSources": effective.Sources,
 	})
 }
 
@@ -695,11 +808,11 @@ func (c *DeviceController) GetDeviceFullAdvertisements() {
 
 // 10.HealthTest 设备健康测试
 // @Summary      10. 设备健康测试
-// @Description  设备上报健康状态，用于检测设备是否在线，更新设备最后活跃时间
+// @Description  设备上报健康状态，用于检测设备是否在线，更新设备最后活跃时间；可选携带 telemetry 遥测数据与已安装版本 appVersion、buildNumber
 // @Tags         Device
 // @Accept       json
 // @Produce      json
-// @Param        data body object false "健康测试数据"
+// @Param        data body object false "健康测试数据，可选字段 telemetry、appVersion、buildNumber"
 // @Success      200  {object}  map[string]interface{} "健康测试成功响应"
 // @Failure      400  {object}  map[string]interface{} "错误信息"
 // @Router       /device/client/health_test [post]
@@ -739,9 +852,46 @@ func (c *DeviceController) HealthTest() {
 		return
 	}
 
-	c.Ctx.JSON(200, gin.H{
-		"message": "Health check successful",
-	})
+	// 可选的遥测数据与已安装版本，旧版本设备不带请求体
+	var form struct {
+		Telemetry   *models.DeviceTelemetry `json:"telemetry"`
+		AppVersion  string                  `json:"appVersion"`
+		BuildNumber string                  `json:"buildNumber"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil && !errors.Is(err, io.EOF) {
+		log.Warn("心跳请求体解析失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+	}
+
+	appVersion := form.AppVersion
+	if appVersion == "" && form.Telemetry != nil && form.Telemetry.AppVersion != nil {
+		appVersion = *form.Telemetry.AppVersion
+	}
+	if err := c.Container.GetService("appFleet").(base_services.InterfaceAppFleetService).ReportInstalled(device, appVersion, form.BuildNumber); err != nil {
+		log.Warn("记录设备已安装版本失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+	}
+
+	var telemetry *models.DeviceTelemetry
+	if form.Telemetry != nil {
+		telemetry, err = c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).Record(device.ID, *form.Telemetry)
+		if err != nil {
+			log.Warn("记录设备遥测失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+		}
+	}
+
+	// 心跳响应中附带待执行的远程命令
+	commands, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).FetchPending(device.ID)
+	if err != nil {
+		commands = []models.DeviceCommand{}
+	}
+
+	response := gin.H{
+		"message":  "Health check successful",
+		"commands": commands,
+	}
+	if telemetry != nil {
+		response["health"] = telemetry.Health
+	}
+	c.Ctx.JSON(200, response)
 }
 
 // 11.GetTopAdCarousel 获取顶部广告轮播顺序
@@ -1222,6 +1372,8 @@ func (c *DeviceController) PrintersHealthCheck() {
 			Status       *string `json:"status"`
 			Reason       *string `json:"reason"`
 			MarkerLevels *string `json:"marker_levels"` // 新增：墨盒墨水量信息
+			MarkerNames  *string `json:"marker_names"`  // 墨盒名称，与 marker_levels 一一对应
+			MarkerColors *string `json:"marker_colors"` // 墨盒颜色
 		} `json:"printers"`
 	}
 
@@ -1272,6 +1424,12 @@ func (c *DeviceController) PrintersHealthCheck() {
 		if p.MarkerLevels != nil {
 			printerMap["marker_levels"] = *p.MarkerLevels
 		}
+		if p.MarkerNames != nil {
+			printerMap["marker_names"] = *p.MarkerNames
+		}
+		if p.MarkerColors != nil {
+			printerMap["marker_colors"] = *p.MarkerColors
+		}
 
 		printersInterface[i] = printerMap
 	}
@@ -1356,7 +1514,10 @@ func (c *DeviceController) PrintersCallback() {
 			Status       *string `json:"status"`
 			Reason       *string `json:"reason"`
 			MarkerLevels *string `json:"marker_levels"`
+			MarkerNames  *string `json:"marker_names"`
+			MarkerColors *string `json:"marker_colors"`
 		} `json:"printers"`
+		PrintJobs []base_services.PrintJobReport `json:"print_jobs"` // 可选，本次回调对应的打印任务
 	}
 
 	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
@@ -1418,6 +1579,8 @@ func (c *DeviceController) PrintersCallback() {
 			Status:       p.Status,
 			Reason:       p.Reason,
 			MarkerLevels: p.MarkerLevels,
+			MarkerNames:  p.MarkerNames,
+			MarkerColors: p.MarkerColors,
 		}
 	}
 
@@ -1430,12 +1593,455 @@ func (c *DeviceController) PrintersCallback() {
 		return
 	}
 
+	// 打印机信息更新后再记录打印任务，便于按 IP 关联打印机
+	recorded := 0
+	if len(form.PrintJobs) > 0 {
+		recorded, _, err = c.Container.GetService("printJob").(base_services.InterfacePrintJobService).Record(device.ID, form.PrintJobs)
+		if err != nil {
+			log.Warn("记录打印任务失败 | 设备ID: %d | 错误: %v", device.ID, err)
+		}
+	}
+
 	c.Ctx.JSON(200, gin.H{
 		"success":          true,
 		"message":          "Printers callback processed successfully",
 		"orange_pi_status": form.OrangePi.Status,
 		"summary": gin.H{
-			"updated": len(printers),
+			"updated":    len(printers),
+			"print_jobs": recorded,
 		},
 	})
 }
+
+// SubscribeEvents 设备事件推送 (SSE)
+// @Summary      订阅设备事件
+// @Description  设备客户端建立 SSE 长连接，实时接收 notices_changed、carousel_changed、settings_changed、app_update_available 事件
+// @Tags         Device
+// @Produce      text/event-stream
+// @Success      200  {string}  string "事件流"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/events [get]
+// @Security     JWT
+func (c *DeviceController) SubscribeEvents() {
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	eventService := c.Container.GetService("deviceEvent").(base_services.InterfaceDeviceEventService)
+	events, unsubscribe := eventService.Subscribe(device.ID, device.BuildingID)
+	defer unsubscribe()
+
+	c.Ctx.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Ctx.Writer.Header().Set("Connection", "keep-alive")
+	c.Ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+
+	// 首条事件告知设备连接已建立
+	c.Ctx.SSEvent("connected", gin.H{"deviceId": device.DeviceID, "buildingId": device.BuildingID})
+	c.Ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventService.HeartbeatInterval())
+	defer heartbeat.Stop()
+
+	c.Ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Ctx.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.Ctx.SSEvent(string(event.Type), event)
+			return true
+		case <-heartbeat.C:
+			c.Ctx.SSEvent("ping", gin.H{"timestamp": time.Now().Unix()})
+			return true
+		}
+	})
+}
+
+// GetSettings 设备获取生效设置
+// @Summary      获取设备生效设置
+// @Description  按 默认值 -> 全局 -> 建筑 -> 分组 -> 设备 逐级合并后的设置，sources 标明每个字段的来源
+// @Tags         Device
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "生效设置"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/settings [get]
+// @Security     JWT
+func (c *DeviceController) GetSettings() {
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	effective, err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).ResolveEffectiveSettings(device)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Get settings success", "data": effective})
+}
+
+// GetEffectiveSettings 管理员查看设备生效设置
+// @Summary      查看设备生效设置
+// @Tags         Device
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Success      200  {object}  map[string]interface{} "生效设置"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/device/{id}/settings [get]
+// @Security     JWT
+func (c *DeviceController) GetEffectiveSettings() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	effective, err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).ResolveEffectiveSettingsByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Get settings success", "data": effective})
+}
+
+// FetchCommands 设备拉取待执行命令
+// @Summary      拉取待执行命令
+// @Description  返回该设备排队中的命令，返回后命令状态变为 delivered
+// @Tags         Device
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "命令列表"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/commands [get]
+// @Security     JWT
+func (c *DeviceController) FetchCommands() {
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	commands, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).FetchPending(device.ID)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Fetch commands success", "data": commands})
+}
+
+// FetchPrintDispatches 设备拉取管理端下发的打印任务
+// @Summary      拉取待打印任务
+// @Description  返回该设备排队中的打印任务，返回后状态变为 delivered；打印完成后通过打印回调的 print_jobs 上报 job_id 与结果
+// @Tags         Device
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "打印任务列表"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/print_dispatches [get]
+// @Security     JWT
+func (c *DeviceController) FetchPrintDispatches() {
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	dispatches, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).FetchPending(device.ID)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 与推送到香橙派的内容一致，设备可直接转发给打印桥接服务
+	payloads := make([]base_services.PrintDispatchPayload, 0, len(dispatches))
+	for _, dispatch := range dispatches {
+		payloads = append(payloads, base_services.NewPrintDispatchPayload(dispatch))
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Fetch print dispatches success", "data": payloads})
+}
+
+// ReportAppUpdate 设备上报版本更新结果
+// @Summary      上报版本更新结果
+// @Description  失败次数或失败率超过灰度发布的限制时，灰度自动暂停或回滚
+// @Tags         Device
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "versionId/versionNumber, fromVersion, success, errorReason"
+// @Success      200  {object}  map[string]interface{} "更新记录"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/app_update [post]
+// @Security     JWT
+func (c *DeviceController) ReportAppUpdate() {
+	var form base_services.AppUpdateReport
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	attempt, err := c.Container.GetService("appRollout").(base_services.InterfaceAppRolloutService).ReportUpdate(device, form)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Report app update success", "data": attempt})
+}
+
+// ReportCommandResult 设备回报命令执行结果
+// @Summary      回报命令执行结果
+// @Tags         Device
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "commandId, success, result"
+// @Success      200  {object}  map[string]interface{} "更新后的命令"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/commands/result [post]
+// @Security     JWT
+func (c *DeviceController) ReportCommandResult() {
+	var form struct {
+		CommandID uint   `json:"commandId" binding:"required"`
+		Success   *bool  `json:"success" binding:"required"`
+		Result    string `json:"result"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	command, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).ReportResult(device.ID, form.CommandID, *form.Success, form.Result)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Report command result success", "data": command})
+}
+
+// UploadScreenshot 设备上传屏幕截图
+// @Summary      上传屏幕截图
+// @Description  设备上传当前屏幕截图（png/jpeg/webp），服务端保留最近若干张；由 screenshot 命令触发时传入 commandId，命令自动标记为成功
+// @Tags         Device
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "截图文件"
+// @Param        trigger formData string false "触发方式: command, periodic, manual"
+// @Param        commandId formData int false "对应的命令ID"
+// @Success      200  {object}  map[string]interface{} "截图记录"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/screenshot [post]
+// @Security     JWT
+func (c *DeviceController) UploadScreenshot() {
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	screenshotService := c.Container.GetService("deviceScreenshot").(base_services.InterfaceDeviceScreenshotService)
+
+	fileHeader, err := c.Ctx.FormFile("file")
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > screenshotService.MaxSize() {
+		c.Ctx.JSON(400, gin.H{"error": "screenshot too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, screenshotService.MaxSize()+1))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var commandID *uint
+	if raw := c.Ctx.PostForm("commandId"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.Ctx.JSON(400, gin.H{"error": "invalid commandId"})
+			return
+		}
+		cid := uint(id)
+		commandID = &cid
+	}
+
+	screenshot, err := screenshotService.Upload(device.ID, content, c.Ctx.PostForm("trigger"), commandID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if commandID != nil {
+		// 截图命令以上传成功作为完成标志
+		if _, err := c.Container.GetService("deviceCommand").(base_services.InterfaceDeviceCommandService).ReportResult(device.ID, *commandID, true, screenshot.File.Path); err != nil {
+			log.Warn("截图命令回报失败 | 设备ID: %d | 命令ID: %d | 错误: %v", device.ID, *commandID, err)
+		}
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Upload screenshot success", "data": screenshot})
+}
+
+// GetScreenshots 获取设备截图历史
+// @Summary      获取设备截图历史
+// @Tags         Device
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Success      200  {object}  map[string]interface{} "截图列表，按时间倒序"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/device/{id}/screenshots [get]
+// @Security     JWT
+func (c *DeviceController) GetScreenshots() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	screenshots, err := c.Container.GetService("deviceScreenshot").(base_services.InterfaceDeviceScreenshotService).GetHistory(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Get screenshots success", "data": screenshots})
+}
+
+// ReportPrintJobs 设备上报打印任务
+// @Summary      上报打印任务
+// @Description  设备批量上报打印任务（打印的通知或文件、打印机、页数、份数、结果与失败原因），带 job_id 的重复上报会被忽略
+// @Tags         Device
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "print_jobs"
+// @Success      200  {object}  map[string]interface{} "新增与重复数量"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /device/client/print_jobs [post]
+// @Security     JWT
+func (c *DeviceController) ReportPrintJobs() {
+	var form struct {
+		PrintJobs []base_services.PrintJobReport `json:"print_jobs" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	recorded, duplicates, err := c.Container.GetService("printJob").(base_services.InterfacePrintJobService).Record(device.ID, form.PrintJobs)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Report print jobs success",
+		"data": gin.H{
+			"recorded":   recorded,
+			"duplicates": duplicates,
+		},
+	})
+}
+
+// VerifyPrintPassword 设备校验打印密码
+// @Summary      校验打印密码
+// @Description  服务端校验用户输入的打印密码并记录结果，连续失败超过上限后暂时锁定
+// @Tags         Device
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "password"
+// @Success      200  {object}  map[string]interface{} "校验结果"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Failure      429  {object}  map[string]interface{} "失败次数过多"
+// @Router       /device/client/print_password/verify [post]
+// @Security     JWT
+func (c *DeviceController) VerifyPrintPassword() {
+	var form struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, ok := c.currentDevice()
+	if !ok {
+		return
+	}
+
+	result, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Verify(device.ID, form.Password, c.Ctx.ClientIP())
+	if errors.Is(err, base_services.ErrPrintPasswordLocked) {
+		c.Ctx.JSON(429, gin.H{"error": err.Error(), "data": result})
+		return
+	}
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Verify print password success", "data": result})
+}
+
+// deviceSettingsForm 设备设置表单，兼容旧客户端提交的 settings.printPassWord
+type deviceSettingsForm struct {
+	models.DeviceSettings
+	PrintPassWord string `json:"printPassWord"`
+}
+
+// setDevicePrintPassword 将表单中的打印密码保存为设备级哈希密码
+func (c *DeviceController) setDevicePrintPassword(deviceID uint, password string) error {
+	updatedBy, _ := c.Ctx.Value("email").(string)
+	_, _, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Set(
+		field.PrintPasswordScopeDevice, deviceID, password, nil, updatedBy)
+	return err
+}
+
+// currentDevice 从设备 JWT 中解析当前设备，失败时已写入响应
+func (c *DeviceController) currentDevice() (*models.Device, bool) {
+	claims, exists := c.Ctx.Get("claims")
+	if !exists {
+		c.Ctx.JSON(401, gin.H{"error": "No token claims found"})
+		return nil, false
+	}
+
+	claimsMap, ok := claims.(map[string]interface{})
+	if !ok {
+		c.Ctx.JSON(401, gin.H{"error": "Invalid token claims format"})
+		return nil, false
+	}
+
+	deviceId, ok := claimsMap["deviceId"].(string)
+	if !ok {
+		c.Ctx.JSON(401, gin.H{"error": "Invalid device ID format"})
+		return nil, false
+	}
+
+	device, err := c.Container.GetService("device").(base_services.InterfaceDeviceService).GetByDeviceID(deviceId)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+
+	return device, true
+}
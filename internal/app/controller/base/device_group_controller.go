@@ -0,0 +1,331 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceDeviceGroupController interface {
+	Create()
+	Get()
+	Update()
+	Delete()
+	GetOne()
+	AssignDevices()
+	RemoveDevices()
+}
+
+type DeviceGroupController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewDeviceGroupController(ctx *gin.Context, container *container.ServiceContainer) *DeviceGroupController {
+	return &DeviceGroupController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncDeviceGroup returns a gin.HandlerFunc for the specified method
+func HandleFuncDeviceGroup(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "create":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.Create()
+		}
+	case "get":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.Get()
+		}
+	case "update":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.Update()
+		}
+	case "delete":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.Delete()
+		}
+	case "getOne":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.GetOne()
+		}
+	case "assignDevices":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.AssignDevices()
+		}
+	case "removeDevices":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceGroupController(ctx, container)
+			controller.RemoveDevices()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// Create 创建设备分组
+// @Summary      创建设备分组
+// @Tags         DeviceGroup
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "name, description, buildingId(可选)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_group [post]
+// @Security     JWT
+func (c *DeviceGroupController) Create() {
+	var form struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		BuildingID  *uint  `json:"buildingId"`
+	}
+
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	group := &models.DeviceGroup{
+		Name:        form.Name,
+		Description: form.Description,
+		BuildingID:  form.BuildingID,
+	}
+
+	if err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).Create(group); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "create device group failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "create device group success",
+		"data":    group,
+	})
+}
+
+// Get 获取设备分组列表
+// @Summary      获取设备分组列表
+// @Tags         DeviceGroup
+// @Produce      json
+// @Param        search query string false "搜索关键词"
+// @Param        buildingId query int false "建筑ID"
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/device_group [get]
+// @Security     JWT
+func (c *DeviceGroupController) Get() {
+	var searchQuery struct {
+		Search     string `form:"search"`
+		BuildingID uint   `form:"buildingId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"search":     searchQuery.Search,
+		"buildingId": searchQuery.BuildingID,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	groups, paginationResult, err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).Get(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       groups,
+		"pagination": paginationResult,
+	})
+}
+
+// Update 更新设备分组
+// @Summary      更新设备分组
+// @Tags         DeviceGroup
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "id, name, description"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_group [put]
+// @Security     JWT
+func (c *DeviceGroupController) Update() {
+	var form struct {
+		ID          uint    `json:"id" binding:"required"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if form.Name != nil {
+		updates["name"] = *form.Name
+	}
+	if form.Description != nil {
+		updates["description"] = *form.Description
+	}
+
+	group, err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).Update(form.ID, updates)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "update device group success",
+		"data":    group,
+	})
+}
+
+// Delete 删除设备分组
+// @Summary      删除设备分组
+// @Tags         DeviceGroup
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "ids"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_group [delete]
+// @Security     JWT
+func (c *DeviceGroupController) Delete() {
+	var form struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).Delete(form.IDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "delete device group success"})
+}
+
+// GetOne 获取单个设备分组（包含设备列表）
+// @Summary      获取设备分组详情
+// @Tags         DeviceGroup
+// @Produce      json
+// @Param        id path int true "分组ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /admin/device_group/{id} [get]
+// @Security     JWT
+func (c *DeviceGroupController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device group ID"})
+		return
+	}
+
+	group, err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get device group success",
+		"data":    group,
+	})
+}
+
+// AssignDevices 将设备加入分组
+// @Summary      设备加入分组
+// @Tags         DeviceGroup
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "groupId, deviceIds"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_group/assign [post]
+// @Security     JWT
+func (c *DeviceGroupController) AssignDevices() {
+	var form struct {
+		GroupID   uint   `json:"groupId" binding:"required"`
+		DeviceIDs []uint `json:"deviceIds" binding:"required,min=1"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).AssignDevices(form.GroupID, form.DeviceIDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "assign devices success"})
+}
+
+// RemoveDevices 将设备移出分组
+// @Summary      设备移出分组
+// @Tags         DeviceGroup
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "groupId, deviceIds"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_group/remove [post]
+// @Security     JWT
+func (c *DeviceGroupController) RemoveDevices() {
+	var form struct {
+		GroupID   uint   `json:"groupId" binding:"required"`
+		DeviceIDs []uint `json:"deviceIds" binding:"required,min=1"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("deviceGroup").(base_services.InterfaceDeviceGroupService).RemoveDevices(form.GroupID, form.DeviceIDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "remove devices success"})
+}
@@ -0,0 +1,156 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceDeviceSettingsController interface {
+	GetProfiles()
+	SaveProfile()
+	DeleteProfile()
+}
+
+type DeviceSettingsController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewDeviceSettingsController(ctx *gin.Context, container *container.ServiceContainer) *DeviceSettingsController {
+	return &DeviceSettingsController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncDeviceSettings returns a gin.HandlerFunc for the specified method
+func HandleFuncDeviceSettings(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getProfiles":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceSettingsController(ctx, container)
+			controller.GetProfiles()
+		}
+	case "saveProfile":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceSettingsController(ctx, container)
+			controller.SaveProfile()
+		}
+	case "deleteProfile":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceSettingsController(ctx, container)
+			controller.DeleteProfile()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// GetProfiles 获取设备设置配置列表
+// @Summary      获取设备设置配置列表
+// @Tags         DeviceSettings
+// @Produce      json
+// @Param        scope query string false "层级: global, building, group, device"
+// @Param        targetId query int false "目标ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/settings_profile [get]
+// @Security     JWT
+func (c *DeviceSettingsController) GetProfiles() {
+	var query struct {
+		Scope    string `form:"scope"`
+		TargetID *uint  `form:"targetId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if query.Scope != "" && !field.IsValidSettingsScope(query.Scope) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid scope"})
+		return
+	}
+
+	profiles, err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).GetProfiles(query.Scope, query.TargetID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get settings profiles success",
+		"data":    profiles,
+	})
+}
+
+// SaveProfile 创建或更新设备设置配置
+// @Summary      保存设备设置配置
+// @Description  settings 只需包含要覆盖的字段，值为 null 表示移除该层级的覆盖；replace 为 true 时整体替换
+// @Tags         DeviceSettings
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "scope, targetId, settings, replace"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/settings_profile [put]
+// @Security     JWT
+func (c *DeviceSettingsController) SaveProfile() {
+	var form struct {
+		Scope    string                 `json:"scope" binding:"required"`
+		TargetID uint                   `json:"targetId"`
+		Settings map[string]interface{} `json:"settings" binding:"required"`
+		Replace  bool                   `json:"replace"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	profile, err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).SaveProfile(
+		field.SettingsScope(form.Scope), form.TargetID, form.Settings, form.Replace)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "save settings profile failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "save settings profile success",
+		"data":    profile,
+	})
+}
+
+// DeleteProfile 删除设备设置配置
+// @Summary      删除设备设置配置
+// @Tags         DeviceSettings
+// @Produce      json
+// @Param        id path int true "配置ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/settings_profile/{id} [delete]
+// @Security     JWT
+func (c *DeviceSettingsController) DeleteProfile() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid profile ID"})
+		return
+	}
+
+	if err := c.Container.GetService("deviceSettings").(base_services.InterfaceDeviceSettingsService).DeleteProfile(uint(id)); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "delete settings profile success"})
+}
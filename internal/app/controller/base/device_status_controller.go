@@ -0,0 +1,160 @@
+package http_base_controller
+
+import (
+	"strconv"
+	"time"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceDeviceStatusController interface {
+	GetEvents()
+	GetDeviceUptime()
+	GetBuildingUptime()
+}
+
+type DeviceStatusController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewDeviceStatusController(ctx *gin.Context, container *container.ServiceContainer) *DeviceStatusController {
+	return &DeviceStatusController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncDeviceStatus returns a gin.HandlerFunc for the specified method
+func HandleFuncDeviceStatus(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getEvents":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceStatusController(ctx, container)
+			controller.GetEvents()
+		}
+	case "getDeviceUptime":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceStatusController(ctx, container)
+			controller.GetDeviceUptime()
+		}
+	case "getBuildingUptime":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceStatusController(ctx, container)
+			controller.GetBuildingUptime()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// GetEvents 获取设备上下线记录
+// @Summary      获取设备上下线记录
+// @Tags         DeviceStatus
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 7 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device/{id}/status_events [get]
+// @Security     JWT
+func (c *DeviceStatusController) GetEvents() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	events, err := c.Container.GetService("deviceStatus").(base_services.InterfaceDeviceStatusService).GetEvents(uint(id), from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get status events success",
+		"data":    events,
+	})
+}
+
+// GetDeviceUptime 获取设备在线率
+// @Summary      获取设备在线率
+// @Description  统计时间范围内的在线率、离线次数与最长离线时长
+// @Tags         DeviceStatus
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 7 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device/{id}/uptime [get]
+// @Security     JWT
+func (c *DeviceStatusController) GetDeviceUptime() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	report, err := c.Container.GetService("deviceStatus").(base_services.InterfaceDeviceStatusService).GetDeviceUptime(uint(id), from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get device uptime success",
+		"data":    report,
+	})
+}
+
+// GetBuildingUptime 获取建筑设备在线率
+// @Summary      获取建筑设备在线率
+// @Description  汇总建筑下所有设备的在线率、离线次数与最长离线时长，并返回每台设备的明细
+// @Tags         DeviceStatus
+// @Produce      json
+// @Param        id path int true "建筑ID"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 7 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/building/{id}/uptime [get]
+// @Security     JWT
+func (c *DeviceStatusController) GetBuildingUptime() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid building ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	report, err := c.Container.GetService("deviceStatus").(base_services.InterfaceDeviceStatusService).GetBuildingUptime(uint(id), from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get building uptime success",
+		"data":    report,
+	})
+}
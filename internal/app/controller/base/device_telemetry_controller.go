@@ -0,0 +1,313 @@
+package http_base_controller
+
+import (
+	"strconv"
+	"time"
+
+	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfaceDeviceTelemetryController interface {
+	GetHistory()
+	GetRules()
+	CreateRule()
+	UpdateRule()
+	DeleteRules()
+}
+
+type DeviceTelemetryController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewDeviceTelemetryController(ctx *gin.Context, container *container.ServiceContainer) *DeviceTelemetryController {
+	return &DeviceTelemetryController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncDeviceTelemetry returns a gin.HandlerFunc for the specified method
+func HandleFuncDeviceTelemetry(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getHistory":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceTelemetryController(ctx, container)
+			controller.GetHistory()
+		}
+	case "getRules":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceTelemetryController(ctx, container)
+			controller.GetRules()
+		}
+	case "createRule":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceTelemetryController(ctx, container)
+			controller.CreateRule()
+		}
+	case "updateRule":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceTelemetryController(ctx, container)
+			controller.UpdateRule()
+		}
+	case "deleteRules":
+		return func(ctx *gin.Context) {
+			controller := NewDeviceTelemetryController(ctx, container)
+			controller.DeleteRules()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// parseTimeRange 解析 from/to 查询参数，支持 RFC3339 与 2006-01-02，默认最近 24 小时
+func parseTimeRange(ctx *gin.Context, defaultSpan time.Duration) (time.Time, time.Time, bool) {
+	parse := func(v string, endOfDay bool) (time.Time, error) {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		return t, nil
+	}
+
+	to := time.Now()
+	if v := ctx.Query("to"); v != "" {
+		t, err := parse(v, true)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid to"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultSpan)
+	if v := ctx.Query("from"); v != "" {
+		t, err := parse(v, false)
+		if err != nil {
+			ctx.JSON(400, gin.H{"error": "invalid from"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		ctx.JSON(400, gin.H{"error": "from must be before to"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// GetHistory 获取设备遥测
+// @Summary      获取设备遥测
+// @Description  返回设备最新遥测、健康状态以及降采样历史（默认最近 24 小时）
+// @Tags         DeviceTelemetry
+// @Produce      json
+// @Param        id path int true "设备ID"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device/{id}/telemetry [get]
+// @Security     JWT
+func (c *DeviceTelemetryController) GetHistory() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid device ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 24*time.Hour)
+	if !ok {
+		return
+	}
+
+	device, err := c.Container.GetService("device").(base_services.InterfaceDeviceService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": "Device not found"})
+		return
+	}
+
+	samples, err := c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).GetHistory(device.ID, from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get telemetry success",
+		"data": gin.H{
+			"deviceId": device.ID,
+			"latest":   device.Telemetry,
+			"from":     from,
+			"to":       to,
+			"samples":  samples,
+		},
+	})
+}
+
+// GetRules 获取设备健康规则
+// @Summary      获取设备健康规则
+// @Description  未配置任何规则时使用内置规则（温度 > 80、存储剩余 < 10%、内存剩余 < 5%）
+// @Tags         DeviceTelemetry
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /admin/device_health_rule [get]
+// @Security     JWT
+func (c *DeviceTelemetryController) GetRules() {
+	rules, err := c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).GetRules()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get health rules success",
+		"data":    rules,
+	})
+}
+
+// CreateRule 创建设备健康规则
+// @Summary      创建设备健康规则
+// @Tags         DeviceTelemetry
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "name, metric, operator, threshold, enabled, description"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_health_rule [post]
+// @Security     JWT
+func (c *DeviceTelemetryController) CreateRule() {
+	var form struct {
+		Name        string  `json:"name" binding:"required"`
+		Metric      string  `json:"metric" binding:"required"`
+		Operator    string  `json:"operator" binding:"required"`
+		Threshold   float64 `json:"threshold"`
+		Enabled     *bool   `json:"enabled"`
+		Description string  `json:"description"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	rule := &models.DeviceHealthRule{
+		Name:        form.Name,
+		Metric:      field.TelemetryMetric(form.Metric),
+		Operator:    field.RuleOperator(form.Operator),
+		Threshold:   form.Threshold,
+		Enabled:     form.Enabled == nil || *form.Enabled,
+		Description: form.Description,
+	}
+
+	if err := c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).CreateRule(rule); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "create health rule failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "create health rule success",
+		"data":    rule,
+	})
+}
+
+// UpdateRule 更新设备健康规则
+// @Summary      更新设备健康规则
+// @Tags         DeviceTelemetry
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "id, name, metric, operator, threshold, enabled, description"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_health_rule [put]
+// @Security     JWT
+func (c *DeviceTelemetryController) UpdateRule() {
+	var form struct {
+		ID          uint     `json:"id" binding:"required"`
+		Name        *string  `json:"name"`
+		Metric      *string  `json:"metric"`
+		Operator    *string  `json:"operator"`
+		Threshold   *float64 `json:"threshold"`
+		Enabled     *bool    `json:"enabled"`
+		Description *string  `json:"description"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if form.Name != nil {
+		updates["name"] = *form.Name
+	}
+	if form.Metric != nil {
+		updates["metric"] = *form.Metric
+	}
+	if form.Operator != nil {
+		updates["operator"] = *form.Operator
+	}
+	if form.Threshold != nil {
+		updates["threshold"] = *form.Threshold
+	}
+	if form.Enabled != nil {
+		updates["enabled"] = *form.Enabled
+	}
+	if form.Description != nil {
+		updates["description"] = *form.Description
+	}
+
+	rule, err := c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).UpdateRule(form.ID, updates)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "update health rule success",
+		"data":    rule,
+	})
+}
+
+// DeleteRules 删除设备健康规则
+// @Summary      删除设备健康规则
+// @Tags         DeviceTelemetry
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "ids"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/device_health_rule [delete]
+// @Security     JWT
+func (c *DeviceTelemetryController) DeleteRules() {
+	var form struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("deviceTelemetry").(base_services.InterfaceDeviceTelemetryService).DeleteRules(form.IDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "delete health rules success"})
+}
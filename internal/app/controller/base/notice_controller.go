@@ -81,6 +81,11 @@ func HandleFuncNotice(container *container.ServiceContainer, method string) gin.
 			controller := NewNoticeController(ctx, container)
 			controller.GetOne()
 		}
+	case "resetOverrides":
+		return func(ctx *gin.Context) {
+			controller := NewNoticeController(ctx, container)
+			controller.ResetOverrides()
+		}
 	case "syncCreateWithFile":
 		return func(ctx *gin.Context) {
 			controller := NewNoticeController(ctx, container)
@@ -646,6 +651,53 @@ func (c *NoticeController) GetOne() {
 	})
 }
 
+// ResetOverrides 取消 iSmart 通知的本地修改
+// @Summary      取消通知的本地修改
+// @Description  取消 iSmart 通知被本地修改的字段（title、type、endTime、priority、file），下次同步重新使用来源的标题、类型与文件；不传 fields 时取消全部
+// @Tags         Notice
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "通知ID"
+// @Param        fields body object false "要取消的字段列表 fields，为空时取消全部"
+// @Success      200  {object}  map[string]interface{} "返回更新后的通知信息"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice/{id}/reset_overrides [post]
+// @Security     BearerAuth
+func (c *NoticeController) ResetOverrides() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid notice ID"})
+		return
+	}
+
+	var form struct {
+		Fields []field.NoticeSyncField `json:"fields" example:"title,file"`
+	}
+	if c.Ctx.Request.ContentLength > 0 {
+		if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for _, f := range form.Fields {
+		if !field.IsValidNoticeSyncField(string(f)) {
+			c.Ctx.JSON(400, gin.H{"error": fmt.Sprintf("invalid field: %s", f)})
+			return
+		}
+	}
+
+	notice, err := c.Container.GetService("notice").(base_services.InterfaceNoticeService).ResetOverrides(uint(id), form.Fields)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "reset notice overrides success",
+		"data":    notice,
+	})
+}
+
 type SyncCreateNoticeRequest struct {
 	Title       string           `json:"title" binding:"required" example:"系统维护通知"`
 	Description string           `json:"description" example:"系统升级说明"`
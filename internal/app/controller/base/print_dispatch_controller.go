@@ -0,0 +1,288 @@
+package http_base_controller
+
+import (
+	"strconv"
+	"time"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfacePrintDispatchController interface {
+	Create()
+	Get()
+	GetOne()
+	Push()
+	Cancel()
+}
+
+type PrintDispatchController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewPrintDispatchController(ctx *gin.Context, container *container.ServiceContainer) *PrintDispatchController {
+	return &PrintDispatchController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncPrintDispatch returns a gin.HandlerFunc for the specified method
+func HandleFuncPrintDispatch(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "create":
+		return func(ctx *gin.Context) {
+			controller := NewPrintDispatchController(ctx, container)
+			controller.Create()
+		}
+	case "get":
+		return func(ctx *gin.Context) {
+			controller := NewPrintDispatchController(ctx, container)
+			controller.Get()
+		}
+	case "getOne":
+		return func(ctx *gin.Context) {
+			controller := NewPrintDispatchController(ctx, container)
+			controller.GetOne()
+		}
+	case "push":
+		return func(ctx *gin.Context) {
+			controller := NewPrintDispatchController(ctx, container)
+			controller.Push()
+		}
+	case "cancel":
+		return func(ctx *gin.Context) {
+			controller := NewPrintDispatchController(ctx, container)
+			controller.Cancel()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// printDispatchForm 发起打印的请求参数
+type printDispatchForm struct {
+	PrinterID    uint   `json:"printerId" binding:"required"`
+	NoticeID     *uint  `json:"noticeId"`
+	FileID       *uint  `json:"fileId"`
+	Copies       int    `json:"copies"`
+	DeliveryMode string `json:"deliveryMode"` // pull（默认）或 push
+	TTL          int    `json:"ttl"`          // 秒，0 使用默认值
+}
+
+// toRequest 转换为服务层参数
+func (f printDispatchForm) toRequest(issuedBy string) base_services.PrintDispatchRequest {
+	return base_services.PrintDispatchRequest{
+		PrinterID:    f.PrinterID,
+		NoticeID:     f.NoticeID,
+		FileID:       f.FileID,
+		Copies:       f.Copies,
+		DeliveryMode: field.PrintDeliveryMode(f.DeliveryMode),
+		TTL:          time.Duration(f.TTL) * time.Second,
+		IssuedBy:     issuedBy,
+	}
+}
+
+// Create 下发打印任务
+// @Summary      下发打印任务
+// @Description  将通知或 PDF 文件下发到指定打印机所在设备的香橙派打印；pull 由设备拉取，push 直接推送到设备记录的香橙派地址
+// @Tags         PrintDispatch
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "printerId, noticeId/fileId, copies, deliveryMode, ttl"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_dispatch [post]
+// @Security     JWT
+func (c *PrintDispatchController) Create() {
+	var form printDispatchForm
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	issuedBy, _ := c.Ctx.Value("email").(string)
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Dispatch(form.toRequest(issuedBy), nil)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "dispatch print job failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "dispatch print job success",
+		"data":    dispatch,
+	})
+}
+
+// Get 获取下发的打印任务列表
+// @Summary      获取下发的打印任务列表
+// @Tags         PrintDispatch
+// @Produce      json
+// @Param        deviceId query int false "设备ID"
+// @Param        buildingId query int false "建筑ID"
+// @Param        printerId query int false "打印机ID"
+// @Param        noticeId query int false "通知ID"
+// @Param        status query string false "状态: queued, delivered, succeeded, failed, expired, canceled"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_dispatch [get]
+// @Security     JWT
+func (c *PrintDispatchController) Get() {
+	queryMap, paginationMap, ok := bindPrintDispatchQuery(c.Ctx)
+	if !ok {
+		return
+	}
+
+	dispatches, paginationResult, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Get(queryMap, paginationMap, nil)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       dispatches,
+		"pagination": paginationResult,
+	})
+}
+
+// bindPrintDispatchQuery 解析打印任务列表的查询与分页参数
+func bindPrintDispatchQuery(ctx *gin.Context) (map[string]interface{}, map[string]interface{}, bool) {
+	var searchQuery struct {
+		DeviceID   uint   `form:"deviceId"`
+		BuildingID uint   `form:"buildingId"`
+		PrinterID  uint   `form:"printerId"`
+		NoticeID   uint   `form:"noticeId"`
+		Status     string `form:"status"`
+	}
+	if err := ctx.ShouldBindQuery(&searchQuery); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := ctx.ShouldBindQuery(&pagination); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	queryMap := map[string]interface{}{
+		"deviceId":   searchQuery.DeviceID,
+		"buildingId": searchQuery.BuildingID,
+		"printerId":  searchQuery.PrinterID,
+		"noticeId":   searchQuery.NoticeID,
+		"status":     searchQuery.Status,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+	return queryMap, paginationMap, true
+}
+
+// GetOne 获取下发的打印任务详情
+// @Summary      获取下发的打印任务详情
+// @Tags         PrintDispatch
+// @Produce      json
+// @Param        id path int true "下发ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_dispatch/{id} [get]
+// @Security     JWT
+func (c *PrintDispatchController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print dispatch ID"})
+		return
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).GetByID(uint(id), nil)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get print dispatch success",
+		"data":    dispatch,
+	})
+}
+
+// Push 重新推送打印任务到香橙派
+// @Summary      重新推送打印任务
+// @Description  只能推送排队中的任务；推送失败时任务保持排队，设备仍可拉取
+// @Tags         PrintDispatch
+// @Produce      json
+// @Param        id path int true "下发ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_dispatch/{id}/push [post]
+// @Security     JWT
+func (c *PrintDispatchController) Push() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print dispatch ID"})
+		return
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Push(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "push print dispatch success",
+		"data":    dispatch,
+	})
+}
+
+// Cancel 取消打印任务
+// @Summary      取消打印任务
+// @Description  只能取消尚未送达设备的任务
+// @Tags         PrintDispatch
+// @Produce      json
+// @Param        id path int true "下发ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_dispatch/{id}/cancel [post]
+// @Security     JWT
+func (c *PrintDispatchController) Cancel() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print dispatch ID"})
+		return
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Cancel(uint(id), nil)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "cancel print dispatch success",
+		"data":    dispatch,
+	})
+}
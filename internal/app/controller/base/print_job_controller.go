@@ -0,0 +1,260 @@
+package http_base_controller
+
+import (
+	"strconv"
+	"time"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfacePrintJobController interface {
+	Get()
+	GetOne()
+	GetStats()
+	GetFailureReport()
+}
+
+type PrintJobController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewPrintJobController(ctx *gin.Context, container *container.ServiceContainer) *PrintJobController {
+	return &PrintJobController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncPrintJob returns a gin.HandlerFunc for the specified method
+func HandleFuncPrintJob(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "get":
+		return func(ctx *gin.Context) {
+			controller := NewPrintJobController(ctx, container)
+			controller.Get()
+		}
+	case "getOne":
+		return func(ctx *gin.Context) {
+			controller := NewPrintJobController(ctx, container)
+			controller.GetOne()
+		}
+	case "getStats":
+		return func(ctx *gin.Context) {
+			controller := NewPrintJobController(ctx, container)
+			controller.GetStats()
+		}
+	case "getFailureReport":
+		return func(ctx *gin.Context) {
+			controller := NewPrintJobController(ctx, container)
+			controller.GetFailureReport()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// Get 获取打印任务列表
+// @Summary      获取打印任务列表
+// @Tags         PrintJob
+// @Produce      json
+// @Param        deviceId query int false "设备ID"
+// @Param        buildingId query int false "建筑ID"
+// @Param        printerId query int false "打印机ID"
+// @Param        noticeId query int false "通知ID"
+// @Param        status query string false "结果: succeeded, failed"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 30 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_job [get]
+// @Security     JWT
+func (c *PrintJobController) Get() {
+	var searchQuery struct {
+		DeviceID   uint   `form:"deviceId"`
+		BuildingID uint   `form:"buildingId"`
+		PrinterID  uint   `form:"printerId"`
+		NoticeID   uint   `form:"noticeId"`
+		Status     string `form:"status"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"deviceId":   searchQuery.DeviceID,
+		"buildingId": searchQuery.BuildingID,
+		"printerId":  searchQuery.PrinterID,
+		"noticeId":   searchQuery.NoticeID,
+		"status":     searchQuery.Status,
+		"from":       from,
+		"to":         to,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	jobs, paginationResult, err := c.Container.GetService("printJob").(base_services.InterfacePrintJobService).Get(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       jobs,
+		"pagination": paginationResult,
+	})
+}
+
+// GetOne 获取单条打印任务
+// @Summary      获取打印任务详情
+// @Tags         PrintJob
+// @Produce      json
+// @Param        id path int true "打印任务ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /admin/print_job/{id} [get]
+// @Security     JWT
+func (c *PrintJobController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print job ID"})
+		return
+	}
+
+	job, err := c.Container.GetService("printJob").(base_services.InterfacePrintJobService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get print job success",
+		"data":    job,
+	})
+}
+
+// GetStats 打印量统计
+// @Summary      打印量统计
+// @Description  按建筑、设备或打印机统计打印任务数、成功打印页数与失败率
+// @Tags         PrintJob
+// @Produce      json
+// @Param        groupBy query string false "分组: building, device, printer，默认 building"
+// @Param        buildingId query int false "建筑ID，不传统计所有建筑"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 30 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_job/stats [get]
+// @Security     JWT
+func (c *PrintJobController) GetStats() {
+	var query struct {
+		GroupBy    string `form:"groupBy"`
+		BuildingID uint   `form:"buildingId"`
+	}
+	query.GroupBy = string(field.PrintStatsByBuilding)
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !field.IsValidPrintStatsGroup(query.GroupBy) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid groupBy"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	report, err := c.Container.GetService("printJob").(base_services.InterfacePrintJobService).GetStats(field.PrintStatsGroup(query.GroupBy), from, to, query.BuildingID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get print stats success",
+		"data":    report,
+	})
+}
+
+// GetFailureReport 打印失败率报表
+// @Summary      打印失败率报表
+// @Description  列出失败率不低于 minFailureRate 的建筑、设备或打印机，按失败率倒序，并附最常见的失败原因
+// @Tags         PrintJob
+// @Produce      json
+// @Param        groupBy query string false "分组: building, device, printer，默认 printer"
+// @Param        buildingId query int false "建筑ID，不传统计所有建筑"
+// @Param        minJobs query int false "最少任务数，默认 5"
+// @Param        minFailureRate query number false "最低失败率（%），默认 10"
+// @Param        from query string false "开始时间，RFC3339 或 2006-01-02，默认 30 天前"
+// @Param        to query string false "结束时间，RFC3339 或 2006-01-02，默认当前时间"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_job/failures [get]
+// @Security     JWT
+func (c *PrintJobController) GetFailureReport() {
+	var query struct {
+		GroupBy        string  `form:"groupBy"`
+		BuildingID     uint    `form:"buildingId"`
+		MinJobs        int64   `form:"minJobs"`
+		MinFailureRate float64 `form:"minFailureRate"`
+	}
+	query.GroupBy = string(field.PrintStatsByPrinter)
+	query.MinJobs = 5
+	query.MinFailureRate = 10
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if !field.IsValidPrintStatsGroup(query.GroupBy) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid groupBy"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	report, err := c.Container.GetService("printJob").(base_services.InterfacePrintJobService).GetFailureReport(
+		field.PrintStatsGroup(query.GroupBy), from, to, query.BuildingID, query.MinJobs, query.MinFailureRate)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get print failure report success",
+		"data":    report,
+	})
+}
@@ -0,0 +1,286 @@
+package http_base_controller
+
+import (
+	"errors"
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+type InterfacePrintPasswordController interface {
+	Get()
+	Set()
+	Rotate()
+	Delete()
+	GetAttempts()
+}
+
+type PrintPasswordController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewPrintPasswordController(ctx *gin.Context, container *container.ServiceContainer) *PrintPasswordController {
+	return &PrintPasswordController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncPrintPassword returns a gin.HandlerFunc for the specified method
+func HandleFuncPrintPassword(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "get":
+		return func(ctx *gin.Context) {
+			controller := NewPrintPasswordController(ctx, container)
+			controller.Get()
+		}
+	case "set":
+		return func(ctx *gin.Context) {
+			controller := NewPrintPasswordController(ctx, container)
+			controller.Set()
+		}
+	case "rotate":
+		return func(ctx *gin.Context) {
+			controller := NewPrintPasswordController(ctx, container)
+			controller.Rotate()
+		}
+	case "delete":
+		return func(ctx *gin.Context) {
+			controller := NewPrintPasswordController(ctx, container)
+			controller.Delete()
+		}
+	case "getAttempts":
+		return func(ctx *gin.Context) {
+			controller := NewPrintPasswordController(ctx, container)
+			controller.GetAttempts()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// Get 获取打印密码配置列表
+// @Summary      获取打印密码配置列表
+// @Description  只返回层级、版本与轮换信息，不包含密码或哈希
+// @Tags         PrintPassword
+// @Produce      json
+// @Param        scope query string false "层级: global, building, device"
+// @Param        targetId query int false "目标ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_password [get]
+// @Security     JWT
+func (c *PrintPasswordController) Get() {
+	var query struct {
+		Scope    string `form:"scope"`
+		TargetID *uint  `form:"targetId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if query.Scope != "" && !field.IsValidPrintPasswordScope(query.Scope) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid scope"})
+		return
+	}
+
+	records, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Get(query.Scope, query.TargetID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get print passwords success",
+		"data":    records,
+	})
+}
+
+// Set 设置打印密码
+// @Summary      设置打印密码
+// @Description  为全局、建筑或单个设备设置打印密码，password 为空时随机生成；明文只在本次响应中返回
+// @Tags         PrintPassword
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "scope, targetId, password, rotationDays"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /admin/print_password [put]
+// @Security     JWT
+func (c *PrintPasswordController) Set() {
+	var form struct {
+		Scope        string `json:"scope" binding:"required"`
+		TargetID     uint   `json:"targetId"`
+		Password     string `json:"password"`
+		RotationDays *int   `json:"rotationDays"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "invalid form",
+		})
+		return
+	}
+
+	updatedBy, _ := c.Ctx.Value("email").(string)
+
+	record, password, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Set(
+		field.PrintPasswordScope(form.Scope), form.TargetID, form.Password, form.RotationDays, updatedBy)
+	if errors.Is(err, base_services.ErrPrintPasswordConflict) {
+		c.Ctx.JSON(409, gin.H{
+			"error":   err.Error(),
+			"message": "set print password failed",
+		})
+		return
+	}
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{
+			"error":   err.Error(),
+			"message": "set print password failed",
+		})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "set print password success",
+		"data": gin.H{
+			"printPassword": record,
+			"password":      password,
+		},
+	})
+}
+
+// Rotate 立即轮换打印密码
+// @Summary      轮换打印密码
+// @Description  随机生成新密码，版本加 1；明文只在本次响应中返回
+// @Tags         PrintPassword
+// @Produce      json
+// @Param        id path int true "打印密码ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /admin/print_password/{id}/rotate [post]
+// @Security     JWT
+func (c *PrintPasswordController) Rotate() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print password ID"})
+		return
+	}
+
+	updatedBy, _ := c.Ctx.Value("email").(string)
+
+	record, password, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Rotate(uint(id), updatedBy)
+	if errors.Is(err, base_services.ErrPrintPasswordConflict) {
+		c.Ctx.JSON(409, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "rotate print password success",
+		"data": gin.H{
+			"printPassword": record,
+			"password":      password,
+		},
+	})
+}
+
+// Delete 删除打印密码配置
+// @Summary      删除打印密码配置
+// @Description  删除建筑或设备级密码后回退到上一级；全局密码不能删除
+// @Tags         PrintPassword
+// @Accept       json
+// @Produce      json
+// @Param        data body object true "ids"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_password [delete]
+// @Security     JWT
+func (c *PrintPasswordController) Delete() {
+	var form struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.Ctx.ShouldBindJSON(&form); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).Delete(form.IDs); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "delete print password success"})
+}
+
+// GetAttempts 获取打印密码校验记录
+// @Summary      获取打印密码校验记录
+// @Tags         PrintPassword
+// @Produce      json
+// @Param        deviceId query int false "设备ID"
+// @Param        buildingId query int false "建筑ID"
+// @Param        success query bool false "是否校验成功"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/print_password/attempts [get]
+// @Security     JWT
+func (c *PrintPasswordController) GetAttempts() {
+	var searchQuery struct {
+		DeviceID   uint  `form:"deviceId"`
+		BuildingID uint  `form:"buildingId"`
+		Success    *bool `form:"success"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"deviceId":   searchQuery.DeviceID,
+		"buildingId": searchQuery.BuildingID,
+		"success":    searchQuery.Success,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	attempts, paginationResult, err := c.Container.GetService("printPassword").(base_services.InterfacePrintPasswordService).GetAttempts(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       attempts,
+		"pagination": paginationResult,
+	})
+}
@@ -2,6 +2,7 @@ package http_base_controller
 
 import (
 	"strconv"
+	"time"
 
 	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
 	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
@@ -16,6 +17,10 @@ type InterfacePrinterController interface {
 	Update()
 	Delete()
 	GetOne()
+	GetSupplies()
+	GetSuppliesReport()
+	GetTimeline()
+	GetFlapping()
 }
 
 type PrinterController struct {
@@ -58,6 +63,26 @@ func HandleFuncPrinter(container *container.ServiceContainer, method string) gin
 			controller := NewPrinterController(ctx, container)
 			controller.GetOne()
 		}
+	case "getSupplies":
+		return func(ctx *gin.Context) {
+			controller := NewPrinterController(ctx, container)
+			controller.GetSupplies()
+		}
+	case "getSuppliesReport":
+		return func(ctx *gin.Context) {
+			controller := NewPrinterController(ctx, container)
+			controller.GetSuppliesReport()
+		}
+	case "getTimeline":
+		return func(ctx *gin.Context) {
+			controller := NewPrinterController(ctx, container)
+			controller.GetTimeline()
+		}
+	case "getFlapping":
+		return func(ctx *gin.Context) {
+			controller := NewPrinterController(ctx, container)
+			controller.GetFlapping()
+		}
 	default:
 		return func(ctx *gin.Context) {
 			ctx.JSON(400, gin.H{"error": "invalid method"})
@@ -256,3 +281,110 @@ func (c *PrinterController) GetOne() {
 		"data":    printer,
 	})
 }
+
+// GetSupplies 获取打印机墨盒信息与墨量历史
+func (c *PrinterController) GetSupplies() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid printer ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	markers, history, err := c.Container.GetService("printerSupply").(base_services.InterfacePrinterSupplyService).GetPrinterSupplies(uint(id), from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get printer supplies success",
+		"data": gin.H{
+			"printerId": id,
+			"markers":   markers,
+			"history":   history,
+		},
+	})
+}
+
+// GetSuppliesReport 需要补充耗材的打印机报表（所有建筑）
+func (c *PrinterController) GetSuppliesReport() {
+	var query struct {
+		ThresholdPercent float64 `form:"thresholdPercent"`
+		WithinDays       float64 `form:"withinDays"`
+		BuildingID       uint    `form:"buildingId"`
+	}
+	query.ThresholdPercent = 20
+	query.WithinDays = 14
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := c.Container.GetService("printerSupply").(base_services.InterfacePrinterSupplyService).GetSuppliesReport(query.ThresholdPercent, query.WithinDays, query.BuildingID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get supplies report success",
+		"data":    items,
+		"total":   len(items),
+	})
+}
+
+// GetTimeline 获取打印机状态时间线
+func (c *PrinterController) GetTimeline() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid printer ID"})
+		return
+	}
+
+	from, to, ok := parseTimeRange(c.Ctx, 7*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	timeline, err := c.Container.GetService("printerStatus").(base_services.InterfacePrinterStatusService).GetTimeline(uint(id), from, to)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get printer timeline success",
+		"data":    timeline,
+	})
+}
+
+// GetFlapping 频繁抖动的打印机列表
+func (c *PrinterController) GetFlapping() {
+	var query struct {
+		WindowMinutes int   `form:"windowMinutes"`
+		Threshold     int64 `form:"threshold"`
+		BuildingID    uint  `form:"buildingId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&query); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	items, err := c.Container.GetService("printerStatus").(base_services.InterfacePrinterStatusService).GetFlapping(
+		time.Duration(query.WindowMinutes)*time.Minute, query.Threshold, query.BuildingID)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Get flapping printers success",
+		"data":    items,
+		"total":   len(items),
+	})
+}
@@ -0,0 +1,251 @@
+package http_base_controller
+
+import (
+	"strconv"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+// SyncRunController 通知同步记录控制器
+type SyncRunController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+// NewSyncRunController 创建控制器
+func NewSyncRunController(ctx *gin.Context, container *container.ServiceContainer) *SyncRunController {
+	return &SyncRunController{Ctx: ctx, Container: container}
+}
+
+// HandleFuncSyncRun 根据方法返回处理函数
+func HandleFuncSyncRun(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "get":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).Get() }
+	case "getOne":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).GetOne() }
+	case "getBuildingHistory":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).GetBuildingHistory() }
+	case "getFailingBuildings":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).GetFailingBuildings() }
+	case "getStatus":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).GetStatus() }
+	case "dryRun":
+		return func(ctx *gin.Context) { NewSyncRunController(ctx, container).DryRun() }
+	default:
+		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
+	}
+}
+
+// Get 获取通知同步记录列表
+// @Summary      获取通知同步记录列表
+// @Description  每次定时或手动同步为一条记录，列表不含建筑明细
+// @Tags         NoticeSync
+// @Produce      json
+// @Param        trigger query string false "触发方式 scheduled/manual"
+// @Param        status query string false "状态 running/success/partial/failed"
+// @Param        buildingId query int false "包含该建筑的同步"
+// @Param        pageSize query int false "每页数量" default(10)
+// @Param        pageNum query int false "页码" default(1)
+// @Param        desc query bool false "是否降序" default(true)
+// @Success      200  {object}  map[string]interface{} "返回同步记录和分页信息"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice_sync/runs [get]
+// @Security     BearerAuth
+func (c *SyncRunController) Get() {
+	var searchQuery struct {
+		Trigger    string `form:"trigger"`
+		Status     string `form:"status"`
+		BuildingID uint   `form:"buildingId"`
+	}
+	if err := c.Ctx.ShouldBindQuery(&searchQuery); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if searchQuery.Trigger != "" && !field.IsValidSyncRunTrigger(searchQuery.Trigger) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid trigger"})
+		return
+	}
+	if searchQuery.Status != "" && !field.IsValidSyncRunStatus(searchQuery.Status) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid status"})
+		return
+	}
+
+	pagination := struct {
+		PageSize int  `form:"pageSize"`
+		PageNum  int  `form:"pageNum"`
+		Desc     bool `form:"desc"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+		Desc:     true,
+	}
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if pagination.PageSize <= 0 || pagination.PageNum <= 0 {
+		c.Ctx.JSON(400, gin.H{"error": "invalid pagination"})
+		return
+	}
+
+	queryMap := map[string]interface{}{
+		"trigger":    searchQuery.Trigger,
+		"status":     searchQuery.Status,
+		"buildingId": searchQuery.BuildingID,
+	}
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+		"desc":     pagination.Desc,
+	}
+
+	runs, paginationResult, err := c.Container.GetService("syncRun").(base_services.InterfaceSyncRunService).Get(queryMap, paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": runs, "pagination": paginationResult})
+}
+
+// GetOne 获取通知同步记录详情
+// @Summary      获取通知同步记录详情
+// @Description  包含每个建筑的同步统计、失败通知、耗时与错误
+// @Tags         NoticeSync
+// @Produce      json
+// @Param        id path int true "同步记录ID"
+// @Success      200  {object}  map[string]interface{} "返回同步记录"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice_sync/runs/{id} [get]
+// @Security     BearerAuth
+func (c *SyncRunController) GetOne() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	run, err := c.Container.GetService("syncRun").(base_services.InterfaceSyncRunService).GetByID(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": run, "message": "Get sync run success"})
+}
+
+// GetBuildingHistory 获取建筑的通知同步历史
+// @Summary      获取建筑的通知同步历史
+// @Tags         NoticeSync
+// @Produce      json
+// @Param        id path int true "建筑ID"
+// @Param        pageSize query int false "每页数量" default(10)
+// @Param        pageNum query int false "页码" default(1)
+// @Success      200  {object}  map[string]interface{} "返回同步结果和分页信息，最新的在前"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/building/{id}/sync_history [get]
+// @Security     BearerAuth
+func (c *SyncRunController) GetBuildingHistory() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	pagination := struct {
+		PageSize int `form:"pageSize"`
+		PageNum  int `form:"pageNum"`
+	}{
+		PageSize: 10,
+		PageNum:  1,
+	}
+	if err := c.Ctx.ShouldBindQuery(&pagination); err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if pagination.PageSize <= 0 || pagination.PageNum <= 0 {
+		c.Ctx.JSON(400, gin.H{"error": "invalid pagination"})
+		return
+	}
+
+	paginationMap := map[string]interface{}{
+		"pageSize": pagination.PageSize,
+		"pageNum":  pagination.PageNum,
+	}
+
+	records, paginationResult, err := c.Container.GetService("syncRun").(base_services.InterfaceSyncRunService).GetBuildingHistory(uint(id), paginationMap)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": records, "pagination": paginationResult})
+}
+
+// GetFailingBuildings 获取连续同步失败的建筑
+// @Summary      获取连续同步失败的建筑
+// @Description  最近 n 次同步均失败（整个建筑同步出错，不含部分通知失败）的建筑
+// @Tags         NoticeSync
+// @Produce      json
+// @Param        n query int false "连续失败次数，默认 NOTICE_SYNC_FAILURE_THRESHOLD(3)"
+// @Success      200  {object}  map[string]interface{} "返回建筑与最近的同步结果"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice_sync/failing_buildings [get]
+// @Security     BearerAuth
+func (c *SyncRunController) GetFailingBuildings() {
+	n, err := strconv.Atoi(c.Ctx.DefaultQuery("n", "0"))
+	if err != nil || n < 0 {
+		c.Ctx.JSON(400, gin.H{"error": "invalid n"})
+		return
+	}
+
+	buildings, err := c.Container.GetService("syncRun").(base_services.InterfaceSyncRunService).GetFailingBuildings(n)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": buildings, "message": "Get failing buildings success"})
+}
+
+// GetStatus 获取通知同步状态
+// @Summary      获取通知同步状态
+// @Description  返回各上游主机熔断器的实时状态（closed/open/half_open）与最近一次同步
+// @Tags         NoticeSync
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "返回熔断器状态与最近一次同步"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice_sync/status [get]
+// @Security     BearerAuth
+func (c *SyncRunController) GetStatus() {
+	status, err := c.Container.GetService("syncRun").(base_services.InterfaceSyncRunService).GetStatus()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": status, "message": "Get notice sync status success"})
+}
+
+// DryRun 试运行定时同步
+// @Summary      试运行定时同步
+// @Description  对定时同步的所有建筑推演计划的创建、绑定、更新、解绑与设备轮播变更及原因，不写入 MySQL、Redis 与 OSS
+// @Tags         NoticeSync
+// @Produce      json
+// @Success      200  {object}  map[string]interface{} "返回每个建筑的试运行计划"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/notice_sync/dry_run [post]
+// @Security     BearerAuth
+func (c *SyncRunController) DryRun() {
+	results, err := c.Container.GetService("noticeSync").(base_services.InterfaceNoticeSyncService).DryRunScheduledSync()
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": results, "message": "Dry run completed, nothing was changed"})
+}
@@ -7,6 +7,7 @@ import (
 	models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
 	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
 	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"github.com/gin-gonic/gin"
 )
 
@@ -36,6 +37,8 @@ func HandleFuncVersion(container *container.ServiceContainer, method string) gin
 		return func(ctx *gin.Context) { NewVersionController(ctx, container).Delete() }
 	case "getActive":
 		return func(ctx *gin.Context) { NewVersionController(ctx, container).GetActive() }
+	case "inspect":
+		return func(ctx *gin.Context) { NewVersionController(ctx, container).Inspect() }
 	default:
 		return func(ctx *gin.Context) { ctx.JSON(400, gin.H{"error": "invalid method"}) }
 	}
@@ -43,7 +46,7 @@ func HandleFuncVersion(container *container.ServiceContainer, method string) gin
 
 // Create 创建版本
 // @Summary      创建新版本
-// @Description  创建新的应用版本
+// @Description  创建新的应用版本；传 fileId（已上传的 APK）或 downloadUrl，服务端下载 APK 记录 SHA-256、大小、最低 API 级别并签名
 // @Tags         Version
 // @Accept       json
 // @Produce      json
@@ -194,16 +197,36 @@ func (c *VersionController) Delete() {
 
 // GetActive 获取活跃版本列表
 // @Summary      获取活跃版本列表
-// @Description  获取所有状态为活跃的版本
+// @Description  获取所有状态为活跃的版本；指定 channel 或 deviceId 时只返回该渠道（或设备实际生效的渠道）可见的版本
 // @Tags         Version
 // @Accept       json
 // @Produce      json
+// @Param        channel query string false "发布渠道: stable, beta, pilot"
+// @Param        deviceId query int false "设备ID"
 // @Success      200  {object}  map[string]interface{} "返回活跃版本列表"
 // @Failure      500  {object}  map[string]interface{} "错误信息"
 // @Router       /admin/versions/active [get]
 // @Security     BearerAuth
 func (c *VersionController) GetActive() {
-	versions, err := c.Container.GetService("version").(base_services.InterfaceVersionService).GetActiveVersions()
+	channel := field.ReleaseChannel(c.Ctx.Query("channel"))
+	if channel != "" && !field.IsValidReleaseChannel(string(channel)) {
+		c.Ctx.JSON(400, gin.H{"error": "invalid channel"})
+		return
+	}
+	if deviceIDStr := c.Ctx.Query("deviceId"); deviceIDStr != "" {
+		deviceID, err := strconv.ParseUint(deviceIDStr, 10, 32)
+		if err != nil {
+			c.Ctx.JSON(400, gin.H{"error": "invalid deviceId"})
+			return
+		}
+		channel, err = c.Container.GetService("appRelease").(base_services.InterfaceAppReleaseService).GetDeviceChannel(uint(deviceID))
+		if err != nil {
+			c.Ctx.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	versions, err := c.Container.GetService("version").(base_services.InterfaceVersionService).GetActiveVersions(channel)
 	if err != nil {
 		c.Ctx.JSON(500, gin.H{"error": err.Error()})
 		return
@@ -211,3 +234,29 @@ func (c *VersionController) GetActive() {
 
 	c.Ctx.JSON(200, gin.H{"data": versions, "message": "Get active versions success"})
 }
+
+// Inspect 重新读取 APK 元数据
+// @Summary      重新读取 APK 元数据
+// @Description  重新下载版本的 APK，更新 SHA-256、大小、包名、versionCode、最低 API 级别并重新签名，用于补全已有版本
+// @Tags         Version
+// @Produce      json
+// @Param        id path int true "版本ID"
+// @Success      200  {object}  map[string]interface{} "返回更新后的版本"
+// @Failure      400  {object}  map[string]interface{} "错误信息"
+// @Router       /admin/version/{id}/inspect [post]
+// @Security     BearerAuth
+func (c *VersionController) Inspect() {
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 32)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid id"})
+		return
+	}
+
+	version, err := c.Container.GetService("version").(base_services.InterfaceVersionService).Inspect(uint(id))
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"message": "Inspect version success", "data": version})
+}
@@ -0,0 +1,239 @@
+package building_admin_controllers
+
+import (
+	"strconv"
+	"time"
+
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
+	building_admin_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/building_admin"
+	container "github.com/The-Healthist/iboard_http_service/internal/domain/services/container"
+	relationship_service "github.com/The-Healthist/iboard_http_service/internal/domain/services/relationship"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+type BuildingAdminPrintDispatchController struct {
+	Ctx       *gin.Context
+	Container *container.ServiceContainer
+}
+
+func NewBuildingAdminPrintDispatchController(
+	ctx *gin.Context,
+	container *container.ServiceContainer,
+) *BuildingAdminPrintDispatchController {
+	return &BuildingAdminPrintDispatchController{
+		Ctx:       ctx,
+		Container: container,
+	}
+}
+
+// HandleFuncBuildingAdminPrintDispatch returns a gin.HandlerFunc for the specified method
+func HandleFuncBuildingAdminPrintDispatch(container *container.ServiceContainer, method string) gin.HandlerFunc {
+	switch method {
+	case "getPrinters":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingAdminPrintDispatchController(ctx, container)
+			controller.GetPrinters()
+		}
+	case "createPrintDispatch":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingAdminPrintDispatchController(ctx, container)
+			controller.CreatePrintDispatch()
+		}
+	case "getPrintDispatches":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingAdminPrintDispatchController(ctx, container)
+			controller.GetPrintDispatches()
+		}
+	case "getPrintDispatch":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingAdminPrintDispatchController(ctx, container)
+			controller.GetPrintDispatch()
+		}
+	case "cancelPrintDispatch":
+		return func(ctx *gin.Context) {
+			controller := NewBuildingAdminPrintDispatchController(ctx, container)
+			controller.CancelPrintDispatch()
+		}
+	default:
+		return func(ctx *gin.Context) {
+			ctx.JSON(400, gin.H{"error": "invalid method"})
+		}
+	}
+}
+
+// buildingIDs 获取当前建筑管理员管理的建筑ID
+func (c *BuildingAdminPrintDispatchController) buildingIDs() ([]uint, bool) {
+	email := c.Ctx.GetString("email")
+	if email == "" {
+		c.Ctx.JSON(401, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+
+	buildings, err := c.Container.GetService("buildingAdminBuilding").(relationship_service.InterfaceBuildingAdminBuildingService).GetBuildingsByAdminEmail(email)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// 非 nil 的空列表表示没有可管理的建筑
+	ids := make([]uint, 0, len(buildings))
+	for _, building := range buildings {
+		ids = append(ids, building.ID)
+	}
+	return ids, true
+}
+
+func (c *BuildingAdminPrintDispatchController) GetPrinters() {
+	buildingIDs, ok := c.buildingIDs()
+	if !ok {
+		return
+	}
+
+	buildingID, _ := strconv.ParseUint(c.Ctx.Query("buildingId"), 10, 64)
+
+	printers, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).GetPrinters(buildingIDs, uint(buildingID))
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": printers})
+}
+
+type CreatePrintDispatchRequest struct {
+	PrinterID    uint   `json:"printerId" binding:"required"`
+	NoticeID     *uint  `json:"noticeId"`
+	FileID       *uint  `json:"fileId"`
+	Copies       int    `json:"copies"`
+	DeliveryMode string `json:"deliveryMode"`
+	TTL          int    `json:"ttl"`
+}
+
+func (c *BuildingAdminPrintDispatchController) CreatePrintDispatch() {
+	buildingIDs, ok := c.buildingIDs()
+	if !ok {
+		return
+	}
+
+	var req CreatePrintDispatchRequest
+	if err := c.Ctx.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c.Ctx, err)
+		return
+	}
+
+	// 只能打印绑定到所管理建筑的文件，或自己上传的文件；通知由下发服务按打印机所在建筑校验
+	if req.NoticeID == nil && req.FileID != nil {
+		if _, err := c.Container.GetService("buildingAdminFile").(building_admin_services.InterfaceBuildingAdminFileService).GetByID(*req.FileID, c.Ctx.GetString("email")); err != nil {
+			c.Ctx.JSON(404, gin.H{"error": "file not found or no permission"})
+			return
+		}
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Dispatch(base_services.PrintDispatchRequest{
+		PrinterID:    req.PrinterID,
+		NoticeID:     req.NoticeID,
+		FileID:       req.FileID,
+		Copies:       req.Copies,
+		DeliveryMode: field.PrintDeliveryMode(req.DeliveryMode),
+		TTL:          time.Duration(req.TTL) * time.Second,
+		IssuedBy:     c.Ctx.GetString("email"),
+	}, buildingIDs)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Print job dispatched successfully",
+		"data":    dispatch,
+	})
+}
+
+func (c *BuildingAdminPrintDispatchController) GetPrintDispatches() {
+	buildingIDs, ok := c.buildingIDs()
+	if !ok {
+		return
+	}
+
+	// 处理分页参数
+	pageSize, _ := strconv.Atoi(c.Ctx.DefaultQuery("pageSize", "10"))
+	pageNum, _ := strconv.Atoi(c.Ctx.DefaultQuery("pageNum", "1"))
+	desc := c.Ctx.DefaultQuery("desc", "true") == "true"
+
+	// 处理查询参数
+	query := make(map[string]interface{})
+	for _, key := range []string{"buildingId", "printerId", "noticeId"} {
+		if value := c.Ctx.Query(key); value != "" {
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				query[key] = uint(id)
+			}
+		}
+	}
+	if status := c.Ctx.Query("status"); status != "" {
+		query["status"] = status
+	}
+
+	paginate := map[string]interface{}{
+		"pageSize": pageSize,
+		"pageNum":  pageNum,
+		"desc":     desc,
+	}
+
+	dispatches, pagination, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Get(query, paginate, buildingIDs)
+	if err != nil {
+		c.Ctx.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"data":       dispatches,
+		"pagination": pagination,
+	})
+}
+
+func (c *BuildingAdminPrintDispatchController) GetPrintDispatch() {
+	buildingIDs, ok := c.buildingIDs()
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print dispatch ID"})
+		return
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).GetByID(uint(id), buildingIDs)
+	if err != nil {
+		c.Ctx.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{"data": dispatch})
+}
+
+func (c *BuildingAdminPrintDispatchController) CancelPrintDispatch() {
+	buildingIDs, ok := c.buildingIDs()
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Ctx.Param("id"), 10, 64)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": "invalid print dispatch ID"})
+		return
+	}
+
+	dispatch, err := c.Container.GetService("printDispatch").(base_services.InterfacePrintDispatchService).Cancel(uint(id), buildingIDs)
+	if err != nil {
+		c.Ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Ctx.JSON(200, gin.H{
+		"message": "Print job canceled successfully",
+		"data":    dispatch,
+	})
+}
@@ -31,6 +31,7 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 	// Admin login
 	r.POST("/api/admin/login", http_base_controller.HandleFuncSuperAdmin(serviceContainer, "login"))
 	r.GET("/api/app/version", http_base_controller.HandleFuncApp(serviceContainer, "get"))
+	r.GET("/api/app/signing_key", http_base_controller.HandleFuncApp(serviceContainer, "getSigningKey"))
 
 	// Public notice sync endpoints - for old system integration
 	r.POST("/api/notice/sync/create", http_base_controller.HandleFuncNotice(serviceContainer, "syncCreateWithFile"))
@@ -84,6 +85,7 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		adminGroup.GET("/notice/:id", http_base_controller.HandleFuncNotice(serviceContainer, "getOne"))
 		adminGroup.PUT("/notice", http_base_controller.HandleFuncNotice(serviceContainer, "update"))
 		adminGroup.DELETE("/notice", http_base_controller.HandleFuncNotice(serviceContainer, "delete"))
+		adminGroup.POST("/notice/:id/reset_overrides", http_base_controller.HandleFuncNotice(serviceContainer, "resetOverrides"))
 
 		// Building routes
 		adminGroup.POST("/building", http_base_controller.HandleFuncBuilding(serviceContainer, "create"))
@@ -92,6 +94,16 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		adminGroup.PUT("/building", http_base_controller.HandleFuncBuilding(serviceContainer, "update"))
 		adminGroup.DELETE("/building", http_base_controller.HandleFuncBuilding(serviceContainer, "delete"))
 		adminGroup.POST("/building/:id/sync_notice", http_base_controller.HandleFuncBuilding(serviceContainer, "manualSyncNotice"))
+		adminGroup.GET("/building_upstream", http_base_controller.HandleFuncBuilding(serviceContainer, "getUpstream"))
+		adminGroup.POST("/building_upstream/check", http_base_controller.HandleFuncBuilding(serviceContainer, "checkUpstream"))
+		adminGroup.POST("/building_upstream/import", http_base_controller.HandleFuncBuilding(serviceContainer, "importUpstream"))
+		adminGroup.GET("/building/:id/sync_history", http_base_controller.HandleFuncSyncRun(serviceContainer, "getBuildingHistory"))
+		adminGroup.GET("/notice_sync/runs", http_base_controller.HandleFuncSyncRun(serviceContainer, "get"))
+		adminGroup.GET("/notice_sync/runs/:id", http_base_controller.HandleFuncSyncRun(serviceContainer, "getOne"))
+		adminGroup.GET("/notice_sync/failing_buildings", http_base_controller.HandleFuncSyncRun(serviceContainer, "getFailingBuildings"))
+		adminGroup.GET("/notice_sync/status", http_base_controller.HandleFuncSyncRun(serviceContainer, "getStatus"))
+		adminGroup.POST("/notice_sync/dry_run", http_base_controller.HandleFuncSyncRun(serviceContainer, "dryRun"))
+		adminGroup.GET("/building/:id/uptime", http_base_controller.HandleFuncDeviceStatus(serviceContainer, "getBuildingUptime"))
 
 		// Version routes
 		adminGroup.POST("/version", http_base_controller.HandleFuncVersion(serviceContainer, "create"))
@@ -100,10 +112,34 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		adminGroup.PUT("/version", http_base_controller.HandleFuncVersion(serviceContainer, "update"))
 		adminGroup.DELETE("/version/:id", http_base_controller.HandleFuncVersion(serviceContainer, "delete"))
 		adminGroup.GET("/versions/active", http_base_controller.HandleFuncVersion(serviceContainer, "getActive"))
+		adminGroup.POST("/version/:id/inspect", http_base_controller.HandleFuncVersion(serviceContainer, "inspect"))
 
 		// App routes
 		adminGroup.PUT("/app/version", http_base_controller.HandleFuncApp(serviceContainer, "update"))
 
+		// App rollout routes
+		adminGroup.POST("/app/rollout", http_base_controller.HandleFuncAppRollout(serviceContainer, "create"))
+		adminGroup.GET("/app/rollout", http_base_controller.HandleFuncAppRollout(serviceContainer, "get"))
+		adminGroup.GET("/app/rollout/:id", http_base_controller.HandleFuncAppRollout(serviceContainer, "getOne"))
+		adminGroup.PUT("/app/rollout/:id", http_base_controller.HandleFuncAppRollout(serviceContainer, "update"))
+		adminGroup.POST("/app/rollout/:id/pause", http_base_controller.HandleFuncAppRollout(serviceContainer, "pause"))
+		adminGroup.POST("/app/rollout/:id/resume", http_base_controller.HandleFuncAppRollout(serviceContainer, "resume"))
+		adminGroup.POST("/app/rollout/:id/rollback", http_base_controller.HandleFuncAppRollout(serviceContainer, "rollback"))
+		adminGroup.POST("/app/rollout/:id/cancel", http_base_controller.HandleFuncAppRollout(serviceContainer, "cancel"))
+		adminGroup.POST("/app/rollout/:id/complete", http_base_controller.HandleFuncAppRollout(serviceContainer, "complete"))
+		adminGroup.GET("/app/update_attempts", http_base_controller.HandleFuncAppRollout(serviceContainer, "getAttempts"))
+
+		// 发布渠道与版本固定
+		adminGroup.PUT("/app/channel/device", http_base_controller.HandleFuncAppRelease(serviceContainer, "setDeviceChannel"))
+		adminGroup.PUT("/app/channel/building", http_base_controller.HandleFuncAppRelease(serviceContainer, "setBuildingChannel"))
+		adminGroup.PUT("/app/pin", http_base_controller.HandleFuncAppRelease(serviceContainer, "pin"))
+		adminGroup.GET("/app/device/:id/release", http_base_controller.HandleFuncAppRelease(serviceContainer, "getDeviceRelease"))
+
+		// 设备 App 版本统计
+		adminGroup.GET("/app/versions/distribution", http_base_controller.HandleFuncAppFleet(serviceContainer, "getDistribution"))
+		adminGroup.GET("/app/versions/outdated", http_base_controller.HandleFuncAppFleet(serviceContainer, "getOutdated"))
+		adminGroup.GET("/app/device/:id/update_history", http_base_controller.HandleFuncAppFleet(serviceContainer, "getDeviceHistory"))
+
 		// Relationship routes
 		// Building Admin Building routes
 		adminGroup.POST("/building_admin_building/bind", http_relationship_controller.HandleFuncBuildingAdminBuilding(serviceContainer, "bindBuildings"))
@@ -142,6 +178,45 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		adminGroup.PUT("/device", http_base_controller.HandleFuncDevice(serviceContainer, "update"))
 		adminGroup.DELETE("/device", http_base_controller.HandleFuncDevice(serviceContainer, "delete"))
 		adminGroup.GET("/device/:id", http_base_controller.HandleFuncDevice(serviceContainer, "getOne"))
+		adminGroup.GET("/device/:id/settings", http_base_controller.HandleFuncDevice(serviceContainer, "getEffectiveSettings"))
+		adminGroup.GET("/device/:id/screenshots", http_base_controller.HandleFuncDevice(serviceContainer, "getScreenshots"))
+		adminGroup.GET("/device/:id/telemetry", http_base_controller.HandleFuncDeviceTelemetry(serviceContainer, "getHistory"))
+		adminGroup.GET("/device/:id/status_events", http_base_controller.HandleFuncDeviceStatus(serviceContainer, "getEvents"))
+		adminGroup.GET("/device/:id/uptime", http_base_controller.HandleFuncDeviceStatus(serviceContainer, "getDeviceUptime"))
+
+		// Device health rule routes
+		adminGroup.POST("/device_health_rule", http_base_controller.HandleFuncDeviceTelemetry(serviceContainer, "createRule"))
+		adminGroup.GET("/device_health_rule", http_base_controller.HandleFuncDeviceTelemetry(serviceContainer, "getRules"))
+		adminGroup.PUT("/device_health_rule", http_base_controller.HandleFuncDeviceTelemetry(serviceContainer, "updateRule"))
+		adminGroup.DELETE("/device_health_rule", http_base_controller.HandleFuncDeviceTelemetry(serviceContainer, "deleteRules"))
+
+		// Alert routes
+		adminGroup.POST("/alert_rule", http_base_controller.HandleFuncAlert(serviceContainer, "createRule"))
+		adminGroup.GET("/alert_rule", http_base_controller.HandleFuncAlert(serviceContainer, "getRules"))
+		adminGroup.PUT("/alert_rule", http_base_controller.HandleFuncAlert(serviceContainer, "updateRule"))
+		adminGroup.DELETE("/alert_rule", http_base_controller.HandleFuncAlert(serviceContainer, "deleteRules"))
+		adminGroup.GET("/alert", http_base_controller.HandleFuncAlert(serviceContainer, "getAlerts"))
+		adminGroup.POST("/alert/evaluate", http_base_controller.HandleFuncAlert(serviceContainer, "evaluate"))
+		adminGroup.GET("/alert/:id", http_base_controller.HandleFuncAlert(serviceContainer, "getAlert"))
+
+		// Device group routes
+		adminGroup.POST("/device_group", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "create"))
+		adminGroup.GET("/device_group", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "get"))
+		adminGroup.GET("/device_group/:id", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "getOne"))
+		adminGroup.PUT("/device_group", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "update"))
+		adminGroup.DELETE("/device_group", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "delete"))
+		adminGroup.POST("/device_group/assign", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "assignDevices"))
+		adminGroup.POST("/device_group/remove", http_base_controller.HandleFuncDeviceGroup(serviceContainer, "removeDevices"))
+
+		// Device remote command routes
+		adminGroup.POST("/device_command", http_base_controller.HandleFuncDeviceCommand(serviceContainer, "enqueue"))
+		adminGroup.GET("/device_command", http_base_controller.HandleFuncDeviceCommand(serviceContainer, "get"))
+		adminGroup.GET("/device_command/:id", http_base_controller.HandleFuncDeviceCommand(serviceContainer, "getOne"))
+
+		// Device settings profile routes
+		adminGroup.GET("/settings_profile", http_base_controller.HandleFuncDeviceSettings(serviceContainer, "getProfiles"))
+		adminGroup.PUT("/settings_profile", http_base_controller.HandleFuncDeviceSettings(serviceContainer, "saveProfile"))
+		adminGroup.DELETE("/settings_profile/:id", http_base_controller.HandleFuncDeviceSettings(serviceContainer, "deleteProfile"))
 
 		// Printer routes
 		adminGroup.POST("/printer", http_base_controller.HandleFuncPrinter(serviceContainer, "create"))
@@ -149,6 +224,30 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		adminGroup.PUT("/printer", http_base_controller.HandleFuncPrinter(serviceContainer, "update"))
 		adminGroup.DELETE("/printer", http_base_controller.HandleFuncPrinter(serviceContainer, "delete"))
 		adminGroup.GET("/printer/:id", http_base_controller.HandleFuncPrinter(serviceContainer, "getOne"))
+		adminGroup.GET("/printer/:id/supplies", http_base_controller.HandleFuncPrinter(serviceContainer, "getSupplies"))
+		adminGroup.GET("/printer/supplies_report", http_base_controller.HandleFuncPrinter(serviceContainer, "getSuppliesReport"))
+		adminGroup.GET("/printer/:id/timeline", http_base_controller.HandleFuncPrinter(serviceContainer, "getTimeline"))
+		adminGroup.GET("/printer/flapping", http_base_controller.HandleFuncPrinter(serviceContainer, "getFlapping"))
+
+		// Print password routes
+		adminGroup.GET("/print_password", http_base_controller.HandleFuncPrintPassword(serviceContainer, "get"))
+		adminGroup.PUT("/print_password", http_base_controller.HandleFuncPrintPassword(serviceContainer, "set"))
+		adminGroup.DELETE("/print_password", http_base_controller.HandleFuncPrintPassword(serviceContainer, "delete"))
+		adminGroup.POST("/print_password/:id/rotate", http_base_controller.HandleFuncPrintPassword(serviceContainer, "rotate"))
+		adminGroup.GET("/print_password/attempts", http_base_controller.HandleFuncPrintPassword(serviceContainer, "getAttempts"))
+
+		// Print dispatch routes
+		adminGroup.POST("/print_dispatch", http_base_controller.HandleFuncPrintDispatch(serviceContainer, "create"))
+		adminGroup.GET("/print_dispatch", http_base_controller.HandleFuncPrintDispatch(serviceContainer, "get"))
+		adminGroup.GET("/print_dispatch/:id", http_base_controller.HandleFuncPrintDispatch(serviceContainer, "getOne"))
+		adminGroup.POST("/print_dispatch/:id/push", http_base_controller.HandleFuncPrintDispatch(serviceContainer, "push"))
+		adminGroup.POST("/print_dispatch/:id/cancel", http_base_controller.HandleFuncPrintDispatch(serviceContainer, "cancel"))
+
+		// Print job routes
+		adminGroup.GET("/print_job", http_base_controller.HandleFuncPrintJob(serviceContainer, "get"))
+		adminGroup.GET("/print_job/stats", http_base_controller.HandleFuncPrintJob(serviceContainer, "getStats"))
+		adminGroup.GET("/print_job/failures", http_base_controller.HandleFuncPrintJob(serviceContainer, "getFailureReport"))
+		adminGroup.GET("/print_job/:id", http_base_controller.HandleFuncPrintJob(serviceContainer, "getOne"))
 
 		// Device-Building relationship routes
 		adminGroup.POST("/device_building/bind", http_relationship_controller.HandleFuncDeviceBuilding(serviceContainer, "bindDevice"))
@@ -191,6 +290,13 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		buildingAdminGroup.PUT("/notice", http_building_admin_controller.HandleFuncBuildingAdminNotice(serviceContainer, "updateNotice"))
 		buildingAdminGroup.DELETE("/notice/:id", http_building_admin_controller.HandleFuncBuildingAdminNotice(serviceContainer, "deleteNotice"))
 		buildingAdminGroup.POST("/notice/upload/params", http_building_admin_controller.HandleFuncBuildingAdminNotice(serviceContainer, "getUploadParams"))
+
+		// Print dispatch routes
+		buildingAdminGroup.GET("/printer", http_building_admin_controller.HandleFuncBuildingAdminPrintDispatch(serviceContainer, "getPrinters"))
+		buildingAdminGroup.POST("/print_dispatch", http_building_admin_controller.HandleFuncBuildingAdminPrintDispatch(serviceContainer, "createPrintDispatch"))
+		buildingAdminGroup.GET("/print_dispatch", http_building_admin_controller.HandleFuncBuildingAdminPrintDispatch(serviceContainer, "getPrintDispatches"))
+		buildingAdminGroup.GET("/print_dispatch/:id", http_building_admin_controller.HandleFuncBuildingAdminPrintDispatch(serviceContainer, "getPrintDispatch"))
+		buildingAdminGroup.POST("/print_dispatch/:id/cancel", http_building_admin_controller.HandleFuncBuildingAdminPrintDispatch(serviceContainer, "cancelPrintDispatch"))
 	}
 
 	// Device client routes (requires device JWT)
@@ -211,6 +317,18 @@ func RegisterRoute(r *gin.Engine) *gin.Engine {
 		// Printer routes
 		deviceClientGroup.POST("/printers/health", http_base_controller.HandleFuncDevice(serviceContainer, "printersHealthCheck"))
 		deviceClientGroup.POST("/printers/callback", http_base_controller.HandleFuncDevice(serviceContainer, "printersCallback"))
+		deviceClientGroup.POST("/print_jobs", http_base_controller.HandleFuncDevice(serviceContainer, "reportPrintJobs"))
+		deviceClientGroup.POST("/print_password/verify", http_base_controller.HandleFuncDevice(serviceContainer, "verifyPrintPassword"))
+		deviceClientGroup.GET("/print_dispatches", http_base_controller.HandleFuncDevice(serviceContainer, "fetchPrintDispatches"))
+
+		deviceClientGroup.POST("/app_update", http_base_controller.HandleFuncDevice(serviceContainer, "reportAppUpdate"))
+		deviceClientGroup.GET("/settings", http_base_controller.HandleFuncDevice(serviceContainer, "getSettings"))
+		deviceClientGroup.GET("/commands", http_base_controller.HandleFuncDevice(serviceContainer, "fetchCommands"))
+		deviceClientGroup.POST("/commands/result", http_base_controller.HandleFuncDevice(serviceContainer, "reportCommandResult"))
+		deviceClientGroup.POST("/screenshot", http_base_controller.HandleFuncDevice(serviceContainer, "uploadScreenshot"))
+
+		// Real-time events (SSE)
+		deviceClientGroup.GET("/events", http_base_controller.HandleFuncDevice(serviceContainer, "subscribeEvents"))
 	}
 
 	return r
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// AlertRule 告警规则
+type AlertRule struct {
+	ModelFields
+	Name            string              `json:"name" gorm:"size:255;not null"`
+	Type            field.AlertRuleType `json:"type" gorm:"size:50;not null"`
+	Threshold       float64             `json:"threshold"`                         // device_offline: 分钟; ink_low: 百分比
+	BuildingID      *uint               `json:"buildingId,omitempty" gorm:"index"` // 为空表示所有建筑
+	CooldownMinutes int                 `json:"cooldownMinutes"`                   // 同一目标两次告警通知的最小间隔，0 表示不限制；创建时默认值由接口设置
+	NotifyRecovery  bool                `json:"notifyRecovery"`
+	Enabled         bool                `json:"enabled"`
+}
+
+// Alert 告警记录，同一规则同一目标同时只有一条 open 记录
+type Alert struct {
+	ModelFields
+	RuleID         uint                `json:"ruleId" gorm:"not null;index:idx_alert_rule_target"`
+	RuleType       field.AlertRuleType `json:"ruleType" gorm:"size:50"`
+	TargetKey      string              `json:"targetKey" gorm:"size:100;index:idx_alert_rule_target"` // 去重键，如 device:12、printer:5
+	BuildingID     uint                `json:"buildingId" gorm:"index"`
+	DeviceID       uint                `json:"deviceId" gorm:"index"`
+	PrinterID      *uint               `json:"printerId,omitempty"`
+	Status         field.AlertStatus   `json:"status" gorm:"size:20;not null;index"`
+	Message        string              `json:"message" gorm:"type:text"`
+	OpenedAt       time.Time           `json:"openedAt"`
+	ResolvedAt     *time.Time          `json:"resolvedAt,omitempty"`
+	Notified       bool                `json:"notified"` // 是否已发送告警通知（冷却期内不发送）
+	NotifiedAt     *time.Time          `json:"notifiedAt,omitempty"`
+	RecoveryNotice bool                `json:"recoveryNotice"` // 是否已发送恢复通知
+	Rule           *AlertRule          `json:"rule,omitempty" gorm:"foreignKey:RuleID"`
+}
@@ -8,6 +8,8 @@ type App struct {
 	ModelFields
 	CurrentVersionID uint      `json:"currentVersionId" gorm:"default:1;comment:'当前使用的版本ID，1表示使用第一个版本'"`
 	CurrentVersion   *Version  `json:"currentVersion" gorm:"foreignKey:CurrentVersionID;comment:'当前版本信息'"`
+	MinVersionID     *uint     `json:"minVersionId" gorm:"comment:'最低支持版本ID，低于该版本的设备强制更新'"`
+	MinVersion       *Version  `json:"minVersion,omitempty" gorm:"foreignKey:MinVersionID"`
 	LastCheckTime    time.Time `json:"lastCheckTime" gorm:"type:datetime;comment:'最后检查更新时间'"`
 	UpdateInterval   int       `json:"updateInterval" gorm:"default:3600;comment:'检查更新间隔(秒)'"`
 	AutoUpdate       bool      `json:"autoUpdate" gorm:"default:false;comment:'是否自动更新'"`
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// AppRollout 应用版本灰度发布
+// 设备先按建筑/分组限定范围（均为空时为全部设备），再按 DeviceID 的稳定哈希取 Percentage% 的设备
+type AppRollout struct {
+	ModelFields
+	Name           string                        `json:"name" gorm:"size:255"`
+	VersionID      uint                          `json:"versionId" gorm:"not null;index"`
+	Version        *Version                      `json:"version,omitempty" gorm:"foreignKey:VersionID"`
+	Percentage     int                           `json:"percentage" gorm:"default:0"`     // 0-100
+	BuildingIDs    datatypes.JSON                `json:"buildingIds" gorm:"type:json"`    // 限定建筑ID列表
+	DeviceGroupIDs datatypes.JSON                `json:"deviceGroupIds" gorm:"type:json"` // 限定设备分组ID列表
+	Status         field.AppRolloutStatus        `json:"status" gorm:"size:20;not null;default:'active';index"`
+	MaxFailures    int                           `json:"maxFailures" gorm:"default:0"`    // 失败次数达到该值时自动处理，0 不限
+	MaxFailureRate float64                       `json:"maxFailureRate" gorm:"default:0"` // 失败率(%)超过该值时自动处理，0 不限
+	MinReports     int                           `json:"minReports" gorm:"default:10"`    // 计算失败率所需的最少上报次数
+	FailureAction  field.AppRolloutFailureAction `json:"failureAction" gorm:"size:20;default:'pause'"`
+	StatusReason   string                        `json:"statusReason" gorm:"size:500"` // 暂停、回滚的原因
+	CreatedBy      string                        `json:"createdBy" gorm:"size:255"`
+}
+
+// AppUpdateAttempt 设备上报的版本更新结果
+type AppUpdateAttempt struct {
+	ModelFields
+	DeviceID    uint      `json:"deviceId" gorm:"not null;index"`
+	BuildingID  uint      `json:"buildingId" gorm:"index"`
+	RolloutID   *uint     `json:"rolloutId,omitempty" gorm:"index"`
+	VersionID   uint      `json:"versionId" gorm:"index"`
+	FromVersion string    `json:"fromVersion" gorm:"size:50"` // 更新前的版本号
+	Success     bool      `json:"success"`
+	ErrorReason string    `json:"errorReason" gorm:"size:500"`
+	Detected    bool      `json:"detected"` // 由设备上报的已安装版本变化推断，设备未主动上报结果
+	AttemptedAt time.Time `json:"attemptedAt" gorm:"index"`
+}
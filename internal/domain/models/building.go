@@ -1,14 +1,34 @@
 package models
 
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
 // Building 建筑模型
 type Building struct {
 	ModelFields
-	Name           string          `json:"name" gorm:"size:255;not null"`
-	IsmartID       string          `json:"ismartId" gorm:"size:255;not null;unique"`
-	Remark         string          `json:"remark" gorm:"type:text"`
-	Location       string          `json:"location" gorm:"size:255"`
-	Devices        []Device        `json:"devices" gorm:"foreignKey:BuildingID"`
-	BuildingAdmins []BuildingAdmin `json:"-" gorm:"many2many:building_admins_buildings;"`
-	Notices        []Notice        `json:"notices" gorm:"many2many:notice_buildings;"`
-	Advertisements []Advertisement `json:"advertisements" gorm:"many2many:advertisement_buildings;"`
+	Name           string               `json:"name" gorm:"size:255;not null"`
+	IsmartID       string               `json:"ismartId" gorm:"size:255;not null;unique"`
+	Remark         string               `json:"remark" gorm:"type:text"`
+	Location       string               `json:"location" gorm:"size:255"`
+	ReleaseChannel field.ReleaseChannel `json:"releaseChannel" gorm:"size:20"`  // App 发布渠道，为空时为 stable
+	NoticeSources  datatypes.JSON       `json:"noticeSources" gorm:"type:json"` // 通知来源列表，为空时使用 NOTICE_SOURCES_DEFAULT
+	Devices        []Device             `json:"devices" gorm:"foreignKey:BuildingID"`
+	BuildingAdmins []BuildingAdmin      `json:"-" gorm:"many2many:building_admins_buildings;"`
+	Notices        []Notice             `json:"notices" gorm:"many2many:notice_buildings;"`
+	Advertisements []Advertisement      `json:"advertisements" gorm:"many2many:advertisement_buildings;"`
+
+	// 通知同步设置
+	NoticeSyncEnabled    *bool          `json:"noticeSyncEnabled" gorm:"default:true"` // 是否参与定时同步，手动同步不受影响
+	NoticeSyncInterval   int            `json:"noticeSyncInterval"`                    // 定时同步间隔(分钟)，0 时使用 NOTICE_SYNC_INTERVAL
+	NoticeSyncQuietStart string         `json:"noticeSyncQuietStart" gorm:"size:5"`    // 免同步时段开始(HH:MM，北京时间)，可跨零点
+	NoticeSyncQuietEnd   string         `json:"noticeSyncQuietEnd" gorm:"size:5"`      // 免同步时段结束(HH:MM，北京时间)
+	NoticeTypeMapping    datatypes.JSON `json:"noticeTypeMapping" gorm:"type:json"`    // 来源通知类型到通知类型的映射，覆盖默认映射
+	NoticeSyncEndTime    *time.Time     `json:"noticeSyncEndTime"`                     // 同步创建的通知的结束时间，为空时为 2100-02-01
+
+	// 上游建筑检查
+	UpstreamMissing datatypes.JSON `json:"upstreamMissing" gorm:"type:json"` // 建筑列表中没有该 IsmartID 的通知来源，为空表示均已找到
 }
@@ -1,21 +1,37 @@
 package models
 
-import "gorm.io/datatypes"
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
 
 // Device represents a display device in a building
 type Device struct {
 	ModelFields
-	DeviceID   string         `json:"deviceId" gorm:"size:255;not null;unique"`
-	Building   Building       `json:"building" gorm:"foreignKey:BuildingID"`
-	BuildingID uint           `json:"buildingId" `
-	Printers   []Printer      `json:"-" gorm:"foreignKey:DeviceID"`                        // 一对多关系，不直接序列化
-	OrangePi   OrangePiInfo   `json:"orangePi" gorm:"embedded;embedded_prefix:orange_pi_"` // 包含打印机信息
-	Settings   DeviceSettings `json:"settings" gorm:"embedded"`
+	DeviceID        string               `json:"deviceId" gorm:"size:255;not null;unique"`
+	Building        Building             `json:"building" gorm:"foreignKey:BuildingID"`
+	BuildingID      uint                 `json:"buildingId" `
+	DeviceGroupID   *uint                `json:"deviceGroupId,omitempty" gorm:"index"` // 所属设备分组（可选）
+	ReleaseChannel  field.ReleaseChannel `json:"releaseChannel" gorm:"size:20"`        // App 发布渠道，为空时跟随建筑
+	PinnedVersionID *uint                `json:"pinnedVersionId,omitempty"`            // 固定安装的 App 版本
+	// 设备在登录与心跳时上报的已安装 App 版本
+	InstalledVersion  string          `json:"installedVersion" gorm:"size:50;index"`
+	InstalledBuild    string          `json:"installedBuild" gorm:"size:50"`
+	VersionReportedAt *time.Time      `json:"versionReportedAt,omitempty"`
+	Printers          []Printer       `json:"-" gorm:"foreignKey:DeviceID"`                        // 一对多关系，不直接序列化
+	OrangePi          OrangePiInfo    `json:"orangePi" gorm:"embedded;embedded_prefix:orange_pi_"` // 包含打印机信息
+	Settings          DeviceSettings  `json:"settings" gorm:"embedded"`
+	Telemetry         DeviceTelemetry `json:"telemetry" gorm:"embedded;embedded_prefix:telemetry_"` // 最近一次心跳上报的遥测数据
 	// 轮播顺序管理列表（JSON 数组，存储 ID 顺序）
 	TopAdvertisementCarouselList  datatypes.JSON `json:"topAdvertisementCarouselList" gorm:"type:json"`
 	FullAdvertisementCarouselList datatypes.JSON `json:"fullAdvertisementCarouselList" gorm:"type:json"`
 	NoticeCarouselList            datatypes.JSON `json:"noticeCarouselList" gorm:"type:json"`
 	Status                        string         `json:"status" gorm:"-"` // 设备在线状态，不存储在数据库中
+	// 截图（运行时填充，不存储）
+	LatestScreenshot *DeviceScreenshot  `json:"latestScreenshot,omitempty" gorm:"-"`
+	Screenshots      []DeviceScreenshot `json:"screenshots,omitempty" gorm:"-"`
 }
 
 // OrangePiInfo 香橙派服务信息（包含打印机列表）
@@ -29,6 +45,23 @@ type OrangePiInfo struct {
 	Printers     []Printer `json:"printers" gorm:"-"` // 打印机列表（运行时填充，不存储）
 }
 
+// DeviceTelemetry 设备心跳上报的最新遥测数据，未上报的字段保持上一次的值
+type DeviceTelemetry struct {
+	AppVersion     *string            `json:"appVersion,omitempty" gorm:"size:50"`
+	UptimeSeconds  *int64             `json:"uptimeSeconds,omitempty"`
+	StorageFreeMB  *int64             `json:"storageFreeMB,omitempty"`
+	StorageTotalMB *int64             `json:"storageTotalMB,omitempty"`
+	MemoryFreeMB   *int64             `json:"memoryFreeMB,omitempty"`
+	MemoryTotalMB  *int64             `json:"memoryTotalMB,omitempty"`
+	Temperature    *float64           `json:"temperature,omitempty"`                // 摄氏度
+	NetworkType    *string            `json:"networkType,omitempty" gorm:"size:20"` // wifi, ethernet, cellular
+	IPAddress      *string            `json:"ipAddress,omitempty" gorm:"size:64"`
+	ScreenOn       *bool              `json:"screenOn,omitempty"`
+	ReportedAt     *time.Time         `json:"reportedAt,omitempty"`
+	Health         field.DeviceHealth `json:"health" gorm:"size:20;default:'unknown';index"`
+	HealthIssues   datatypes.JSON     `json:"healthIssues,omitempty" gorm:"type:json"` // 命中的健康规则
+}
+
 // DeviceSettings contains all settings for a device
 type DeviceSettings struct {
 	// Update durations (in minutes)
@@ -47,5 +80,23 @@ type DeviceSettings struct {
 	PaymentTableOnePageDuration                   int    `json:"paymentTableOnePageDuration" gorm:"default:5"`                    // 缴费表格单页停留时间
 	NormalToAnnouncementCarouselDuration          int    `json:"normalToAnnouncementCarouselDuration" gorm:"default:10"`          // 正常播放到公告轮播时间
 	AnnouncementCarouselToFullAdsCarouselDuration int    `json:"announcementCarouselToFullAdsCarouselDuration" gorm:"default:10"` // 公告轮播到全屏广告轮播时间
-	PrintPassWord                                 string `json:"printPassWord" gorm:"default:'1090119'"`                          // 打印密码
+	PrintPassWord                                 string `json:"-" gorm:"default:''"`                                             // 已废弃：打印密码改由 PrintPassword 哈希存储，旧值在启动时迁移后清空
+	ScreenshotInterval                            int    `json:"screenshotInterval" gorm:"default:0"`                             // 定时截图间隔（分钟），0 表示关闭
+}
+
+// DefaultDeviceSettings 返回与数据库默认值一致的设备设置
+func DefaultDeviceSettings() DeviceSettings {
+	return DeviceSettings{
+		ArrearageUpdateDuration:                       5,
+		NoticeUpdateDuration:                          10,
+		AdvertisementUpdateDuration:                   15,
+		AppUpdateDuration:                             600,
+		AdvertisementPlayDuration:                     30,
+		NoticeStayDuration:                            10,
+		BottomCarouselDuration:                        10,
+		PaymentTableOnePageDuration:                   5,
+		NormalToAnnouncementCarouselDuration:          10,
+		AnnouncementCarouselToFullAdsCarouselDuration: 10,
+		ScreenshotInterval:                            0,
+	}
 }
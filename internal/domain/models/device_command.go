@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// DeviceCommand 下发给设备的远程命令，每台设备一条记录
+// 同一次对分组或建筑的下发共享 BatchID
+type DeviceCommand struct {
+	ModelFields
+	DeviceID    uint                      `json:"deviceId" gorm:"not null;index"`
+	BatchID     string                    `json:"batchId" gorm:"size:64;index"`
+	TargetType  field.CommandTarget       `json:"targetType" gorm:"size:20"` // 下发目标类型: device, group, building
+	TargetID    uint                      `json:"targetId"`
+	Command     field.DeviceCommandType   `json:"command" gorm:"size:50;not null"`
+	Payload     datatypes.JSON            `json:"payload" gorm:"type:json"`
+	Status      field.DeviceCommandStatus `json:"status" gorm:"size:20;not null;default:'queued';index"`
+	ExpiresAt   time.Time                 `json:"expiresAt" gorm:"index"`
+	DeliveredAt *time.Time                `json:"deliveredAt"`
+	CompletedAt *time.Time                `json:"completedAt"`
+	Result      string                    `json:"result" gorm:"type:text"` // 设备回报的结果或失败原因
+	IssuedBy    string                    `json:"issuedBy" gorm:"size:255"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// DeviceGroup 设备分组，一个设备最多属于一个分组，可用于批量下发设置与命令
+type DeviceGroup struct {
+	ModelFields
+	Name        string   `json:"name" gorm:"size:255;not null"`
+	Description string   `json:"description" gorm:"type:text"`
+	BuildingID  *uint    `json:"buildingId,omitempty" gorm:"index"` // 可选，限定分组所属建筑
+	Devices     []Device `json:"devices,omitempty" gorm:"foreignKey:DeviceGroupID"`
+}
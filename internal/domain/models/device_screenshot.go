@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DeviceScreenshot 设备截图记录，每台设备只保留最近若干张
+type DeviceScreenshot struct {
+	ModelFields
+	DeviceID   uint      `json:"deviceId" gorm:"not null;index"`
+	FileID     uint      `json:"fileId" gorm:"not null"`
+	File       *File     `json:"file,omitempty" gorm:"foreignKey:FileID"`
+	Trigger    string    `json:"trigger" gorm:"size:20"` // 触发方式: command, periodic, manual
+	CommandID  *uint     `json:"commandId,omitempty"`    // 由远程命令触发时对应的命令ID
+	CapturedAt time.Time `json:"capturedAt" gorm:"index"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// DeviceSettingsProfile 设备设置配置，按 global -> building -> group -> device 逐级覆盖
+// Settings 只保存该层级需要覆盖的字段，字段名与 DeviceSettings 的 JSON 字段一致
+type DeviceSettingsProfile struct {
+	ModelFields
+	Scope    field.SettingsScope `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_settings_scope_target"`
+	TargetID uint                `json:"targetId" gorm:"not null;default:0;uniqueIndex:idx_settings_scope_target"` // global 为 0
+	Settings datatypes.JSON      `json:"settings" gorm:"type:json"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// DeviceStatusEvent 设备上下线记录，仅在状态发生变化时写入
+type DeviceStatusEvent struct {
+	ModelFields
+	DeviceID   uint                     `json:"deviceId" gorm:"not null;index:idx_status_event_device_time"`
+	BuildingID uint                     `json:"buildingId" gorm:"index"`
+	Status     field.DeviceOnlineStatus `json:"status" gorm:"size:20;not null"`
+	OccurredAt time.Time                `json:"occurredAt" gorm:"not null;index:idx_status_event_device_time"`
+}
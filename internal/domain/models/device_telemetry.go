@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// DeviceTelemetrySample 降采样后的遥测历史，每台设备每个时间桶一行，
+// 保存桶内最后一次上报的值以及温度最高值、存储/内存最低值
+type DeviceTelemetrySample struct {
+	ModelFields
+	DeviceID         uint      `json:"deviceId" gorm:"not null;uniqueIndex:idx_telemetry_device_bucket"`
+	BucketStart      time.Time `json:"bucketStart" gorm:"not null;uniqueIndex:idx_telemetry_device_bucket"`
+	SampleCount      int       `json:"sampleCount"`
+	UptimeSeconds    *int64    `json:"uptimeSeconds,omitempty"`
+	StorageFreeMB    *int64    `json:"storageFreeMB,omitempty"`
+	MinStorageFreeMB *int64    `json:"minStorageFreeMB,omitempty"`
+	MemoryFreeMB     *int64    `json:"memoryFreeMB,omitempty"`
+	MinMemoryFreeMB  *int64    `json:"minMemoryFreeMB,omitempty"`
+	Temperature      *float64  `json:"temperature,omitempty"`
+	MaxTemperature   *float64  `json:"maxTemperature,omitempty"`
+	NetworkType      *string   `json:"networkType,omitempty" gorm:"size:20"`
+	ScreenOn         *bool     `json:"screenOn,omitempty"`
+}
+
+// DeviceHealthRule 遥测阈值规则，命中任意启用规则的设备被标记为 unhealthy
+type DeviceHealthRule struct {
+	ModelFields
+	Name        string                `json:"name" gorm:"size:255;not null"`
+	Metric      field.TelemetryMetric `json:"metric" gorm:"size:50;not null"`
+	Operator    field.RuleOperator    `json:"operator" gorm:"size:10;not null"`
+	Threshold   float64               `json:"threshold"`
+	Enabled     bool                  `json:"enabled"` // 创建时默认启用，由接口设置
+	Description string                `json:"description" gorm:"type:text"`
+}
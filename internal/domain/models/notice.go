@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
 )
 
 // Notice 通知模型
@@ -14,6 +15,7 @@ type Notice struct {
 	Type           field.NoticeType `json:"type"           gorm:"size:50"` //urgent , common ,system, government
 	IsPublic       bool             `json:"isPublic"       gorm:"default:true"`
 	IsIsmartNotice bool             `json:"isIsmartNotice" gorm:"default:false"` // use for sync with ismart notice
+	LocalOverrides datatypes.JSON   `json:"localOverrides" gorm:"type:json"`     // iSmart 通知被本地修改、同步不再覆盖的字段
 	Priority       int              `json:"priority"       gorm:"default:0"`     //0 - 100, 100 is the highest priority (default 0)
 	Status         field.Status     `json:"status"         gorm:"size:50"`       // pending, active, inactive
 	StartTime      time.Time        `json:"startTime"      gorm:"type:datetime"`
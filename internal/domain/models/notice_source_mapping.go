@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// NoticeSourceMapping 来源通知(如 iSmart 通知ID)与本地通知、文件的对应关系
+// 同步时按来源通知ID判断新增与变化，文件地址未变时用保存的 ETag/Last-Modified 条件请求，内容未变不再下载
+type NoticeSourceMapping struct {
+	ModelFields
+	Source       field.NoticeSource `json:"source" gorm:"size:20;not null;uniqueIndex:idx_notice_source_external,priority:1"`
+	ExternalID   int                `json:"externalId" gorm:"not null;uniqueIndex:idx_notice_source_external,priority:2"` // 来源系统中的通知ID
+	NoticeID     uint               `json:"noticeId" gorm:"not null;index"`
+	FileID       *uint              `json:"fileId" gorm:"index"`
+	FileURL      string             `json:"fileUrl" gorm:"size:1024"`
+	Md5          string             `json:"md5" gorm:"size:64"`
+	ETag         string             `json:"etag" gorm:"size:255"`
+	LastModified string             `json:"lastModified" gorm:"size:64"`
+	Title        string             `json:"title" gorm:"size:255"` // 来源标题
+	Type         string             `json:"type" gorm:"size:50"`   // 来源通知类型
+	SyncedAt     time.Time          `json:"syncedAt"`              // 最近一次下载文件的时间
+	CheckedAt    time.Time          `json:"checkedAt"`             // 最近一次确认未变化的时间
+}
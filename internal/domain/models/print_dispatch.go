@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// PrintDispatch 管理端发起、下发到香橙派打印的任务
+// 设备通过打印回调上报 job_id 为 JobID 的打印结果后完成，结果同时记入 PrintJob
+type PrintDispatch struct {
+	ModelFields
+	JobID        string                    `json:"jobId" gorm:"size:64;not null;uniqueIndex"` // 下发给设备的任务ID，回调时作为 job_id 上报
+	DeviceID     uint                      `json:"deviceId" gorm:"not null;index"`
+	BuildingID   uint                      `json:"buildingId" gorm:"index"`
+	PrinterID    uint                      `json:"printerId" gorm:"index"`
+	PrinterName  string                    `json:"printerName" gorm:"size:255"` // CUPS 打印机名称
+	PrinterIP    string                    `json:"printerIp" gorm:"size:255"`
+	NoticeID     *uint                     `json:"noticeId,omitempty" gorm:"index"`
+	FileID       uint                      `json:"fileId" gorm:"not null"`
+	FileName     string                    `json:"fileName" gorm:"size:255"`
+	FileURL      string                    `json:"fileUrl" gorm:"size:512"`
+	FileMd5      string                    `json:"fileMd5" gorm:"size:255"`
+	Copies       int                       `json:"copies" gorm:"default:1"`
+	DeliveryMode field.PrintDeliveryMode   `json:"deliveryMode" gorm:"size:20;not null;default:'pull'"`
+	Status       field.PrintDispatchStatus `json:"status" gorm:"size:20;not null;default:'queued';index"`
+	PushAttempts int                       `json:"pushAttempts" gorm:"default:0"`
+	LastError    string                    `json:"lastError" gorm:"size:500"` // 推送失败或打印失败原因
+	ExpiresAt    time.Time                 `json:"expiresAt" gorm:"index"`
+	DeliveredAt  *time.Time                `json:"deliveredAt"`
+	CompletedAt  *time.Time                `json:"completedAt"`
+	PrintJobID   *uint                     `json:"printJobId,omitempty"` // 完成后对应的打印任务记录
+	IssuedBy     string                    `json:"issuedBy" gorm:"size:255"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// PrintJob 设备上报的打印任务记录
+type PrintJob struct {
+	ModelFields
+	DeviceID    uint                 `json:"deviceId" gorm:"not null;uniqueIndex:idx_print_job_client;index:idx_print_job_device_time"`
+	BuildingID  uint                 `json:"buildingId" gorm:"index"`
+	PrinterID   *uint                `json:"printerId,omitempty" gorm:"index"`
+	PrinterIP   string               `json:"printerIp" gorm:"size:255"`                                              // 上报时的打印机IP，打印机被删除后仍可追溯
+	ClientJobID *string              `json:"clientJobId,omitempty" gorm:"size:100;uniqueIndex:idx_print_job_client"` // 设备端任务ID，用于重复上报去重
+	NoticeID    *uint                `json:"noticeId,omitempty" gorm:"index"`
+	FileID      *uint                `json:"fileId,omitempty" gorm:"index"`
+	FileName    string               `json:"fileName" gorm:"size:255"`
+	Pages       int                  `json:"pages" gorm:"default:1"`  // 文档页数
+	Copies      int                  `json:"copies" gorm:"default:1"` // 打印份数
+	Status      field.PrintJobStatus `json:"status" gorm:"size:20;not null;index"`
+	ErrorReason string               `json:"errorReason" gorm:"size:500"`
+	PrintedAt   time.Time            `json:"printedAt" gorm:"not null;index:idx_print_job_device_time"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// PrintPassword 打印密码，按 global -> building -> device 逐级覆盖
+// 只保存 PBKDF2-SHA256 哈希，明文仅在设置或轮换时返回一次
+type PrintPassword struct {
+	ModelFields
+	Scope          field.PrintPasswordScope `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_print_password_scope_target"`
+	TargetID       uint                     `json:"targetId" gorm:"not null;default:0;uniqueIndex:idx_print_password_scope_target"` // global 为 0
+	Salt           string                   `json:"-" gorm:"size:64;not null"`
+	Hash           string                   `json:"-" gorm:"size:128;not null"`
+	Iterations     int                      `json:"-" gorm:"not null"`
+	Version        int                      `json:"version" gorm:"not null;default:1"` // 每次修改或轮换加 1
+	RotationDays   int                      `json:"rotationDays" gorm:"default:0"`     // 自动轮换周期（天），0 表示不轮换
+	RotatedAt      time.Time                `json:"rotatedAt"`                         // 最近一次修改或轮换时间
+	NextRotationAt *time.Time               `json:"nextRotationAt" gorm:"index"`       // 下次自动轮换时间
+	UpdatedBy      string                   `json:"updatedBy" gorm:"size:255"`         // 操作人邮箱，自动轮换为 system
+
+	// 不符合规则的旧密码按原值迁移时为 true，管理员重新设置或轮换后清除
+	NeedsReset bool `json:"needsReset"`
+}
+
+// PrintPasswordAttempt 设备校验打印密码的记录
+type PrintPasswordAttempt struct {
+	ModelFields
+	DeviceID        uint      `json:"deviceId" gorm:"not null;index:idx_print_attempt_device_time"`
+	BuildingID      uint      `json:"buildingId" gorm:"index"`
+	PrintPasswordID *uint     `json:"printPasswordId,omitempty"`
+	Version         int       `json:"version"`
+	Success         bool      `json:"success" gorm:"index"`
+	ClientIP        string    `json:"clientIp" gorm:"size:64"`
+	AttemptedAt     time.Time `json:"attemptedAt" gorm:"not null;index:idx_print_attempt_device_time"`
+}
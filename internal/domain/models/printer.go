@@ -12,4 +12,18 @@ type Printer struct {
 	Status       *string `json:"status,omitempty" gorm:"size:100"`        // 打印机网络状态: "online" 或 "offline"
 	Reason       *string `json:"reason,omitempty" gorm:"size:500"`        // 状态原因，online时为空，offline时包含失败原因
 	MarkerLevels *string `json:"marker_levels,omitempty" gorm:"size:255"` // 墨盒墨水量，格式如 "30,20"
+	MarkerNames  *string `json:"marker_names,omitempty" gorm:"size:500"`  // 墨盒名称，与 MarkerLevels 一一对应，如 "Black,Tri-color"
+	MarkerColors *string `json:"marker_colors,omitempty" gorm:"size:255"` // 墨盒颜色，如 "#000000,#00FFFF"
+	// 解析后的墨盒信息（运行时填充，不存储）
+	Markers []PrinterMarker `json:"markers,omitempty" gorm:"-"`
+}
+
+// PrinterMarker 单个墨盒的墨量信息
+type PrinterMarker struct {
+	Index          int      `json:"index"`
+	Name           string   `json:"name"`
+	Color          string   `json:"color,omitempty"`
+	Level          *int     `json:"level"`                    // 百分比，打印机无法获取时为 null
+	DailyUsage     *float64 `json:"dailyUsage,omitempty"`     // 近期每日消耗百分比
+	DaysUntilEmpty *float64 `json:"daysUntilEmpty,omitempty"` // 按近期消耗速度预测的剩余天数
 }
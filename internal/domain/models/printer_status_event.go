@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// PrinterStatusEvent 打印机状态变化记录，仅在 Status 发生变化时写入
+// 打印机从上报列表中消失时记录 removed；按设备与 IP（或打印机名称）关联，打印机被删除重建后历史仍连续
+type PrinterStatusEvent struct {
+	ModelFields
+	PrinterID      *uint               `json:"printerId,omitempty" gorm:"index"`
+	DeviceID       uint                `json:"deviceId" gorm:"not null;index:idx_printer_event_target_time"`
+	BuildingID     uint                `json:"buildingId" gorm:"index"`
+	IPAddress      string              `json:"ipAddress" gorm:"size:255;index:idx_printer_event_target_time"`
+	PrinterName    string              `json:"printerName" gorm:"size:255"`
+	Status         field.PrinterStatus `json:"status" gorm:"size:20;not null"`
+	PreviousStatus field.PrinterStatus `json:"previousStatus" gorm:"size:20"`
+	State          string              `json:"state" gorm:"size:100"`  // CUPS 状态: idle, processing, stopped
+	Reason         string              `json:"reason" gorm:"size:500"` // 离线原因
+	OccurredAt     time.Time           `json:"occurredAt" gorm:"not null;index:idx_printer_event_target_time"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PrinterSupplySample 墨盒墨量历史，墨量变化时记录
+type PrinterSupplySample struct {
+	ModelFields
+	PrinterID   uint      `json:"printerId" gorm:"not null;index:idx_supply_printer_time"`
+	MarkerIndex int       `json:"markerIndex"`
+	Name        string    `json:"name" gorm:"size:100"`
+	Color       string    `json:"color,omitempty" gorm:"size:50"`
+	Level       int       `json:"level"`
+	RecordedAt  time.Time `json:"recordedAt" gorm:"not null;index:idx_supply_printer_time"`
+}
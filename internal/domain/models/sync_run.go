@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// SyncRun 一次通知同步（定时或手动），每个参与同步的建筑对应一条 SyncRunBuilding
+type SyncRun struct {
+	ModelFields
+	Trigger       field.SyncRunTrigger `json:"trigger" gorm:"column:trigger_type;size:20;not null;index"`
+	Status        field.SyncRunStatus  `json:"status" gorm:"size:20;not null;default:'running';index"`
+	TriggeredBy   string               `json:"triggeredBy" gorm:"size:255"` // 手动同步的操作人
+	BuildingCount int                  `json:"buildingCount"`
+	SuccessCount  int                  `json:"successCount"` // 同步成功的建筑数（含部分通知失败）
+	FailedCount   int                  `json:"failedCount"`  // 同步失败的建筑数
+	StartedAt     time.Time            `json:"startedAt" gorm:"index"`
+	FinishedAt    *time.Time           `json:"finishedAt"`
+	DurationMs    int64                `json:"durationMs"`
+	Breakers      datatypes.JSON       `json:"breakers" gorm:"type:json"` // 同步结束时各上游主机的熔断器状态
+	Buildings     []SyncRunBuilding    `json:"buildings,omitempty" gorm:"foreignKey:RunID"`
+}
+
+// SyncRunBuilding 单个建筑在一次通知同步中的结果
+// idx_sync_run_building_last 覆盖调度器按建筑取上次同步时间的查询
+type SyncRunBuilding struct {
+	ModelFields
+	RunID          uint                `json:"runId" gorm:"not null;index"`
+	BuildingID     uint                `json:"buildingId" gorm:"not null;index:idx_sync_run_building_started,priority:1;index:idx_sync_run_building_last,priority:1"`
+	BuildingName   string              `json:"buildingName" gorm:"size:255"`
+	Status         field.SyncRunStatus `json:"status" gorm:"size:20;not null;index;index:idx_sync_run_building_last,priority:2"`
+	TotalProcessed int                 `json:"totalProcessed"` // 来源通知总数
+	SuccessCount   int                 `json:"successCount"`   // 新增或重新绑定的通知数
+	HasSyncedCount int                 `json:"hasSyncedCount"` // 已同步无需处理的通知数
+	DeleteCount    int                 `json:"deleteCount"`    // 解绑的通知数
+	FailedCount    int                 `json:"failedCount"`
+	FailedNotices  datatypes.JSON      `json:"failedNotices" gorm:"type:json"` // 失败通知详情
+	Error          string              `json:"error" gorm:"size:1000"`         // 整个建筑同步失败的原因
+	StartedAt      time.Time           `json:"startedAt" gorm:"index:idx_sync_run_building_started,priority:2;index:idx_sync_run_building_last,priority:3"`
+	DurationMs     int64               `json:"durationMs"`
+	ConflictCount  int                 `json:"conflictCount"`              // 因本地修改未合并的来源变化数
+	Conflicts      datatypes.JSON      `json:"conflicts" gorm:"type:json"` // 冲突详情
+}
@@ -1,11 +1,24 @@
 package models
 
+import "github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+
 // Version 版本信息模型
 type Version struct {
 	ModelFields
-	VersionNumber string `json:"versionNumber" gorm:"size:50;not null;uniqueIndex"`
-	BuildNumber   string `json:"buildNumber" gorm:"size:50;comment:'构建号'"`
-	Description   string `json:"description" gorm:"type:text"`
-	DownloadUrl   string `json:"downloadUrl" gorm:"size:500;not null"`
-	Status        string `json:"status" gorm:"size:50;default:'active';comment:'状态:active,inactive,deprecated'"`
+	VersionNumber string               `json:"versionNumber" gorm:"size:50;not null;uniqueIndex"`
+	BuildNumber   string               `json:"buildNumber" gorm:"size:50;comment:'构建号'"`
+	Description   string               `json:"description" gorm:"type:text"`
+	DownloadUrl   string               `json:"downloadUrl" gorm:"size:500;not null"`
+	Status        string               `json:"status" gorm:"size:50;default:'active';comment:'状态:active,inactive,deprecated'"`
+	Channel       field.ReleaseChannel `json:"channel" gorm:"size:20;default:'stable';index;comment:'发布渠道:stable,beta,pilot'"`
+	// APK 元数据，创建时从下载地址读取，设备安装前用于校验
+	FileID        *uint  `json:"fileId,omitempty" gorm:"index;comment:'上传的 APK 文件ID'"`
+	Sha256        string `json:"sha256" gorm:"size:64"`
+	FileSize      int64  `json:"fileSize"`
+	PackageName   string `json:"packageName" gorm:"size:255"`
+	VersionCode   int    `json:"versionCode"`
+	MinSdkVersion int    `json:"minSdkVersion" gorm:"comment:'最低 Android API 级别'"`
+	Signature     string `json:"signature" gorm:"size:255;comment:'服务端对元数据的 Ed25519 签名(base64)'"`
+	// 无法下载 APK 时使用管理员提交的元数据，未经服务端校验，不签名，重新读取成功后清除
+	MetadataUnverified bool `json:"metadataUnverified" gorm:"default:false;comment:'元数据未经服务端校验'"`
 }
@@ -168,6 +168,13 @@ func (s *AdvertisementService) Update(id uint, updates map[string]interface{}) (
 		return nil, err
 	}
 
+	// 通知绑定建筑下的设备刷新轮播
+	buildingIDs := make([]uint, 0, len(advertisement.Buildings))
+	for _, building := range advertisement.Buildings {
+		buildingIDs = append(buildingIDs, building.ID)
+	}
+	PublishDeviceEvent(field.DeviceEventCarouselChanged, buildingIDs, nil, map[string]interface{}{"advertisementId": id})
+
 	return &advertisement, nil
 }
 
@@ -341,6 +348,9 @@ func (s *AdvertisementService) Delete(ids []uint) error {
 		return errors.New("database connection is nil")
 	}
 
+	var buildingIDs []uint
+	s.db.Table("advertisement_buildings").Where("advertisement_id IN ?", ids).Distinct().Pluck("building_id", &buildingIDs)
+
 	result := s.db.Delete(&base_models.Advertisement{}, ids)
 	if result.Error != nil {
 		return result.Error
@@ -348,6 +358,8 @@ func (s *AdvertisementService) Delete(ids []uint) error {
 	if result.RowsAffected == 0 {
 		return errors.New("no records found to delete")
 	}
+
+	PublishDeviceEvent(field.DeviceEventCarouselChanged, buildingIDs, nil, map[string]interface{}{"advertisementIds": ids})
 	return nil
 }
 
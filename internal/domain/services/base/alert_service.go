@@ -0,0 +1,524 @@
+package base_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	redis "github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+// 多实例部署时只有持有该租约的实例执行告警检测
+const alertEngineLeaderKey = "alert:engine:leader"
+
+// getAlertCheckInterval returns the alert evaluation interval from environment variables
+func getAlertCheckInterval() time.Duration {
+	interval := os.Getenv("ALERT_CHECK_INTERVAL")
+	if interval == "" {
+		return time.Minute
+	}
+
+	seconds, err := strconv.Atoi(interval)
+	if err != nil || seconds <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// alertCandidate 规则命中的目标
+type alertCandidate struct {
+	TargetKey  string
+	BuildingID uint
+	DeviceID   uint
+	PrinterID  *uint
+	Message    string
+}
+
+// InterfaceAlertService 告警规则引擎接口
+type InterfaceAlertService interface {
+	// 执行一次告警检测，返回新开启与恢复的告警数量
+	Evaluate() (int, int, error)
+	// 启动定时告警检测
+	StartAlertScheduler(ctx context.Context)
+	// 规则管理
+	GetRules() ([]models.AlertRule, error)
+	CreateRule(rule *models.AlertRule) error
+	UpdateRule(id uint, updates map[string]interface{}) (*models.AlertRule, error)
+	DeleteRules(ids []uint) error
+	// 告警记录
+	GetAlerts(query map[string]interface{}, paginate map[string]interface{}) ([]models.Alert, models.PaginationResult, error)
+	GetAlertByID(id uint) (*models.Alert, error)
+}
+
+// AlertService 告警规则引擎实现
+type AlertService struct {
+	db           *gorm.DB
+	emailService IEmailService
+}
+
+// NewAlertService 创建告警服务，emailService 为空时只记录告警不发送邮件
+func NewAlertService(db *gorm.DB, emailService IEmailService) InterfaceAlertService {
+	return &AlertService{
+		db:           db,
+		emailService: emailService,
+	}
+}
+
+func (s *AlertService) StartAlertScheduler(ctx context.Context) {
+	interval := getAlertCheckInterval()
+	ticker := time.NewTicker(interval)
+	leader := newSchedulerLeader(redis.REDIS_CONN, alertEngineLeaderKey, "告警检测")
+	log.Info("告警检测已启动 | 间隔: %s", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				leader.Release()
+				log.Info("告警检测已停止")
+				return
+			case <-ticker.C:
+				if !leader.IsLeader(ctx) {
+					continue
+				}
+				opened, resolved, err := s.Evaluate()
+				if err != nil {
+					log.Error("告警检测失败 | 错误: %v", err)
+					continue
+				}
+				if opened > 0 || resolved > 0 {
+					log.Info("告警检测完成 | 新告警: %d | 已恢复: %d", opened, resolved)
+				}
+			}
+		}
+	}()
+}
+
+func (s *AlertService) Evaluate() (int, int, error) {
+	var rules []models.AlertRule
+	if err := s.db.Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to load alert rules: %v", err)
+	}
+
+	opened, resolved := 0, 0
+	for _, rule := range rules {
+		candidates, err := s.collectCandidates(rule)
+		if err != nil {
+			log.Warn("告警规则检测失败 | 规则ID: %d | 类型: %s | 错误: %v", rule.ID, rule.Type, err)
+			continue
+		}
+
+		o, r, err := s.reconcile(rule, candidates)
+		if err != nil {
+			log.Warn("告警状态更新失败 | 规则ID: %d | 错误: %v", rule.ID, err)
+			continue
+		}
+		opened += o
+		resolved += r
+	}
+
+	return opened, resolved, nil
+}
+
+// collectCandidates 根据规则类型找出当前命中的目标
+func (s *AlertService) collectCandidates(rule models.AlertRule) (map[string]alertCandidate, error) {
+	candidates := make(map[string]alertCandidate)
+
+	switch rule.Type {
+	case field.AlertRuleDeviceOffline:
+		cutoff := time.Now().Add(-time.Duration(rule.Threshold * float64(time.Minute)))
+		latestIDs := s.db.Model(&models.DeviceStatusEvent{}).Select("MAX(id)").Group("device_id")
+		var events []models.DeviceStatusEvent
+		db := s.db.Where("id IN (?) AND status = ? AND occurred_at <= ?", latestIDs, field.DeviceStatusOffline, cutoff)
+		if rule.BuildingID != nil {
+			db = db.Where("building_id = ?", *rule.BuildingID)
+		}
+		if err := db.Find(&events).Error; err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			return candidates, nil
+		}
+
+		deviceIDs := make([]uint, 0, len(events))
+		offlineSince := make(map[uint]time.Time, len(events))
+		for _, event := range events {
+			deviceIDs = append(deviceIDs, event.DeviceID)
+			offlineSince[event.DeviceID] = event.OccurredAt
+		}
+		var devices []models.Device
+		if err := s.db.Select("id", "device_id", "building_id").Where("id IN ?", deviceIDs).Find(&devices).Error; err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			key := fmt.Sprintf("device:%d", device.ID)
+			candidates[key] = alertCandidate{
+				TargetKey:  key,
+				BuildingID: device.BuildingID,
+				DeviceID:   device.ID,
+				Message: fmt.Sprintf("设备 %s 自 %s 起离线已超过 %.0f 分钟",
+					device.DeviceID, offlineSince[device.ID].Format("2006-01-02 15:04:05"), rule.Threshold),
+			}
+		}
+
+	case field.AlertRuleOrangePiOffline:
+		var devices []models.Device
+		db := s.db.Select("id", "device_id", "building_id", "orange_pi_reason").Where("orange_pi_status = ?", "offline")
+		if rule.BuildingID != nil {
+			db = db.Where("building_id = ?", *rule.BuildingID)
+		}
+		if err := db.Find(&devices).Error; err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			key := fmt.Sprintf("orangepi:%d", device.ID)
+			message := fmt.Sprintf("设备 %s 的香橙派打印服务离线", device.DeviceID)
+			if device.OrangePi.Reason != nil && *device.OrangePi.Reason != "" {
+				message += "，原因: " + *device.OrangePi.Reason
+			}
+			candidates[key] = alertCandidate{
+				TargetKey:  key,
+				BuildingID: device.BuildingID,
+				DeviceID:   device.ID,
+				Message:    message,
+			}
+		}
+
+	case field.AlertRulePrinterOffline, field.AlertRuleInkLow:
+		type printerRow struct {
+			models.Printer
+			DeviceCode string
+			BuildingID uint
+		}
+		var printers []printerRow
+		db := s.db.Model(&models.Printer{}).
+			Select("printers.*, devices.device_id AS device_code, devices.building_id AS building_id").
+			Joins("JOIN devices ON devices.id = printers.device_id")
+		if rule.Type == field.AlertRulePrinterOffline {
+			db = db.Where("printers.status = ?", "offline")
+		} else {
+			db = db.Where("printers.marker_levels IS NOT NULL AND printers.marker_levels <> ''")
+		}
+		if rule.BuildingID != nil {
+			db = db.Where("devices.building_id = ?", *rule.BuildingID)
+		}
+		if err := db.Find(&printers).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range printers {
+			printerID := row.ID
+			name := printerDisplayName(row.Printer)
+
+			if rule.Type == field.AlertRulePrinterOffline {
+				key := fmt.Sprintf("printer:%d", row.ID)
+				message := fmt.Sprintf("设备 %s 的打印机 %s 离线", row.DeviceCode, name)
+				if row.Reason != nil && *row.Reason != "" {
+					message += "，原因: " + *row.Reason
+				}
+				candidates[key] = alertCandidate{TargetKey: key, BuildingID: row.BuildingID, DeviceID: *row.DeviceID, PrinterID: &printerID, Message: message}
+				continue
+			}
+
+			var low []string
+			for _, marker := range ParsePrinterMarkers(row.Printer) {
+				if marker.Level != nil && float64(*marker.Level) < rule.Threshold {
+					low = append(low, fmt.Sprintf("%s: %d%%", marker.Name, *marker.Level))
+				}
+			}
+			if len(low) > 0 {
+				key := fmt.Sprintf("ink:%d", row.ID)
+				candidates[key] = alertCandidate{
+					TargetKey:  key,
+					BuildingID: row.BuildingID,
+					DeviceID:   *row.DeviceID,
+					PrinterID:  &printerID,
+					Message:    fmt.Sprintf("设备 %s 的打印机 %s 墨量低于 %.0f%%（%s）", row.DeviceCode, name, rule.Threshold, strings.Join(low, ", ")),
+				}
+			}
+		}
+
+	case field.AlertRulePrinterFlapping:
+		var buildingID uint
+		if rule.BuildingID != nil {
+			buildingID = *rule.BuildingID
+		}
+		items, err := findFlappingPrinters(s.db, 0, int64(rule.Threshold), buildingID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			key := fmt.Sprintf("flap:%d:%s", item.DeviceID, item.IPAddress)
+			candidates[key] = alertCandidate{
+				TargetKey:  key,
+				BuildingID: item.BuildingID,
+				DeviceID:   item.DeviceID,
+				PrinterID:  item.PrinterID,
+				Message: fmt.Sprintf("设备 %s 的打印机 %s 在 %.0f 分钟内状态变化 %d 次（离线 %d 次），疑似网络不稳定",
+					item.DeviceCode, item.PrinterName, getPrinterFlapWindow().Minutes(), item.Changes, item.OfflineCount),
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported alert rule type: %s", rule.Type)
+	}
+
+	return candidates, nil
+}
+
+func printerDisplayName(printer models.Printer) string {
+	if printer.DisplayName != nil && *printer.DisplayName != "" {
+		return *printer.DisplayName
+	}
+	if printer.Name != nil && *printer.Name != "" {
+		return *printer.Name
+	}
+	return fmt.Sprintf("#%d", printer.ID)
+}
+
+// reconcile 为新命中的目标开启告警，为不再命中的目标关闭告警
+func (s *AlertService) reconcile(rule models.AlertRule, candidates map[string]alertCandidate) (int, int, error) {
+	var openAlerts []models.Alert
+	if err := s.db.Where("rule_id = ? AND status = ?", rule.ID, field.AlertStatusOpen).Find(&openAlerts).Error; err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	opened, resolved := 0, 0
+	openByKey := make(map[string]bool, len(openAlerts))
+
+	for i := range openAlerts {
+		alert := &openAlerts[i]
+		openByKey[alert.TargetKey] = true
+		if _, firing := candidates[alert.TargetKey]; firing {
+			continue
+		}
+
+		alert.Status = field.AlertStatusResolved
+		alert.ResolvedAt = &now
+		if err := s.db.Model(alert).Updates(map[string]interface{}{
+			"status":      alert.Status,
+			"resolved_at": now,
+		}).Error; err != nil {
+			return opened, resolved, err
+		}
+		resolved++
+		log.Info("告警恢复 | 规则ID: %d | 目标: %s", rule.ID, alert.TargetKey)
+
+		// 只有发送过告警通知的才发送恢复通知
+		if rule.NotifyRecovery && alert.Notified {
+			if s.notify(rule, alert, true) {
+				s.db.Model(alert).Update("recovery_notice", true)
+			}
+		}
+	}
+
+	for key, candidate := range candidates {
+		if openByKey[key] {
+			continue
+		}
+
+		alert := &models.Alert{
+			RuleID:     rule.ID,
+			RuleType:   rule.Type,
+			TargetKey:  key,
+			BuildingID: candidate.BuildingID,
+			DeviceID:   candidate.DeviceID,
+			PrinterID:  candidate.PrinterID,
+			Status:     field.AlertStatusOpen,
+			Message:    candidate.Message,
+			OpenedAt:   now,
+		}
+		if err := s.db.Create(alert).Error; err != nil {
+			return opened, resolved, err
+		}
+		opened++
+		log.Warn("告警触发 | 规则ID: %d | 目标: %s | %s", rule.ID, key, candidate.Message)
+
+		if s.inCooldown(rule, key, now) {
+			log.Info("告警处于冷却期，不发送通知 | 规则ID: %d | 目标: %s", rule.ID, key)
+			continue
+		}
+		if s.notify(rule, alert, false) {
+			s.db.Model(alert).Updates(map[string]interface{}{"notified": true, "notified_at": now})
+		}
+	}
+
+	return opened, resolved, nil
+}
+
+// inCooldown 同一规则同一目标在冷却时间内已发送过通知
+func (s *AlertService) inCooldown(rule models.AlertRule, targetKey string, now time.Time) bool {
+	if rule.CooldownMinutes <= 0 {
+		return false
+	}
+	var count int64
+	s.db.Model(&models.Alert{}).
+		Where("rule_id = ? AND target_key = ? AND notified = ? AND notified_at > ?",
+			rule.ID, targetKey, true, now.Add(-time.Duration(rule.CooldownMinutes)*time.Minute)).
+		Count(&count)
+	return count > 0
+}
+
+// buildingAdminEmails 建筑下所有启用的管理员邮箱
+func buildingAdminEmails(db *gorm.DB, buildingID uint) ([]string, error) {
+	var recipients []string
+	err := db.Table("building_admins").
+		Joins("JOIN building_admins_buildings ON building_admins_buildings.building_admin_id = building_admins.id").
+		Where("building_admins_buildings.building_id = ? AND building_admins.status = ?", buildingID, field.StatusActive).
+		Pluck("building_admins.email", &recipients).Error
+	return recipients, err
+}
+
+// notify 发送邮件给建筑绑定的管理员，返回是否发送成功
+func (s *AlertService) notify(rule models.AlertRule, alert *models.Alert, recovery bool) bool {
+	if s.emailService == nil {
+		log.Warn("邮件服务未初始化，跳过告警通知 | 告警ID: %d", alert.ID)
+		return false
+	}
+
+	recipients, err := buildingAdminEmails(s.db, alert.BuildingID)
+	if err != nil {
+		log.Warn("获取告警收件人失败 | 告警ID: %d | 错误: %v", alert.ID, err)
+		return false
+	}
+	if len(recipients) == 0 {
+		log.Warn("建筑未绑定管理员，跳过告警通知 | 告警ID: %d | 建筑ID: %d", alert.ID, alert.BuildingID)
+		return false
+	}
+
+	var building models.Building
+	s.db.Select("id", "name").First(&building, alert.BuildingID)
+
+	subject := fmt.Sprintf("[iBoard 告警] %s - %s", building.Name, rule.Name)
+	body := fmt.Sprintf("<p>%s</p><p>触发时间: %s</p>", html.EscapeString(alert.Message), alert.OpenedAt.Format("2006-01-02 15:04:05"))
+	if recovery {
+		subject = fmt.Sprintf("[iBoard 恢复] %s - %s", building.Name, rule.Name)
+		body = fmt.Sprintf("<p>已恢复: %s</p><p>触发时间: %s</p><p>恢复时间: %s</p>",
+			html.EscapeString(alert.Message), alert.OpenedAt.Format("2006-01-02 15:04:05"), alert.ResolvedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	if err := s.emailService.SendEmail(recipients, subject, body); err != nil {
+		return false
+	}
+	return true
+}
+
+func (s *AlertService) GetRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := s.db.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DefaultAlertCooldownMinutes 创建规则未指定冷却时间时使用
+const DefaultAlertCooldownMinutes = 60
+
+// CreateRule 按传入的值保存规则，false 与 0 不会被替换为默认值
+func (s *AlertService) CreateRule(rule *models.AlertRule) error {
+	if !field.IsValidAlertRuleType(string(rule.Type)) {
+		return fmt.Errorf("invalid alert rule type: %s", rule.Type)
+	}
+	return s.db.Create(rule).Error
+}
+
+func (s *AlertService) UpdateRule(id uint, updates map[string]interface{}) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	if t, ok := updates["type"].(string); ok && !field.IsValidAlertRuleType(t) {
+		return nil, fmt.Errorf("invalid alert rule type: %s", t)
+	}
+
+	if err := s.db.Model(&rule).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *AlertService) DeleteRules(ids []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 规则删除后关闭其未恢复的告警，不发送恢复通知
+		if err := tx.Model(&models.Alert{}).
+			Where("rule_id IN ? AND status = ?", ids, field.AlertStatusOpen).
+			Updates(map[string]interface{}{"status": field.AlertStatusResolved, "resolved_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.AlertRule{}, ids)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("no records found to delete")
+		}
+		return nil
+	})
+}
+
+func (s *AlertService) GetAlerts(query map[string]interface{}, paginate map[string]interface{}) ([]models.Alert, models.PaginationResult, error) {
+	var alerts []models.Alert
+	var total int64
+	db := s.db.Model(&models.Alert{})
+
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	if deviceID, ok := query["deviceId"].(uint); ok && deviceID != 0 {
+		db = db.Where("device_id = ?", deviceID)
+	}
+	if ruleID, ok := query["ruleId"].(uint); ok && ruleID != 0 {
+		db = db.Where("rule_id = ?", ruleID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("opened_at DESC")
+	} else {
+		db = db.Order("opened_at ASC")
+	}
+
+	if err := db.Preload("Rule").Limit(pageSize).Offset(offset).Find(&alerts).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return alerts, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *AlertService) GetAlertByID(id uint) (*models.Alert, error) {
+	var alert models.Alert
+	if err := s.db.Preload("Rule").First(&alert, id).Error; err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
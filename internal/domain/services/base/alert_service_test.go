@@ -0,0 +1,50 @@
+package base_services
+
+import (
+	"testing"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+func TestCreateAlertRuleKeepsZeroValues(t *testing.T) {
+	tests := []struct {
+		name            string
+		cooldownMinutes int
+		notifyRecovery  bool
+		enabled         bool
+	}{
+		{"全部为零值", 0, false, false},
+		{"停用的规则", DefaultAlertCooldownMinutes, true, false},
+		{"不发送恢复通知", DefaultAlertCooldownMinutes, false, true},
+		{"不限制通知间隔", 0, true, true},
+		{"默认值", DefaultAlertCooldownMinutes, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.AlertRule{})
+			s := &AlertService{db: db}
+			rule := &models.AlertRule{
+				Name:            tt.name,
+				Type:            field.AlertRuleDeviceOffline,
+				Threshold:       10,
+				CooldownMinutes: tt.cooldownMinutes,
+				NotifyRecovery:  tt.notifyRecovery,
+				Enabled:         tt.enabled,
+			}
+			if err := s.CreateRule(rule); err != nil {
+				t.Fatalf("create rule: %v", err)
+			}
+
+			var stored models.AlertRule
+			if err := db.First(&stored, rule.ID).Error; err != nil {
+				t.Fatalf("load rule: %v", err)
+			}
+			if stored.CooldownMinutes != tt.cooldownMinutes || stored.NotifyRecovery != tt.notifyRecovery || stored.Enabled != tt.enabled {
+				t.Errorf("stored cooldown=%d notifyRecovery=%v enabled=%v, want %d %v %v",
+					stored.CooldownMinutes, stored.NotifyRecovery, stored.Enabled,
+					tt.cooldownMinutes, tt.notifyRecovery, tt.enabled)
+			}
+		})
+	}
+}
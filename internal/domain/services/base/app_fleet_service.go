@@ -0,0 +1,354 @@
+package base_services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"gorm.io/gorm"
+)
+
+// unknownAppVersion 未上报版本的设备在统计中的版本号
+const unknownAppVersion = "unknown"
+
+// AppVersionCount 某个版本的设备数
+type AppVersionCount struct {
+	Version string `json:"version"`
+	Count   int64  `json:"count"`
+}
+
+// BuildingVersionDistribution 建筑内的版本分布
+type BuildingVersionDistribution struct {
+	BuildingID   uint              `json:"buildingId"`
+	BuildingName string            `json:"buildingName"`
+	Total        int64             `json:"total"`
+	Versions     []AppVersionCount `json:"versions"`
+}
+
+// AppVersionDistribution 全部设备与各建筑的版本分布
+type AppVersionDistribution struct {
+	Total     int64                         `json:"total"`
+	Versions  []AppVersionCount             `json:"versions"`
+	Buildings []BuildingVersionDistribution `json:"buildings"`
+}
+
+// OutdatedDevice 已安装版本与应安装版本不一致的设备
+type OutdatedDevice struct {
+	ID                uint                     `json:"id"`
+	DeviceID          string                   `json:"deviceId"`
+	BuildingID        uint                     `json:"buildingId"`
+	BuildingName      string                   `json:"buildingName"`
+	InstalledVersion  string                   `json:"installedVersion"`
+	InstalledBuild    string                   `json:"installedBuild"`
+	VersionReportedAt *time.Time               `json:"versionReportedAt"`
+	TargetVersion     *models.Version          `json:"targetVersion"`
+	TargetSource      string                   `json:"targetSource"`
+	ForceUpdate       bool                     `json:"forceUpdate"`
+	LastAttempt       *models.AppUpdateAttempt `json:"lastAttempt"`
+}
+
+// DeviceVersionHistory 设备的版本状态与更新记录
+type DeviceVersionHistory struct {
+	DeviceID          uint                      `json:"deviceId"`
+	InstalledVersion  string                    `json:"installedVersion"`
+	InstalledBuild    string                    `json:"installedBuild"`
+	VersionReportedAt *time.Time                `json:"versionReportedAt"`
+	Target            *AppVersionResolution     `json:"target"`
+	Outdated          bool                      `json:"outdated"`
+	Attempts          []models.AppUpdateAttempt `json:"attempts"`
+}
+
+// InterfaceAppFleetService 设备 App 版本统计服务接口
+type InterfaceAppFleetService interface {
+	// 记录设备上报的已安装版本，版本变化且设备未上报结果时补记一条更新记录
+	ReportInstalled(device *models.Device, version string, build string) error
+	// 版本分布，buildingID 为 0 时统计全部建筑
+	GetDistribution(buildingID uint) (*AppVersionDistribution, error)
+	// 版本落后于应安装版本的设备
+	GetOutdated(query map[string]interface{}, paginate map[string]interface{}) ([]OutdatedDevice, models.PaginationResult, error)
+	GetDeviceHistory(deviceID uint, limit int) (*DeviceVersionHistory, error)
+}
+
+// AppFleetService 设备 App 版本统计服务实现
+type AppFleetService struct {
+	db *gorm.DB
+}
+
+// NewAppFleetService 创建设备 App 版本统计服务
+func NewAppFleetService(db *gorm.DB) InterfaceAppFleetService {
+	return &AppFleetService{db: db}
+}
+
+// isOutdated 已安装版本低于应安装版本；允许降级时（固定版本或回滚）只要不一致即视为落后
+func isOutdated(installed string, resolution *AppVersionResolution) bool {
+	if installed == "" || resolution.Version == nil {
+		return false
+	}
+	compare := CompareVersionNumbers(installed, resolution.Version.VersionNumber)
+	if resolution.AllowDowngrade {
+		return compare != 0
+	}
+	return compare < 0
+}
+
+func (s *AppFleetService) ReportInstalled(device *models.Device, version string, build string) error {
+	version = truncateString(strings.TrimSpace(version), 50)
+	build = truncateString(strings.TrimSpace(build), 50)
+	if version == "" {
+		return nil
+	}
+
+	now := time.Now()
+	previous := device.InstalledVersion
+	previousReportedAt := device.VersionReportedAt
+
+	if err := s.db.Model(&models.Device{}).Where("id = ?", device.ID).Updates(map[string]interface{}{
+		"installed_version":   version,
+		"installed_build":     build,
+		"version_reported_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update installed version: %v", err)
+	}
+	device.InstalledVersion = version
+	device.InstalledBuild = build
+	device.VersionReportedAt = &now
+
+	if previous != "" && previous != version {
+		log.Info("设备版本变化 | 设备ID: %s | %s -> %s", device.DeviceID, previous, version)
+		s.recordDetectedUpdate(device, previous, version, previousReportedAt)
+	}
+	return nil
+}
+
+// recordDetectedUpdate 上次上报后设备没有该版本的成功记录时补记一条
+func (s *AppFleetService) recordDetectedUpdate(device *models.Device, from string, to string, since *time.Time) {
+	var version models.Version
+	if err := s.db.Where("version_number = ?", to).First(&version).Error; err != nil {
+		return
+	}
+
+	db := s.db.Model(&models.AppUpdateAttempt{}).
+		Where("device_id = ? AND version_id = ? AND success = ?", device.ID, version.ID, true)
+	if since != nil {
+		db = db.Where("attempted_at >= ?", *since)
+	}
+	var count int64
+	if err := db.Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+
+	attempt := models.AppUpdateAttempt{
+		DeviceID:    device.ID,
+		BuildingID:  device.BuildingID,
+		VersionID:   version.ID,
+		FromVersion: from,
+		Success:     true,
+		Detected:    true,
+		AttemptedAt: time.Now(),
+	}
+	if err := s.db.Create(&attempt).Error; err != nil {
+		log.Warn("补记设备更新记录失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+	}
+}
+
+func (s *AppFleetService) GetDistribution(buildingID uint) (*AppVersionDistribution, error) {
+	var rows []struct {
+		BuildingID       uint
+		InstalledVersion string
+		Count            int64
+	}
+	db := s.db.Model(&models.Device{}).
+		Select("building_id, COALESCE(installed_version, '') AS installed_version, COUNT(*) AS count").
+		Group("building_id, COALESCE(installed_version, '')")
+	if buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	if err := db.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count device versions: %v", err)
+	}
+
+	buildingIDs := make([]uint, 0)
+	buildingIndex := make(map[uint]int)
+	totals := make(map[string]int64)
+	distribution := &AppVersionDistribution{}
+	for _, row := range rows {
+		version := row.InstalledVersion
+		if version == "" {
+			version = unknownAppVersion
+		}
+		index, ok := buildingIndex[row.BuildingID]
+		if !ok {
+			index = len(distribution.Buildings)
+			buildingIndex[row.BuildingID] = index
+			buildingIDs = append(buildingIDs, row.BuildingID)
+			distribution.Buildings = append(distribution.Buildings, BuildingVersionDistribution{BuildingID: row.BuildingID})
+		}
+		building := &distribution.Buildings[index]
+		building.Total += row.Count
+		building.Versions = append(building.Versions, AppVersionCount{Version: version, Count: row.Count})
+		totals[version] += row.Count
+		distribution.Total += row.Count
+	}
+
+	names, err := s.buildingNames(buildingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range distribution.Buildings {
+		distribution.Buildings[i].BuildingName = names[distribution.Buildings[i].BuildingID]
+		sortVersionCounts(distribution.Buildings[i].Versions)
+	}
+	sort.Slice(distribution.Buildings, func(i, j int) bool {
+		return distribution.Buildings[i].BuildingID < distribution.Buildings[j].BuildingID
+	})
+
+	distribution.Versions = make([]AppVersionCount, 0, len(totals))
+	for version, count := range totals {
+		distribution.Versions = append(distribution.Versions, AppVersionCount{Version: version, Count: count})
+	}
+	sortVersionCounts(distribution.Versions)
+	return distribution, nil
+}
+
+// sortVersionCounts 版本号从高到低，未知版本排在最后
+func sortVersionCounts(counts []AppVersionCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Version == unknownAppVersion || counts[j].Version == unknownAppVersion {
+			return counts[j].Version == unknownAppVersion && counts[i].Version != unknownAppVersion
+		}
+		return CompareVersionNumbers(counts[i].Version, counts[j].Version) > 0
+	})
+}
+
+func (s *AppFleetService) buildingNames(ids []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var buildings []models.Building
+	if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load buildings: %v", err)
+	}
+	for _, building := range buildings {
+		names[building.ID] = building.Name
+	}
+	return names, nil
+}
+
+func (s *AppFleetService) GetOutdated(query map[string]interface{}, paginate map[string]interface{}) ([]OutdatedDevice, models.PaginationResult, error) {
+	var devices []models.Device
+	db := s.db.Select("id", "device_id", "building_id", "device_group_id", "release_channel",
+		"pinned_version_id", "installed_version", "installed_build", "version_reported_at")
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	includeUnknown, _ := query["includeUnknown"].(bool)
+	if !includeUnknown {
+		db = db.Where("installed_version <> ''")
+	}
+	if err := db.Order("building_id ASC, id ASC").Find(&devices).Error; err != nil {
+		return nil, models.PaginationResult{}, fmt.Errorf("failed to load devices: %v", err)
+	}
+
+	resolver, err := newAppVersionResolver(s.db)
+	if err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	forceOnly, _ := query["forceOnly"].(bool)
+	outdated := make([]OutdatedDevice, 0)
+	for i := range devices {
+		device := &devices[i]
+		resolution, err := resolver.resolve(device, device.InstalledVersion)
+		if err != nil {
+			return nil, models.PaginationResult{}, err
+		}
+		if device.InstalledVersion != "" && !isOutdated(device.InstalledVersion, resolution) {
+			continue
+		}
+		if forceOnly && !resolution.ForceUpdate {
+			continue
+		}
+		item := OutdatedDevice{
+			ID:                device.ID,
+			DeviceID:          device.DeviceID,
+			BuildingID:        device.BuildingID,
+			InstalledVersion:  device.InstalledVersion,
+			InstalledBuild:    device.InstalledBuild,
+			VersionReportedAt: device.VersionReportedAt,
+			TargetVersion:     resolution.Version,
+			TargetSource:      resolution.Source,
+			ForceUpdate:       resolution.ForceUpdate,
+		}
+		outdated = append(outdated, item)
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	total := len(outdated)
+	start := (pageNum - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	page := outdated[start:end]
+
+	buildingIDs := make([]uint, 0, len(page))
+	for _, item := range page {
+		buildingIDs = append(buildingIDs, item.BuildingID)
+	}
+	names, err := s.buildingNames(buildingIDs)
+	if err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+	for i := range page {
+		page[i].BuildingName = names[page[i].BuildingID]
+		var attempt models.AppUpdateAttempt
+		if err := s.db.Where("device_id = ?", page[i].ID).Order("attempted_at DESC").First(&attempt).Error; err == nil {
+			page[i].LastAttempt = &attempt
+		}
+	}
+
+	return page, models.PaginationResult{
+		Total:    total,
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *AppFleetService) GetDeviceHistory(deviceID uint, limit int) (*DeviceVersionHistory, error) {
+	var device models.Device
+	if err := s.db.First(&device, deviceID).Error; err != nil {
+		return nil, errors.New("device not found")
+	}
+
+	resolution, err := NewAppRolloutService(s.db).Resolve(&device, device.InstalledVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 50
+	}
+	var attempts []models.AppUpdateAttempt
+	if err := s.db.Where("device_id = ?", deviceID).Order("attempted_at DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load update attempts: %v", err)
+	}
+
+	return &DeviceVersionHistory{
+		DeviceID:          device.ID,
+		InstalledVersion:  device.InstalledVersion,
+		InstalledBuild:    device.InstalledBuild,
+		VersionReportedAt: device.VersionReportedAt,
+		Target:            resolution,
+		Outdated:          isOutdated(device.InstalledVersion, resolution),
+		Attempts:          attempts,
+	}, nil
+}
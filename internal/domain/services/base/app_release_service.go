@@ -0,0 +1,261 @@
+package base_services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+// 发布渠道来源
+const (
+	ReleaseChannelSourceDevice   = "device"
+	ReleaseChannelSourceBuilding = "building"
+	ReleaseChannelSourceDefault  = "default"
+)
+
+// DeviceRelease 设备的发布渠道与应安装版本
+type DeviceRelease struct {
+	DeviceID      uint                  `json:"deviceId"`
+	Channel       field.ReleaseChannel  `json:"channel"`
+	ChannelSource string                `json:"channelSource"`
+	PinnedVersion *models.Version       `json:"pinnedVersion"`
+	Resolution    *AppVersionResolution `json:"resolution"`
+}
+
+// InterfaceAppReleaseService 发布渠道与版本固定服务接口
+type InterfaceAppReleaseService interface {
+	// 设置设备渠道，空字符串表示跟随建筑
+	SetDeviceChannel(deviceIDs []uint, channel field.ReleaseChannel) error
+	// 设置建筑渠道，空字符串表示 stable
+	SetBuildingChannel(buildingIDs []uint, channel field.ReleaseChannel) error
+	// 固定设备版本，versionID 为 nil 时取消固定
+	PinDevices(deviceIDs []uint, versionID *uint) error
+	// 获取设备实际生效的渠道
+	GetDeviceChannel(deviceID uint) (field.ReleaseChannel, error)
+	GetDeviceRelease(deviceID uint) (*DeviceRelease, error)
+}
+
+// AppReleaseService 发布渠道与版本固定服务实现
+type AppReleaseService struct {
+	db *gorm.DB
+}
+
+// NewAppReleaseService 创建发布渠道服务
+func NewAppReleaseService(db *gorm.DB) InterfaceAppReleaseService {
+	return &AppReleaseService{db: db}
+}
+
+// CompareVersionNumbers 按点分数字比较版本号（如 1.10.0 > 1.9.2），返回 -1、0 或 1
+func CompareVersionNumbers(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(strings.TrimSpace(a), "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(strings.TrimSpace(b), "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = leadingNumber(as[i])
+		}
+		if i < len(bs) {
+			y = leadingNumber(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// leadingNumber 取版本段开头的数字部分，如 "3-beta" 为 3
+func leadingNumber(segment string) int {
+	end := 0
+	for end < len(segment) && segment[end] >= '0' && segment[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(segment[:end])
+	return n
+}
+
+// releaseChannelRank 渠道顺序，越靠后的渠道越早拿到新版本
+func releaseChannelRank(channel field.ReleaseChannel) int {
+	switch channel {
+	case field.ReleaseChannelBeta:
+		return 1
+	case field.ReleaseChannelPilot:
+		return 2
+	}
+	return 0
+}
+
+// visibleReleaseChannels 渠道可见的版本渠道（包含之前的渠道）
+func visibleReleaseChannels(channel field.ReleaseChannel) []field.ReleaseChannel {
+	channels := []field.ReleaseChannel{field.ReleaseChannelStable, field.ReleaseChannelBeta, field.ReleaseChannelPilot}
+	return channels[:releaseChannelRank(channel)+1]
+}
+
+// subscribedReleaseChannels 能收到该渠道版本的渠道（包含之后的渠道）
+func subscribedReleaseChannels(channel field.ReleaseChannel) []field.ReleaseChannel {
+	channels := []field.ReleaseChannel{field.ReleaseChannelStable, field.ReleaseChannelBeta, field.ReleaseChannelPilot}
+	return channels[releaseChannelRank(channel):]
+}
+
+// effectiveReleaseChannel 设备渠道优先，其次为所在建筑的渠道，都未设置时为 stable
+func effectiveReleaseChannel(db *gorm.DB, device *models.Device) (field.ReleaseChannel, string) {
+	if device.ReleaseChannel != "" {
+		return device.ReleaseChannel, ReleaseChannelSourceDevice
+	}
+
+	channel := device.Building.ReleaseChannel
+	if device.Building.ID == 0 && device.BuildingID != 0 {
+		var building models.Building
+		if err := db.Select("id", "release_channel").First(&building, device.BuildingID).Error; err == nil {
+			channel = building.ReleaseChannel
+		}
+	}
+	if channel != "" {
+		return channel, ReleaseChannelSourceBuilding
+	}
+	return field.ReleaseChannelStable, ReleaseChannelSourceDefault
+}
+
+// channelDeviceIDs 实际生效渠道在 channels 中的设备ID
+func channelDeviceIDs(db *gorm.DB, channels []field.ReleaseChannel) ([]uint, error) {
+	includeStable := false
+	for _, channel := range channels {
+		if channel == field.ReleaseChannelStable {
+			includeStable = true
+		}
+	}
+
+	buildingQuery := db.Model(&models.Building{}).Select("id").Where("release_channel IN ?", channels)
+	if includeStable {
+		buildingQuery = buildingQuery.Or("release_channel = '' OR release_channel IS NULL")
+	}
+
+	var ids []uint
+	if err := db.Model(&models.Device{}).
+		Where("release_channel IN ?", channels).
+		Or("(release_channel = '' OR release_channel IS NULL) AND building_id IN (?)", buildingQuery).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to load channel devices: %v", err)
+	}
+	return ids, nil
+}
+
+func (s *AppReleaseService) SetDeviceChannel(deviceIDs []uint, channel field.ReleaseChannel) error {
+	if len(deviceIDs) == 0 {
+		return errors.New("deviceIds is required")
+	}
+	if channel != "" && !field.IsValidReleaseChannel(string(channel)) {
+		return fmt.Errorf("invalid release channel: %s", channel)
+	}
+
+	if err := s.db.Model(&models.Device{}).Where("id IN ?", deviceIDs).
+		Update("release_channel", channel).Error; err != nil {
+		return fmt.Errorf("failed to update device channel: %v", err)
+	}
+
+	log.Info("设置设备发布渠道 | 设备数: %d | 渠道: %s", len(deviceIDs), channel)
+	PublishDeviceEvent(field.DeviceEventAppUpdateAvailable, nil, deviceIDs, map[string]interface{}{
+		"channel": channel,
+	})
+	return nil
+}
+
+func (s *AppReleaseService) SetBuildingChannel(buildingIDs []uint, channel field.ReleaseChannel) error {
+	if len(buildingIDs) == 0 {
+		return errors.New("buildingIds is required")
+	}
+	if channel != "" && !field.IsValidReleaseChannel(string(channel)) {
+		return fmt.Errorf("invalid release channel: %s", channel)
+	}
+
+	if err := s.db.Model(&models.Building{}).Where("id IN ?", buildingIDs).
+		Update("release_channel", channel).Error; err != nil {
+		return fmt.Errorf("failed to update building channel: %v", err)
+	}
+
+	log.Info("设置建筑发布渠道 | 建筑数: %d | 渠道: %s", len(buildingIDs), channel)
+
+	// 只有跟随建筑的设备受影响
+	var deviceIDs []uint
+	if err := s.db.Model(&models.Device{}).
+		Where("building_id IN ? AND (release_channel = '' OR release_channel IS NULL)", buildingIDs).
+		Pluck("id", &deviceIDs).Error; err != nil {
+		log.Warn("获取建筑设备失败 | 错误: %v", err)
+		return nil
+	}
+	PublishDeviceEvent(field.DeviceEventAppUpdateAvailable, nil, deviceIDs, map[string]interface{}{
+		"channel": channel,
+	})
+	return nil
+}
+
+func (s *AppReleaseService) PinDevices(deviceIDs []uint, versionID *uint) error {
+	if len(deviceIDs) == 0 {
+		return errors.New("deviceIds is required")
+	}
+
+	data := map[string]interface{}{}
+	if versionID != nil {
+		var version models.Version
+		if err := s.db.First(&version, *versionID).Error; err != nil {
+			return errors.New("version not found")
+		}
+		data["versionId"] = version.ID
+		data["versionNumber"] = version.VersionNumber
+	}
+
+	if err := s.db.Model(&models.Device{}).Where("id IN ?", deviceIDs).
+		Update("pinned_version_id", versionID).Error; err != nil {
+		return fmt.Errorf("failed to pin device version: %v", err)
+	}
+
+	if versionID != nil {
+		log.Info("固定设备版本 | 设备数: %d | 版本ID: %d", len(deviceIDs), *versionID)
+	} else {
+		log.Info("取消固定设备版本 | 设备数: %d", len(deviceIDs))
+	}
+	PublishDeviceEvent(field.DeviceEventAppUpdateAvailable, nil, deviceIDs, data)
+	return nil
+}
+
+func (s *AppReleaseService) GetDeviceChannel(deviceID uint) (field.ReleaseChannel, error) {
+	var device models.Device
+	if err := s.db.Select("id", "building_id", "release_channel").First(&device, deviceID).Error; err != nil {
+		return "", errors.New("device not found")
+	}
+	channel, _ := effectiveReleaseChannel(s.db, &device)
+	return channel, nil
+}
+
+func (s *AppReleaseService) GetDeviceRelease(deviceID uint) (*DeviceRelease, error) {
+	var device models.Device
+	if err := s.db.First(&device, deviceID).Error; err != nil {
+		return nil, errors.New("device not found")
+	}
+
+	release := &DeviceRelease{DeviceID: device.ID}
+	release.Channel, release.ChannelSource = effectiveReleaseChannel(s.db, &device)
+
+	if device.PinnedVersionID != nil {
+		var version models.Version
+		if err := s.db.First(&version, *device.PinnedVersionID).Error; err == nil {
+			release.PinnedVersion = &version
+		}
+	}
+
+	resolution, err := NewAppRolloutService(s.db).Resolve(&device, "")
+	if err != nil {
+		return nil, err
+	}
+	release.Resolution = resolution
+	return release, nil
+}
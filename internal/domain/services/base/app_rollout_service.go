@@ -0,0 +1,778 @@
+package base_services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// 版本来源
+const (
+	AppVersionSourceDefault = "default" // App.CurrentVersion
+	AppVersionSourceChannel = "channel" // 所在渠道的最新活跃版本
+	AppVersionSourceRollout = "rollout"
+	AppVersionSourcePinned  = "pinned"
+	AppVersionSourceMinimum = "minimum" // 低于最低支持版本时提升到最低版本
+)
+
+// AppRolloutInput 创建或修改灰度发布的参数，修改时为 nil 的字段保持不变
+type AppRolloutInput struct {
+	Name           *string
+	VersionID      *uint
+	Percentage     *int
+	BuildingIDs    []uint
+	DeviceGroupIDs []uint
+	MaxFailures    *int
+	MaxFailureRate *float64
+	MinReports     *int
+	FailureAction  *field.AppRolloutFailureAction
+}
+
+// AppVersionResolution 设备应安装的版本
+type AppVersionResolution struct {
+	Version   *models.Version      `json:"version"`
+	Source    string               `json:"source"`
+	RolloutID *uint                `json:"rolloutId,omitempty"`
+	Rollback  bool                 `json:"rollback"` // 所在灰度已回滚，允许安装低于当前的版本
+	Channel   field.ReleaseChannel `json:"channel"`
+	// 允许安装低于已安装版本的版本（灰度回滚或固定版本）
+	AllowDowngrade bool            `json:"allowDowngrade"`
+	MinVersion     *models.Version `json:"minVersion"`
+	ForceUpdate    bool            `json:"forceUpdate"` // 已安装版本低于最低支持版本
+}
+
+// AppRolloutStats 灰度发布进度
+type AppRolloutStats struct {
+	TargetDevices int64    `json:"targetDevices"` // 当前命中灰度的设备数
+	Attempts      int64    `json:"attempts"`
+	Succeeded     int64    `json:"succeeded"`
+	Failed        int64    `json:"failed"`
+	FailureRate   *float64 `json:"failureRate"` // 失败率（%）
+}
+
+// AppRolloutDetail 灰度发布详情
+type AppRolloutDetail struct {
+	models.AppRollout
+	Stats AppRolloutStats `json:"stats"`
+}
+
+// AppUpdateReport 设备上报的更新结果
+type AppUpdateReport struct {
+	VersionID     uint   `json:"versionId"`
+	VersionNumber string `json:"versionNumber"` // 与 versionId 二选一
+	FromVersion   string `json:"fromVersion"`
+	Success       *bool  `json:"success" binding:"required"`
+	ErrorReason   string `json:"errorReason"`
+}
+
+// InterfaceAppRolloutService 应用灰度发布服务接口
+type InterfaceAppRolloutService interface {
+	Create(input AppRolloutInput, createdBy string) (*models.AppRollout, error)
+	Update(id uint, input AppRolloutInput) (*models.AppRollout, error)
+	Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.AppRollout, models.PaginationResult, error)
+	GetByID(id uint) (*AppRolloutDetail, error)
+	// 暂停、恢复、回滚或取消
+	SetStatus(id uint, status field.AppRolloutStatus, reason string) (*models.AppRollout, error)
+	// 全量发布：设为 App 当前版本并结束灰度
+	Complete(id uint) (*models.AppRollout, error)
+	// 解析设备应安装的版本，installedVersion 为设备已安装的版本号（可为空）
+	Resolve(device *models.Device, installedVersion string) (*AppVersionResolution, error)
+	// 记录设备更新结果，失败过多时自动暂停或回滚所在灰度
+	ReportUpdate(device *models.Device, report AppUpdateReport) (*models.AppUpdateAttempt, error)
+	GetAttempts(query map[string]interface{}, paginate map[string]interface{}) ([]models.AppUpdateAttempt, models.PaginationResult, error)
+}
+
+// AppRolloutService 应用灰度发布服务实现
+type AppRolloutService struct {
+	db *gorm.DB
+}
+
+// NewAppRolloutService 创建应用灰度发布服务
+func NewAppRolloutService(db *gorm.DB) InterfaceAppRolloutService {
+	return &AppRolloutService{db: db}
+}
+
+// rolloutBucket 按版本与 DeviceID 计算 0-99 的稳定分桶，同一版本调大比例时已命中的设备保持不变
+func rolloutBucket(versionID uint, deviceID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(fmt.Sprintf("%d:%s", versionID, deviceID)))
+	return int(h.Sum32() % 100)
+}
+
+func parseIDList(raw datatypes.JSON) []uint {
+	var ids []uint
+	if len(raw) == 0 {
+		return ids
+	}
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil
+	}
+	return ids
+}
+
+func toIDList(ids []uint) datatypes.JSON {
+	if ids == nil {
+		ids = []uint{}
+	}
+	b, _ := json.Marshal(ids)
+	return datatypes.JSON(b)
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, value := range ids {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutMatches 判断设备是否命中灰度
+func rolloutMatches(rollout models.AppRollout, device *models.Device) bool {
+	buildingIDs := parseIDList(rollout.BuildingIDs)
+	groupIDs := parseIDList(rollout.DeviceGroupIDs)
+	if len(buildingIDs) > 0 || len(groupIDs) > 0 {
+		inScope := containsID(buildingIDs, device.BuildingID)
+		if !inScope && device.DeviceGroupID != nil {
+			inScope = containsID(groupIDs, *device.DeviceGroupID)
+		}
+		if !inScope {
+			return false
+		}
+	}
+	return rolloutBucket(rollout.VersionID, device.DeviceID) < rollout.Percentage
+}
+
+func (s *AppRolloutService) applyInput(rollout *models.AppRollout, input AppRolloutInput) error {
+	if input.Name != nil {
+		rollout.Name = *input.Name
+	}
+	if input.VersionID != nil {
+		var version models.Version
+		if err := s.db.First(&version, *input.VersionID).Error; err != nil {
+			return errors.New("version not found")
+		}
+		rollout.VersionID = version.ID
+	}
+	if input.Percentage != nil {
+		if *input.Percentage < 0 || *input.Percentage > 100 {
+			return errors.New("percentage must be between 0 and 100")
+		}
+		rollout.Percentage = *input.Percentage
+	}
+	if input.BuildingIDs != nil {
+		rollout.BuildingIDs = toIDList(input.BuildingIDs)
+	}
+	if input.DeviceGroupIDs != nil {
+		rollout.DeviceGroupIDs = toIDList(input.DeviceGroupIDs)
+	}
+	if input.MaxFailures != nil {
+		if *input.MaxFailures < 0 {
+			return errors.New("maxFailures must not be negative")
+		}
+		rollout.MaxFailures = *input.MaxFailures
+	}
+	if input.MaxFailureRate != nil {
+		if *input.MaxFailureRate < 0 || *input.MaxFailureRate > 100 {
+			return errors.New("maxFailureRate must be between 0 and 100")
+		}
+		rollout.MaxFailureRate = *input.MaxFailureRate
+	}
+	if input.MinReports != nil {
+		if *input.MinReports < 1 {
+			return errors.New("minReports must be at least 1")
+		}
+		rollout.MinReports = *input.MinReports
+	}
+	if input.FailureAction != nil {
+		if !field.IsValidAppRolloutFailureAction(string(*input.FailureAction)) {
+			return fmt.Errorf("invalid failure action: %s", *input.FailureAction)
+		}
+		rollout.FailureAction = *input.FailureAction
+	}
+	return nil
+}
+
+func (s *AppRolloutService) Create(input AppRolloutInput, createdBy string) (*models.AppRollout, error) {
+	if input.VersionID == nil {
+		return nil, errors.New("versionId is required")
+	}
+
+	rollout := models.AppRollout{
+		Status:         field.AppRolloutActive,
+		MinReports:     10,
+		FailureAction:  field.AppRolloutFailurePause,
+		BuildingIDs:    toIDList(nil),
+		DeviceGroupIDs: toIDList(nil),
+		CreatedBy:      createdBy,
+	}
+	if err := s.applyInput(&rollout, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&rollout).Error; err != nil {
+		return nil, fmt.Errorf("failed to create rollout: %v", err)
+	}
+
+	log.Info("创建灰度发布 | 灰度ID: %d | 版本ID: %d | 比例: %d%%", rollout.ID, rollout.VersionID, rollout.Percentage)
+	s.notifyDevices(rollout)
+	return s.getRollout(rollout.ID)
+}
+
+func (s *AppRolloutService) Update(id uint, input AppRolloutInput) (*models.AppRollout, error) {
+	rollout, err := s.getRollout(id)
+	if err != nil {
+		return nil, err
+	}
+	switch rollout.Status {
+	case field.AppRolloutCompleted, field.AppRolloutRolledBack, field.AppRolloutCanceled:
+		return nil, fmt.Errorf("rollout already %s", rollout.Status)
+	}
+
+	if err := s.applyInput(rollout, input); err != nil {
+		return nil, err
+	}
+	rollout.Version = nil
+
+	if err := s.db.Save(rollout).Error; err != nil {
+		return nil, fmt.Errorf("failed to update rollout: %v", err)
+	}
+
+	log.Info("更新灰度发布 | 灰度ID: %d | 比例: %d%%", rollout.ID, rollout.Percentage)
+	if rollout.Status == field.AppRolloutActive {
+		s.notifyDevices(*rollout)
+	}
+	return s.getRollout(id)
+}
+
+func (s *AppRolloutService) getRollout(id uint) (*models.AppRollout, error) {
+	var rollout models.AppRollout
+	if err := s.db.Preload("Version").First(&rollout, id).Error; err != nil {
+		return nil, err
+	}
+	return &rollout, nil
+}
+
+func (s *AppRolloutService) Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.AppRollout, models.PaginationResult, error) {
+	var rollouts []models.AppRollout
+	var total int64
+	db := s.db.Model(&models.AppRollout{})
+
+	if versionID, ok := query["versionId"].(uint); ok && versionID != 0 {
+		db = db.Where("version_id = ?", versionID)
+	}
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("created_at DESC")
+	} else {
+		db = db.Order("created_at ASC")
+	}
+
+	if err := db.Preload("Version").Limit(pageSize).Offset(offset).Find(&rollouts).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return rollouts, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *AppRolloutService) GetByID(id uint) (*AppRolloutDetail, error) {
+	rollout, err := s.getRollout(id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &AppRolloutDetail{AppRollout: *rollout}
+	targets, err := s.targetDevices(*rollout)
+	if err != nil {
+		return nil, err
+	}
+	detail.Stats.TargetDevices = int64(len(targets))
+
+	succeeded, failed, err := s.countAttempts(rollout.ID)
+	if err != nil {
+		return nil, err
+	}
+	detail.Stats.Succeeded = succeeded
+	detail.Stats.Failed = failed
+	detail.Stats.Attempts = succeeded + failed
+	if detail.Stats.Attempts > 0 {
+		rate := float64(failed) * 100 / float64(detail.Stats.Attempts)
+		detail.Stats.FailureRate = &rate
+	}
+	return detail, nil
+}
+
+func (s *AppRolloutService) countAttempts(rolloutID uint) (int64, int64, error) {
+	var row struct {
+		Succeeded int64
+		Failed    int64
+	}
+	if err := s.db.Model(&models.AppUpdateAttempt{}).
+		Select("COALESCE(SUM(CASE WHEN success THEN 1 ELSE 0 END), 0) AS succeeded, COALESCE(SUM(CASE WHEN success THEN 0 ELSE 1 END), 0) AS failed").
+		Where("rollout_id = ?", rolloutID).Scan(&row).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to count update attempts: %v", err)
+	}
+	return row.Succeeded, row.Failed, nil
+}
+
+// targetDevices 命中灰度的设备ID
+func (s *AppRolloutService) targetDevices(rollout models.AppRollout) ([]uint, error) {
+	var devices []models.Device
+	db := s.db.Select("id", "device_id", "building_id", "device_group_id")
+
+	buildingIDs := parseIDList(rollout.BuildingIDs)
+	groupIDs := parseIDList(rollout.DeviceGroupIDs)
+	if len(buildingIDs) > 0 && len(groupIDs) > 0 {
+		db = db.Where("building_id IN ? OR device_group_id IN ?", buildingIDs, groupIDs)
+	} else if len(buildingIDs) > 0 {
+		db = db.Where("building_id IN ?", buildingIDs)
+	} else if len(groupIDs) > 0 {
+		db = db.Where("device_group_id IN ?", groupIDs)
+	}
+
+	if err := db.Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to load devices: %v", err)
+	}
+
+	ids := make([]uint, 0)
+	for i := range devices {
+		if rolloutMatches(rollout, &devices[i]) {
+			ids = append(ids, devices[i].ID)
+		}
+	}
+	return ids, nil
+}
+
+// notifyDevices 通知命中灰度的设备检查更新
+func (s *AppRolloutService) notifyDevices(rollout models.AppRollout) {
+	deviceIDs, err := s.targetDevices(rollout)
+	if err != nil {
+		log.Warn("获取灰度设备失败 | 灰度ID: %d | 错误: %v", rollout.ID, err)
+		return
+	}
+	PublishDeviceEvent(field.DeviceEventAppUpdateAvailable, nil, deviceIDs, map[string]interface{}{
+		"versionId": rollout.VersionID,
+		"rolloutId": rollout.ID,
+	})
+}
+
+func (s *AppRolloutService) SetStatus(id uint, status field.AppRolloutStatus, reason string) (*models.AppRollout, error) {
+	rollout, err := s.getRollout(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case field.AppRolloutPaused:
+		if rollout.Status != field.AppRolloutActive {
+			return nil, fmt.Errorf("rollout is %s", rollout.Status)
+		}
+	case field.AppRolloutActive:
+		if rollout.Status != field.AppRolloutPaused {
+			return nil, fmt.Errorf("rollout is %s", rollout.Status)
+		}
+	case field.AppRolloutRolledBack, field.AppRolloutCanceled:
+		if rollout.Status != field.AppRolloutActive && rollout.Status != field.AppRolloutPaused {
+			return nil, fmt.Errorf("rollout is %s", rollout.Status)
+		}
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+
+	if err := s.db.Model(&models.AppRollout{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "status_reason": reason}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update rollout status: %v", err)
+	}
+
+	log.Info("灰度发布状态变更 | 灰度ID: %d | 状态: %s -> %s | 原因: %s", id, rollout.Status, status, reason)
+
+	// 恢复与回滚都需要设备重新检查版本
+	if status == field.AppRolloutActive || status == field.AppRolloutRolledBack {
+		s.notifyDevices(*rollout)
+	}
+	return s.getRollout(id)
+}
+
+func (s *AppRolloutService) Complete(id uint) (*models.AppRollout, error) {
+	rollout, err := s.getRollout(id)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.Status != field.AppRolloutActive && rollout.Status != field.AppRolloutPaused {
+		return nil, fmt.Errorf("rollout is %s", rollout.Status)
+	}
+
+	var app models.App
+	if err := s.db.First(&app).Error; err != nil {
+		return nil, fmt.Errorf("app config not found: %v", err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.App{}).Where("id = ?", app.ID).
+			Update("current_version_id", rollout.VersionID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AppRollout{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"status": field.AppRolloutCompleted, "percentage": 100}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete rollout: %v", err)
+	}
+
+	log.Info("灰度发布全量 | 灰度ID: %d | 版本ID: %d", id, rollout.VersionID)
+
+	data := map[string]interface{}{"versionId": rollout.VersionID}
+	if rollout.Version != nil {
+		data["versionNumber"] = rollout.Version.VersionNumber
+		data["buildNumber"] = rollout.Version.BuildNumber
+	}
+	BroadcastDeviceEvent(field.DeviceEventAppUpdateAvailable, data)
+
+	return s.getRollout(id)
+}
+
+// Resolve 优先级：固定版本 > 灰度（需高于渠道版本）> 渠道最新版本 > App 当前版本，最后不低于最低支持版本
+func (s *AppRolloutService) Resolve(device *models.Device, installedVersion string) (*AppVersionResolution, error) {
+	resolver, err := newAppVersionResolver(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return resolver.resolve(device, installedVersion)
+}
+
+// appVersionResolver 缓存 App 配置、灰度与渠道版本，批量解析多台设备时只查询一次
+type appVersionResolver struct {
+	db               *gorm.DB
+	app              models.App
+	rollouts         []models.AppRollout
+	rolloutsLoaded   bool
+	channelVersions  map[field.ReleaseChannel]*models.Version
+	buildingChannels map[uint]field.ReleaseChannel
+	versions         map[uint]*models.Version
+}
+
+func newAppVersionResolver(db *gorm.DB) (*appVersionResolver, error) {
+	resolver := &appVersionResolver{
+		db:              db,
+		channelVersions: make(map[field.ReleaseChannel]*models.Version),
+		versions:        make(map[uint]*models.Version),
+	}
+	if err := db.Preload("CurrentVersion").Preload("MinVersion").First(&resolver.app).Error; err != nil {
+		return nil, fmt.Errorf("app config not found: %v", err)
+	}
+	return resolver, nil
+}
+
+func (r *appVersionResolver) resolve(device *models.Device, installedVersion string) (*AppVersionResolution, error) {
+	resolution := &AppVersionResolution{
+		Version:    r.app.CurrentVersion,
+		Source:     AppVersionSourceDefault,
+		Channel:    field.ReleaseChannelStable,
+		MinVersion: r.app.MinVersion,
+	}
+
+	if device != nil {
+		if err := r.resolveDevice(device, resolution); err != nil {
+			return nil, err
+		}
+	}
+
+	if minVersion := r.app.MinVersion; minVersion != nil {
+		if resolution.Version == nil || CompareVersionNumbers(resolution.Version.VersionNumber, minVersion.VersionNumber) < 0 {
+			resolution.Version = minVersion
+			resolution.Source = AppVersionSourceMinimum
+			resolution.RolloutID = nil
+			resolution.AllowDowngrade = false
+		}
+		if installedVersion != "" && CompareVersionNumbers(installedVersion, minVersion.VersionNumber) < 0 {
+			resolution.ForceUpdate = true
+		}
+	}
+	return resolution, nil
+}
+
+func (r *appVersionResolver) resolveDevice(device *models.Device, resolution *AppVersionResolution) error {
+	// 渠道：取可见渠道中版本号最高的活跃版本
+	resolution.Channel = r.deviceChannel(device)
+	if resolution.Channel != field.ReleaseChannelStable {
+		version, err := r.channelVersion(resolution.Channel)
+		if err != nil {
+			return err
+		}
+		if version != nil && (resolution.Version == nil || CompareVersionNumbers(version.VersionNumber, resolution.Version.VersionNumber) > 0) {
+			resolution.Version = version
+			resolution.Source = AppVersionSourceChannel
+		}
+	}
+
+	// 灰度：最新创建的优先，渠道已提供更高版本时不降级
+	rollouts, err := r.activeRollouts()
+	if err != nil {
+		return err
+	}
+	for _, rollout := range rollouts {
+		if !rolloutMatches(rollout, device) {
+			continue
+		}
+		if rollout.Status == field.AppRolloutRolledBack {
+			resolution.Rollback = true
+			continue
+		}
+		if rollout.Version == nil {
+			continue
+		}
+		if resolution.Source == AppVersionSourceChannel && CompareVersionNumbers(rollout.Version.VersionNumber, resolution.Version.VersionNumber) <= 0 {
+			break
+		}
+		rolloutID := rollout.ID
+		resolution.Version = rollout.Version
+		resolution.Source = AppVersionSourceRollout
+		resolution.RolloutID = &rolloutID
+		resolution.Rollback = false
+		break
+	}
+	resolution.AllowDowngrade = resolution.Rollback
+
+	// 固定版本
+	if device.PinnedVersionID != nil {
+		if version := r.version(*device.PinnedVersionID); version != nil {
+			resolution.Version = version
+			resolution.Source = AppVersionSourcePinned
+			resolution.RolloutID = nil
+			resolution.AllowDowngrade = true
+		}
+	}
+	return nil
+}
+
+// deviceChannel 与 effectiveReleaseChannel 相同，建筑渠道一次性加载
+func (r *appVersionResolver) deviceChannel(device *models.Device) field.ReleaseChannel {
+	if device.ReleaseChannel != "" {
+		return device.ReleaseChannel
+	}
+	if device.Building.ID != 0 {
+		if device.Building.ReleaseChannel != "" {
+			return device.Building.ReleaseChannel
+		}
+		return field.ReleaseChannelStable
+	}
+
+	if r.buildingChannels == nil {
+		var buildings []models.Building
+		if err := r.db.Select("id", "release_channel").Where("release_channel <> ''").Find(&buildings).Error; err != nil {
+			log.Warn("加载建筑发布渠道失败 | 错误: %v", err)
+		}
+		r.buildingChannels = make(map[uint]field.ReleaseChannel, len(buildings))
+		for _, building := range buildings {
+			r.buildingChannels[building.ID] = building.ReleaseChannel
+		}
+	}
+	if channel, ok := r.buildingChannels[device.BuildingID]; ok {
+		return channel
+	}
+	return field.ReleaseChannelStable
+}
+
+// channelVersion 渠道可见的活跃版本中版本号最高的一个
+func (r *appVersionResolver) channelVersion(channel field.ReleaseChannel) (*models.Version, error) {
+	if version, ok := r.channelVersions[channel]; ok {
+		return version, nil
+	}
+
+	var versions []models.Version
+	if err := r.db.Where("status = ? AND channel IN ?", "active", visibleReleaseChannels(channel)).
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load channel versions: %v", err)
+	}
+	var latest *models.Version
+	for i := range versions {
+		if latest == nil || CompareVersionNumbers(versions[i].VersionNumber, latest.VersionNumber) > 0 {
+			latest = &versions[i]
+		}
+	}
+	r.channelVersions[channel] = latest
+	return latest, nil
+}
+
+func (r *appVersionResolver) activeRollouts() ([]models.AppRollout, error) {
+	if r.rolloutsLoaded {
+		return r.rollouts, nil
+	}
+	if err := r.db.Preload("Version").
+		Where("status IN ?", []field.AppRolloutStatus{field.AppRolloutActive, field.AppRolloutRolledBack}).
+		Order("id DESC").Find(&r.rollouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rollouts: %v", err)
+	}
+	r.rolloutsLoaded = true
+	return r.rollouts, nil
+}
+
+func (r *appVersionResolver) version(id uint) *models.Version {
+	if version, ok := r.versions[id]; ok {
+		return version
+	}
+	var version models.Version
+	if err := r.db.First(&version, id).Error; err != nil {
+		r.versions[id] = nil
+		return nil
+	}
+	r.versions[id] = &version
+	return &version
+}
+
+func (s *AppRolloutService) ReportUpdate(device *models.Device, report AppUpdateReport) (*models.AppUpdateAttempt, error) {
+	if report.Success == nil {
+		return nil, errors.New("success is required")
+	}
+
+	var version models.Version
+	db := s.db
+	if report.VersionID != 0 {
+		db = db.Where("id = ?", report.VersionID)
+	} else if report.VersionNumber != "" {
+		db = db.Where("version_number = ?", report.VersionNumber)
+	} else {
+		return nil, errors.New("versionId or versionNumber is required")
+	}
+	if err := db.First(&version).Error; err != nil {
+		return nil, errors.New("version not found")
+	}
+
+	attempt := models.AppUpdateAttempt{
+		DeviceID:    device.ID,
+		BuildingID:  device.BuildingID,
+		VersionID:   version.ID,
+		FromVersion: report.FromVersion,
+		Success:     *report.Success,
+		ErrorReason: truncateString(report.ErrorReason, 500),
+		AttemptedAt: time.Now(),
+	}
+
+	// 关联设备当前命中的该版本灰度
+	resolution, err := s.Resolve(device, "")
+	if err != nil {
+		return nil, err
+	}
+	if resolution.RolloutID != nil && resolution.Version != nil && resolution.Version.ID == version.ID {
+		attempt.RolloutID = resolution.RolloutID
+	}
+
+	if err := s.db.Create(&attempt).Error; err != nil {
+		return nil, fmt.Errorf("failed to record update attempt: %v", err)
+	}
+
+	if attempt.Success {
+		log.Info("设备更新成功 | 设备ID: %d | 版本: %s", device.ID, version.VersionNumber)
+	} else {
+		log.Warn("设备更新失败 | 设备ID: %d | 版本: %s | 原因: %s", device.ID, version.VersionNumber, attempt.ErrorReason)
+		if attempt.RolloutID != nil {
+			s.checkFailureLimit(*attempt.RolloutID)
+		}
+	}
+	return &attempt, nil
+}
+
+// checkFailureLimit 失败次数或失败率超过限制时自动暂停或回滚
+func (s *AppRolloutService) checkFailureLimit(rolloutID uint) {
+	rollout, err := s.getRollout(rolloutID)
+	if err != nil || rollout.Status != field.AppRolloutActive {
+		return
+	}
+
+	succeeded, failed, err := s.countAttempts(rolloutID)
+	if err != nil {
+		log.Warn("统计灰度更新结果失败 | 灰度ID: %d | 错误: %v", rolloutID, err)
+		return
+	}
+
+	reason := ""
+	total := succeeded + failed
+	if rollout.MaxFailures > 0 && failed >= int64(rollout.MaxFailures) {
+		reason = fmt.Sprintf("failures %d reached limit %d", failed, rollout.MaxFailures)
+	} else if rollout.MaxFailureRate > 0 && total >= int64(rollout.MinReports) {
+		rate := float64(failed) * 100 / float64(total)
+		if rate > rollout.MaxFailureRate {
+			reason = fmt.Sprintf("failure rate %.1f%% exceeded limit %.1f%%", rate, rollout.MaxFailureRate)
+		}
+	}
+	if reason == "" {
+		return
+	}
+
+	status := field.AppRolloutPaused
+	if rollout.FailureAction == field.AppRolloutFailureRollback {
+		status = field.AppRolloutRolledBack
+	}
+	if _, err := s.SetStatus(rolloutID, status, reason); err != nil {
+		log.Error("自动处理灰度发布失败 | 灰度ID: %d | 错误: %v", rolloutID, err)
+	}
+}
+
+func (s *AppRolloutService) GetAttempts(query map[string]interface{}, paginate map[string]interface{}) ([]models.AppUpdateAttempt, models.PaginationResult, error) {
+	var attempts []models.AppUpdateAttempt
+	var total int64
+	db := s.db.Model(&models.AppUpdateAttempt{})
+
+	if deviceID, ok := query["deviceId"].(uint); ok && deviceID != 0 {
+		db = db.Where("device_id = ?", deviceID)
+	}
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	if rolloutID, ok := query["rolloutId"].(uint); ok && rolloutID != 0 {
+		db = db.Where("rollout_id = ?", rolloutID)
+	}
+	if versionID, ok := query["versionId"].(uint); ok && versionID != 0 {
+		db = db.Where("version_id = ?", versionID)
+	}
+	if success, ok := query["success"].(*bool); ok && success != nil {
+		db = db.Where("success = ?", *success)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("attempted_at DESC")
+	} else {
+		db = db.Order("attempted_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&attempts).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return attempts, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
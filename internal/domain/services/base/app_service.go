@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"gorm.io/gorm"
 )
 
@@ -29,7 +30,7 @@ func NewAppService(db *gorm.DB) InterfaceAppService {
 // Get 获取当前版本配置
 func (s *AppService) Get() (*models.App, error) {
 	var app models.App
-	if err := s.db.Preload("CurrentVersion").First(&app).Error; err != nil {
+	if err := s.db.Preload("CurrentVersion").Preload("MinVersion").First(&app).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// 初始化一条默认记录
 			app = models.App{
@@ -66,11 +67,42 @@ func (s *AppService) Update(app *models.App) (*models.App, error) {
 		}
 	}
 
+	// 最低支持版本，0 表示清除
+	minVersionChanged := app.MinVersionID != nil
+	if app.MinVersionID != nil {
+		if *app.MinVersionID == 0 {
+			if err := s.db.Model(&models.App{}).Where("id = ?", app.ID).Update("min_version_id", nil).Error; err != nil {
+				return nil, err
+			}
+			app.MinVersionID = nil
+		} else {
+			var version models.Version
+			if err := s.db.First(&version, *app.MinVersionID).Error; err != nil {
+				return nil, errors.New("min version not found")
+			}
+		}
+	}
+
 	// 更新应用信息
 	if err := s.db.Model(&models.App{}).Where("id = ?", app.ID).Updates(app).Error; err != nil {
 		return nil, err
 	}
 
+	updatedApp, err := s.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// 当前版本或最低支持版本变化时通知所有设备检查更新
+	if app.CurrentVersionID != 0 || minVersionChanged {
+		data := map[string]interface{}{"versionId": updatedApp.CurrentVersionID}
+		if updatedApp.CurrentVersion != nil {
+			data["versionNumber"] = updatedApp.CurrentVersion.VersionNumber
+			data["buildNumber"] = updatedApp.CurrentVersion.BuildNumber
+		}
+		BroadcastDeviceEvent(field.DeviceEventAppUpdateAvailable, data)
+	}
+
 	// 返回更新后的应用信息
-	return s.Get()
+	return updatedApp, nil
 }
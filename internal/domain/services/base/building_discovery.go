@@ -0,0 +1,320 @@
+package base_services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// UnknownBuilding 本地建筑的 IsmartID 在其通知来源的建筑列表中不存在
+type UnknownBuilding struct {
+	BuildingID uint                 `json:"buildingId"`
+	Name       string               `json:"name"`
+	IsmartID   string               `json:"ismartId"`
+	Sources    []field.NoticeSource `json:"sources"` // 找不到该 IsmartID 的来源
+}
+
+// getUpstreamCheckInterval 定时比对来源建筑的间隔(小时)，0 时不定时比对
+func getUpstreamCheckInterval() time.Duration {
+	interval := os.Getenv("NOTICE_BUILDING_CHECK_INTERVAL")
+	if interval == "" {
+		return 24 * time.Hour // default to 24 hours if not set
+	}
+
+	intervalInt, err := strconv.Atoi(interval)
+	if err != nil || intervalInt < 0 {
+		return 24 * time.Hour // default to 24 hours if invalid value
+	}
+
+	return time.Duration(intervalInt) * time.Hour
+}
+
+// upstreamComparison 来源建筑列表与本地建筑的比对结果
+type upstreamComparison struct {
+	sourceCounts map[field.NoticeSource]int
+	sourceErrors map[field.NoticeSource]string
+	unmapped     []SourceBuilding
+	unknown      []UnknownBuilding
+	buildings    []base_models.Building
+	missing      map[uint][]field.NoticeSource // 各建筑找不到 IsmartID 的来源
+}
+
+// result 转换为接口返回结果
+func (c *upstreamComparison) result() gin.H {
+	return gin.H{
+		"sources":          c.sourceCounts,
+		"sourceErrors":     c.sourceErrors,
+		"unmapped":         c.unmapped,
+		"unmappedCount":    len(c.unmapped),
+		"unknownBuildings": c.unknown,
+		"unknownCount":     len(c.unknown),
+	}
+}
+
+// listUpstreamBuildings 获取所有通知来源的建筑列表，失败的来源记入 errors，不影响其他来源
+func (s *NoticeSyncService) listUpstreamBuildings(ctx context.Context) (map[field.NoticeSource][]SourceBuilding, map[field.NoticeSource]string) {
+	upstream := make(map[field.NoticeSource][]SourceBuilding, len(s.sources))
+	sourceErrors := make(map[field.NoticeSource]string)
+	for name, source := range s.sources {
+		buildings, err := source.ListBuildings(ctx)
+		if err != nil {
+			log.Warn("获取来源建筑失败 | 来源: %s | 错误: %v", name, err)
+			sourceErrors[name] = err.Error()
+			continue
+		}
+		log.Info("获取来源建筑 | 来源: %s | 数量: %d", name, len(buildings))
+		upstream[name] = buildings
+	}
+	return upstream, sourceErrors
+}
+
+// compareUpstreamBuildings 比对来源建筑与本地建筑，不写入数据
+// 获取失败的来源沿用建筑上次保存的标记，避免来源不可用时误标记或误清除
+func (s *NoticeSyncService) compareUpstreamBuildings(ctx context.Context) (*upstreamComparison, error) {
+	upstream, sourceErrors := s.listUpstreamBuildings(ctx)
+
+	var buildings []base_models.Building
+	if err := s.db.Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get buildings: %v", err)
+	}
+	local := make(map[string]bool, len(buildings))
+	for _, building := range buildings {
+		local[building.IsmartID] = true
+	}
+
+	comparison := &upstreamComparison{
+		sourceCounts: make(map[field.NoticeSource]int, len(upstream)),
+		sourceErrors: sourceErrors,
+		unmapped:     []SourceBuilding{},
+		unknown:      []UnknownBuilding{},
+		buildings:    buildings,
+		missing:      make(map[uint][]field.NoticeSource, len(buildings)),
+	}
+
+	known := make(map[field.NoticeSource]map[string]bool, len(upstream))
+	for name, sourceBuildings := range upstream {
+		known[name] = make(map[string]bool, len(sourceBuildings))
+		for _, building := range sourceBuildings {
+			known[name][building.ID] = true
+			if !local[building.ID] {
+				comparison.unmapped = append(comparison.unmapped, building)
+			}
+		}
+		comparison.sourceCounts[name] = len(sourceBuildings)
+	}
+	sort.Slice(comparison.unmapped, func(i, j int) bool {
+		if comparison.unmapped[i].Source != comparison.unmapped[j].Source {
+			return comparison.unmapped[i].Source < comparison.unmapped[j].Source
+		}
+		return comparison.unmapped[i].ID < comparison.unmapped[j].ID
+	})
+
+	for i := range buildings {
+		building := &buildings[i]
+		sources, err := buildingNoticeSources(building)
+		if err != nil {
+			log.Warn("解析建筑通知来源失败 | 建筑ID: %d | 错误: %v", building.ID, err)
+			comparison.missing[building.ID] = buildingUpstreamMissing(building)
+			continue
+		}
+
+		previous := make(map[field.NoticeSource]bool)
+		for _, name := range buildingUpstreamMissing(building) {
+			previous[name] = true
+		}
+		var missing []field.NoticeSource
+		for _, name := range sources {
+			ids, ok := known[name]
+			if (ok && !ids[building.IsmartID]) || (!ok && previous[name]) {
+				missing = append(missing, name)
+			}
+		}
+		comparison.missing[building.ID] = missing
+		if len(missing) > 0 {
+			comparison.unknown = append(comparison.unknown, UnknownBuilding{
+				BuildingID: building.ID,
+				Name:       building.Name,
+				IsmartID:   building.IsmartID,
+				Sources:    missing,
+			})
+		}
+	}
+	return comparison, nil
+}
+
+// DiscoverBuildings 比对来源建筑与本地建筑，返回尚未导入的来源建筑与 IsmartID 在来源中不存在的本地建筑，不更新建筑的标记
+func (s *NoticeSyncService) DiscoverBuildings() (gin.H, error) {
+	comparison, err := s.compareUpstreamBuildings(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return comparison.result(), nil
+}
+
+// RefreshUpstreamMissing 比对来源建筑并保存本地建筑的标记，只更新标记有变化的建筑
+func (s *NoticeSyncService) RefreshUpstreamMissing() (gin.H, error) {
+	ctx := context.Background()
+	comparison, err := s.compareUpstreamBuildings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedCount := 0
+	for i := range comparison.buildings {
+		building := &comparison.buildings[i]
+		updated, err := s.markUpstreamMissing(ctx, building, comparison.missing[building.ID])
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			updatedCount++
+		}
+	}
+	log.Info("来源建筑比对完成 | 本地建筑: %d | 未导入: %d | 来源中不存在: %d | 标记变化: %d | 失败来源: %d",
+		len(comparison.buildings), len(comparison.unmapped), len(comparison.unknown), updatedCount, len(comparison.sourceErrors))
+
+	result := comparison.result()
+	result["updatedCount"] = updatedCount
+	return result, nil
+}
+
+// markUpstreamMissing 标记有变化时保存并清除建筑缓存，返回是否有变化
+func (s *NoticeSyncService) markUpstreamMissing(ctx context.Context, building *base_models.Building, missing []field.NoticeSource) (bool, error) {
+	previous := buildingUpstreamMissing(building)
+	changed := len(previous) != len(missing)
+	for i := 0; !changed && i < len(missing); i++ {
+		changed = previous[i] != missing[i]
+	}
+	if !changed {
+		return false, nil
+	}
+
+	var value datatypes.JSON
+	if len(missing) > 0 {
+		data, err := json.Marshal(missing)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal missing sources: %v", err)
+		}
+		value = datatypes.JSON(data)
+	}
+	if err := s.db.Model(&base_models.Building{}).Where("id = ?", building.ID).
+		Update("upstream_missing", value).Error; err != nil {
+		return false, fmt.Errorf("failed to update building upstream check: %v", err)
+	}
+	if len(missing) > 0 {
+		log.Warn("建筑的 IsmartID 在来源中不存在 | 建筑ID: %d | IsmartID: %s | 来源: %v", building.ID, building.IsmartID, missing)
+	} else {
+		log.Info("建筑的 IsmartID 已在来源中找到 | 建筑ID: %d | IsmartID: %s", building.ID, building.IsmartID)
+	}
+	s.redis.Del(ctx, fmt.Sprintf("building:%d", building.ID))
+	return true, nil
+}
+
+// buildingUpstreamMissing 上次检查时找不到建筑 IsmartID 的通知来源
+func buildingUpstreamMissing(building *base_models.Building) []field.NoticeSource {
+	if len(building.UpstreamMissing) == 0 {
+		return nil
+	}
+	var sources []field.NoticeSource
+	if err := json.Unmarshal(building.UpstreamMissing, &sources); err != nil {
+		return nil
+	}
+	return sources
+}
+
+// ImportBuildings 从通知来源导入尚无本地建筑的建筑，ismartIDs 为空时导入该来源的全部未导入建筑
+// 来源不是默认来源时新建筑的通知来源设为该来源
+func (s *NoticeSyncService) ImportBuildings(source field.NoticeSource, ismartIDs []string) (gin.H, error) {
+	noticeSource, ok := s.sources[source]
+	if !ok {
+		return nil, fmt.Errorf("notice source %s is not available", source)
+	}
+	sourceBuildings, err := noticeSource.ListBuildings(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buildings from %s: %w", source, err)
+	}
+
+	upstream := make(map[string]SourceBuilding, len(sourceBuildings))
+	for _, building := range sourceBuildings {
+		upstream[building.ID] = building
+	}
+	if len(ismartIDs) == 0 {
+		for _, building := range sourceBuildings {
+			ismartIDs = append(ismartIDs, building.ID)
+		}
+	}
+
+	var existingIDs []string
+	if err := s.db.Model(&base_models.Building{}).Where("ismart_id IN ?", ismartIDs).
+		Pluck("ismart_id", &existingIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get existing buildings: %v", err)
+	}
+	existing := make(map[string]bool, len(existingIDs))
+	for _, id := range existingIDs {
+		existing[id] = true
+	}
+
+	var noticeSources datatypes.JSON
+	isDefault := false
+	for _, name := range getDefaultNoticeSources() {
+		isDefault = isDefault || name == source
+	}
+	if !isDefault {
+		data, _ := json.Marshal([]field.NoticeSource{source})
+		noticeSources = datatypes.JSON(data)
+	}
+
+	created := []base_models.Building{}
+	skipped := []string{}
+	notFound := []string{}
+	failed := []string{}
+	for _, id := range ismartIDs {
+		sourceBuilding, ok := upstream[id]
+		switch {
+		case !ok:
+			notFound = append(notFound, id)
+			continue
+		case existing[id]:
+			skipped = append(skipped, id)
+			continue
+		}
+
+		name := sourceBuilding.Name
+		if name == "" {
+			name = sourceBuilding.ID
+		}
+		building := base_models.Building{
+			Name:          name,
+			IsmartID:      sourceBuilding.ID,
+			Location:      sourceBuilding.Address,
+			NoticeSources: noticeSources,
+		}
+		if err := s.buildingService.Create(&building); err != nil {
+			log.Error("导入建筑失败 | 来源: %s | IsmartID: %s | 错误: %v", source, id, err)
+			failed = append(failed, fmt.Sprintf("Failed to import building %s: %v", id, err))
+			continue
+		}
+		existing[id] = true
+		created = append(created, building)
+	}
+	log.Info("导入来源建筑完成 | 来源: %s | 创建: %d | 已存在: %d | 来源中不存在: %d | 失败: %d",
+		source, len(created), len(skipped), len(notFound), len(failed))
+
+	return gin.H{
+		"created":      created,
+		"createdCount": len(created),
+		"skipped":      skipped,
+		"notFound":     notFound,
+		"failed":       failed,
+	}, nil
+}
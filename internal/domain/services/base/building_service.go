@@ -1,11 +1,13 @@
 package base_services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
 	"github.com/The-Healthist/iboard_http_service/pkg/log"
 	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"gorm.io/gorm"
@@ -84,6 +86,13 @@ func (s *BuildingService) Update(id uint, updates map[string]interface{}) error
 	if result.RowsAffected == 0 {
 		return errors.New("building not found")
 	}
+
+	// 通知同步会缓存建筑信息，修改后清除缓存使新的 IsmartID、通知来源立即生效
+	if redis.REDIS_CONN != nil {
+		if err := redis.REDIS_CONN.Del(context.Background(), fmt.Sprintf("building:%d", id)).Err(); err != nil {
+			log.Warn("清除建筑缓存失败 | 建筑ID: %d | 错误: %v", id, err)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,281 @@
+package base_services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// getDeviceCommandDefaultTTL returns the default command TTL from environment variables
+func getDeviceCommandDefaultTTL() time.Duration {
+	ttl := os.Getenv("DEVICE_COMMAND_DEFAULT_TTL")
+	if ttl == "" {
+		return time.Hour
+	}
+
+	seconds, err := strconv.Atoi(ttl)
+	if err != nil || seconds <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// InterfaceDeviceCommandService 设备远程命令服务接口
+type InterfaceDeviceCommandService interface {
+	// 向设备、分组或建筑下发命令，ttl 为 0 时使用默认值
+	Enqueue(target field.CommandTarget, targetID uint, command field.DeviceCommandType, payload map[string]interface{}, ttl time.Duration, issuedBy string) ([]models.DeviceCommand, error)
+	// 设备拉取待执行命令，拉取后状态变为 delivered
+	FetchPending(deviceID uint) ([]models.DeviceCommand, error)
+	// 设备回报执行结果
+	ReportResult(deviceID uint, commandID uint, success bool, result string) (*models.DeviceCommand, error)
+	// 命令历史
+	Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.DeviceCommand, models.PaginationResult, error)
+	GetByID(id uint) (*models.DeviceCommand, error)
+	// 将超时未完成的命令标记为 expired
+	ExpireStale() (int64, error)
+}
+
+// DeviceCommandService 设备远程命令服务实现
+type DeviceCommandService struct {
+	db *gorm.DB
+}
+
+// NewDeviceCommandService 创建设备远程命令服务
+func NewDeviceCommandService(db *gorm.DB) InterfaceDeviceCommandService {
+	return &DeviceCommandService{db: db}
+}
+
+// resolveTargetDevices 获取下发目标对应的设备ID
+func (s *DeviceCommandService) resolveTargetDevices(target field.CommandTarget, targetID uint) ([]uint, error) {
+	var deviceIDs []uint
+	db := s.db.Model(&models.Device{})
+
+	switch target {
+	case field.CommandTargetDevice:
+		db = db.Where("id = ?", targetID)
+	case field.CommandTargetGroup:
+		db = db.Where("device_group_id = ?", targetID)
+	case field.CommandTargetBuilding:
+		db = db.Where("building_id = ?", targetID)
+	default:
+		return nil, fmt.Errorf("invalid command target: %s", target)
+	}
+
+	if err := db.Pluck("id", &deviceIDs).Error; err != nil {
+		return nil, err
+	}
+	return deviceIDs, nil
+}
+
+func (s *DeviceCommandService) Enqueue(target field.CommandTarget, targetID uint, command field.DeviceCommandType, payload map[string]interface{}, ttl time.Duration, issuedBy string) ([]models.DeviceCommand, error) {
+	if !field.IsValidDeviceCommandType(string(command)) {
+		return nil, fmt.Errorf("invalid command: %s", command)
+	}
+	if ttl <= 0 {
+		ttl = getDeviceCommandDefaultTTL()
+	}
+
+	deviceIDs, err := s.resolveTargetDevices(target, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deviceIDs) == 0 {
+		return nil, errors.New("no devices found for target")
+	}
+
+	payloadJSON := datatypes.JSON("{}")
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload: %v", err)
+		}
+		payloadJSON = datatypes.JSON(b)
+	}
+
+	batchID := uuid.New().String()
+	expiresAt := time.Now().Add(ttl)
+	commands := make([]models.DeviceCommand, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		commands = append(commands, models.DeviceCommand{
+			DeviceID:   deviceID,
+			BatchID:    batchID,
+			TargetType: target,
+			TargetID:   targetID,
+			Command:    command,
+			Payload:    payloadJSON,
+			Status:     field.CommandStatusQueued,
+			ExpiresAt:  expiresAt,
+			IssuedBy:   issuedBy,
+		})
+	}
+
+	if err := s.db.Create(&commands).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue commands: %v", err)
+	}
+
+	log.Info("下发设备命令 | 命令: %s | 目标: %s | 目标ID: %d | 设备数量: %d | 批次: %s",
+		command, target, targetID, len(commands), batchID)
+
+	// 通知在线设备立即拉取
+	PublishDeviceEvent(field.DeviceEventCommandQueued, nil, deviceIDs, map[string]interface{}{
+		"command": command,
+		"batchId": batchID,
+	})
+
+	return commands, nil
+}
+
+func (s *DeviceCommandService) FetchPending(deviceID uint) ([]models.DeviceCommand, error) {
+	var commands []models.DeviceCommand
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 先将该设备过期的命令标记为 expired
+		if err := tx.Model(&models.DeviceCommand{}).
+			Where("device_id = ? AND status IN ? AND expires_at < ?", deviceID,
+				[]field.DeviceCommandStatus{field.CommandStatusQueued, field.CommandStatusDelivered}, now).
+			Updates(map[string]interface{}{"status": field.CommandStatusExpired, "completed_at": now}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("device_id = ? AND status = ?", deviceID, field.CommandStatusQueued).
+			Order("created_at ASC").Find(&commands).Error; err != nil {
+			return err
+		}
+
+		if len(commands) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(commands))
+		for i := range commands {
+			ids = append(ids, commands[i].ID)
+			commands[i].Status = field.CommandStatusDelivered
+			commands[i].DeliveredAt = &now
+		}
+
+		return tx.Model(&models.DeviceCommand{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": field.CommandStatusDelivered, "delivered_at": now}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending commands: %v", err)
+	}
+
+	if len(commands) > 0 {
+		log.Info("设备拉取命令 | 设备ID: %d | 命令数量: %d", deviceID, len(commands))
+	}
+	return commands, nil
+}
+
+func (s *DeviceCommandService) ReportResult(deviceID uint, commandID uint, success bool, result string) (*models.DeviceCommand, error) {
+	var command models.DeviceCommand
+	if err := s.db.Where("id = ? AND device_id = ?", commandID, deviceID).First(&command).Error; err != nil {
+		return nil, fmt.Errorf("command not found: %v", err)
+	}
+
+	switch command.Status {
+	case field.CommandStatusSucceeded, field.CommandStatusFailed, field.CommandStatusExpired:
+		return nil, fmt.Errorf("command already %s", command.Status)
+	}
+
+	now := time.Now()
+	status := field.CommandStatusFailed
+	if success {
+		status = field.CommandStatusSucceeded
+	}
+
+	updates := map[string]interface{}{
+		"status":       status,
+		"result":       result,
+		"completed_at": now,
+	}
+	// 设备可能直接执行了 SSE 通知的命令而未经过拉取
+	if command.DeliveredAt == nil {
+		updates["delivered_at"] = now
+	}
+
+	if err := s.db.Model(&command).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update command result: %v", err)
+	}
+
+	log.Info("设备命令执行结果 | 设备ID: %d | 命令ID: %d | 命令: %s | 状态: %s", deviceID, commandID, command.Command, status)
+	return s.GetByID(commandID)
+}
+
+func (s *DeviceCommandService) Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.DeviceCommand, models.PaginationResult, error) {
+	if _, err := s.ExpireStale(); err != nil {
+		log.Warn("标记过期命令失败 | 错误: %v", err)
+	}
+
+	var commands []models.DeviceCommand
+	var total int64
+	db := s.db.Model(&models.DeviceCommand{})
+
+	if deviceID, ok := query["deviceId"].(uint); ok && deviceID != 0 {
+		db = db.Where("device_id = ?", deviceID)
+	}
+	if batchID, ok := query["batchId"].(string); ok && batchID != "" {
+		db = db.Where("batch_id = ?", batchID)
+	}
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if command, ok := query["command"].(string); ok && command != "" {
+		db = db.Where("command = ?", command)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("created_at DESC")
+	} else {
+		db = db.Order("created_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&commands).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return commands, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *DeviceCommandService) GetByID(id uint) (*models.DeviceCommand, error) {
+	var command models.DeviceCommand
+	if err := s.db.First(&command, id).Error; err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (s *DeviceCommandService) ExpireStale() (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.DeviceCommand{}).
+		Where("status IN ? AND expires_at < ?",
+			[]field.DeviceCommandStatus{field.CommandStatusQueued, field.CommandStatusDelivered}, now).
+		Updates(map[string]interface{}{"status": field.CommandStatusExpired, "completed_at": now})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
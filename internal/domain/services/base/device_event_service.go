@@ -0,0 +1,268 @@
+package base_services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	redis "github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// deviceEventChannel Redis 发布订阅频道，用于多实例之间广播设备事件
+const deviceEventChannel = "device:events"
+
+// getDeviceEventBufferSize returns the per-connection event buffer size from environment variables
+func getDeviceEventBufferSize() int {
+	size := os.Getenv("DEVICE_EVENT_BUFFER_SIZE")
+	if size == "" {
+		return 16
+	}
+
+	sizeInt, err := strconv.Atoi(size)
+	if err != nil || sizeInt <= 0 {
+		return 16
+	}
+
+	return sizeInt
+}
+
+// getDeviceEventHeartbeatInterval returns the SSE keep-alive interval from environment variables
+func getDeviceEventHeartbeatInterval() time.Duration {
+	interval := os.Getenv("DEVICE_EVENT_HEARTBEAT_INTERVAL")
+	if interval == "" {
+		return 30 * time.Second
+	}
+
+	seconds, err := strconv.Atoi(interval)
+	if err != nil || seconds <= 0 {
+		return 30 * time.Second
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// DeviceEvent 推送给设备的事件
+// BuildingIDs 和 DeviceIDs 都为空时表示广播给所有设备
+type DeviceEvent struct {
+	Type        field.DeviceEventType  `json:"type"`
+	BuildingIDs []uint                 `json:"buildingIds,omitempty"`
+	DeviceIDs   []uint                 `json:"deviceIds,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// matches 判断事件是否需要推送给指定设备
+func (e *DeviceEvent) matches(deviceID, buildingID uint) bool {
+	if len(e.BuildingIDs) == 0 && len(e.DeviceIDs) == 0 {
+		return true
+	}
+	for _, id := range e.DeviceIDs {
+		if id == deviceID {
+			return true
+		}
+	}
+	if buildingID == 0 {
+		return false
+	}
+	for _, id := range e.BuildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+// InterfaceDeviceEventService 设备事件推送服务接口
+type InterfaceDeviceEventService interface {
+	// 发布事件（经 Redis 分发到所有实例）
+	Publish(event DeviceEvent) error
+	// 订阅指定设备的事件，返回事件通道和取消订阅函数
+	Subscribe(deviceID, buildingID uint) (<-chan DeviceEvent, func())
+	// 当前实例的在线连接数
+	ConnectionCount() int
+	// 长连接保活间隔
+	HeartbeatInterval() time.Duration
+}
+
+type deviceEventSubscriber struct {
+	deviceID   uint
+	buildingID uint
+	events     chan DeviceEvent
+}
+
+// deviceEventHub 进程内的订阅者集合，所有服务容器共享同一个实例
+type deviceEventHub struct {
+	mu          sync.RWMutex
+	nextID      uint64
+	subscribers map[uint64]*deviceEventSubscriber
+	listenOnce  sync.Once
+}
+
+var defaultDeviceEventHub = &deviceEventHub{
+	subscribers: make(map[uint64]*deviceEventSubscriber),
+}
+
+// DeviceEventService 设备事件推送服务实现
+type DeviceEventService struct {
+	hub *deviceEventHub
+}
+
+// NewDeviceEventService 创建设备事件推送服务，并确保 Redis 订阅已启动
+func NewDeviceEventService() InterfaceDeviceEventService {
+	service := &DeviceEventService{hub: defaultDeviceEventHub}
+	service.hub.listenOnce.Do(func() {
+		go service.hub.listen()
+	})
+	return service
+}
+
+// Publish 发布事件
+func (s *DeviceEventService) Publish(event DeviceEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if redis.REDIS_CONN == nil {
+		// Redis 不可用时仅在本实例内分发
+		s.hub.dispatch(event)
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal device event: %v", err)
+	}
+
+	if err := redis.REDIS_CONN.Publish(context.Background(), deviceEventChannel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish device event: %v", err)
+	}
+	return nil
+}
+
+// Subscribe 订阅事件
+func (s *DeviceEventService) Subscribe(deviceID, buildingID uint) (<-chan DeviceEvent, func()) {
+	subscriber := &deviceEventSubscriber{
+		deviceID:   deviceID,
+		buildingID: buildingID,
+		events:     make(chan DeviceEvent, getDeviceEventBufferSize()),
+	}
+
+	s.hub.mu.Lock()
+	s.hub.nextID++
+	id := s.hub.nextID
+	s.hub.subscribers[id] = subscriber
+	s.hub.mu.Unlock()
+
+	log.Info("设备事件连接已建立 | 设备ID: %d | 建筑ID: %d", deviceID, buildingID)
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.hub.mu.Lock()
+			delete(s.hub.subscribers, id)
+			s.hub.mu.Unlock()
+			log.Info("设备事件连接已断开 | 设备ID: %d | 建筑ID: %d", deviceID, buildingID)
+		})
+	}
+
+	return subscriber.events, unsubscribe
+}
+
+// ConnectionCount 当前实例的在线连接数
+func (s *DeviceEventService) ConnectionCount() int {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	return len(s.hub.subscribers)
+}
+
+// HeartbeatInterval 长连接保活间隔
+func (s *DeviceEventService) HeartbeatInterval() time.Duration {
+	return getDeviceEventHeartbeatInterval()
+}
+
+// listen 订阅 Redis 频道，断开后自动重连
+func (h *deviceEventHub) listen() {
+	for {
+		if redis.REDIS_CONN == nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		ctx := context.Background()
+		pubsub := redis.REDIS_CONN.Subscribe(ctx, deviceEventChannel)
+		if _, err := pubsub.Receive(ctx); err != nil {
+			log.Error("订阅设备事件频道失败 | 错误: %v", err)
+			pubsub.Close()
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		log.Info("已订阅设备事件频道 | 频道: %s", deviceEventChannel)
+
+		for msg := range pubsub.Channel() {
+			var event DeviceEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Warn("设备事件解析失败 | 错误: %v", err)
+				continue
+			}
+			h.dispatch(event)
+		}
+
+		pubsub.Close()
+		log.Warn("设备事件频道连接已断开，准备重连")
+		time.Sleep(time.Second)
+	}
+}
+
+// dispatch 将事件分发给本实例内匹配的订阅者
+func (h *deviceEventHub) dispatch(event DeviceEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, subscriber := range h.subscribers {
+		if !event.matches(subscriber.deviceID, subscriber.buildingID) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			// 缓冲区已满说明客户端读取过慢，丢弃事件，设备仍可通过轮询兜底
+			log.Warn("设备事件缓冲区已满，丢弃事件 | 设备ID: %d | 事件类型: %s", subscriber.deviceID, event.Type)
+		}
+	}
+}
+
+// PublishDeviceEvent 供各业务服务在数据变更后调用，失败只记录日志不影响主流程
+// 没有目标建筑和设备时不发送，需要全量广播请使用 BroadcastDeviceEvent
+func PublishDeviceEvent(eventType field.DeviceEventType, buildingIDs []uint, deviceIDs []uint, data map[string]interface{}) {
+	if len(buildingIDs) == 0 && len(deviceIDs) == 0 {
+		return
+	}
+	publishDeviceEvent(eventType, buildingIDs, deviceIDs, data)
+}
+
+// BroadcastDeviceEvent 向所有在线设备广播事件
+func BroadcastDeviceEvent(eventType field.DeviceEventType, data map[string]interface{}) {
+	publishDeviceEvent(eventType, nil, nil, data)
+}
+
+func publishDeviceEvent(eventType field.DeviceEventType, buildingIDs []uint, deviceIDs []uint, data map[string]interface{}) {
+	event := DeviceEvent{
+		Type:        eventType,
+		BuildingIDs: buildingIDs,
+		DeviceIDs:   deviceIDs,
+		Data:        data,
+		Timestamp:   time.Now(),
+	}
+
+	service := &DeviceEventService{hub: defaultDeviceEventHub}
+	if err := service.Publish(event); err != nil {
+		log.Error("发布设备事件失败 | 事件类型: %s | 错误: %v", eventType, err)
+	}
+}
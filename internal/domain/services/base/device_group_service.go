@@ -0,0 +1,183 @@
+package base_services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+// InterfaceDeviceGroupService 设备分组服务接口
+type InterfaceDeviceGroupService interface {
+	Create(group *models.DeviceGroup) error
+	Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.DeviceGroup, models.PaginationResult, error)
+	Update(id uint, updates map[string]interface{}) (*models.DeviceGroup, error)
+	Delete(ids []uint) error
+	GetByID(id uint) (*models.DeviceGroup, error)
+	// 将设备加入分组（设备原分组会被替换）
+	AssignDevices(groupID uint, deviceIDs []uint) error
+	// 将设备移出分组
+	RemoveDevices(groupID uint, deviceIDs []uint) error
+	// 获取分组内的设备ID
+	GetDeviceIDs(groupID uint) ([]uint, error)
+}
+
+// DeviceGroupService 设备分组服务实现
+type DeviceGroupService struct {
+	db *gorm.DB
+}
+
+// NewDeviceGroupService 创建设备分组服务
+func NewDeviceGroupService(db *gorm.DB) InterfaceDeviceGroupService {
+	return &DeviceGroupService{db: db}
+}
+
+func (s *DeviceGroupService) Create(group *models.DeviceGroup) error {
+	if group.BuildingID != nil {
+		var building models.Building
+		if err := s.db.First(&building, *group.BuildingID).Error; err != nil {
+			return fmt.Errorf("building not found: %v", err)
+		}
+	}
+	return s.db.Create(group).Error
+}
+
+func (s *DeviceGroupService) Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.DeviceGroup, models.PaginationResult, error) {
+	var groups []models.DeviceGroup
+	var total int64
+	db := s.db.Model(&models.DeviceGroup{})
+
+	if search, ok := query["search"].(string); ok && search != "" {
+		db = db.Where("name LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("created_at DESC")
+	} else {
+		db = db.Order("created_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&groups).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return groups, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *DeviceGroupService) Update(id uint, updates map[string]interface{}) (*models.DeviceGroup, error) {
+	var group models.DeviceGroup
+	if err := s.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&group).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id)
+}
+
+func (s *DeviceGroupService) Delete(ids []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// 先解除设备与分组的关联
+		if err := tx.Model(&models.Device{}).Where("device_group_id IN ?", ids).
+			Update("device_group_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to release group devices: %v", err)
+		}
+
+		// 删除分组级别的设置配置
+		if err := tx.Where("scope = ? AND target_id IN ?", field.SettingsScopeGroup, ids).
+			Delete(&models.DeviceSettingsProfile{}).Error; err != nil {
+			return fmt.Errorf("failed to delete group settings profiles: %v", err)
+		}
+
+		result := tx.Delete(&models.DeviceGroup{}, ids)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("no records found to delete")
+		}
+		return nil
+	})
+}
+
+func (s *DeviceGroupService) GetByID(id uint) (*models.DeviceGroup, error) {
+	var group models.DeviceGroup
+	if err := s.db.Preload("Devices").First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// AssignDevices 将设备加入分组
+func (s *DeviceGroupService) AssignDevices(groupID uint, deviceIDs []uint) error {
+	var group models.DeviceGroup
+	if err := s.db.First(&group, groupID).Error; err != nil {
+		return fmt.Errorf("device group not found: %v", err)
+	}
+
+	// 分组限定了建筑时，只允许该建筑下的设备加入
+	if group.BuildingID != nil {
+		var mismatched int64
+		if err := s.db.Model(&models.Device{}).
+			Where("id IN ? AND building_id <> ?", deviceIDs, *group.BuildingID).
+			Count(&mismatched).Error; err != nil {
+			return err
+		}
+		if mismatched > 0 {
+			return fmt.Errorf("%d devices do not belong to building %d", mismatched, *group.BuildingID)
+		}
+	}
+
+	result := s.db.Model(&models.Device{}).Where("id IN ?", deviceIDs).Update("device_group_id", groupID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to assign devices: %v", result.Error)
+	}
+
+	log.Info("设备加入分组 | 分组ID: %d | 设备数量: %d", groupID, result.RowsAffected)
+	PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, deviceIDs, nil)
+	return nil
+}
+
+// RemoveDevices 将设备移出分组
+func (s *DeviceGroupService) RemoveDevices(groupID uint, deviceIDs []uint) error {
+	result := s.db.Model(&models.Device{}).
+		Where("id IN ? AND device_group_id = ?", deviceIDs, groupID).
+		Update("device_group_id", nil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove devices: %v", result.Error)
+	}
+
+	log.Info("设备移出分组 | 分组ID: %d | 设备数量: %d", groupID, result.RowsAffected)
+	PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, deviceIDs, nil)
+	return nil
+}
+
+// GetDeviceIDs 获取分组内的设备ID
+func (s *DeviceGroupService) GetDeviceIDs(groupID uint) ([]uint, error) {
+	var ids []uint
+	if err := s.db.Model(&models.Device{}).Where("device_group_id = ?", groupID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,243 @@
+package base_services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 允许上传的截图格式
+var screenshotMimeExt = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/webp": "webp",
+}
+
+// getDeviceScreenshotMaxSize returns the max screenshot size in bytes from environment variables
+func getDeviceScreenshotMaxSize() int64 {
+	size := os.Getenv("DEVICE_SCREENSHOT_MAX_SIZE")
+	if size == "" {
+		return 5 * 1024 * 1024
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n <= 0 {
+		return 5 * 1024 * 1024
+	}
+
+	return n
+}
+
+// getDeviceScreenshotHistory returns how many screenshots are kept per device
+func getDeviceScreenshotHistory() int {
+	history := os.Getenv("DEVICE_SCREENSHOT_HISTORY")
+	if history == "" {
+		return 10
+	}
+
+	n, err := strconv.Atoi(history)
+	if err != nil || n <= 0 {
+		return 10
+	}
+
+	return n
+}
+
+// InterfaceDeviceScreenshotService 设备截图服务接口
+type InterfaceDeviceScreenshotService interface {
+	// 上传截图并保留最近若干张，commandID 不为空时表示由远程命令触发
+	Upload(deviceID uint, content []byte, trigger string, commandID *uint) (*models.DeviceScreenshot, error)
+	// 获取设备截图历史（按时间倒序）
+	GetHistory(deviceID uint) ([]models.DeviceScreenshot, error)
+	MaxSize() int64
+}
+
+// DeviceScreenshotService 设备截图服务实现
+type DeviceScreenshotService struct {
+	db            *gorm.DB
+	uploadService IUploadService
+}
+
+// NewDeviceScreenshotService 创建设备截图服务
+func NewDeviceScreenshotService(db *gorm.DB, uploadService IUploadService) InterfaceDeviceScreenshotService {
+	return &DeviceScreenshotService{
+		db:            db,
+		uploadService: uploadService,
+	}
+}
+
+func (s *DeviceScreenshotService) MaxSize() int64 {
+	return getDeviceScreenshotMaxSize()
+}
+
+func (s *DeviceScreenshotService) Upload(deviceID uint, content []byte, trigger string, commandID *uint) (*models.DeviceScreenshot, error) {
+	if len(content) == 0 {
+		return nil, errors.New("empty screenshot")
+	}
+	if int64(len(content)) > getDeviceScreenshotMaxSize() {
+		return nil, fmt.Errorf("screenshot exceeds max size of %d bytes", getDeviceScreenshotMaxSize())
+	}
+
+	mimeType := http.DetectContentType(content)
+	ext, ok := screenshotMimeExt[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported screenshot type: %s", mimeType)
+	}
+
+	switch trigger {
+	case "command", "periodic", "manual":
+	case "":
+		trigger = "manual"
+		if commandID != nil {
+			trigger = "command"
+		}
+	default:
+		return nil, fmt.Errorf("invalid trigger: %s", trigger)
+	}
+
+	objectKey := fmt.Sprintf("iboard/screenshot/%d/%s.%s", deviceID, uuid.New().String(), ext)
+	url, err := s.uploadService.UploadContentSync(objectKey, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload screenshot: %v", err)
+	}
+
+	sum := md5.Sum(content)
+	screenshot := &models.DeviceScreenshot{
+		DeviceID:   deviceID,
+		Trigger:    trigger,
+		CommandID:  commandID,
+		CapturedAt: time.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		file := &models.File{
+			Size:         int64(len(content)),
+			Md5:          hex.EncodeToString(sum[:]),
+			Path:         url,
+			MimeType:     mimeType,
+			Oss:          "aliyun",
+			Uploader:     fmt.Sprintf("device:%d", deviceID),
+			UploaderID:   deviceID,
+			UploaderType: field.UploaderTypeDevice,
+		}
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+
+		screenshot.FileID = file.ID
+		screenshot.File = file
+		return tx.Create(screenshot).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save screenshot: %v", err)
+	}
+
+	log.Info("设备上传截图 | 设备ID: %d | 触发方式: %s | 大小: %d | 路径: %s", deviceID, trigger, len(content), url)
+
+	if err := s.prune(deviceID); err != nil {
+		log.Warn("清理设备历史截图失败 | 设备ID: %d | 错误: %v", deviceID, err)
+	}
+
+	return screenshot, nil
+}
+
+// prune 只保留最近 DEVICE_SCREENSHOT_HISTORY 张截图，同时删除对应的文件记录与 OSS 对象
+func (s *DeviceScreenshotService) prune(deviceID uint) error {
+	var stale []models.DeviceScreenshot
+	if err := s.db.Preload("File").
+		Where("device_id = ?", deviceID).
+		Order("captured_at DESC, id DESC").
+		Offset(getDeviceScreenshotHistory()).
+		Find(&stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(stale))
+	fileIDs := make([]uint, 0, len(stale))
+	for _, screenshot := range stale {
+		ids = append(ids, screenshot.ID)
+		fileIDs = append(fileIDs, screenshot.FileID)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ?", ids).Delete(&models.DeviceScreenshot{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", fileIDs).Delete(&models.File{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 记录删除后再删除 OSS 对象，删除失败只会留下无记录引用的对象
+	for _, screenshot := range stale {
+		if screenshot.File == nil {
+			continue
+		}
+		objectKey := ossObjectKey(screenshot.File.Path)
+		if objectKey == "" {
+			continue
+		}
+		if err := s.uploadService.DeleteObject(objectKey); err != nil {
+			log.Warn("删除历史截图文件失败 | 设备ID: %d | 对象: %s | 错误: %v", deviceID, objectKey, err)
+		}
+	}
+	return nil
+}
+
+func (s *DeviceScreenshotService) GetHistory(deviceID uint) ([]models.DeviceScreenshot, error) {
+	var screenshots []models.DeviceScreenshot
+	if err := s.db.Preload("File").
+		Where("device_id = ?", deviceID).
+		Order("captured_at DESC, id DESC").
+		Limit(getDeviceScreenshotHistory()).
+		Find(&screenshots).Error; err != nil {
+		return nil, err
+	}
+	return screenshots, nil
+}
+
+// AttachLatestScreenshots 为设备列表填充最新截图
+func AttachLatestScreenshots(db *gorm.DB, devices []models.Device) {
+	if len(devices) == 0 {
+		return
+	}
+
+	deviceIDs := make([]uint, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+
+	latestIDs := db.Model(&models.DeviceScreenshot{}).
+		Select("MAX(id)").
+		Where("device_id IN ?", deviceIDs).
+		Group("device_id")
+
+	var screenshots []models.DeviceScreenshot
+	if err := db.Preload("File").Where("id IN (?)", latestIDs).Find(&screenshots).Error; err != nil {
+		log.Warn("获取设备最新截图失败 | 错误: %v", err)
+		return
+	}
+
+	latest := make(map[uint]*models.DeviceScreenshot, len(screenshots))
+	for i := range screenshots {
+		latest[screenshots[i].DeviceID] = &screenshots[i]
+	}
+	for i := range devices {
+		devices[i].LatestScreenshot = latest[devices[i].ID]
+	}
+}
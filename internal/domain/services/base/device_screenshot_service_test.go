@@ -0,0 +1,77 @@
+package base_services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+)
+
+// recordingUploadService 记录上传与删除的对象，不访问 OSS
+type recordingUploadService struct {
+	IUploadService
+	uploaded []string
+	deleted  []string
+}
+
+func (s *recordingUploadService) UploadContentSync(objectKey string, _ []byte) (string, error) {
+	s.uploaded = append(s.uploaded, objectKey)
+	return "http://bucket.oss-cn-beijing.aliyuncs.com/" + objectKey, nil
+}
+
+func (s *recordingUploadService) DeleteObject(objectKey string) error {
+	s.deleted = append(s.deleted, objectKey)
+	return nil
+}
+
+func TestDeviceScreenshotPruneDeletesObjects(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	tests := []struct {
+		name    string
+		history string
+		uploads int
+		deleted int
+	}{
+		{"未超出保留数量不删除", "3", 3, 0},
+		{"超出部分删除记录与对象", "2", 5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEVICE_SCREENSHOT_HISTORY", tt.history)
+			db := newTestDB(t, &models.File{}, &models.DeviceScreenshot{})
+			upload := &recordingUploadService{}
+			s := NewDeviceScreenshotService(db, upload)
+
+			for i := 0; i < tt.uploads; i++ {
+				if _, err := s.Upload(1, png, "manual", nil); err != nil {
+					t.Fatalf("upload %d: %v", i, err)
+				}
+			}
+
+			if len(upload.deleted) != tt.deleted {
+				t.Fatalf("deleted objects = %v, want %d", upload.deleted, tt.deleted)
+			}
+			// 最早上传的对象被删除
+			for i, key := range upload.deleted {
+				if key != upload.uploaded[i] {
+					t.Errorf("deleted[%d] = %s, want %s", i, key, upload.uploaded[i])
+				}
+			}
+
+			var screenshots, files int64
+			db.Model(&models.DeviceScreenshot{}).Count(&screenshots)
+			db.Model(&models.File{}).Count(&files)
+			kept := int64(tt.uploads - tt.deleted)
+			if screenshots != kept || files != kept {
+				t.Errorf("screenshots = %d, files = %d, want %d", screenshots, files, kept)
+			}
+			for _, key := range upload.deleted {
+				var count int64
+				db.Model(&models.File{}).Where("path = ?", fmt.Sprintf("http://bucket.oss-cn-beijing.aliyuncs.com/%s", key)).Count(&count)
+				if count != 0 {
+					t.Errorf("file record of deleted object %s still exists", key)
+				}
+			}
+		})
+	}
+}
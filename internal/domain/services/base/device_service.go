@@ -260,6 +260,10 @@ func (s *DeviceService) Get(query map[string]interface{}, paginate map[string]in
 		db = db.Where("building_id = ?", buildingID)
 	}
 
+	if health, ok := query["health"].(string); ok && health != "" {
+		db = db.Where("telemetry_health = ?", health)
+	}
+
 	if err := db.Count(&total).Error; err != nil {
 		return nil, models.PaginationResult{}, err
 	}
@@ -295,6 +299,7 @@ func (s *DeviceService) Get(query map[string]interface{}, paginate map[string]in
 		if err := s.db.Where("device_id = ?", devices[i].ID).Find(&printers).Error; err != nil {
 			return nil, models.PaginationResult{}, err
 		}
+		FillPrinterMarkers(printers)
 		devices[i].OrangePi.Printers = printers
 	}
 
@@ -307,6 +312,7 @@ func (s *DeviceService) Get(query map[string]interface{}, paginate map[string]in
 
 func (s *DeviceService) Update(id uint, updates map[string]interface{}) (*models.Device, error) {
 	var updatedDevice *models.Device
+	var settingsChanged, buildingChanged bool
 
 	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// 获取当前设备信息
@@ -349,6 +355,7 @@ func (s *DeviceService) Update(id uint, updates map[string]interface{}) (*models
 
 				// 只有当 buildingId 真正发生改变时才进行处理
 				if oldBuildingID != newBuildingIDUint {
+					buildingChanged = true
 					log.Info("检测到设备建筑变更 | 设备ID: %d | 从建筑ID: %d 变更为建筑ID: %d",
 						device.ID, oldBuildingID, newBuildingIDUint)
 
@@ -415,7 +422,7 @@ func (s *DeviceService) Update(id uint, updates map[string]interface{}) (*models
 			"payment_table_one_page_duration",
 			"normal_to_announcement_carousel_duration",
 			"announcement_carousel_to_full_ads_carousel_duration",
-			"print_pass_word",
+			"screenshot_interval",
 		}
 
 		for _, field := range settingsFields {
@@ -430,6 +437,7 @@ func (s *DeviceService) Update(id uint, updates map[string]interface{}) (*models
 			if err := tx.Model(&device).Updates(settingsUpdates).Error; err != nil {
 				return fmt.Errorf("failed to update settings: %v", err)
 			}
+			settingsChanged = true
 		}
 
 		// 获取更新后的设备信息，确保获取最新状态
@@ -445,6 +453,15 @@ func (s *DeviceService) Update(id uint, updates map[string]interface{}) (*models
 		return nil, err
 	}
 
+	if settingsChanged {
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, []uint{id}, nil)
+	}
+	if buildingChanged {
+		// 更换建筑后轮播列表已重置
+		PublishDeviceEvent(field.DeviceEventCarouselChanged, nil, []uint{id}, nil)
+		PublishDeviceEvent(field.DeviceEventNoticesChanged, nil, []uint{id}, nil)
+	}
+
 	return updatedDevice, nil
 }
 
@@ -525,6 +542,7 @@ func (s *DeviceService) GetByID(id uint) (*models.Device, error) {
 	if err := s.db.Where("device_id = ?", id).Find(&printers).Error; err != nil {
 		return nil, err
 	}
+	FillPrinterMarkers(printers)
 	device.OrangePi.Printers = printers
 
 	return &device, nil
@@ -566,6 +584,7 @@ func (s *DeviceService) GetByDeviceID(deviceID string) (*models.Device, error) {
 	if err := s.db.Where("device_id = ?", device.ID).Find(&printers).Error; err != nil {
 		return nil, err
 	}
+	FillPrinterMarkers(printers)
 	device.OrangePi.Printers = printers
 
 	return &device, nil
@@ -594,8 +613,12 @@ func toUintSliceFromJSON(j datatypes.JSON) ([]uint, error) {
 
 // 1.UpdateTopAdCarousel 更新顶部广告轮播顺序
 func (s *DeviceService) UpdateTopAdCarousel(deviceID uint, ids []uint) error {
-	return s.db.Model(&models.Device{}).Where("id = ?", deviceID).
-		Update("top_advertisement_carousel_list", toJSONFromUintSlice(ids)).Error
+	if err := s.db.Model(&models.Device{}).Where("id = ?", deviceID).
+		Update("top_advertisement_carousel_list", toJSONFromUintSlice(ids)).Error; err != nil {
+		return err
+	}
+	PublishDeviceEvent(field.DeviceEventCarouselChanged, nil, []uint{deviceID}, map[string]interface{}{"carousel": "top_advertisements"})
+	return nil
 }
 
 // 2.GetTopAdCarousel 获取顶部广告轮播顺序
@@ -609,8 +632,12 @@ func (s *DeviceService) GetTopAdCarousel(deviceID uint) ([]uint, error) {
 
 // 3.UpdateFullAdCarousel 更新全屏广告轮播顺序
 func (s *DeviceService) UpdateFullAdCarousel(deviceID uint, ids []uint) error {
-	return s.db.Model(&models.Device{}).Where("id = ?", deviceID).
-		Update("full_advertisement_carousel_list", toJSONFromUintSlice(ids)).Error
+	if err := s.db.Model(&models.Device{}).Where("id = ?", deviceID).
+		Update("full_advertisement_carousel_list", toJSONFromUintSlice(ids)).Error; err != nil {
+		return err
+	}
+	PublishDeviceEvent(field.DeviceEventCarouselChanged, nil, []uint{deviceID}, map[string]interface{}{"carousel": "full_advertisements"})
+	return nil
 }
 
 // 4.GetFullAdCarousel 获取全屏广告轮播顺序
@@ -624,8 +651,12 @@ func (s *DeviceService) GetFullAdCarousel(deviceID uint) ([]uint, error) {
 
 // 5.UpdateNoticeCarousel 更新公告轮播顺序
 func (s *DeviceService) UpdateNoticeCarousel(deviceID uint, ids []uint) error {
-	return s.db.Model(&models.Device{}).Where("id = ?", deviceID).
-		Update("notice_carousel_list", toJSONFromUintSlice(ids)).Error
+	if err := s.db.Model(&models.Device{}).Where("id = ?", deviceID).
+		Update("notice_carousel_list", toJSONFromUintSlice(ids)).Error; err != nil {
+		return err
+	}
+	PublishDeviceEvent(field.DeviceEventCarouselChanged, nil, []uint{deviceID}, map[string]interface{}{"carousel": "notices"})
+	return nil
 }
 
 // 6.GetNoticeCarousel 获取公告轮播顺序
@@ -987,13 +1018,42 @@ func (s *DeviceService) UpdateDeviceHealth(deviceID uint) error {
 	key := fmt.Sprintf("device:online:%d", deviceID)
 	ctx := context.Background()
 
+	existed, err := redis.REDIS_CONN.Exists(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to update device health: %v", err)
+	}
+
 	// 设置设备在线状态，使用环境变量中的超时时间
 	timeout := getDeviceHealthTimeout()
-	err := redis.REDIS_CONN.Set(ctx, key, "true", time.Duration(timeout)*time.Second).Err()
+	err = redis.REDIS_CONN.Set(ctx, key, "true", time.Duration(timeout)*time.Second).Err()
 	if err != nil {
 		return fmt.Errorf("failed to update device health: %v", err)
 	}
 
+	now := time.Now()
+	lastSeenField := strconv.FormatUint(uint64(deviceID), 10)
+	lastSeen, lastSeenErr := redis.REDIS_CONN.HGet(ctx, deviceLastSeenKey, lastSeenField).Int64()
+	if err := redis.REDIS_CONN.HSet(ctx, deviceLastSeenKey, lastSeenField, now.Unix()).Err(); err != nil {
+		log.Warn("记录设备最后心跳时间失败 | 设备ID: %d | 错误: %v", deviceID, err)
+	}
+
+	// 在线标记不存在说明设备刚上线
+	if existed == 0 {
+		// 离线检测未及时执行时补记离线：距上次心跳已超过超时时间，离线时间为最后心跳 + 超时时间
+		// 最近一次记录已是离线时不会重复记录
+		if lastSeenErr == nil {
+			offlineAt := time.Unix(lastSeen, 0).Add(time.Duration(timeout) * time.Second)
+			if offlineAt.Before(now) {
+				if _, err := recordDeviceStatusTransition(s.db, deviceID, field.DeviceStatusOffline, offlineAt); err != nil {
+					log.Warn("补记设备离线失败 | 设备ID: %d | 错误: %v", deviceID, err)
+				}
+			}
+		}
+		if _, err := recordDeviceStatusTransition(s.db, deviceID, field.DeviceStatusOnline, now); err != nil {
+			log.Warn("记录设备上线失败 | 设备ID: %d | 错误: %v", deviceID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -1016,6 +1076,8 @@ func (s *DeviceService) GetWithStatus(query map[string]interface{}, pagination m
 		return nil, models.PaginationResult{}, err
 	}
 
+	AttachLatestScreenshots(s.db, devices)
+
 	// 转换为带状态的设备信息
 	devicesWithStatus := make([]DeviceWithStatus, len(devices))
 	for i, device := range devices {
@@ -1035,6 +1097,15 @@ func (s *DeviceService) GetByIDWithStatus(id uint) (*DeviceWithStatus, error) {
 		return nil, err
 	}
 
+	// 填充截图历史，第一张即为最新截图
+	screenshots, err := NewDeviceScreenshotService(s.db, nil).GetHistory(device.ID)
+	if err != nil {
+		log.Warn("获取设备截图历史失败 | 设备ID: %d | 错误: %v", device.ID, err)
+	} else if len(screenshots) > 0 {
+		device.Screenshots = screenshots
+		device.LatestScreenshot = &screenshots[0]
+	}
+
 	return &DeviceWithStatus{
 		Device: *device,
 		Status: s.CheckDeviceStatus(device.ID),
@@ -1052,6 +1123,8 @@ func (s *DeviceService) GetDevicesByBuildingWithStatus(buildingID uint) ([]Devic
 		return nil, err
 	}
 
+	AttachLatestScreenshots(s.db, devices)
+
 	// 转换为带状态的设备信息
 	devicesWithStatus := make([]DeviceWithStatus, len(devices))
 	for i, device := range devices {
@@ -1332,6 +1405,12 @@ func (s *DeviceService) syncPrintersFromHealthCheck(deviceID uint, printersData
 		if markerLevels, ok := printerMap["marker_levels"].(string); ok {
 			printer.MarkerLevels = &markerLevels
 		}
+		if markerNames, ok := printerMap["marker_names"].(string); ok {
+			printer.MarkerNames = &markerNames
+		}
+		if markerColors, ok := printerMap["marker_colors"].(string); ok {
+			printer.MarkerColors = &markerColors
+		}
 
 		printers = append(printers, printer)
 	}
@@ -1373,6 +1452,9 @@ func (s *DeviceService) syncPrintersFromHealthCheck(deviceID uint, printersData
 				if err := tx.Delete(&models.Printer{}, existing.ID).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterRemoved(tx, existing); err != nil {
+					return err
+				}
 				deleted++
 			}
 		}
@@ -1418,11 +1500,27 @@ func (s *DeviceService) syncPrintersFromHealthCheck(deviceID uint, printersData
 					updateFields["marker_levels"] = *printer.MarkerLevels
 					hasChanges = true
 				}
+				if printer.MarkerNames != nil && (existing.MarkerNames == nil || *printer.MarkerNames != *existing.MarkerNames) {
+					updateFields["marker_names"] = *printer.MarkerNames
+					hasChanges = true
+				}
+				if printer.MarkerColors != nil && (existing.MarkerColors == nil || *printer.MarkerColors != *existing.MarkerColors) {
+					updateFields["marker_colors"] = *printer.MarkerColors
+					hasChanges = true
+				}
 
 				if hasChanges {
+					previousLevels := existing.MarkerLevels
+					previousStatus := existing.Status
 					if err := tx.Model(&existing).Updates(updateFields).Error; err != nil {
 						return err
 					}
+					if err := recordPrinterSupplyLevels(tx, existing.ID, previousLevels, printer); err != nil {
+						return err
+					}
+					if err := recordPrinterStatusChange(tx, existing.ID, previousStatus, printer); err != nil {
+						return err
+					}
 					updated++
 				} else {
 					unchanged++
@@ -1432,6 +1530,12 @@ func (s *DeviceService) syncPrintersFromHealthCheck(deviceID uint, printersData
 				if err := tx.Create(&printer).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterSupplyLevels(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
+				if err := recordPrinterStatusChange(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
 				added++
 			}
 
@@ -0,0 +1,336 @@
+package base_services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// settingsSourceDefault 表示取值来自系统默认值
+const settingsSourceDefault field.SettingsScope = "default"
+
+// SettingSource 设置值的来源
+type SettingSource struct {
+	Scope     field.SettingsScope `json:"scope"`
+	TargetID  uint                `json:"targetId,omitempty"`
+	ProfileID uint                `json:"profileId,omitempty"` // 为 0 表示来自默认值或设备表中的旧字段
+}
+
+// EffectiveDeviceSettings 设备最终生效的设置及每个字段的来源
+type EffectiveDeviceSettings struct {
+	DeviceID uint                     `json:"deviceId"`
+	Settings models.DeviceSettings    `json:"settings"`
+	Sources  map[string]SettingSource `json:"sources"`
+}
+
+// InterfaceDeviceSettingsService 设备设置配置服务接口
+type InterfaceDeviceSettingsService interface {
+	// 获取配置列表，scope 为空时返回全部
+	GetProfiles(scope string, targetID *uint) ([]models.DeviceSettingsProfile, error)
+	// 保存配置：replace 为 true 时整体替换，否则与已有字段合并（值为 null 的字段会被移除）
+	SaveProfile(scope field.SettingsScope, targetID uint, settings map[string]interface{}, replace bool) (*models.DeviceSettingsProfile, error)
+	// 删除配置
+	DeleteProfile(id uint) error
+	// 计算设备最终生效的设置
+	ResolveEffectiveSettings(device *models.Device) (*EffectiveDeviceSettings, error)
+	ResolveEffectiveSettingsByID(deviceID uint) (*EffectiveDeviceSettings, error)
+}
+
+// DeviceSettingsService 设备设置配置服务实现
+type DeviceSettingsService struct {
+	db *gorm.DB
+}
+
+// NewDeviceSettingsService 创建设备设置配置服务
+func NewDeviceSettingsService(db *gorm.DB) InterfaceDeviceSettingsService {
+	return &DeviceSettingsService{db: db}
+}
+
+// deviceSettingsKeys DeviceSettings 中允许被配置覆盖的 JSON 字段
+var deviceSettingsKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(models.DeviceSettings{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+// settingsToMap 将设备设置转换为以 JSON 字段名为键的 map
+func settingsToMap(settings models.DeviceSettings) (map[string]interface{}, error) {
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateSettings 校验字段名和字段类型
+func validateSettings(settings map[string]interface{}) error {
+	for key, value := range settings {
+		if !deviceSettingsKeys[key] {
+			return fmt.Errorf("unknown settings field: %s", key)
+		}
+		if value == nil {
+			continue
+		}
+		b, err := json.Marshal(map[string]interface{}{key: value})
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %v", key, err)
+		}
+		var probe models.DeviceSettings
+		if err := json.Unmarshal(b, &probe); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *DeviceSettingsService) GetProfiles(scope string, targetID *uint) ([]models.DeviceSettingsProfile, error) {
+	var profiles []models.DeviceSettingsProfile
+	db := s.db.Model(&models.DeviceSettingsProfile{})
+	if scope != "" {
+		db = db.Where("scope = ?", scope)
+	}
+	if targetID != nil {
+		db = db.Where("target_id = ?", *targetID)
+	}
+	if err := db.Order("scope ASC, target_id ASC").Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (s *DeviceSettingsService) SaveProfile(scope field.SettingsScope, targetID uint, settings map[string]interface{}, replace bool) (*models.DeviceSettingsProfile, error) {
+	if !field.IsValidSettingsScope(string(scope)) {
+		return nil, fmt.Errorf("invalid settings scope: %s", scope)
+	}
+	if scope == field.SettingsScopeGlobal {
+		targetID = 0
+	} else if targetID == 0 {
+		return nil, errors.New("targetId is required for non-global scope")
+	}
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+	if err := s.checkTarget(scope, targetID); err != nil {
+		return nil, err
+	}
+
+	var profile models.DeviceSettingsProfile
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		merged := make(map[string]interface{})
+		err := tx.Where("scope = ? AND target_id = ?", scope, targetID).First(&profile).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && !replace && len(profile.Settings) > 0 {
+			if err := json.Unmarshal(profile.Settings, &merged); err != nil {
+				return fmt.Errorf("failed to parse existing settings: %v", err)
+			}
+		}
+
+		for key, value := range settings {
+			if value == nil {
+				delete(merged, key)
+				continue
+			}
+			merged[key] = value
+		}
+
+		b, err := json.Marshal(merged)
+		if err != nil {
+			return err
+		}
+
+		profile.Scope = scope
+		profile.TargetID = targetID
+		profile.Settings = datatypes.JSON(b)
+		return tx.Save(&profile).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("保存设备设置配置 | 层级: %s | 目标ID: %d | 字段数: %d", scope, targetID, len(settings))
+	s.notifySettingsChanged(scope, targetID)
+	return &profile, nil
+}
+
+func (s *DeviceSettingsService) DeleteProfile(id uint) error {
+	var profile models.DeviceSettingsProfile
+	if err := s.db.First(&profile, id).Error; err != nil {
+		return err
+	}
+	if err := s.db.Delete(&profile).Error; err != nil {
+		return err
+	}
+
+	log.Info("删除设备设置配置 | 配置ID: %d | 层级: %s | 目标ID: %d", id, profile.Scope, profile.TargetID)
+	s.notifySettingsChanged(profile.Scope, profile.TargetID)
+	return nil
+}
+
+// ResolveEffectiveSettings 按 默认值 -> global -> building -> group -> device 逐级覆盖
+// device 层先取设备表中与默认值不同的旧字段，再叠加 device 配置
+func (s *DeviceSettingsService) ResolveEffectiveSettings(device *models.Device) (*EffectiveDeviceSettings, error) {
+	values, err := settingsToMap(models.DefaultDeviceSettings())
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]SettingSource, len(values))
+	for key := range values {
+		sources[key] = SettingSource{Scope: settingsSourceDefault}
+	}
+
+	var profiles []models.DeviceSettingsProfile
+	conditions := s.db.Where("scope = ?", field.SettingsScopeGlobal)
+	if device.BuildingID != 0 {
+		conditions = conditions.Or("scope = ? AND target_id = ?", field.SettingsScopeBuilding, device.BuildingID)
+	}
+	if device.DeviceGroupID != nil {
+		conditions = conditions.Or("scope = ? AND target_id = ?", field.SettingsScopeGroup, *device.DeviceGroupID)
+	}
+	conditions = conditions.Or("scope = ? AND target_id = ?", field.SettingsScopeDevice, device.ID)
+	if err := s.db.Where(conditions).Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to load settings profiles: %v", err)
+	}
+
+	byScope := make(map[field.SettingsScope]models.DeviceSettingsProfile, len(profiles))
+	for _, profile := range profiles {
+		byScope[profile.Scope] = profile
+	}
+
+	apply := func(profile models.DeviceSettingsProfile) error {
+		if len(profile.Settings) == 0 {
+			return nil
+		}
+		var overrides map[string]interface{}
+		if err := json.Unmarshal(profile.Settings, &overrides); err != nil {
+			return fmt.Errorf("failed to parse settings profile %d: %v", profile.ID, err)
+		}
+		for key, value := range overrides {
+			if !deviceSettingsKeys[key] || value == nil {
+				continue
+			}
+			values[key] = value
+			sources[key] = SettingSource{Scope: profile.Scope, TargetID: profile.TargetID, ProfileID: profile.ID}
+		}
+		return nil
+	}
+
+	for _, scope := range []field.SettingsScope{field.SettingsScopeGlobal, field.SettingsScopeBuilding, field.SettingsScopeGroup} {
+		if profile, ok := byScope[scope]; ok {
+			if err := apply(profile); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 兼容设备表中单独修改过的旧设置字段
+	defaults, _ := settingsToMap(models.DefaultDeviceSettings())
+	legacy, err := settingsToMap(device.Settings)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range legacy {
+		if isZeroSettingValue(value) || reflect.DeepEqual(value, defaults[key]) {
+			continue
+		}
+		values[key] = value
+		sources[key] = SettingSource{Scope: field.SettingsScopeDevice, TargetID: device.ID}
+	}
+
+	if profile, ok := byScope[field.SettingsScopeDevice]; ok {
+		if err := apply(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var settings models.DeviceSettings
+	if err := json.Unmarshal(b, &settings); err != nil {
+		return nil, fmt.Errorf("failed to build effective settings: %v", err)
+	}
+
+	return &EffectiveDeviceSettings{
+		DeviceID: device.ID,
+		Settings: settings,
+		Sources:  sources,
+	}, nil
+}
+
+func (s *DeviceSettingsService) ResolveEffectiveSettingsByID(deviceID uint) (*EffectiveDeviceSettings, error) {
+	var device models.Device
+	if err := s.db.First(&device, deviceID).Error; err != nil {
+		return nil, err
+	}
+	return s.ResolveEffectiveSettings(&device)
+}
+
+// isZeroSettingValue 未初始化的旧字段（0 或空字符串）不视为覆盖
+func isZeroSettingValue(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	case nil:
+		return true
+	}
+	return false
+}
+
+// checkTarget 校验配置目标是否存在
+func (s *DeviceSettingsService) checkTarget(scope field.SettingsScope, targetID uint) error {
+	var err error
+	switch scope {
+	case field.SettingsScopeBuilding:
+		err = s.db.First(&models.Building{}, targetID).Error
+	case field.SettingsScopeGroup:
+		err = s.db.First(&models.DeviceGroup{}, targetID).Error
+	case field.SettingsScopeDevice:
+		err = s.db.First(&models.Device{}, targetID).Error
+	}
+	if err != nil {
+		return fmt.Errorf("%s %d not found: %v", scope, targetID, err)
+	}
+	return nil
+}
+
+// notifySettingsChanged 通知受影响的设备重新获取设置
+func (s *DeviceSettingsService) notifySettingsChanged(scope field.SettingsScope, targetID uint) {
+	switch scope {
+	case field.SettingsScopeGlobal:
+		BroadcastDeviceEvent(field.DeviceEventSettingsChanged, nil)
+	case field.SettingsScopeBuilding:
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, []uint{targetID}, nil, nil)
+	case field.SettingsScopeGroup:
+		var deviceIDs []uint
+		if err := s.db.Model(&models.Device{}).Where("device_group_id = ?", targetID).Pluck("id", &deviceIDs).Error; err != nil {
+			log.Warn("获取分组设备失败 | 分组ID: %d | 错误: %v", targetID, err)
+			return
+		}
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, deviceIDs, nil)
+	case field.SettingsScopeDevice:
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, []uint{targetID}, nil)
+	}
+}
@@ -0,0 +1,399 @@
+package base_services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	redis "github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+const (
+	// 设备最后一次心跳时间（Hash: 设备ID -> Unix 秒）
+	deviceLastSeenKey = "device:last_seen"
+	// 多实例部署时只有持有该租约的实例执行离线检测
+	deviceStatusDetectorLeaderKey = "device:status:detector:leader"
+)
+
+// getDeviceStatusCheckInterval returns the offline detection interval from environment variables
+func getDeviceStatusCheckInterval() time.Duration {
+	interval := os.Getenv("DEVICE_STATUS_CHECK_INTERVAL")
+	if interval == "" {
+		return time.Minute
+	}
+
+	seconds, err := strconv.Atoi(interval)
+	if err != nil || seconds <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// DeviceUptimeReport 单个设备在时间范围内的在线统计
+type DeviceUptimeReport struct {
+	ID                   uint                      `json:"id"`
+	DeviceID             string                    `json:"deviceId"`
+	BuildingID           uint                      `json:"buildingId"`
+	From                 time.Time                 `json:"from"`
+	To                   time.Time                 `json:"to"`
+	ObservedSeconds      int64                     `json:"observedSeconds"` // 有状态记录的时长，首次记录之前的时间不计入
+	OnlineSeconds        int64                     `json:"onlineSeconds"`
+	OfflineSeconds       int64                     `json:"offlineSeconds"`
+	UptimePercent        *float64                  `json:"uptimePercent"` // 无状态记录时为 null
+	OutageCount          int                       `json:"outageCount"`
+	LongestOutageSeconds int64                     `json:"longestOutageSeconds"`
+	CurrentStatus        *field.DeviceOnlineStatus `json:"currentStatus,omitempty"`
+}
+
+// BuildingUptimeReport 建筑下所有设备的在线统计
+type BuildingUptimeReport struct {
+	BuildingID            uint                 `json:"buildingId"`
+	From                  time.Time            `json:"from"`
+	To                    time.Time            `json:"to"`
+	DeviceCount           int                  `json:"deviceCount"`
+	ObservedSeconds       int64                `json:"observedSeconds"`
+	OnlineSeconds         int64                `json:"onlineSeconds"`
+	UptimePercent         *float64             `json:"uptimePercent"`
+	OutageCount           int                  `json:"outageCount"`
+	LongestOutageSeconds  int64                `json:"longestOutageSeconds"`
+	LongestOutageDeviceID string               `json:"longestOutageDeviceId,omitempty"`
+	Devices               []DeviceUptimeReport `json:"devices"`
+}
+
+// InterfaceDeviceStatusService 设备上下线记录与在线率统计服务接口
+type InterfaceDeviceStatusService interface {
+	// 扫描所有设备，将 Redis 在线状态与最近一次记录比较并写入状态变化
+	DetectTransitions() (int, error)
+	// 启动定时离线检测
+	StartStatusDetector(ctx context.Context)
+	GetEvents(deviceID uint, from, to time.Time) ([]models.DeviceStatusEvent, error)
+	GetDeviceUptime(deviceID uint, from, to time.Time) (*DeviceUptimeReport, error)
+	GetBuildingUptime(buildingID uint, from, to time.Time) (*BuildingUptimeReport, error)
+}
+
+// DeviceStatusService 设备上下线记录服务实现
+type DeviceStatusService struct {
+	db *gorm.DB
+}
+
+// NewDeviceStatusService 创建设备上下线记录服务
+func NewDeviceStatusService(db *gorm.DB) InterfaceDeviceStatusService {
+	return &DeviceStatusService{db: db}
+}
+
+// recordDeviceStatusTransition 状态与最近一次记录不同时写入新记录
+func recordDeviceStatusTransition(db *gorm.DB, deviceID uint, status field.DeviceOnlineStatus, at time.Time) (bool, error) {
+	var last models.DeviceStatusEvent
+	err := db.Where("device_id = ?", deviceID).Order("occurred_at DESC, id DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err == nil && last.Status == status {
+		return false, nil
+	}
+	// 离线时间由最后心跳推算，不能早于上一条记录
+	if err == nil && at.Before(last.OccurredAt) {
+		at = last.OccurredAt
+	}
+
+	var device models.Device
+	if err := db.Select("id", "device_id", "building_id").First(&device, deviceID).Error; err != nil {
+		return false, err
+	}
+
+	event := &models.DeviceStatusEvent{
+		DeviceID:   deviceID,
+		BuildingID: device.BuildingID,
+		Status:     status,
+		OccurredAt: at,
+	}
+	if err := db.Create(event).Error; err != nil {
+		return false, err
+	}
+
+	log.Info("设备状态变化 | 设备ID: %s | 状态: %s | 时间: %s", device.DeviceID, status, at.Format(time.RFC3339))
+	return true, nil
+}
+
+func (s *DeviceStatusService) DetectTransitions() (int, error) {
+	ctx := context.Background()
+
+	var devices []models.Device
+	if err := s.db.Select("id", "device_id", "building_id").Find(&devices).Error; err != nil {
+		return 0, fmt.Errorf("failed to load devices: %v", err)
+	}
+	if len(devices) == 0 {
+		return 0, nil
+	}
+
+	// 每台设备最近一次记录的状态
+	latestIDs := s.db.Model(&models.DeviceStatusEvent{}).Select("MAX(id)").Group("device_id")
+	var latest []models.DeviceStatusEvent
+	if err := s.db.Where("id IN (?)", latestIDs).Find(&latest).Error; err != nil {
+		return 0, fmt.Errorf("failed to load latest status events: %v", err)
+	}
+	lastStatus := make(map[uint]field.DeviceOnlineStatus, len(latest))
+	for _, event := range latest {
+		lastStatus[event.DeviceID] = event.Status
+	}
+
+	pipe := redis.REDIS_CONN.Pipeline()
+	existsCmds := make([]interface{ Val() int64 }, len(devices))
+	for i, device := range devices {
+		existsCmds[i] = pipe.Exists(ctx, fmt.Sprintf("device:online:%d", device.ID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to check device online keys: %v", err)
+	}
+
+	timeout := time.Duration(getDeviceHealthTimeout()) * time.Second
+	now := time.Now()
+	changed := 0
+	for i, device := range devices {
+		status := field.DeviceStatusOffline
+		if existsCmds[i].Val() > 0 {
+			status = field.DeviceStatusOnline
+		}
+		if prev, ok := lastStatus[device.ID]; ok && prev == status {
+			continue
+		}
+
+		at := now
+		if status == field.DeviceStatusOffline {
+			// 离线时间 = 最后一次心跳 + 超时时间
+			if ts, err := redis.REDIS_CONN.HGet(ctx, deviceLastSeenKey, strconv.FormatUint(uint64(device.ID), 10)).Int64(); err == nil {
+				if offlineAt := time.Unix(ts, 0).Add(timeout); offlineAt.Before(now) {
+					at = offlineAt
+				}
+			}
+		}
+
+		recorded, err := recordDeviceStatusTransition(s.db, device.ID, status, at)
+		if err != nil {
+			log.Warn("记录设备状态变化失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+			continue
+		}
+		if recorded {
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+func (s *DeviceStatusService) StartStatusDetector(ctx context.Context) {
+	interval := getDeviceStatusCheckInterval()
+	ticker := time.NewTicker(interval)
+	leader := newSchedulerLeader(redis.REDIS_CONN, deviceStatusDetectorLeaderKey, "设备离线检测")
+	log.Info("设备离线检测已启动 | 间隔: %s", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				leader.Release()
+				log.Info("设备离线检测已停止")
+				return
+			case <-ticker.C:
+				if !leader.IsLeader(ctx) {
+					continue
+				}
+				changed, err := s.DetectTransitions()
+				if err != nil {
+					log.Error("设备离线检测失败 | 错误: %v", err)
+					continue
+				}
+				if changed > 0 {
+					log.Info("设备离线检测完成 | 状态变化: %d", changed)
+				}
+			}
+		}
+	}()
+}
+
+func (s *DeviceStatusService) GetEvents(deviceID uint, from, to time.Time) ([]models.DeviceStatusEvent, error) {
+	var events []models.DeviceStatusEvent
+	if err := s.db.Where("device_id = ? AND occurred_at >= ? AND occurred_at <= ?", deviceID, from, to).
+		Order("occurred_at ASC, id ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get status events: %v", err)
+	}
+	return events, nil
+}
+
+// buildUptimeReports 批量计算设备在线统计
+func (s *DeviceStatusService) buildUptimeReports(devices []models.Device, from, to time.Time) ([]DeviceUptimeReport, error) {
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return nil, errors.New("invalid time range")
+	}
+
+	deviceIDs := make([]uint, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+
+	// 范围开始前每台设备的最后状态
+	initialIDs := s.db.Model(&models.DeviceStatusEvent{}).Select("MAX(id)").
+		Where("device_id IN ? AND occurred_at < ?", deviceIDs, from).Group("device_id")
+	var initialEvents []models.DeviceStatusEvent
+	if err := s.db.Where("id IN (?)", initialIDs).Find(&initialEvents).Error; err != nil {
+		return nil, fmt.Errorf("failed to load initial status: %v", err)
+	}
+	initial := make(map[uint]field.DeviceOnlineStatus, len(initialEvents))
+	for _, event := range initialEvents {
+		initial[event.DeviceID] = event.Status
+	}
+
+	var events []models.DeviceStatusEvent
+	if err := s.db.Where("device_id IN ? AND occurred_at >= ? AND occurred_at <= ?", deviceIDs, from, to).
+		Order("occurred_at ASC, id ASC").
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load status events: %v", err)
+	}
+	eventsByDevice := make(map[uint][]models.DeviceStatusEvent)
+	for _, event := range events {
+		eventsByDevice[event.DeviceID] = append(eventsByDevice[event.DeviceID], event)
+	}
+
+	reports := make([]DeviceUptimeReport, 0, len(devices))
+	for _, device := range devices {
+		report := DeviceUptimeReport{
+			ID:         device.ID,
+			DeviceID:   device.DeviceID,
+			BuildingID: device.BuildingID,
+			From:       from,
+			To:         to,
+		}
+
+		var state *field.DeviceOnlineStatus
+		if status, ok := initial[device.ID]; ok {
+			state = &status
+		}
+		cursor := from
+		var outageStart time.Time
+		if state != nil && *state == field.DeviceStatusOffline {
+			report.OutageCount++
+			outageStart = from
+		}
+
+		accumulate := func(until time.Time) {
+			if state == nil {
+				return
+			}
+			seconds := int64(until.Sub(cursor).Seconds())
+			if *state == field.DeviceStatusOnline {
+				report.OnlineSeconds += seconds
+			} else {
+				report.OfflineSeconds += seconds
+			}
+		}
+
+		for _, event := range eventsByDevice[device.ID] {
+			accumulate(event.OccurredAt)
+			cursor = event.OccurredAt
+			if state != nil && *state == event.Status {
+				continue
+			}
+
+			if event.Status == field.DeviceStatusOffline {
+				report.OutageCount++
+				outageStart = event.OccurredAt
+			} else if state != nil && *state == field.DeviceStatusOffline {
+				if d := int64(event.OccurredAt.Sub(outageStart).Seconds()); d > report.LongestOutageSeconds {
+					report.LongestOutageSeconds = d
+				}
+			}
+			status := event.Status
+			state = &status
+		}
+		accumulate(to)
+		if state != nil && *state == field.DeviceStatusOffline {
+			if d := int64(to.Sub(outageStart).Seconds()); d > report.LongestOutageSeconds {
+				report.LongestOutageSeconds = d
+			}
+		}
+
+		report.CurrentStatus = state
+		report.ObservedSeconds = report.OnlineSeconds + report.OfflineSeconds
+		report.UptimePercent = uptimePercent(report.OnlineSeconds, report.ObservedSeconds)
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func uptimePercent(online, observed int64) *float64 {
+	if observed <= 0 {
+		return nil
+	}
+	percent := float64(online) * 100 / float64(observed)
+	return &percent
+}
+
+func (s *DeviceStatusService) GetDeviceUptime(deviceID uint, from, to time.Time) (*DeviceUptimeReport, error) {
+	var device models.Device
+	if err := s.db.Select("id", "device_id", "building_id").First(&device, deviceID).Error; err != nil {
+		return nil, fmt.Errorf("device not found: %v", err)
+	}
+
+	reports, err := s.buildUptimeReports([]models.Device{device}, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+func (s *DeviceStatusService) GetBuildingUptime(buildingID uint, from, to time.Time) (*BuildingUptimeReport, error) {
+	var building models.Building
+	if err := s.db.Select("id").First(&building, buildingID).Error; err != nil {
+		return nil, fmt.Errorf("building not found: %v", err)
+	}
+
+	var devices []models.Device
+	if err := s.db.Select("id", "device_id", "building_id").Where("building_id = ?", buildingID).Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to load building devices: %v", err)
+	}
+
+	result := &BuildingUptimeReport{
+		BuildingID:  buildingID,
+		From:        from,
+		To:          to,
+		DeviceCount: len(devices),
+		Devices:     []DeviceUptimeReport{},
+	}
+	if len(devices) == 0 {
+		return result, nil
+	}
+
+	reports, err := s.buildUptimeReports(devices, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, report := range reports {
+		result.ObservedSeconds += report.ObservedSeconds
+		result.OnlineSeconds += report.OnlineSeconds
+		result.OutageCount += report.OutageCount
+		if report.LongestOutageSeconds > result.LongestOutageSeconds {
+			result.LongestOutageSeconds = report.LongestOutageSeconds
+			result.LongestOutageDeviceID = report.DeviceID
+		}
+		result.To = report.To
+	}
+	result.UptimePercent = uptimePercent(result.OnlineSeconds, result.ObservedSeconds)
+	result.Devices = reports
+
+	return result, nil
+}
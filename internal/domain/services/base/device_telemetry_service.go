@@ -0,0 +1,376 @@
+package base_services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// getDeviceTelemetryBucket returns the downsampling bucket size from environment variables
+func getDeviceTelemetryBucket() time.Duration {
+	minutes := os.Getenv("DEVICE_TELEMETRY_BUCKET_MINUTES")
+	if minutes == "" {
+		return 15 * time.Minute
+	}
+
+	n, err := strconv.Atoi(minutes)
+	if err != nil || n <= 0 {
+		return 15 * time.Minute
+	}
+
+	return time.Duration(n) * time.Minute
+}
+
+// getDeviceTelemetryRetention returns how long telemetry history is kept
+func getDeviceTelemetryRetention() time.Duration {
+	days := os.Getenv("DEVICE_TELEMETRY_RETENTION_DAYS")
+	if days == "" {
+		return 30 * 24 * time.Hour
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n <= 0 {
+		return 30 * 24 * time.Hour
+	}
+
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// defaultDeviceHealthRules 未配置任何规则时使用的内置规则
+func defaultDeviceHealthRules() []models.DeviceHealthRule {
+	return []models.DeviceHealthRule{
+		{Name: "温度过高", Metric: field.TelemetryMetricTemperature, Operator: field.RuleOperatorGT, Threshold: 80, Enabled: true},
+		{Name: "存储空间不足", Metric: field.TelemetryMetricStorageFreePercent, Operator: field.RuleOperatorLT, Threshold: 10, Enabled: true},
+		{Name: "内存不足", Metric: field.TelemetryMetricMemoryFreePercent, Operator: field.RuleOperatorLT, Threshold: 5, Enabled: true},
+	}
+}
+
+// DeviceHealthIssue 命中的健康规则
+type DeviceHealthIssue struct {
+	RuleID    uint                  `json:"ruleId,omitempty"`
+	Name      string                `json:"name"`
+	Metric    field.TelemetryMetric `json:"metric"`
+	Operator  field.RuleOperator    `json:"operator"`
+	Threshold float64               `json:"threshold"`
+	Value     float64               `json:"value"`
+}
+
+// InterfaceDeviceTelemetryService 设备遥测服务接口
+type InterfaceDeviceTelemetryService interface {
+	// 记录心跳上报的遥测数据，返回合并后的最新遥测及健康状态
+	Record(deviceID uint, report models.DeviceTelemetry) (*models.DeviceTelemetry, error)
+	// 获取时间范围内的降采样历史
+	GetHistory(deviceID uint, from, to time.Time) ([]models.DeviceTelemetrySample, error)
+	// 健康规则管理
+	GetRules() ([]models.DeviceHealthRule, error)
+	CreateRule(rule *models.DeviceHealthRule) error
+	UpdateRule(id uint, updates map[string]interface{}) (*models.DeviceHealthRule, error)
+	DeleteRules(ids []uint) error
+}
+
+// DeviceTelemetryService 设备遥测服务实现
+type DeviceTelemetryService struct {
+	db *gorm.DB
+}
+
+// NewDeviceTelemetryService 创建设备遥测服务
+func NewDeviceTelemetryService(db *gorm.DB) InterfaceDeviceTelemetryService {
+	return &DeviceTelemetryService{db: db}
+}
+
+func (s *DeviceTelemetryService) Record(deviceID uint, report models.DeviceTelemetry) (*models.DeviceTelemetry, error) {
+	var device models.Device
+	if err := s.db.Where("id = ?", deviceID).First(&device).Error; err != nil {
+		return nil, fmt.Errorf("device not found: %v", err)
+	}
+
+	previousHealth := device.Telemetry.Health
+	telemetry := mergeTelemetry(device.Telemetry, report)
+	now := time.Now()
+	telemetry.ReportedAt = &now
+
+	rules, err := s.activeRules()
+	if err != nil {
+		return nil, err
+	}
+	issues := evaluateHealthRules(telemetry, rules)
+	telemetry.Health = field.DeviceHealthHealthy
+	if len(issues) > 0 {
+		telemetry.Health = field.DeviceHealthUnhealthy
+	}
+	issuesJSON, _ := json.Marshal(issues)
+	telemetry.HealthIssues = datatypes.JSON(issuesJSON)
+
+	if err := s.db.Model(&models.Device{}).Where("id = ?", deviceID).Omit(clause.Associations).
+		Updates(&models.Device{Telemetry: telemetry}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update device telemetry: %v", err)
+	}
+
+	if telemetry.Health != previousHealth && telemetry.Health == field.DeviceHealthUnhealthy {
+		log.Warn("设备健康异常 | 设备ID: %s | 命中规则: %s", device.DeviceID, string(issuesJSON))
+	} else if previousHealth == field.DeviceHealthUnhealthy && telemetry.Health == field.DeviceHealthHealthy {
+		log.Info("设备健康恢复 | 设备ID: %s", device.DeviceID)
+	}
+
+	if err := s.recordSample(deviceID, telemetry, now); err != nil {
+		log.Warn("记录设备遥测历史失败 | 设备ID: %s | 错误: %v", device.DeviceID, err)
+	}
+
+	return &telemetry, nil
+}
+
+// mergeTelemetry 用本次上报的非空字段覆盖已有值
+func mergeTelemetry(current, report models.DeviceTelemetry) models.DeviceTelemetry {
+	if report.AppVersion != nil {
+		current.AppVersion = report.AppVersion
+	}
+	if report.UptimeSeconds != nil {
+		current.UptimeSeconds = report.UptimeSeconds
+	}
+	if report.StorageFreeMB != nil {
+		current.StorageFreeMB = report.StorageFreeMB
+	}
+	if report.StorageTotalMB != nil {
+		current.StorageTotalMB = report.StorageTotalMB
+	}
+	if report.MemoryFreeMB != nil {
+		current.MemoryFreeMB = report.MemoryFreeMB
+	}
+	if report.MemoryTotalMB != nil {
+		current.MemoryTotalMB = report.MemoryTotalMB
+	}
+	if report.Temperature != nil {
+		current.Temperature = report.Temperature
+	}
+	if report.NetworkType != nil {
+		current.NetworkType = report.NetworkType
+	}
+	if report.IPAddress != nil {
+		current.IPAddress = report.IPAddress
+	}
+	if report.ScreenOn != nil {
+		current.ScreenOn = report.ScreenOn
+	}
+	return current
+}
+
+// recordSample 将遥测写入所在时间桶，桶内只保留最后值与极值
+func (s *DeviceTelemetryService) recordSample(deviceID uint, telemetry models.DeviceTelemetry, now time.Time) error {
+	bucket := now.Truncate(getDeviceTelemetryBucket())
+
+	var sample models.DeviceTelemetrySample
+	err := s.db.Where("device_id = ? AND bucket_start = ?", deviceID, bucket).First(&sample).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+
+	sample.DeviceID = deviceID
+	sample.BucketStart = bucket
+	sample.SampleCount++
+	sample.UptimeSeconds = telemetry.UptimeSeconds
+	sample.StorageFreeMB = telemetry.StorageFreeMB
+	sample.MemoryFreeMB = telemetry.MemoryFreeMB
+	sample.Temperature = telemetry.Temperature
+	sample.NetworkType = telemetry.NetworkType
+	sample.ScreenOn = telemetry.ScreenOn
+
+	if v := telemetry.StorageFreeMB; v != nil && (sample.MinStorageFreeMB == nil || *v < *sample.MinStorageFreeMB) {
+		sample.MinStorageFreeMB = v
+	}
+	if v := telemetry.MemoryFreeMB; v != nil && (sample.MinMemoryFreeMB == nil || *v < *sample.MinMemoryFreeMB) {
+		sample.MinMemoryFreeMB = v
+	}
+	if v := telemetry.Temperature; v != nil && (sample.MaxTemperature == nil || *v > *sample.MaxTemperature) {
+		sample.MaxTemperature = v
+	}
+
+	if err := s.db.Save(&sample).Error; err != nil {
+		return err
+	}
+
+	// 每进入新的时间桶时清理过期历史
+	if isNew {
+		if err := s.db.Where("device_id = ? AND bucket_start < ?", deviceID, now.Add(-getDeviceTelemetryRetention())).
+			Delete(&models.DeviceTelemetrySample{}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeRules 返回启用的规则，未配置任何规则时返回内置规则
+func (s *DeviceTelemetryService) activeRules() ([]models.DeviceHealthRule, error) {
+	var total int64
+	if err := s.db.Model(&models.DeviceHealthRule{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to load health rules: %v", err)
+	}
+	if total == 0 {
+		return defaultDeviceHealthRules(), nil
+	}
+
+	var rules []models.DeviceHealthRule
+	if err := s.db.Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to load health rules: %v", err)
+	}
+	return rules, nil
+}
+
+// telemetryMetricValue 取遥测中的指标值，缺少数据时返回 false
+func telemetryMetricValue(t models.DeviceTelemetry, metric field.TelemetryMetric) (float64, bool) {
+	switch metric {
+	case field.TelemetryMetricTemperature:
+		if t.Temperature != nil {
+			return *t.Temperature, true
+		}
+	case field.TelemetryMetricStorageFreeMB:
+		if t.StorageFreeMB != nil {
+			return float64(*t.StorageFreeMB), true
+		}
+	case field.TelemetryMetricStorageFreePercent:
+		if t.StorageFreeMB != nil && t.StorageTotalMB != nil && *t.StorageTotalMB > 0 {
+			return float64(*t.StorageFreeMB) * 100 / float64(*t.StorageTotalMB), true
+		}
+	case field.TelemetryMetricMemoryFreeMB:
+		if t.MemoryFreeMB != nil {
+			return float64(*t.MemoryFreeMB), true
+		}
+	case field.TelemetryMetricMemoryFreePercent:
+		if t.MemoryFreeMB != nil && t.MemoryTotalMB != nil && *t.MemoryTotalMB > 0 {
+			return float64(*t.MemoryFreeMB) * 100 / float64(*t.MemoryTotalMB), true
+		}
+	case field.TelemetryMetricUptimeSeconds:
+		if t.UptimeSeconds != nil {
+			return float64(*t.UptimeSeconds), true
+		}
+	case field.TelemetryMetricScreenOn:
+		if t.ScreenOn != nil {
+			if *t.ScreenOn {
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func evaluateHealthRules(t models.DeviceTelemetry, rules []models.DeviceHealthRule) []DeviceHealthIssue {
+	issues := []DeviceHealthIssue{}
+	for _, rule := range rules {
+		value, ok := telemetryMetricValue(t, rule.Metric)
+		if !ok {
+			continue
+		}
+
+		var hit bool
+		switch rule.Operator {
+		case field.RuleOperatorGT:
+			hit = value > rule.Threshold
+		case field.RuleOperatorGTE:
+			hit = value >= rule.Threshold
+		case field.RuleOperatorLT:
+			hit = value < rule.Threshold
+		case field.RuleOperatorLTE:
+			hit = value <= rule.Threshold
+		case field.RuleOperatorEQ:
+			hit = value == rule.Threshold
+		}
+
+		if hit {
+			issues = append(issues, DeviceHealthIssue{
+				RuleID:    rule.ID,
+				Name:      rule.Name,
+				Metric:    rule.Metric,
+				Operator:  rule.Operator,
+				Threshold: rule.Threshold,
+				Value:     value,
+			})
+		}
+	}
+	return issues
+}
+
+func (s *DeviceTelemetryService) GetHistory(deviceID uint, from, to time.Time) ([]models.DeviceTelemetrySample, error) {
+	var samples []models.DeviceTelemetrySample
+	if err := s.db.Where("device_id = ? AND bucket_start >= ? AND bucket_start <= ?", deviceID, from, to).
+		Order("bucket_start ASC").
+		Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to get telemetry history: %v", err)
+	}
+	return samples, nil
+}
+
+func (s *DeviceTelemetryService) GetRules() ([]models.DeviceHealthRule, error) {
+	var rules []models.DeviceHealthRule
+	if err := s.db.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func validateHealthRule(metric field.TelemetryMetric, operator field.RuleOperator) error {
+	if !field.IsValidTelemetryMetric(string(metric)) {
+		return fmt.Errorf("invalid metric: %s", metric)
+	}
+	if !field.IsValidRuleOperator(string(operator)) {
+		return fmt.Errorf("invalid operator: %s", operator)
+	}
+	return nil
+}
+
+// CreateRule 按传入的值保存规则，Enabled 为 false 时保存为停用
+func (s *DeviceTelemetryService) CreateRule(rule *models.DeviceHealthRule) error {
+	if err := validateHealthRule(rule.Metric, rule.Operator); err != nil {
+		return err
+	}
+	return s.db.Create(rule).Error
+}
+
+func (s *DeviceTelemetryService) UpdateRule(id uint, updates map[string]interface{}) (*models.DeviceHealthRule, error) {
+	var rule models.DeviceHealthRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+
+	metric := rule.Metric
+	if v, ok := updates["metric"].(string); ok {
+		metric = field.TelemetryMetric(v)
+	}
+	operator := rule.Operator
+	if v, ok := updates["operator"].(string); ok {
+		operator = field.RuleOperator(v)
+	}
+	if err := validateHealthRule(metric, operator); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&rule).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *DeviceTelemetryService) DeleteRules(ids []uint) error {
+	result := s.db.Delete(&models.DeviceHealthRule{}, ids)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("no records found to delete")
+	}
+	return nil
+}
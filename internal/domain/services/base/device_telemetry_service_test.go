@@ -0,0 +1,50 @@
+package base_services
+
+import (
+	"testing"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+func TestCreateHealthRuleKeepsEnabled(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		wantActive int
+	}{
+		{"启用的规则参与计算", true, 1},
+		{"停用的规则不参与计算", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.DeviceHealthRule{})
+			s := &DeviceTelemetryService{db: db}
+			rule := &models.DeviceHealthRule{
+				Name:      "温度过高",
+				Metric:    field.TelemetryMetricTemperature,
+				Operator:  field.RuleOperatorGT,
+				Threshold: 75,
+				Enabled:   tt.enabled,
+			}
+			if err := s.CreateRule(rule); err != nil {
+				t.Fatalf("create rule: %v", err)
+			}
+
+			var stored models.DeviceHealthRule
+			if err := db.First(&stored, rule.ID).Error; err != nil {
+				t.Fatalf("load rule: %v", err)
+			}
+			if stored.Enabled != tt.enabled {
+				t.Errorf("stored enabled = %v, want %v", stored.Enabled, tt.enabled)
+			}
+			active, err := s.activeRules()
+			if err != nil {
+				t.Fatalf("active rules: %v", err)
+			}
+			if len(active) != tt.wantActive {
+				t.Errorf("active rules = %d, want %d", len(active), tt.wantActive)
+			}
+		})
+	}
+}
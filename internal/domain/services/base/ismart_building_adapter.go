@@ -0,0 +1,48 @@
+package base_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// iSmart 建筑列表接口的占位适配
+//
+// iSmart 目前只提供了通知接口（ISMART_NOTICE_API_URL），建筑列表接口没有文档，也没有可用的实际响应。
+// 这里按暂定的格式解析 ISMART_BUILDING_API_URL 的 GET 响应，格式见 testdata/ismart_buildings_placeholder.json：
+//
+//	[{"blg_id": "B001", "blg_name": "...", "blg_address": "..."}]
+//
+// 与 iSmart 确认接口后，替换 decodeIsmartBuildings 与该样例文件。解析严格校验格式，
+// 响应不是数组或缺少 blg_id 时返回错误，避免格式不一致时静默得到空的建筑列表。
+
+// OldSystemBuilding 暂定的 iSmart 建筑列表接口返回的建筑结构
+type OldSystemBuilding struct {
+	BlgID      string `json:"blg_id"`
+	BlgName    string `json:"blg_name"`
+	BlgAddress string `json:"blg_address"`
+}
+
+// decodeIsmartBuildings 解析暂定格式的建筑列表响应
+func decodeIsmartBuildings(body io.Reader) ([]SourceBuilding, error) {
+	var oldBuildings []OldSystemBuilding
+	if err := json.NewDecoder(body).Decode(&oldBuildings); err != nil {
+		return nil, fmt.Errorf("unexpected ismart building list format, want an array of buildings: %v", err)
+	}
+
+	buildings := make([]SourceBuilding, 0, len(oldBuildings))
+	for i, building := range oldBuildings {
+		if building.BlgID == "" {
+			return nil, fmt.Errorf("unexpected ismart building list format: item %d has no blg_id", i)
+		}
+		buildings = append(buildings, SourceBuilding{
+			Source:  field.NoticeSourceIsmart,
+			ID:      building.BlgID,
+			Name:    building.BlgName,
+			Address: building.BlgAddress,
+		})
+	}
+	return buildings, nil
+}
@@ -0,0 +1,71 @@
+package base_services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+func TestIsmartListBuildingsPlaceholderResponse(t *testing.T) {
+	body, err := os.ReadFile("testdata/ismart_buildings_placeholder.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("method = %s, want GET", r.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	source := NewIsmartNoticeSource("", server.URL, 5*time.Second)
+	buildings, err := source.ListBuildings(context.Background())
+	if err != nil {
+		t.Fatalf("list buildings: %v", err)
+	}
+	want := []SourceBuilding{
+		{Source: field.NoticeSourceIsmart, ID: "B001", Name: "海景花园一期", Address: "香港九龙海景道 1 号"},
+		{Source: field.NoticeSourceIsmart, ID: "B002", Name: "海景花园二期"},
+	}
+	if !reflect.DeepEqual(buildings, want) {
+		t.Errorf("buildings = %+v, want %+v", buildings, want)
+	}
+}
+
+func TestDecodeIsmartBuildingsRejectsUnexpectedSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{"空列表", `[]`, 0, false},
+		{"暂定格式", `[{"blg_id":"B001","blg_name":"A"}]`, 1, false},
+		{"多出的字段不影响解析", `[{"blg_id":"B001","blg_name":"A","extra":1}]`, 1, false},
+		{"外层为对象", `{"data":[{"blg_id":"B001"}]}`, 0, true},
+		{"字段名不同", `[{"id":"B001","name":"A"}]`, 0, true},
+		{"部分建筑缺少 blg_id", `[{"blg_id":"B001"},{"blg_name":"B"}]`, 0, true},
+		{"blg_id 类型不同", `[{"blg_id":1001}]`, 0, true},
+		{"不是 JSON", `<html>502 Bad Gateway</html>`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buildings, err := decodeIsmartBuildings(strings.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(buildings) != tt.want {
+				t.Errorf("buildings = %d, want %d", len(buildings), tt.want)
+			}
+		})
+	}
+}
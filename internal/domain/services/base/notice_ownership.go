@@ -0,0 +1,199 @@
+package base_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// noticeOverrideColumns 通知更新字段对应的同步字段
+var noticeOverrideColumns = map[string]field.NoticeSyncField{
+	"title":       field.NoticeSyncFieldTitle,
+	"description": field.NoticeSyncFieldTitle,
+	"type":        field.NoticeSyncFieldType,
+	"end_time":    field.NoticeSyncFieldEndTime,
+	"priority":    field.NoticeSyncFieldPriority,
+	"file_id":     field.NoticeSyncFieldFile,
+}
+
+// NoticeSyncConflict 来源的变化因本地覆盖未写入本地通知
+type NoticeSyncConflict struct {
+	NoticeID      uint                  `json:"noticeId"`
+	Source        field.NoticeSource    `json:"source,omitempty"`
+	ExternalID    int                   `json:"externalId,omitempty"`
+	Field         field.NoticeSyncField `json:"field,omitempty"`
+	LocalValue    string                `json:"localValue,omitempty"`
+	UpstreamValue string                `json:"upstreamValue,omitempty"`
+	Reason        string                `json:"reason"`
+}
+
+// noticeOverrides 解析通知的本地覆盖字段
+func noticeOverrides(notice *base_models.Notice) map[field.NoticeSyncField]bool {
+	overrides := make(map[field.NoticeSyncField]bool)
+	if len(notice.LocalOverrides) == 0 {
+		return overrides
+	}
+	var fields []field.NoticeSyncField
+	if err := json.Unmarshal(notice.LocalOverrides, &fields); err != nil {
+		return overrides
+	}
+	for _, f := range fields {
+		overrides[f] = true
+	}
+	return overrides
+}
+
+// noticeOverridesJSON 本地覆盖字段转换为有序的 JSON 列表，没有覆盖时为 nil
+func noticeOverridesJSON(overrides map[field.NoticeSyncField]bool) datatypes.JSON {
+	if len(overrides) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(overrides))
+	for f := range overrides {
+		fields = append(fields, string(f))
+	}
+	sort.Strings(fields)
+	data, _ := json.Marshal(fields)
+	return datatypes.JSON(data)
+}
+
+// MarkNoticeOverrides 管理员修改 iSmart 通知的同步字段时记录为本地覆盖，写入 updates["local_overrides"]
+// 之后同步不再覆盖这些字段；值与原值相同的字段不记录
+func MarkNoticeOverrides(notice *base_models.Notice, updates map[string]interface{}) {
+	if !notice.IsIsmartNotice {
+		return
+	}
+
+	overrides := noticeOverrides(notice)
+	marked := false
+	for column, value := range updates {
+		syncField, ok := noticeOverrideColumns[column]
+		if !ok || overrides[syncField] || !noticeColumnChanged(notice, column, value) {
+			continue
+		}
+		overrides[syncField] = true
+		marked = true
+	}
+	if marked {
+		updates["local_overrides"] = noticeOverridesJSON(overrides)
+	}
+}
+
+// noticeColumnChanged 更新值与通知当前值是否不同，无法比较的类型视为不同
+func noticeColumnChanged(notice *base_models.Notice, column string, value interface{}) bool {
+	switch column {
+	case "title":
+		v, ok := value.(string)
+		return !ok || v != notice.Title
+	case "description":
+		v, ok := value.(string)
+		return !ok || v != notice.Description
+	case "type":
+		switch v := value.(type) {
+		case field.NoticeType:
+			return v != notice.Type
+		case string:
+			return field.NoticeType(v) != notice.Type
+		}
+	case "priority":
+		v, ok := value.(int)
+		return !ok || v != notice.Priority
+	case "end_time":
+		switch v := value.(type) {
+		case time.Time:
+			return !v.Equal(notice.EndTime)
+		case *time.Time:
+			return v == nil || !v.Equal(notice.EndTime)
+		}
+	case "file_id":
+		v, ok := value.(uint)
+		return !ok || notice.FileID == nil || v != *notice.FileID
+	}
+	return true
+}
+
+// sourceConflict 来源的变化因本地覆盖未写入时的冲突
+func sourceConflict(item *noticeSyncItem, syncField field.NoticeSyncField, localValue, upstreamValue, reason string) NoticeSyncConflict {
+	return NoticeSyncConflict{
+		NoticeID:      item.local.ID,
+		Source:        item.notice.Source,
+		ExternalID:    item.notice.ID,
+		Field:         syncField,
+		LocalValue:    localValue,
+		UpstreamValue: upstreamValue,
+		Reason:        reason,
+	}
+}
+
+// mergeSourceMetadata 合并来源的标题与类型，返回需要写入本地通知的字段，并更新对应关系中来源的标题与类型
+// 本地覆盖的字段不写入，与来源不一致时记为冲突；冲突期间对应关系保留上次同步的值，之后每次同步都会再次报告，
+// 直到来源与本地一致或取消本地修改
+// 定时同步只在来源的标题或类型变化时合并，避免共用同一通知的建筑类型映射不同时反复修改；手动同步按当前建筑的映射重新比对
+func (s *NoticeSyncService) mergeSourceMetadata(item *noticeSyncItem, mapping *base_models.NoticeSourceMapping, building *base_models.Building, opts noticeSyncOptions) (map[string]interface{}, []NoticeSyncConflict) {
+	updates := map[string]interface{}{}
+	var conflicts []NoticeSyncConflict
+	if item.local == nil {
+		mapping.Title = item.notice.Title
+		mapping.Type = item.notice.Type
+		return updates, conflicts
+	}
+
+	local := item.local
+	overrides := noticeOverrides(local)
+	noticeType := s.buildingNoticeType(building, item.notice.Type)
+	titleChanged := opts.full || mapping.Title != item.notice.Title
+	typeChanged := opts.full || mapping.Type != item.notice.Type
+
+	titleConflict := false
+	if titleChanged && local.Title != item.notice.Title {
+		if overrides[field.NoticeSyncFieldTitle] {
+			conflicts = append(conflicts, sourceConflict(item, field.NoticeSyncFieldTitle,
+				local.Title, item.notice.Title, "upstream title differs from local edit"))
+			titleConflict = true
+		} else {
+			updates["title"] = item.notice.Title
+			updates["description"] = item.notice.Title
+		}
+	}
+	if !titleConflict {
+		mapping.Title = item.notice.Title
+	}
+
+	typeConflict := false
+	if typeChanged && local.Type != noticeType {
+		if overrides[field.NoticeSyncFieldType] {
+			conflicts = append(conflicts, sourceConflict(item, field.NoticeSyncFieldType,
+				string(local.Type), string(noticeType), "upstream type differs from local edit"))
+			typeConflict = true
+		} else {
+			updates["type"] = noticeType
+		}
+	}
+	if !typeConflict {
+		mapping.Type = item.notice.Type
+	}
+	return updates, conflicts
+}
+
+// fileConflict 来源文件内容变化，但本地替换过通知文件
+func fileConflict(item *noticeSyncItem, upstreamMD5 string) NoticeSyncConflict {
+	localValue := ""
+	if item.local.FileID != nil {
+		localValue = fmt.Sprintf("file %d", *item.local.FileID)
+	}
+	return sourceConflict(item, field.NoticeSyncFieldFile, localValue, upstreamMD5,
+		"upstream file changed but the file was replaced locally")
+}
+
+// removedConflict 来源已删除通知，但通知有本地修改，保留绑定
+func removedConflict(notice *base_models.Notice) NoticeSyncConflict {
+	return NoticeSyncConflict{
+		NoticeID: notice.ID,
+		Reason:   "removed upstream but kept because of local edits",
+	}
+}
@@ -3,6 +3,7 @@ package base_services
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
@@ -16,6 +17,7 @@ type InterfaceNoticeService interface {
 	Update(id uint, updates map[string]interface{}) (*base_models.Notice, error)
 	Delete(ids []uint) error
 	GetByID(id uint) (*base_models.Notice, error)
+	ResetOverrides(id uint, fields []field.NoticeSyncField) (*base_models.Notice, error)
 }
 
 type NoticeService struct {
@@ -114,6 +116,9 @@ func (s *NoticeService) Update(id uint, updates map[string]interface{}) (*base_m
 			return err
 		}
 
+		// iSmart 通知被修改的同步字段记为本地覆盖，同步不再覆盖
+		MarkNoticeOverrides(&originalNotice, updates)
+
 		// 更新通知
 		if err := tx.Model(&base_models.Notice{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 			return err
@@ -150,10 +155,20 @@ func (s *NoticeService) Update(id uint, updates map[string]interface{}) (*base_m
 		return nil, err
 	}
 
+	// 通知绑定建筑下的设备刷新公告
+	buildingIDs := make([]uint, 0, len(notice.Buildings))
+	for _, building := range notice.Buildings {
+		buildingIDs = append(buildingIDs, building.ID)
+	}
+	PublishDeviceEvent(field.DeviceEventNoticesChanged, buildingIDs, nil, map[string]interface{}{"noticeId": id})
+
 	return &notice, nil
 }
 
 func (s *NoticeService) Delete(ids []uint) error {
+	var buildingIDs []uint
+	s.db.Table("notice_buildings").Where("notice_id IN ?", ids).Distinct().Pluck("building_id", &buildingIDs)
+
 	result := s.db.Delete(&base_models.Notice{}, ids)
 	if result.Error != nil {
 		return result.Error
@@ -161,6 +176,8 @@ func (s *NoticeService) Delete(ids []uint) error {
 	if result.RowsAffected == 0 {
 		return errors.New("no records found to delete")
 	}
+
+	PublishDeviceEvent(field.DeviceEventNoticesChanged, buildingIDs, nil, map[string]interface{}{"noticeIds": ids})
 	return nil
 }
 
@@ -190,6 +207,59 @@ func (s *NoticeService) GetByID(id uint) (*base_models.Notice, error) {
 	return &notice, nil
 }
 
+// ResetOverrides 取消 iSmart 通知的本地覆盖，fields 为空时取消全部
+// 同时清空对应关系中这些字段的同步状态，下次同步重新使用来源的标题、类型与文件
+func (s *NoticeService) ResetOverrides(id uint, fields []field.NoticeSyncField) (*base_models.Notice, error) {
+	var notice base_models.Notice
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&notice, id).Error; err != nil {
+			return err
+		}
+		if !notice.IsIsmartNotice {
+			return fmt.Errorf("notice %d is not an iSmart notice", id)
+		}
+
+		overrides := noticeOverrides(&notice)
+		if len(fields) == 0 {
+			for f := range overrides {
+				fields = append(fields, f)
+			}
+		}
+		mappingUpdates := map[string]interface{}{}
+		for _, f := range fields {
+			delete(overrides, f)
+			switch f {
+			case field.NoticeSyncFieldTitle:
+				mappingUpdates["title"] = ""
+			case field.NoticeSyncFieldType:
+				mappingUpdates["type"] = ""
+			case field.NoticeSyncFieldFile:
+				mappingUpdates["file_url"] = ""
+				mappingUpdates["md5"] = ""
+				mappingUpdates["e_tag"] = ""
+				mappingUpdates["last_modified"] = ""
+			}
+		}
+
+		notice.LocalOverrides = noticeOverridesJSON(overrides)
+		if err := tx.Model(&base_models.Notice{}).Where("id = ?", id).
+			Update("local_overrides", notice.LocalOverrides).Error; err != nil {
+			return fmt.Errorf("failed to reset notice overrides: %v", err)
+		}
+		if len(mappingUpdates) > 0 {
+			if err := tx.Model(&base_models.NoticeSourceMapping{}).Where("notice_id = ?", id).
+				Updates(mappingUpdates).Error; err != nil {
+				return fmt.Errorf("failed to reset notice source mappings: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &notice, nil
+}
+
 // syncDeviceNoticeCarouselLists 同步设备的通知轮播列表
 func (s *NoticeService) syncDeviceNoticeCarouselLists(tx *gorm.DB, noticeID uint, statusChanged bool, oldStatus interface{}, newStatus interface{}) error {
 	// 获取与该通知关联的建筑物ID
@@ -0,0 +1,402 @@
+package base_services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/httpclient"
+)
+
+const defaultIsmartNoticeAPIURL = "https://uqf0jqfm77.execute-api.ap-east-1.amazonaws.com/prod/v1/building_board/building-notices"
+
+// SourceNotice 通知来源返回的一条通知
+type SourceNotice struct {
+	Source  field.NoticeSource `json:"source"`
+	ID      int                `json:"id"`      // 来源系统中的通知ID
+	Title   string             `json:"title"`   // 标题
+	Type    string             `json:"type"`    // 来源系统中的通知类型，同步时经 mapNoticeType 转换
+	FileURL string             `json:"fileUrl"` // 通知文件地址，由 FetchFile 读取
+}
+
+// SourceBuilding 通知来源中可同步的建筑
+type SourceBuilding struct {
+	Source  field.NoticeSource `json:"source"`
+	ID      string             `json:"ismartId"` // 来源系统中的建筑ID，对应 Building.IsmartID
+	Name    string             `json:"name"`
+	Address string             `json:"address"`
+}
+
+// FileValidators 上次下载文件时保存的 HTTP 缓存校验信息
+type FileValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchedFile 读取通知文件的结果，NotModified 时 Content 为空
+type FetchedFile struct {
+	Content      []byte
+	NotModified  bool
+	ETag         string
+	LastModified string
+}
+
+// NoticeSource 通知来源适配器
+type NoticeSource interface {
+	Name() field.NoticeSource
+	// ListBuildings 获取来源中可同步的建筑
+	ListBuildings(ctx context.Context) ([]SourceBuilding, error)
+	// ListNotices 获取建筑当前应展示的通知
+	ListNotices(ctx context.Context, building *base_models.Building) ([]SourceNotice, error)
+	// FetchFile 读取通知文件内容，validators 不为空时为条件请求，文件未变化时返回 NotModified
+	FetchFile(ctx context.Context, notice SourceNotice, validators FileValidators) (*FetchedFile, error)
+}
+
+// 通知来源配置
+func getIsmartNoticeAPIURL() string {
+	url := os.Getenv("ISMART_NOTICE_API_URL")
+	if url == "" {
+		return defaultIsmartNoticeAPIURL
+	}
+	return url
+}
+
+// getIsmartBuildingAPIURL iSmart 建筑列表接口地址，没有默认值，未配置时无法获取 iSmart 的建筑
+func getIsmartBuildingAPIURL() string {
+	return os.Getenv("ISMART_BUILDING_API_URL")
+}
+
+func getIsmartNoticeAPITimeout() time.Duration {
+	timeout := os.Getenv("ISMART_NOTICE_API_TIMEOUT")
+	if timeout == "" {
+		return 60 * time.Second // default to 60 seconds if not set
+	}
+
+	timeoutInt, err := strconv.Atoi(timeout)
+	if err != nil || timeoutInt <= 0 {
+		return 60 * time.Second // default to 60 seconds if invalid value
+	}
+
+	return time.Duration(timeoutInt) * time.Second
+}
+
+func getLocalNoticeSourceDir() string {
+	dir := os.Getenv("LOCAL_NOTICE_SOURCE_DIR")
+	if dir == "" {
+		return "./data/notice_source"
+	}
+	return dir
+}
+
+// getDefaultNoticeSources 未配置通知来源的建筑使用的来源，逗号分隔
+func getDefaultNoticeSources() []field.NoticeSource {
+	var sources []field.NoticeSource
+	for _, name := range strings.Split(os.Getenv("NOTICE_SOURCES_DEFAULT"), ",") {
+		name = strings.TrimSpace(name)
+		if field.IsValidNoticeSource(name) {
+			sources = append(sources, field.NoticeSource(name))
+		}
+	}
+	if len(sources) == 0 {
+		return []field.NoticeSource{field.NoticeSourceIsmart}
+	}
+	return sources
+}
+
+// newNoticeSources 创建所有可用的通知来源
+func newNoticeSources() map[field.NoticeSource]NoticeSource {
+	sources := []NoticeSource{
+		NewIsmartNoticeSource(getIsmartNoticeAPIURL(), getIsmartBuildingAPIURL(), getIsmartNoticeAPITimeout()),
+		NewLocalNoticeSource(getLocalNoticeSourceDir()),
+	}
+
+	registry := make(map[field.NoticeSource]NoticeSource, len(sources))
+	for _, source := range sources {
+		registry[source.Name()] = source
+	}
+	return registry
+}
+
+// ParseNoticeSources 解析并校验建筑的通知来源配置，去除重复项
+func ParseNoticeSources(names []string) ([]field.NoticeSource, error) {
+	var sources []field.NoticeSource
+	seen := make(map[field.NoticeSource]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !field.IsValidNoticeSource(name) {
+			return nil, fmt.Errorf("invalid notice source: %s", name)
+		}
+		if seen[field.NoticeSource(name)] {
+			continue
+		}
+		seen[field.NoticeSource(name)] = true
+		sources = append(sources, field.NoticeSource(name))
+	}
+	return sources, nil
+}
+
+// buildingNoticeSources 获取建筑配置的通知来源，为空时使用默认来源
+func buildingNoticeSources(building *base_models.Building) ([]field.NoticeSource, error) {
+	if len(building.NoticeSources) == 0 || string(building.NoticeSources) == "null" {
+		return getDefaultNoticeSources(), nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(building.NoticeSources, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse notice sources: %v", err)
+	}
+	sources, err := ParseNoticeSources(names)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return getDefaultNoticeSources(), nil
+	}
+	return sources, nil
+}
+
+// OldSystemNotice iSmart 接口返回的通知结构
+type OldSystemNotice struct {
+	ID        int    `json:"id"`
+	MessTitle string `json:"mess_title"`
+	MessType  string `json:"mess_type"`
+	MessFile  string `json:"mess_file"`
+}
+
+var (
+	noticeSourceClient     *httpclient.Client
+	noticeSourceClientOnce sync.Once
+)
+
+// getNoticeSourceClient 通知来源专用的客户端，熔断器只包含通知同步请求过的上游主机，随同步记录保存
+func getNoticeSourceClient() *httpclient.Client {
+	noticeSourceClientOnce.Do(func() {
+		noticeSourceClient = httpclient.New(httpclient.EnvConfig())
+	})
+	return noticeSourceClient
+}
+
+// IsmartNoticeSource 旧系统(iSmart)通知来源，按建筑的 IsmartID 查询
+// 请求经通知来源专用的 httpclient 发出，失败时重试，上游主机持续失败时熔断
+type IsmartNoticeSource struct {
+	url          string
+	buildingsURL string
+	timeout      time.Duration
+	client       *httpclient.Client
+}
+
+// NewIsmartNoticeSource 创建 iSmart 通知来源，url 与 buildingsURL 为通知与建筑列表接口，timeout 为单次请求超时
+func NewIsmartNoticeSource(url string, buildingsURL string, timeout time.Duration) *IsmartNoticeSource {
+	return &IsmartNoticeSource{
+		url:          url,
+		buildingsURL: buildingsURL,
+		timeout:      timeout,
+		client:       getNoticeSourceClient(),
+	}
+}
+
+func (s *IsmartNoticeSource) Name() field.NoticeSource {
+	return field.NoticeSourceIsmart
+}
+
+func (s *IsmartNoticeSource) ListBuildings(ctx context.Context) ([]SourceBuilding, error) {
+	if s.buildingsURL == "" {
+		return nil, fmt.Errorf("ISMART_BUILDING_API_URL is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.buildingsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := s.client.DoTimeout(req, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request old system: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("old system returned status %d", resp.StatusCode)
+	}
+
+	return decodeIsmartBuildings(resp.Body)
+}
+
+func (s *IsmartNoticeSource) ListNotices(ctx context.Context, building *base_models.Building) ([]SourceNotice, error) {
+	reqBody := struct {
+		BlgID string `json:"blg_id"`
+	}{
+		BlgID: building.IsmartID,
+	}
+
+	reqBodyJSON, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewBuffer(reqBodyJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.DoTimeout(req, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request old system: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("old system returned status %d", resp.StatusCode)
+	}
+
+	var oldNotices []OldSystemNotice
+	if err := json.NewDecoder(resp.Body).Decode(&oldNotices); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	notices := make([]SourceNotice, len(oldNotices))
+	for i, notice := range oldNotices {
+		notices[i] = SourceNotice{
+			Source:  field.NoticeSourceIsmart,
+			ID:      notice.ID,
+			Title:   notice.MessTitle,
+			Type:    notice.MessType,
+			FileURL: notice.MessFile,
+		}
+	}
+	return notices, nil
+}
+
+func (s *IsmartNoticeSource) FetchFile(ctx context.Context, notice SourceNotice, validators FileValidators) (*FetchedFile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, notice.FileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := s.client.DoTimeout(req, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	fetched := &FetchedFile{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		// 304 可能不带校验信息，沿用上次保存的值
+		if fetched.ETag == "" {
+			fetched.ETag = validators.ETag
+		}
+		if fetched.LastModified == "" {
+			fetched.LastModified = validators.LastModified
+		}
+		fetched.NotModified = true
+		return fetched, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("failed to download file: status %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+	fetched.Content = content
+	return fetched, nil
+}
+
+// LocalNoticeSource 本地通知来源，用于测试与联调
+// 建筑的通知列表为 {dir}/{IsmartID}.json，文件地址为相对 dir 的路径，文件不存在时视为没有通知
+type LocalNoticeSource struct {
+	dir string
+}
+
+// NewLocalNoticeSource 创建本地通知来源
+func NewLocalNoticeSource(dir string) *LocalNoticeSource {
+	return &LocalNoticeSource{dir: dir}
+}
+
+func (s *LocalNoticeSource) Name() field.NoticeSource {
+	return field.NoticeSourceLocal
+}
+
+// ListBuildings 目录下每个 {IsmartID}.json 为一个建筑
+func (s *LocalNoticeSource) ListBuildings(_ context.Context) ([]SourceBuilding, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []SourceBuilding{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read local notice source dir: %v", err)
+	}
+
+	buildings := []SourceBuilding{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		buildings = append(buildings, SourceBuilding{Source: field.NoticeSourceLocal, ID: id, Name: id})
+	}
+	return buildings, nil
+}
+
+func (s *LocalNoticeSource) ListNotices(_ context.Context, building *base_models.Building) ([]SourceNotice, error) {
+	content, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(building.IsmartID)+".json"))
+	if os.IsNotExist(err) {
+		return []SourceNotice{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read local notices: %v", err)
+	}
+
+	var notices []SourceNotice
+	if err := json.Unmarshal(content, &notices); err != nil {
+		return nil, fmt.Errorf("failed to decode local notices: %v", err)
+	}
+	for i := range notices {
+		notices[i].Source = field.NoticeSourceLocal
+	}
+	return notices, nil
+}
+
+// FetchFile 以文件修改时间作为 Last-Modified
+func (s *LocalNoticeSource) FetchFile(_ context.Context, notice SourceNotice, validators FileValidators) (*FetchedFile, error) {
+	path := filepath.Join(s.dir, filepath.Clean("/"+notice.FileURL))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+
+	fetched := &FetchedFile{LastModified: info.ModTime().UTC().Format(http.TimeFormat)}
+	if validators.LastModified != "" && validators.LastModified == fetched.LastModified {
+		fetched.NotModified = true
+		return fetched, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %v", err)
+	}
+	fetched.Content = content
+	return fetched, nil
+}
@@ -0,0 +1,411 @@
+package base_services
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/gin-gonic/gin"
+)
+
+// NoticeSyncPlanItem 试运行中一条通知的计划操作
+type NoticeSyncPlanItem struct {
+	Action     field.NoticeSyncAction `json:"action"`
+	NoticeID   uint                   `json:"noticeId,omitempty"` // 本地通知ID，新建时为空
+	Source     field.NoticeSource     `json:"source,omitempty"`
+	ExternalID int                    `json:"externalId,omitempty"` // 来源通知ID，解绑时为空
+	Title      string                 `json:"title"`
+	Reasons    []string               `json:"reasons"`
+	Conflicts  []NoticeSyncConflict   `json:"conflicts,omitempty"` // 因本地修改不会合并的来源变化
+}
+
+// DeviceCarouselEdit 试运行中一台设备轮播列表的计划变更
+type DeviceCarouselEdit struct {
+	DeviceID uint     `json:"deviceId"`
+	Add      []uint   `json:"add,omitempty"`    // 加入轮播的已有通知
+	AddNew   []string `json:"addNew,omitempty"` // 加入轮播的新建通知，值为 来源:来源通知ID
+	Remove   []uint   `json:"remove,omitempty"` // 移出轮播的通知
+}
+
+// getNoticeSyncDryRun 定时同步是否只试运行
+func getNoticeSyncDryRun() bool {
+	dryRun, err := strconv.ParseBool(os.Getenv("NOTICE_SYNC_DRY_RUN"))
+	if err != nil {
+		return false // default to false if not set or invalid value
+	}
+	return dryRun
+}
+
+// DryRunScheduledSync 对参与定时同步（已启用）的所有建筑试运行，不考虑同步间隔与免同步时段，单个建筑失败时记录错误
+func (s *NoticeSyncService) DryRunScheduledSync() ([]gin.H, error) {
+	var buildings []base_models.Building
+	if err := s.db.Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get buildings: %v", err)
+	}
+
+	var enabled []base_models.Building
+	for _, building := range buildings {
+		if noticeSyncEnabled(&building) {
+			enabled = append(enabled, building)
+		}
+	}
+	return s.dryRunBuildings(enabled), nil
+}
+
+// dryRunBuildings 并发试运行多个建筑，结果按建筑顺序返回
+func (s *NoticeSyncService) dryRunBuildings(buildings []base_models.Building) []gin.H {
+	results := make([]gin.H, len(buildings))
+	if len(buildings) == 0 {
+		return results
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, calculateBuildingWorkers(len(buildings), runtime.NumCPU()))
+	for i := range buildings {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			building := &buildings[i]
+			result, err := s.dryRunBuildingNotices(context.Background(), building, noticeSyncOptions{})
+			if err != nil {
+				result = gin.H{
+					"dryRun":       true,
+					"buildingId":   building.ID,
+					"buildingName": building.Name,
+					"error":        err.Error(),
+				}
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runDryRunSync 定时同步的试运行模式，按建筑的同步设置试运行到期的建筑，只记录日志
+// lastRun 为各建筑上次试运行的时间，试运行不保存同步记录
+func (s *NoticeSyncService) runDryRunSync(lastRun map[uint]time.Time) {
+	now := time.Now()
+	buildings, err := s.dueBuildings(now, lastRun)
+	if err != nil {
+		log.Error("通知同步试运行失败 | 错误: %v", err)
+		return
+	}
+	for _, building := range buildings {
+		lastRun[building.ID] = now
+	}
+
+	for _, result := range s.dryRunBuildings(buildings) {
+		if errMsg, ok := result["error"].(string); ok {
+			log.Error("建筑通知同步试运行失败 | 建筑ID: %v | 名称: %v | 错误: %s",
+				result["buildingId"], result["buildingName"], errMsg)
+			continue
+		}
+		log.Info("建筑通知同步试运行 | 建筑ID: %v | 名称: %v | 新建: %v | 绑定: %v | 更新: %v | 解绑: %v | 失败: %d",
+			result["buildingId"], result["buildingName"], result["createCount"], result["bindCount"],
+			result["updateCount"], result["unbindCount"], len(result["failedNotices"].([]string)))
+		if missing, ok := result["upstreamMissing"].([]field.NoticeSource); ok && len(missing) > 0 {
+			log.Warn("试运行建筑的 IsmartID 在来源建筑列表中不存在，解绑计划可能由 IsmartID 错误导致 | 建筑ID: %v | 来源: %v",
+				result["buildingId"], missing)
+		}
+		for _, item := range result["plan"].([]NoticeSyncPlanItem) {
+			if item.Action == field.NoticeSyncActionKeep {
+				continue
+			}
+			log.Info("试运行计划 | 建筑ID: %v | 操作: %s | 通知ID: %d | 来源: %s | 来源ID: %d | 标题: %s | 原因: %v",
+				result["buildingId"], item.Action, item.NoticeID, item.Source, item.ExternalID, item.Title, item.Reasons)
+		}
+	}
+}
+
+// dryRunBuildingNotices 按实际同步的规则推演每条通知的操作，返回计划的创建、绑定、更新、解绑与设备轮播变更
+// 只读取数据库与通知来源，不写入 MySQL、Redis 与 OSS，也不需要建筑同步锁
+func (s *NoticeSyncService) dryRunBuildingNotices(ctx context.Context, building *base_models.Building, opts noticeSyncOptions) (gin.H, error) {
+	existingNotices, sourceNotices, items, err := s.loadBuildingSync(ctx, building, opts)
+	if err != nil {
+		return nil, err
+	}
+	boundNotices := make(map[uint]bool, len(existingNotices))
+	for _, notice := range existingNotices {
+		boundNotices[notice.ID] = true
+	}
+
+	var downloadCount, notModifiedCount, skippedCount int
+	failedNotices := []string{}
+	plan := []NoticeSyncPlanItem{}
+	counts := make(map[field.NoticeSyncAction]int)
+	conflicts := []NoticeSyncConflict{}
+	keep := make(map[uint]bool)
+	var addNotices []uint
+	var addNewNotices []string
+	var removeNotices []uint
+
+	for _, item := range items {
+		switch {
+		case !item.fetch:
+			skippedCount++
+		case item.fetchErr == nil && item.fetched.NotModified:
+			notModifiedCount++
+		case item.fetchErr == nil:
+			downloadCount++
+		}
+
+		entry, err := s.planSourceNoticeAction(item, building, boundNotices, opts)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Action == field.NoticeSyncActionFailed {
+			failedNotices = append(failedNotices, fmt.Sprintf("Failed to process notice %s: %v",
+				sourceNoticeKey(item.notice.Source, item.notice.ID), item.fetchErr))
+			if entry.NoticeID != 0 {
+				keep[entry.NoticeID] = true
+			}
+		} else if entry.NoticeID != 0 {
+			if keep[entry.NoticeID] {
+				// 多条来源通知对应同一本地通知（内容相同）
+				entry.Action = field.NoticeSyncActionKeep
+				entry.Reasons = []string{"same content as another upstream notice"}
+			}
+			keep[entry.NoticeID] = true
+		}
+
+		conflicts = append(conflicts, entry.Conflicts...)
+		switch entry.Action {
+		case field.NoticeSyncActionCreate:
+			addNewNotices = append(addNewNotices, sourceNoticeKey(item.notice.Source, item.notice.ID))
+		case field.NoticeSyncActionBind:
+			addNotices = append(addNotices, entry.NoticeID)
+		}
+		counts[entry.Action]++
+		plan = append(plan, entry)
+	}
+
+	// 来源中已不存在的通知
+	for i, notice := range existingNotices {
+		if keep[notice.ID] {
+			continue
+		}
+		if len(noticeOverrides(&existingNotices[i])) > 0 {
+			conflict := removedConflict(&existingNotices[i])
+			plan = append(plan, NoticeSyncPlanItem{
+				Action:    field.NoticeSyncActionKeep,
+				NoticeID:  notice.ID,
+				Title:     notice.Title,
+				Reasons:   []string{"not in upstream notices", "notice has local edits"},
+				Conflicts: []NoticeSyncConflict{conflict},
+			})
+			counts[field.NoticeSyncActionKeep]++
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+		reasons := []string{"not in upstream notices"}
+		var otherBuildings int64
+		if err := s.db.Table("notice_buildings").
+			Where("notice_id = ? AND building_id <> ?", notice.ID, building.ID).
+			Count(&otherBuildings).Error; err != nil {
+			return nil, fmt.Errorf("failed to check notice bindings: %v", err)
+		}
+		if otherBuildings == 0 {
+			reasons = append(reasons, "notice will be deleted, no other building is bound")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("notice kept for %d other buildings", otherBuildings))
+		}
+
+		plan = append(plan, NoticeSyncPlanItem{
+			Action:   field.NoticeSyncActionUnbind,
+			NoticeID: notice.ID,
+			Title:    notice.Title,
+			Reasons:  reasons,
+		})
+		counts[field.NoticeSyncActionUnbind]++
+		removeNotices = append(removeNotices, notice.ID)
+	}
+
+	carouselEdits, err := s.planCarouselEdits(building.ID, addNotices, addNewNotices, removeNotices)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"dryRun":           true,
+		"message":          "Dry run completed, nothing was changed",
+		"buildingId":       building.ID,
+		"buildingName":     building.Name,
+		"plan":             plan,
+		"carouselEdits":    carouselEdits,
+		"createCount":      counts[field.NoticeSyncActionCreate],
+		"bindCount":        counts[field.NoticeSyncActionBind],
+		"updateCount":      counts[field.NoticeSyncActionUpdate],
+		"unbindCount":      counts[field.NoticeSyncActionUnbind],
+		"keepCount":        counts[field.NoticeSyncActionKeep],
+		"failedNotices":    failedNotices,
+		"totalProcessed":   len(sourceNotices),
+		"downloadCount":    downloadCount,
+		"notModifiedCount": notModifiedCount,
+		"skippedCount":     skippedCount,
+		"conflicts":        conflicts,
+		"conflictCount":    len(conflicts),
+		"upstreamMissing":  buildingUpstreamMissing(building),
+	}, nil
+}
+
+// planSourceNoticeAction 推演一条来源通知的操作，与 applySourceNotice 的规则一致
+func (s *NoticeSyncService) planSourceNoticeAction(item *noticeSyncItem, building *base_models.Building, boundNotices map[uint]bool, opts noticeSyncOptions) (NoticeSyncPlanItem, error) {
+	entry := NoticeSyncPlanItem{
+		Source:     item.notice.Source,
+		ExternalID: item.notice.ID,
+		Title:      item.notice.Title,
+	}
+
+	if item.fetchErr != nil {
+		entry.Action = field.NoticeSyncActionFailed
+		entry.Reasons = []string{item.fetchErr.Error()}
+		if item.local != nil && boundNotices[item.local.ID] {
+			entry.NoticeID = item.local.ID
+			entry.Reasons = append(entry.Reasons, "bound notice is kept")
+		}
+		return entry, nil
+	}
+
+	if item.local != nil {
+		entry.NoticeID = item.local.ID
+		var changes []string
+		switch {
+		case !item.fetch:
+			entry.Reasons = []string{"file url unchanged, no validators to check"}
+		case item.fetched.NotModified:
+			entry.Reasons = []string{"upstream file not modified"}
+		default:
+			md5Hash := md5.Sum(item.fetched.Content)
+			md5Str := hex.EncodeToString(md5Hash[:])
+			switch {
+			case md5Str == item.mapping.Md5:
+				entry.Reasons = []string{"file content unchanged"}
+			case noticeOverrides(item.local)[field.NoticeSyncFieldFile]:
+				entry.Conflicts = append(entry.Conflicts, fileConflict(item, md5Str))
+			default:
+				changes = append(changes, "file content changed")
+			}
+		}
+		// 推演时使用对应关系的副本，不修改已加载的对应关系
+		snapshot := *item.mapping
+		metadata, conflicts := s.mergeSourceMetadata(item, &snapshot, building, opts)
+		if len(metadata) > 0 {
+			changes = append(changes, "title or type changed")
+		}
+		if len(changes) > 0 {
+			shared, err := s.noticeShared(item.local.ID, building.ID, item.mapping.ID)
+			if err != nil {
+				return entry, err
+			}
+			if shared {
+				changes = append(changes, "notice is shared, this building will be rebound to a copy")
+			}
+		}
+		entry.Conflicts = append(entry.Conflicts, conflicts...)
+		if len(entry.Conflicts) > 0 {
+			entry.Reasons = append(entry.Reasons, "upstream changes conflict with local edits")
+		}
+
+		switch {
+		case !boundNotices[item.local.ID]:
+			entry.Action = field.NoticeSyncActionBind
+			entry.Reasons = append([]string{"synced notice is not bound to this building"}, changes...)
+		case len(changes) > 0:
+			entry.Action = field.NoticeSyncActionUpdate
+			entry.Reasons = changes
+		default:
+			entry.Action = field.NoticeSyncActionKeep
+		}
+		return entry, nil
+	}
+
+	// 新通知或本地通知已被删除，按文件内容查找可复用的通知
+	reason := "new upstream notice"
+	if item.mapping != nil {
+		reason = "synced notice was deleted"
+	}
+	md5Hash := md5.Sum(item.fetched.Content)
+	var file base_models.File
+	fileExists := s.db.Where("md5 = ?", hex.EncodeToString(md5Hash[:])).Limit(1).Find(&file).RowsAffected > 0
+	if fileExists {
+		existing, err := s.findIsmartNoticeByFile(file.ID)
+		if err != nil {
+			return entry, err
+		}
+		if existing != nil {
+			entry.NoticeID = existing.ID
+			if boundNotices[existing.ID] {
+				entry.Action = field.NoticeSyncActionKeep
+				entry.Reasons = []string{reason, "same file as a bound notice"}
+			} else {
+				entry.Action = field.NoticeSyncActionBind
+				entry.Reasons = []string{reason, "reuse existing notice with the same file"}
+			}
+			return entry, nil
+		}
+	}
+
+	entry.Action = field.NoticeSyncActionCreate
+	if fileExists {
+		entry.Reasons = []string{reason, "file already uploaded"}
+	} else {
+		entry.Reasons = []string{reason, "file will be uploaded"}
+	}
+	return entry, nil
+}
+
+// planCarouselEdits 推演建筑下设备轮播列表的变更，与 updateDeviceNoticeCarousel 的规则一致
+func (s *NoticeSyncService) planCarouselEdits(buildingID uint, addNotices []uint, addNewNotices []string, removeNotices []uint) ([]DeviceCarouselEdit, error) {
+	edits := []DeviceCarouselEdit{}
+	if len(addNotices) == 0 && len(addNewNotices) == 0 && len(removeNotices) == 0 {
+		return edits, nil
+	}
+
+	var devices []base_models.Device
+	if err := s.db.Select("id", "notice_carousel_list").Where("building_id = ?", buildingID).Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to get building devices: %v", err)
+	}
+
+	for _, device := range devices {
+		current := make(map[uint]bool)
+		if device.NoticeCarouselList != nil {
+			var list []uint
+			if err := json.Unmarshal(device.NoticeCarouselList, &list); err == nil {
+				for _, id := range list {
+					current[id] = true
+				}
+			}
+		}
+
+		edit := DeviceCarouselEdit{DeviceID: device.ID, AddNew: addNewNotices}
+		for _, id := range addNotices {
+			if !current[id] {
+				edit.Add = append(edit.Add, id)
+			}
+		}
+		for _, id := range removeNotices {
+			if current[id] {
+				edit.Remove = append(edit.Remove, id)
+			}
+		}
+		if len(edit.Add) > 0 || len(edit.AddNew) > 0 || len(edit.Remove) > 0 {
+			edits = append(edits, edit)
+		}
+	}
+	return edits, nil
+}
@@ -1,14 +1,12 @@
 package base_services
 
 import (
-	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"runtime"
@@ -24,17 +22,9 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// processResult represents the result of processing a single notice
-type processResult struct {
-	noticeID     int
-	md5          string
-	success      bool
-	syncRequired bool
-	error        error
-}
-
 const (
 	minWorkers       = 10 // increase min worker count
 	maxWorkers       = 30 // increase max worker count
@@ -46,15 +36,16 @@ const (
 	buildingWorkerLoadFactor = 0.75 // building worker load factor (0-1)
 
 	// Redis cache keys
-	syncedNoticeIDsPrefix = "building_synced_notice_ids" // 用于存储上次同步的旧系统通知ID和MD5
-	syncedNoticeMD5Prefix = "building_synced_notice_md5" // 用于存储上次同步的通知MD5列表
+	syncedNoticeIDsPrefix = "building_synced_notice_ids" // 旧版本同步使用的缓存，仅在清除缓存时删除
+	syncedNoticeMD5Prefix = "building_synced_notice_md5" // 旧版本同步使用的缓存，仅在清除缓存时删除
+
+	// Redis lease keys
+	noticeSyncLeaderKey          = "notice_sync:scheduler:leader" // 定时同步主实例
+	noticeSyncBuildingLockPrefix = "notice_sync:building:lock"    // 单个建筑的同步锁
 )
 
-// 定义同步通知信息结构体
-type SyncedNoticeInfo struct {
-	ID  int    `json:"id"`
-	MD5 string `json:"md5"`
-}
+// ErrNoticeSyncInProgress 建筑正在由其他同步（其他实例或手动同步）处理
+var ErrNoticeSyncInProgress = errors.New("notice sync already in progress for this building")
 
 func getNoticeSyncInterval() time.Duration {
 	interval := os.Getenv("NOTICE_SYNC_INTERVAL")
@@ -70,21 +61,6 @@ func getNoticeSyncInterval() time.Duration {
 	return time.Duration(intervalInt) * time.Minute
 }
 
-// notice list redis cache duration
-func getNoticeSyncCacheDuration() time.Duration {
-	duration := os.Getenv("NOTICE_SYNC_CACHE_DURATION")
-	if duration == "" {
-		return 4 * time.Minute // default to 4 minutes if not set
-	}
-
-	durationInt, err := strconv.Atoi(duration)
-	if err != nil {
-		return 4 * time.Minute // default to 4 minutes if invalid value
-	}
-
-	return time.Duration(durationInt) * time.Minute
-}
-
 // building redis
 func getNoticeSyncBuildingCacheDuration() time.Duration {
 	duration := os.Getenv("NOTICE_SYNC_BUILDING_CACHE_DURATION")
@@ -100,20 +76,6 @@ func getNoticeSyncBuildingCacheDuration() time.Duration {
 	return time.Duration(durationInt) * time.Minute
 }
 
-func getNoticeSyncCountCacheDuration() time.Duration {
-	duration := os.Getenv("NOTICE_SYNC_COUNT_CACHE_DURATION")
-	if duration == "" {
-		return 60 * time.Minute // default to 60 minutes if not set
-	}
-
-	durationInt, err := strconv.Atoi(duration)
-	if err != nil {
-		return 60 * time.Minute // default to 60 minutes if invalid value
-	}
-
-	return time.Duration(durationInt) * time.Minute
-}
-
 // calculateWorkerCount dynamically calculate worker count based on notice count
 func calculateWorkerCount(noticeCount int) int {
 	// Calculate suggested worker count based on notice count and available CPU cores
@@ -156,7 +118,11 @@ func calculateBuildingWorkers(buildingCount int, cpuCores int) int {
 type InterfaceNoticeSyncService interface {
 	SyncBuildingNotices(buildingID uint, claims jwt.MapClaims) (gin.H, error)
 	StartSyncScheduler(ctx context.Context)
-	ManualSyncBuildingNotices(buildingID uint, claims jwt.MapClaims) (gin.H, error)
+	ManualSyncBuildingNotices(buildingID uint, claims jwt.MapClaims, dryRun bool) (gin.H, error)
+	DryRunScheduledSync() ([]gin.H, error)
+	DiscoverBuildings() (gin.H, error)
+	RefreshUpstreamMissing() (gin.H, error)
+	ImportBuildings(source field.NoticeSource, ismartIDs []string) (gin.H, error)
 }
 
 type NoticeSyncService struct {
@@ -165,6 +131,8 @@ type NoticeSyncService struct {
 	buildingService InterfaceBuildingService
 	uploadService   IUploadService
 	fileService     InterfaceFileService
+	sources         map[field.NoticeSource]NoticeSource
+	syncRunService  InterfaceSyncRunService
 }
 
 func NewNoticeSyncService(db *gorm.DB, redis *redis.Client, buildingService InterfaceBuildingService, uploadService IUploadService, fileService InterfaceFileService) InterfaceNoticeSyncService {
@@ -174,41 +142,41 @@ func NewNoticeSyncService(db *gorm.DB, redis *redis.Client, buildingService Inte
 		buildingService: buildingService,
 		uploadService:   uploadService,
 		fileService:     fileService,
+		sources:         newNoticeSources(),
+		syncRunService:  NewSyncRunService(db),
 	}
 }
 
-type OldSystemNotice struct {
-	ID        int    `json:"id"`
-	MessTitle string `json:"mess_title"`
-	MessType  string `json:"mess_type"`
-	MessFile  string `json:"mess_file"`
-}
-
-// 1. getCachedNoticeIDs
-func (s *NoticeSyncService) getCachedNoticeIDs(ctx context.Context, buildingID uint) ([]int, error) {
-	key := fmt.Sprintf("building_notice_ids:%d", buildingID)
-	val, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil
-	} else if err != nil {
+// listSourceNotices 从建筑配置的所有来源获取通知，任一来源失败时返回错误，避免误解绑该来源的通知
+func (s *NoticeSyncService) listSourceNotices(ctx context.Context, building *base_models.Building) ([]SourceNotice, error) {
+	names, err := buildingNoticeSources(building)
+	if err != nil {
 		return nil, err
 	}
 
-	var ids []int
-	if err := json.Unmarshal([]byte(val), &ids); err != nil {
-		return nil, err
+	var notices []SourceNotice
+	for _, name := range names {
+		source, ok := s.sources[name]
+		if !ok {
+			return nil, fmt.Errorf("notice source %s is not available", name)
+		}
+		sourceNotices, err := source.ListNotices(ctx, building)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notices from %s: %w", name, err)
+		}
+		log.Info("获取来源通知 | 建筑ID: %d | 来源: %s | 数量: %d", building.ID, name, len(sourceNotices))
+		notices = append(notices, sourceNotices...)
 	}
-	return ids, nil
+	return notices, nil
 }
 
-// 2. setCachedNoticeIDs
-func (s *NoticeSyncService) setCachedNoticeIDs(ctx context.Context, buildingID uint, ids []int) error {
-	key := fmt.Sprintf("building_notice_ids:%d", buildingID)
-	jsonIDs, err := json.Marshal(ids)
-	if err != nil {
-		return err
+// fetchSourceFile 从通知所属来源读取文件
+func (s *NoticeSyncService) fetchSourceFile(ctx context.Context, notice SourceNotice, validators FileValidators) (*FetchedFile, error) {
+	source, ok := s.sources[notice.Source]
+	if !ok {
+		return nil, fmt.Errorf("notice source %s is not available", notice.Source)
 	}
-	return s.redis.Set(ctx, key, string(jsonIDs), getNoticeSyncCacheDuration()).Err()
+	return source.FetchFile(ctx, notice, validators)
 }
 
 // 3. getCachedBuilding
@@ -242,71 +210,56 @@ func (s *NoticeSyncService) getCachedBuilding(ctx context.Context, buildingID ui
 	return &building, nil
 }
 
-// 4. getCachedNoticeCount
-func (s *NoticeSyncService) getCachedNoticeCount(ctx context.Context, buildingID uint) (int64, error) {
-	key := fmt.Sprintf("building_ismart_notice_count:%d", buildingID)
-	val, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		// Cache miss, get from DB and cache it
-		var totalIsmartNotices int64
-		if err := s.db.Model(&base_models.Notice{}).
-			Joins("JOIN notice_buildings ON notices.id = notice_buildings.notice_id").
-			Where("notice_buildings.building_id = ? AND notices.is_ismart_notice = ?", buildingID, true).
-			Count(&totalIsmartNotices).Error; err != nil {
-			return 0, fmt.Errorf("failed to get total ismart notices: %v", err)
-		}
-
-		// Cache the count for 1 hour
-		if err := s.redis.Set(ctx, key, totalIsmartNotices, time.Hour).Err(); err != nil {
-			return 0, err
-		}
-		return totalIsmartNotices, nil
-	} else if err != nil {
-		return 0, err
+// lockBuilding 获取建筑的同步锁，已被占用时返回 ErrNoticeSyncInProgress
+func (s *NoticeSyncService) lockBuilding(ctx context.Context, buildingID uint) (*redisLease, error) {
+	key := fmt.Sprintf("%s:%d", noticeSyncBuildingLockPrefix, buildingID)
+	lease, err := acquireLease(ctx, s.redis, key, getSchedulerLeaseTTL())
+	if err != nil {
+		return nil, err
 	}
+	if lease == nil {
+		log.Info("建筑正在同步，跳过 | 建筑ID: %d | 持有者: %s", buildingID, leaseHolder(ctx, s.redis, key))
+		return nil, ErrNoticeSyncInProgress
+	}
+	return lease, nil
+}
 
-	count, err := strconv.ParseInt(val, 10, 64)
+// SyncBuildingNotices 同步建筑通知，同一建筑同时只有一个同步在执行
+func (s *NoticeSyncService) SyncBuildingNotices(buildingID uint, claims jwt.MapClaims) (gin.H, error) {
+	lease, err := s.lockBuilding(context.Background(), buildingID)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return count, nil
-}
+	defer lease.Release()
 
-// 5. updateCachedNoticeCount
-func (s *NoticeSyncService) updateCachedNoticeCount(ctx context.Context, buildingID uint, count int64) error {
-	key := fmt.Sprintf("building_ismart_notice_count:%d", buildingID)
-	return s.redis.Set(ctx, key, count, getNoticeSyncCountCacheDuration()).Err()
+	return s.syncBuildingNotices(buildingID, claims, noticeSyncOptions{})
 }
 
-// 修改获取上次同步的旧系统通知信息函数
-func (s *NoticeSyncService) getSyncedNoticeInfos(ctx context.Context, buildingID uint) ([]SyncedNoticeInfo, error) {
-	key := fmt.Sprintf("%s:%d", syncedNoticeIDsPrefix, buildingID)
-	val, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return []SyncedNoticeInfo{}, nil // 返回空数组而非nil，表示没有缓存数据
-	} else if err != nil {
-		return nil, err
-	}
+// noticeSyncOptions 单次建筑同步的选项
+type noticeSyncOptions struct {
+	full bool // 忽略保存的校验信息，重新下载全部文件并比对内容（手动同步）
+}
 
-	var infos []SyncedNoticeInfo
-	if err := json.Unmarshal([]byte(val), &infos); err != nil {
-		return nil, err
-	}
-	return infos, nil
+// noticeSyncItem 一条来源通知在本次同步中的处理计划与结果
+type noticeSyncItem struct {
+	notice     SourceNotice
+	mapping    *base_models.NoticeSourceMapping // 已有的对应关系
+	local      *base_models.Notice              // 对应的本地通知，不存在或已被删除时为 nil
+	fetch      bool                             // 是否需要请求文件
+	validators FileValidators                   // 条件请求使用的校验信息
+	fetched    *FetchedFile
+	fetchErr   error
+	replaced   uint // 共用的原通知ID，来源文件、标题或类型变化时当前建筑改绑到新通知
 }
 
-// 修改保存本次同步的旧系统通知信息函数
-func (s *NoticeSyncService) setSyncedNoticeInfos(ctx context.Context, buildingID uint, infos []SyncedNoticeInfo) error {
-	key := fmt.Sprintf("%s:%d", syncedNoticeIDsPrefix, buildingID)
-	jsonInfos, err := json.Marshal(infos)
-	if err != nil {
-		return err
-	}
-	return s.redis.Set(ctx, key, string(jsonInfos), getNoticeSyncCacheDuration()).Err()
+// sourceNoticeKey 来源通知的唯一标识
+func sourceNoticeKey(source field.NoticeSource, id int) string {
+	return fmt.Sprintf("%s:%d", source, id)
 }
 
-// 修改SyncBuildingNotices函数
-func (s *NoticeSyncService) SyncBuildingNotices(buildingID uint, claims jwt.MapClaims) (gin.H, error) {
+// syncBuildingNotices 按来源通知ID增量同步建筑通知，调用方需持有建筑的同步锁
+// 只下载新增或文件地址变化的通知，其余通知在保存了 ETag/Last-Modified 时做条件请求确认是否变化
+func (s *NoticeSyncService) syncBuildingNotices(buildingID uint, claims jwt.MapClaims, opts noticeSyncOptions) (gin.H, error) {
 	ctx := context.Background()
 
 	// 获取建筑信息
@@ -314,454 +267,425 @@ func (s *NoticeSyncService) SyncBuildingNotices(buildingID uint, claims jwt.MapC
 	if err != nil {
 		return nil, fmt.Errorf("failed to get building: %v", err)
 	}
-
-	// 获取缓存的通知ID
-	cachedIDs, err := s.getCachedNoticeIDs(ctx, buildingID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cached notice IDs: %v", err)
+	upstreamMissing := buildingUpstreamMissing(building)
+	if len(upstreamMissing) > 0 {
+		log.Warn("建筑的 IsmartID 在来源建筑列表中不存在，请检查 IsmartID | 建筑ID: %d | IsmartID: %s | 来源: %v",
+			buildingID, building.IsmartID, upstreamMissing)
 	}
-	log.Info("Redis缓存通知ID | 建筑ID: %d | 数量: %d", buildingID, len(cachedIDs))
 
-	// 获取上次同步的通知MD5列表
-	syncedMD5s, err := s.getSyncedNoticeMD5s(ctx, buildingID)
+	existingNotices, sourceNotices, items, err := s.loadBuildingSync(ctx, building, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get synced notice MD5s: %v", err)
+		return nil, err
 	}
-	log.Info("Redis缓存MD5数量 | 建筑ID: %d | 数量: %d", buildingID, len(syncedMD5s))
-
-	// 获取上次同步的通知详细信息（包含ID和MD5）
-	syncedNoticeInfos, err := s.getSyncedNoticeInfos(ctx, buildingID)
-	if err != nil {
-		log.Warn("获取上次同步通知详细信息失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-		// 继续执行，不中断同步流程
-	} else {
-		log.Info("获取上次同步通知详细信息 | 建筑ID: %d | 数量: %d", buildingID, len(syncedNoticeInfos))
+	boundNotices := make(map[uint]bool, len(existingNotices))
+	for _, notice := range existingNotices {
+		boundNotices[notice.ID] = true
 	}
 
-	// 获取现有的iSmart通知
-	var existingNotices []base_models.Notice
-	if err := s.db.Joins("JOIN notice_buildings ON notices.id = notice_buildings.notice_id").
-		Where("notice_buildings.building_id = ? AND notices.is_ismart_notice = ?", buildingID, true).
-		Preload("File").Find(&existingNotices).Error; err != nil {
-		return nil, fmt.Errorf("failed to get existing notices: %v", err)
-	}
-	log.Info("数据库中找到现有iSmart通知 | 建筑ID: %d | 数量: %d", buildingID, len(existingNotices))
+	var successCount, hasSyncedCount, deleteCount int
+	var downloadCount, notModifiedCount, skippedCount int
+	var failedNotices []string
+	needSyncNotices := []int{}
+	hasSyncNotices := []int{}
+	changeNotices := []int{}
+	updatedNotices := []int{}
+	conflicts := []NoticeSyncConflict{}
+	keep := make(map[uint]bool)
+	replaced := make(map[uint]bool) // 因文件变化改绑到新通知的原通知
+
+	for _, item := range items {
+		switch {
+		case !item.fetch:
+			skippedCount++
+		case item.fetchErr == nil && item.fetched.NotModified:
+			notModifiedCount++
+		case item.fetchErr == nil:
+			downloadCount++
+		}
 
-	// 创建现有通知的MD5映射
-	existingMD5Map := make(map[string]base_models.Notice)
-	for _, notice := range existingNotices {
-		if notice.File != nil {
-			existingMD5Map[notice.File.Md5] = notice
+		if item.fetchErr != nil {
+			failedNotices = append(failedNotices, fmt.Sprintf("Failed to process notice %s: %v",
+				sourceNoticeKey(item.notice.Source, item.notice.ID), item.fetchErr))
+			// 已同步的通知请求失败时保留，避免误解绑
+			if item.local != nil && boundNotices[item.local.ID] {
+				keep[item.local.ID] = true
+			}
+			continue
 		}
+
+		noticeID, updated, noticeConflicts, err := s.applySourceNotice(item, building, claims, opts)
+		if err != nil {
+			failedNotices = append(failedNotices, fmt.Sprintf("Failed to process notice %s: %v",
+				sourceNoticeKey(item.notice.Source, item.notice.ID), err))
+			if item.local != nil && boundNotices[item.local.ID] {
+				keep[item.local.ID] = true
+			}
+			continue
+		}
+		conflicts = append(conflicts, noticeConflicts...)
+		if item.replaced != 0 {
+			replaced[item.replaced] = true
+		}
+		if keep[noticeID] {
+			continue // 多条来源通知对应同一本地通知（内容相同）
+		}
+		keep[noticeID] = true
+
+		if updated {
+			updatedNotices = append(updatedNotices, int(noticeID))
+		}
+
+		if boundNotices[noticeID] {
+			hasSyncNotices = append(hasSyncNotices, int(noticeID))
+			if updated {
+				successCount++
+			} else {
+				hasSyncedCount++
+			}
+			continue
+		}
+
+		// 绑定到当前建筑
+		if err := s.db.Exec("INSERT INTO notice_buildings (notice_id, building_id) VALUES (?, ?)",
+			noticeID, buildingID).Error; err != nil {
+			failedNotices = append(failedNotices, fmt.Sprintf("Failed to bind notice %s: %v",
+				sourceNoticeKey(item.notice.Source, item.notice.ID), err))
+			continue
+		}
+		needSyncNotices = append(needSyncNotices, int(noticeID))
+		successCount++
 	}
 
-	// 请求旧系统的通知
-	url := "https://uqf0jqfm77.execute-api.ap-east-1.amazonaws.com/prod/v1/building_board/building-notices"
-	reqBody := struct {
-		BlgID string `json:"blg_id"`
-	}{
-		BlgID: building.IsmartID,
+	// 解绑来源中已不存在的通知，有本地修改的通知保留绑定并记为冲突；已改绑到新通知的原通知直接解绑
+	for i, notice := range existingNotices {
+		if keep[notice.ID] {
+			continue
+		}
+		if !replaced[notice.ID] && len(noticeOverrides(&existingNotices[i])) > 0 {
+			log.Warn("来源已删除通知，通知有本地修改，保留 | 通知ID: %d | 建筑ID: %d", notice.ID, buildingID)
+			conflicts = append(conflicts, removedConflict(&existingNotices[i]))
+			keep[notice.ID] = true
+			continue
+		}
+		if err := s.unbindNotice(buildingID, notice.ID, &failedNotices); err != nil {
+			log.Error("解绑通知失败 | 通知ID: %d | 错误: %v | 建筑ID: %d", notice.ID, err, buildingID)
+			continue
+		}
+		changeNotices = append(changeNotices, int(notice.ID))
+		deleteCount++
 	}
 
-	reqBodyJSON, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %v", err)
+	log.Info("同步完成 | 建筑ID: %d | 添加: %d | 更新: %d | 删除: %d | 下载: %d | 未变化: %d | 免请求: %d | 冲突: %d",
+		buildingID, len(needSyncNotices), len(updatedNotices), deleteCount, downloadCount, notModifiedCount, skippedCount, len(conflicts))
+
+	// 保险机制：最终一致性验证
+	if err := s.validateFinalConsistency(ctx, buildingID, len(keep)); err != nil {
+		log.Warn("警告: 最终一致性验证失败 | 建筑ID: %d | 错误: %v", buildingID, err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBodyJSON))
-	if err != nil {
-		return nil, fmt.Errorf("failed to request old system: %v", err)
+	// 同步设备轮播列表
+	if err := s.syncAllDeviceNoticeCarousels(ctx, buildingID, needSyncNotices, changeNotices); err != nil {
+		log.Warn("警告: 设备轮播同步失败 | 建筑ID: %d | 错误: %v", buildingID, err)
+	} else {
+		log.Info("设备轮播同步完成 | 建筑ID: %d | 新增: %d | 删除: %d",
+			buildingID, len(needSyncNotices), len(changeNotices))
 	}
-	defer resp.Body.Close()
 
-	var oldNotices []OldSystemNotice
-	if err := json.NewDecoder(resp.Body).Decode(&oldNotices); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	// 有变更时通知该建筑下的设备刷新公告
+	if len(needSyncNotices) > 0 || len(changeNotices) > 0 || len(updatedNotices) > 0 {
+		PublishDeviceEvent(field.DeviceEventNoticesChanged, []uint{buildingID}, nil, map[string]interface{}{
+			"added":   needSyncNotices,
+			"removed": changeNotices,
+			"updated": updatedNotices,
+		})
 	}
 
-	// 提取旧系统通知ID
-	newIDs := make([]int, len(oldNotices))
-	for i, notice := range oldNotices {
-		newIDs[i] = notice.ID
+	message := "Sync completed"
+	if len(needSyncNotices) == 0 && len(changeNotices) == 0 && len(updatedNotices) == 0 && len(failedNotices) == 0 {
+		message = "No changes detected"
 	}
-	log.Info("旧系统通知ID | 建筑ID: %d | 数量: %d", buildingID, len(newIDs))
 
-	// 更新同步的通知详细信息
-	var newSyncedInfos []SyncedNoticeInfo
-	for _, notice := range oldNotices {
-		// 这里假设我们会在后续处理中计算MD5
-		// 实际实现中，应该在处理通知时计算MD5并更新此列表
-		newSyncedInfos = append(newSyncedInfos, SyncedNoticeInfo{
-			ID: notice.ID,
-			// 暂时使用空MD5，实际实现中应该设置正确的值
-			MD5: "",
-		})
+	return gin.H{
+		"message":           message,
+		"successCount":      successCount,
+		"hasSyncedCount":    hasSyncedCount,
+		"deleteCount":       deleteCount,
+		"failedNotices":     failedNotices,
+		"totalProcessed":    len(sourceNotices),
+		"downloadCount":     downloadCount,
+		"notModifiedCount":  notModifiedCount,
+		"skippedCount":      skippedCount,
+		"has_sync_notices":  hasSyncNotices,
+		"need_sync_notices": needSyncNotices,
+		"change_notices":    changeNotices,
+		"updated_notices":   updatedNotices,
+		"conflicts":         conflicts,
+		"conflictCount":     len(conflicts),
+		"upstreamMissing":   upstreamMissing,
+	}, nil
+}
+
+// loadBuildingSync 获取建筑现有的iSmart通知与来源通知，并请求需要下载或校验的文件，不写入任何数据
+func (s *NoticeSyncService) loadBuildingSync(ctx context.Context, building *base_models.Building, opts noticeSyncOptions) ([]base_models.Notice, []SourceNotice, []*noticeSyncItem, error) {
+	// 获取现有的iSmart通知
+	var existingNotices []base_models.Notice
+	if err := s.db.Joins("JOIN notice_buildings ON notices.id = notice_buildings.notice_id").
+		Where("notice_buildings.building_id = ? AND notices.is_ismart_notice = ?", building.ID, true).
+		Find(&existingNotices).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get existing notices: %v", err)
 	}
+	log.Info("数据库中找到现有iSmart通知 | 建筑ID: %d | 数量: %d", building.ID, len(existingNotices))
 
-	// 保存本次同步的通知详细信息
-	if err := s.setSyncedNoticeInfos(ctx, buildingID, newSyncedInfos); err != nil {
-		log.Warn("保存同步通知详细信息失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-		// 继续执行，不中断同步流程
-	} else {
-		log.Info("保存同步通知详细信息 | 建筑ID: %d | 数量: %d", buildingID, len(newSyncedInfos))
+	// 请求建筑配置的通知来源
+	sourceNotices, err := s.listSourceNotices(ctx, building)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// 检查是否需要强制同步
-	totalIsmartNotices, err := s.getCachedNoticeCount(ctx, buildingID)
+	// 按来源通知ID对比已保存的对应关系，确定需要请求的文件
+	items, err := s.planSourceNotices(sourceNotices, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cached notice count: %v", err)
+		return nil, nil, nil, err
 	}
+	s.fetchSourceFiles(ctx, building.ID, items)
 
-	log.Info("数据库有%d个iSmart通知，旧系统有%d个通知", totalIsmartNotices, len(oldNotices))
+	return existingNotices, sourceNotices, items, nil
+}
 
-	// 保险机制：确保数据一致性检查
-	// 验证所有现有通知确实是 iSmart 通知
-	var nonIsmartCount int64
-	if err := s.db.Model(&base_models.Notice{}).
-		Joins("JOIN notice_buildings ON notices.id = notice_buildings.notice_id").
-		Where("notice_buildings.building_id = ? AND notices.is_ismart_notice = ?", buildingID, false).
-		Count(&nonIsmartCount).Error; err != nil {
-		log.Warn("检查非iSmart通知失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-	} else if nonIsmartCount > 0 {
-		log.Warn("发现%d个非iSmart通知绑定到建筑物 | 建筑ID: %d", nonIsmartCount, buildingID)
+// planSourceNotices 加载来源通知已保存的对应关系，确定每条通知是否需要请求文件
+func (s *NoticeSyncService) planSourceNotices(sourceNotices []SourceNotice, opts noticeSyncOptions) ([]*noticeSyncItem, error) {
+	idsBySource := make(map[field.NoticeSource][]int)
+	for _, notice := range sourceNotices {
+		idsBySource[notice.Source] = append(idsBySource[notice.Source], notice.ID)
 	}
 
-	// 如果通知数量不匹配，强制同步
-	if totalIsmartNotices != int64(len(oldNotices)) {
-		log.Warn("iSmart通知数量不匹配，强制同步 | 建筑ID: %d | 数据库: %d | 旧系统: %d",
-			buildingID, totalIsmartNotices, len(oldNotices))
-		// 清除缓存以强制同步
-		if err := s.redis.Del(ctx, fmt.Sprintf("building_notice_ids:%d", buildingID)).Err(); err != nil {
-			return nil, fmt.Errorf("failed to clear notice IDs cache: %v", err)
+	mappings := make(map[string]*base_models.NoticeSourceMapping)
+	var noticeIDs []uint
+	for source, ids := range idsBySource {
+		var records []base_models.NoticeSourceMapping
+		if err := s.db.Where("source = ? AND external_id IN ?", source, ids).Find(&records).Error; err != nil {
+			return nil, fmt.Errorf("failed to get notice source mappings: %v", err)
 		}
-		if err := s.redis.Del(ctx, fmt.Sprintf("building_ismart_notice_count:%d", buildingID)).Err(); err != nil {
-			return nil, fmt.Errorf("failed to clear notice count cache: %v", err)
+		for i := range records {
+			mappings[sourceNoticeKey(records[i].Source, records[i].ExternalID)] = &records[i]
+			noticeIDs = append(noticeIDs, records[i].NoticeID)
 		}
-		cachedIDs = nil
 	}
 
-	// 计算最佳工作线程数
-	workers := calculateWorkerCount(len(oldNotices))
-	resultChan := make(chan processResult, len(oldNotices))
-	semaphore := make(chan struct{}, workers)
-
-	log.Info("开始并发处理通知 | 工作线程: %d | 通知数: %d | 建筑ID: %d",
-		workers, len(oldNotices), buildingID)
-
-	// 并发处理通知，获取所有MD5值
-	for _, oldNotice := range oldNotices {
-		go func(notice OldSystemNotice) {
-			semaphore <- struct{}{}        // 获取信号量
-			defer func() { <-semaphore }() // 释放信号量
-
-			result := processResult{noticeID: notice.ID}
-
-			// 下载并处理文件
-			fileResp, err := http.Get(notice.MessFile)
-			if err != nil {
-				result.error = fmt.Errorf("failed to download file: %v", err)
-				resultChan <- result
-				return
-			}
-			defer fileResp.Body.Close()
-
-			fileContent, err := io.ReadAll(fileResp.Body)
-			if err != nil {
-				result.error = fmt.Errorf("failed to read file content: %v", err)
-				resultChan <- result
-				return
-			}
-
-			md5Hash := md5.Sum(fileContent)
-			md5Str := hex.EncodeToString(md5Hash[:])
-			result.md5 = md5Str
-
-			// 先不进行处理，只收集MD5
-			result.success = true
-			result.syncRequired = false
-			resultChan <- result
-		}(oldNotice)
-	}
-
-	// 收集所有新通知的MD5值
-	var newMD5s []string
-	var failedNotices []string
-	md5ToNoticeID := make(map[string]int)
-
-	for i := 0; i < len(oldNotices); i++ {
-		result := <-resultChan
-		if result.error != nil {
-			failedNotices = append(failedNotices, fmt.Sprintf("Failed to process notice ID %d: %v", result.noticeID, result.error))
-			continue
+	locals := make(map[uint]*base_models.Notice)
+	if len(noticeIDs) > 0 {
+		var notices []base_models.Notice
+		if err := s.db.Where("id IN ?", noticeIDs).Find(&notices).Error; err != nil {
+			return nil, fmt.Errorf("failed to get mapped notices: %v", err)
+		}
+		for i := range notices {
+			locals[notices[i].ID] = &notices[i]
 		}
-
-		newMD5s = append(newMD5s, result.md5)
-		md5ToNoticeID[result.md5] = result.noticeID
 	}
 
-	log.Info("从旧系统收集MD5值 | 数量: %d | 建筑ID: %d", len(newMD5s), buildingID)
-
-	// 如果缓存的MD5列表存在且与新MD5列表完全匹配，跳过处理
-	if len(syncedMD5s) > 0 && len(syncedMD5s) == len(newMD5s) {
-		// 创建MD5集合用于快速查找
-		syncedMD5Set := make(map[string]bool)
-		for _, md5 := range syncedMD5s {
-			syncedMD5Set[md5] = true
+	seen := make(map[string]bool)
+	items := make([]*noticeSyncItem, 0, len(sourceNotices))
+	for _, notice := range sourceNotices {
+		key := sourceNoticeKey(notice.Source, notice.ID)
+		if seen[key] {
+			continue
 		}
+		seen[key] = true
 
-		allMatch := true
-		for _, md5 := range newMD5s {
-			if !syncedMD5Set[md5] {
-				allMatch = false
-				break
-			}
+		item := &noticeSyncItem{notice: notice, mapping: mappings[key]}
+		if item.mapping != nil {
+			item.local = locals[item.mapping.NoticeID]
 		}
 
-		if allMatch {
-			log.Info("MD5列表完全匹配，跳过同步 | 建筑ID: %d", buildingID)
-			var hasSyncNotices []int
-			for _, notice := range existingNotices {
-				if notice.File != nil {
-					hasSyncNotices = append(hasSyncNotices, int(notice.ID))
-				}
-			}
-
-			// 更新缓存的通知ID
-			if err := s.setCachedNoticeIDs(ctx, buildingID, newIDs); err != nil {
-				return nil, fmt.Errorf("failed to update cached notice IDs: %v", err)
-			}
-
-			return gin.H{
-				"message":           "No changes detected",
-				"successCount":      0,
-				"hasSyncedCount":    len(existingNotices),
-				"deleteCount":       0,
-				"failedNotices":     []string{},
-				"totalProcessed":    len(oldNotices),
-				"has_sync_notices":  hasSyncNotices,
-				"need_sync_notices": []int{},
-				"change_notices":    []int{},
-			}, nil
+		switch {
+		case item.local == nil || item.mapping.FileURL != notice.FileURL || opts.full:
+			// 新通知、本地通知已删除或文件地址变化，下载文件
+			item.fetch = true
+		case item.mapping.ETag != "" || item.mapping.LastModified != "":
+			// 文件地址未变，条件请求确认内容是否变化
+			item.fetch = true
+			item.validators = FileValidators{ETag: item.mapping.ETag, LastModified: item.mapping.LastModified}
 		}
+		items = append(items, item)
 	}
+	return items, nil
+}
 
-	// 比较新旧MD5列表，找出需要添加和删除的通知
-
-	// 1. 找出需要删除的通知（在旧MD5列表中存在但在新MD5列表中不存在）
-	var md5sToDelete []string
-	if len(syncedMD5s) > 0 {
-		newMD5Set := make(map[string]bool)
-		for _, md5 := range newMD5s {
-			newMD5Set[md5] = true
-		}
-
-		for _, md5 := range syncedMD5s {
-			if !newMD5Set[md5] {
-				md5sToDelete = append(md5sToDelete, md5)
-			}
+// fetchSourceFiles 并发请求需要下载或校验的文件
+func (s *NoticeSyncService) fetchSourceFiles(ctx context.Context, buildingID uint, items []*noticeSyncItem) {
+	var pending []*noticeSyncItem
+	for _, item := range items {
+		if item.fetch {
+			pending = append(pending, item)
 		}
 	}
-
-	// 2. 找出需要添加的通知（在新MD5列表中存在但在旧MD5列表中不存在或在现有通知中不存在）
-	var md5sToAdd []string
-	syncedMD5Set := make(map[string]bool)
-	for _, md5 := range syncedMD5s {
-		syncedMD5Set[md5] = true
+	if len(pending) == 0 {
+		return
 	}
 
-	for _, md5 := range newMD5s {
-		if !syncedMD5Set[md5] || existingMD5Map[md5].ID == 0 {
-			md5sToAdd = append(md5sToAdd, md5)
-		}
-	}
+	workers := calculateWorkerCount(len(pending))
+	log.Info("开始并发请求通知文件 | 工作线程: %d | 文件数: %d | 建筑ID: %d", workers, len(pending), buildingID)
 
-	log.Info("发现需要删除的MD5: %d个, 需要添加的MD5: %d个 | 建筑ID: %d",
-		len(md5sToDelete), len(md5sToAdd), buildingID)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workers)
+	for _, item := range pending {
+		wg.Add(1)
+		go func(item *noticeSyncItem) {
+			defer wg.Done()
+			semaphore <- struct{}{}        // 获取信号量
+			defer func() { <-semaphore }() // 释放信号量
 
-	// 处理需要删除的通知
-	deleteCount := 0
-	if len(md5sToDelete) > 0 {
-		for _, md5 := range md5sToDelete {
-			if notice, exists := existingMD5Map[md5]; exists {
-				// 解绑通知
-				if err := s.unbindNotice(buildingID, notice.ID, &failedNotices); err != nil {
-					log.Error("解绑通知失败 | MD5: %s | 错误: %v | 建筑ID: %d", md5, err, buildingID)
-				} else {
-					deleteCount++
-				}
-			}
-		}
+			item.fetched, item.fetchErr = s.fetchSourceFile(ctx, item.notice, item.validators)
+		}(item)
 	}
+	wg.Wait()
+}
 
-	// 处理需要添加的通知
-	var successCount, hasSyncedCount int
-	var needSyncNotices, hasSyncNotices, changeNotices []int
-
-	if len(md5sToAdd) > 0 {
-		// 重新创建信号量和结果通道
-		addWorkers := calculateWorkerCount(len(md5sToAdd))
-		addResultChan := make(chan processResult, len(md5sToAdd))
-		addSemaphore := make(chan struct{}, addWorkers)
-
-		for _, md5 := range md5sToAdd {
-			noticeID := md5ToNoticeID[md5]
+// applySourceNotice 根据请求结果创建或更新本地通知与对应关系，返回本地通知ID、内容或标题是否有更新，以及因本地修改未合并的冲突
+func (s *NoticeSyncService) applySourceNotice(item *noticeSyncItem, building *base_models.Building, claims jwt.MapClaims, opts noticeSyncOptions) (uint, bool, []NoticeSyncConflict, error) {
+	now := time.Now()
+	mapping := item.mapping
+	if mapping == nil {
+		mapping = &base_models.NoticeSourceMapping{Source: item.notice.Source, ExternalID: item.notice.ID}
+	}
+	if item.fetched != nil {
+		mapping.ETag = item.fetched.ETag
+		mapping.LastModified = item.fetched.LastModified
+	}
+
+	var notice *base_models.Notice
+	var conflicts []NoticeSyncConflict
+	updated := false
+
+	switch {
+	case item.local != nil && (item.fetched == nil || item.fetched.NotModified):
+		// 文件未变化
+		notice = item.local
+		mapping.CheckedAt = now
+
+	case item.local != nil:
+		content := item.fetched.Content
+		md5Hash := md5.Sum(content)
+		md5Str := hex.EncodeToString(md5Hash[:])
+		notice = item.local
+		mapping.FileURL = item.notice.FileURL
+		if md5Str == mapping.Md5 {
+			mapping.CheckedAt = now
+			break
+		}
+		if noticeOverrides(notice)[field.NoticeSyncFieldFile] {
+			// 本地替换过文件，只记录来源的新内容
+			log.Warn("来源通知文件已变化，本地已替换文件，不覆盖 | 来源: %s | 来源ID: %d | 通知ID: %d", item.notice.Source, item.notice.ID, notice.ID)
+			conflicts = append(conflicts, fileConflict(item, md5Str))
+			mapping.Md5 = md5Str
+			mapping.CheckedAt = now
+			break
+		}
 
-			// 找到对应的旧系统通知
-			var oldNotice OldSystemNotice
-			for _, notice := range oldNotices {
-				if notice.ID == noticeID {
-					oldNotice = notice
-					break
-				}
+		// 文件内容变化。通知被其他建筑或其他来源通知共用时不修改原通知，
+		// 改用新文件对应的通知，只重新绑定当前建筑与该来源通知
+		file, err := s.storeNoticeFile(content, claims)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		shared, err := s.noticeShared(notice.ID, building.ID, mapping.ID)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		if shared {
+			copied, err := s.copySourceNotice(notice, file)
+			if err != nil {
+				return 0, false, nil, err
 			}
-
-			if oldNotice.ID == 0 {
-				failedNotices = append(failedNotices, fmt.Sprintf("Could not find old notice for MD5 %s", md5))
-				continue
+			log.Info("来源通知文件已变化，原通知被共用，改用新通知 | 来源: %s | 来源ID: %d | 原通知ID: %d | 通知ID: %d",
+				item.notice.Source, item.notice.ID, notice.ID, copied.ID)
+			item.replaced = notice.ID
+			item.local = copied
+			notice = copied
+			mapping.NoticeID = notice.ID
+		} else {
+			if err := s.replaceNoticeFile(notice, file, mapping.ID); err != nil {
+				return 0, false, nil, err
 			}
-
-			go func(notice OldSystemNotice, md5 string) {
-				addSemaphore <- struct{}{}
-				defer func() { <-addSemaphore }()
-
-				result := processResult{noticeID: notice.ID, md5: md5}
-
-				// 检查是否已存在相同MD5的通知
-				if existingNotice, exists := existingMD5Map[md5]; exists {
-					// 通知已存在，检查是否已绑定到当前建筑物
-					var count int64
-					if err := s.db.Table("notice_buildings").
-						Where("notice_id = ? AND building_id = ?", existingNotice.ID, buildingID).
-						Count(&count).Error; err != nil {
-						result.error = fmt.Errorf("failed to check notice binding: %v", err)
-					} else if count > 0 {
-						// 通知已绑定，无需操作
-						result.success = true
-						result.syncRequired = false
-						hasSyncNotices = append(hasSyncNotices, int(existingNotice.ID))
-						hasSyncedCount++
-					} else {
-						// 通知存在但未绑定，需要绑定
-						if err := s.db.Exec("INSERT INTO notice_buildings (notice_id, building_id) VALUES (?, ?)",
-							existingNotice.ID, buildingID).Error; err != nil {
-							result.error = fmt.Errorf("failed to bind existing notice: %v", err)
-						} else {
-							result.success = true
-							result.syncRequired = true
-							needSyncNotices = append(needSyncNotices, int(existingNotice.ID))
-							successCount++
-						}
-					}
-				} else {
-					// 通知不存在，需要下载并处理
-					fileResp, err := http.Get(notice.MessFile)
-					if err != nil {
-						result.error = fmt.Errorf("failed to download file: %v", err)
-						addResultChan <- result
-						return
-					}
-					defer fileResp.Body.Close()
-
-					fileContent, err := io.ReadAll(fileResp.Body)
-					if err != nil {
-						result.error = fmt.Errorf("failed to read file content: %v", err)
-						addResultChan <- result
-						return
-					}
-
-					// 处理通知
-					if err := s.processNotice(buildingID, notice, fileContent, claims); err != nil {
-						result.error = err
-					} else {
-						result.success = true
-						result.syncRequired = true
-						needSyncNotices = append(needSyncNotices, notice.ID)
-						successCount++
-					}
-				}
-
-				addResultChan <- result
-			}(oldNotice, md5)
+			log.Info("来源通知文件已变化 | 来源: %s | 来源ID: %d | 通知ID: %d", item.notice.Source, item.notice.ID, notice.ID)
 		}
+		mapping.FileID = &file.ID
+		mapping.Md5 = md5Str
+		mapping.SyncedAt = now
+		mapping.CheckedAt = now
+		updated = true
 
-		// 收集处理结果
-		for i := 0; i < len(md5sToAdd); i++ {
-			result := <-addResultChan
-			if result.error != nil {
-				failedNotices = append(failedNotices, fmt.Sprintf("Failed to process notice ID %d: %v", result.noticeID, result.error))
+	default:
+		// 新通知或本地通知已被删除
+		content := item.fetched.Content
+		file, err := s.storeNoticeFile(content, claims)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		notice, err = s.findOrCreateSourceNotice(item.notice, file, building)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		mapping.NoticeID = notice.ID
+		mapping.FileID = &file.ID
+		mapping.FileURL = item.notice.FileURL
+		mapping.Md5 = file.Md5
+		mapping.SyncedAt = now
+		mapping.CheckedAt = now
+	}
+
+	// 来源标题或类型变化时更新本地通知，本地修改过的字段不覆盖；通知被共用时复制一份给当前建筑再更新
+	metadata, metadataConflicts := s.mergeSourceMetadata(item, mapping, building, opts)
+	conflicts = append(conflicts, metadataConflicts...)
+	if len(metadata) > 0 {
+		shared, err := s.noticeShared(notice.ID, building.ID, mapping.ID)
+		if err != nil {
+			return 0, false, nil, err
+		}
+		if shared {
+			copied, err := s.cloneNotice(notice, notice.FileID)
+			if err != nil {
+				return 0, false, nil, err
+			}
+			log.Info("来源通知标题或类型已变化，原通知被共用，改用副本 | 来源: %s | 来源ID: %d | 原通知ID: %d | 通知ID: %d",
+				item.notice.Source, item.notice.ID, notice.ID, copied.ID)
+			if item.replaced == 0 {
+				item.replaced = notice.ID
 			}
+			notice = copied
+			mapping.NoticeID = notice.ID
 		}
+		if err := s.db.Model(&base_models.Notice{}).Where("id = ?", notice.ID).Updates(metadata).Error; err != nil {
+			return 0, false, nil, fmt.Errorf("failed to update notice: %v", err)
+		}
+		updated = true
 	}
 
-	log.Info("同步完成 | 建筑ID: %d | 添加: %d | 删除: %d", buildingID, successCount, deleteCount)
-
-	// 更新缓存
-	if err := s.setCachedNoticeIDs(ctx, buildingID, newIDs); err != nil {
-		return nil, fmt.Errorf("failed to update cached notice IDs: %v", err)
-	}
-
-	// 更新同步的通知MD5列表
-	if err := s.setSyncedNoticeMD5s(ctx, buildingID, newMD5s); err != nil {
-		return nil, fmt.Errorf("failed to update synced notice MD5s: %v", err)
-	}
-
-	// 更新缓存的通知数量
-	newCount := int64(len(oldNotices))
-	if err := s.updateCachedNoticeCount(ctx, buildingID, newCount); err != nil {
-		log.Warn("警告: 更新缓存通知数量失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-	}
-
-	// 保险机制：最终一致性验证
-	if err := s.validateFinalConsistency(ctx, buildingID, len(oldNotices)); err != nil {
-		log.Warn("警告: 最终一致性验证失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-	}
-
-	// 同步设备轮播列表
-	if err := s.syncAllDeviceNoticeCarousels(ctx, buildingID, needSyncNotices, changeNotices); err != nil {
-		log.Warn("警告: 设备轮播同步失败 | 建筑ID: %d | 错误: %v", buildingID, err)
-	} else {
-		log.Info("设备轮播同步完成 | 建筑ID: %d | 新增: %d | 删除: %d",
-			buildingID, len(needSyncNotices), len(changeNotices))
+	if err := s.saveSourceMapping(mapping); err != nil {
+		return 0, false, nil, err
 	}
-
-	return gin.H{
-		"message":           "Sync completed",
-		"successCount":      successCount,
-		"hasSyncedCount":    hasSyncedCount,
-		"deleteCount":       deleteCount,
-		"failedNotices":     failedNotices,
-		"totalProcessed":    len(oldNotices),
-		"has_sync_notices":  hasSyncNotices,
-		"need_sync_notices": needSyncNotices,
-		"change_notices":    changeNotices,
-	}, nil
+	return notice.ID, updated, conflicts, nil
 }
 
-// 添加新函数：获取上次同步的通知MD5列表
-func (s *NoticeSyncService) getSyncedNoticeMD5s(ctx context.Context, buildingID uint) ([]string, error) {
-	key := fmt.Sprintf("%s:%d", syncedNoticeMD5Prefix, buildingID)
-	val, err := s.redis.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return []string{}, nil // 返回空数组而非nil，表示没有缓存数据
-	} else if err != nil {
-		return nil, err
-	}
-
-	var md5s []string
-	if err := json.Unmarshal([]byte(val), &md5s); err != nil {
-		return nil, err
+// saveSourceMapping 保存对应关系，其他建筑同时创建同一来源通知时以后保存的为准
+func (s *NoticeSyncService) saveSourceMapping(mapping *base_models.NoticeSourceMapping) error {
+	if mapping.ID != 0 {
+		if err := s.db.Save(mapping).Error; err != nil {
+			return fmt.Errorf("failed to save notice source mapping: %v", err)
+		}
+		return nil
 	}
-	return md5s, nil
-}
 
-// 添加新函数：保存本次同步的通知MD5列表
-func (s *NoticeSyncService) setSyncedNoticeMD5s(ctx context.Context, buildingID uint, md5s []string) error {
-	key := fmt.Sprintf("%s:%d", syncedNoticeMD5Prefix, buildingID)
-	jsonMD5s, err := json.Marshal(md5s)
-	if err != nil {
-		return err
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "source"}, {Name: "external_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"notice_id", "file_id", "file_url", "md5", "e_tag", "last_modified",
+			"title", "type", "synced_at", "checked_at", "updated_at",
+		}),
+	}).Create(mapping).Error; err != nil {
+		return fmt.Errorf("failed to create notice source mapping: %v", err)
 	}
-	return s.redis.Set(ctx, key, string(jsonMD5s), getNoticeSyncCacheDuration()).Err()
+	return nil
 }
 
 // validateFinalConsistency 验证最终数据一致性
@@ -860,29 +784,20 @@ func (s *NoticeSyncService) unbindNotice(buildingID uint, noticeID uint, failedN
 
 		log.Info("删除通知ID | 通知ID: %d", noticeID)
 
-		// 检查文件是否被其他通知使用
+		// 删除来源对应关系，来源重新出现该通知时按新通知下载
+		if err := tx.Where("notice_id = ?", noticeID).Delete(&base_models.NoticeSourceMapping{}).Error; err != nil {
+			tx.Rollback()
+			*failedNotices = append(*failedNotices, fmt.Sprintf("Failed to delete source mappings for notice ID %d: %v", noticeID, err))
+			return err
+		}
+
+		// 文件不再被引用时删除
 		if fileID != nil {
-			var fileCount int64
-			if err := tx.Model(&base_models.Notice{}).Where("file_id = ?", *fileID).Count(&fileCount).Error; err != nil {
+			if err := deleteUnreferencedFile(tx, *fileID, 0); err != nil {
 				tx.Rollback()
-				*failedNotices = append(*failedNotices, fmt.Sprintf("Failed to check file references for notice ID %d: %v", noticeID, err))
+				*failedNotices = append(*failedNotices, fmt.Sprintf("Failed to delete file for notice ID %d: %v", noticeID, err))
 				return err
 			}
-
-			if fileCount == 0 {
-				log.Info("文件ID %d 没有其他通知引用，将被删除", *fileID)
-
-				// 删除文件
-				if err := tx.Delete(&base_models.File{}, *fileID).Error; err != nil {
-					tx.Rollback()
-					*failedNotices = append(*failedNotices, fmt.Sprintf("Failed to delete file for notice ID %d: %v", noticeID, err))
-					return err
-				}
-
-				log.Info("删除文件ID | 文件ID: %d", *fileID)
-			} else {
-				log.Info("文件ID %d 有 %d 个其他通知引用，保留文件", *fileID, fileCount)
-			}
 		}
 	} else {
 		log.Info("通知ID %d 有 %d 个其他建筑绑定，保留通知", noticeID, buildingCount)
@@ -1127,19 +1042,62 @@ func (s *NoticeSyncService) clearBuildingCaches(ctx context.Context, buildingID
 }
 
 // 修改ManualSyncBuildingNotices函数
-func (s *NoticeSyncService) ManualSyncBuildingNotices(buildingID uint, claims jwt.MapClaims) (gin.H, error) {
+// dryRun 为 true 时只返回计划的变更，不加锁、不清除缓存、不保存同步记录
+func (s *NoticeSyncService) ManualSyncBuildingNotices(buildingID uint, claims jwt.MapClaims, dryRun bool) (gin.H, error) {
 	ctx := context.Background()
+	if dryRun {
+		log.Info("开始试运行建筑物通知同步 | 建筑ID: %d", buildingID)
+		var building base_models.Building
+		if err := s.db.First(&building, buildingID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get building: %v", err)
+		}
+		return s.dryRunBuildingNotices(ctx, &building, noticeSyncOptions{full: true})
+	}
+
 	log.Info("开始手动同步建筑物通知 | 建筑ID: %d", buildingID)
 
+	// 与定时同步或其他实例的手动同步互斥
+	lease, err := s.lockBuilding(ctx, buildingID)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Release()
+
 	// 清除该建筑物的所有缓存
 	if err := s.clearBuildingCaches(ctx, buildingID); err != nil {
 		log.Warn("警告: 清除建筑物缓存失败 | 建筑ID: %d | 错误: %v", buildingID, err)
 	}
 
+	// 记录本次手动同步
+	var triggeredBy string
+	if email, ok := claims["email"].(string); ok {
+		triggeredBy = email
+	}
+	run, runErr := s.syncRunService.StartRun(field.SyncRunTriggerManual, triggeredBy, 1)
+	if runErr != nil {
+		log.Warn("创建同步记录失败 | 建筑ID: %d | 错误: %v", buildingID, runErr)
+	}
+
 	// 执行同步
-	result, err := s.SyncBuildingNotices(buildingID, claims)
+	startedAt := time.Now()
+	result, err := s.syncBuildingNotices(buildingID, claims, noticeSyncOptions{full: true})
+	if run != nil {
+		building := base_models.Building{ModelFields: base_models.ModelFields{ID: buildingID}}
+		if cached, cacheErr := s.getCachedBuilding(ctx, buildingID); cacheErr == nil {
+			building = *cached
+		}
+		if recordErr := s.syncRunService.RecordBuilding(run.ID, building, result, err, startedAt); recordErr != nil {
+			log.Warn("保存建筑同步结果失败 | 建筑ID: %d | 错误: %v", buildingID, recordErr)
+		}
+		if _, finishErr := s.syncRunService.FinishRun(run.ID); finishErr != nil {
+			log.Warn("结束同步记录失败 | 同步ID: %d | 错误: %v", run.ID, finishErr)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("手动同步失败: %v", err)
+		return nil, fmt.Errorf("手动同步失败: %w", err)
+	}
+	if run != nil {
+		result["syncRunId"] = run.ID
 	}
 
 	// 手动同步完成后，强制更新所有设备的轮播列表
@@ -1157,15 +1115,24 @@ func (s *NoticeSyncService) ManualSyncBuildingNotices(buildingID uint, claims jw
 }
 
 // 10. StartSyncScheduler
+// 每个实例都会启动调度器，通过 Redis 租约选出一个主实例执行定时同步，主实例退出后其他实例在租约过期后接替
+// 调度器每分钟检查一次，按建筑的同步设置同步到期的建筑
 func (s *NoticeSyncService) StartSyncScheduler(ctx context.Context) {
-	syncInterval := getNoticeSyncInterval()
-	ticker := time.NewTicker(syncInterval)
-	countdownTicker := time.NewTicker(1 * time.Minute)
-	log.Info("调度器已启动")
-
-	// Clear all caches before starting
-	if err := s.clearAllCaches(ctx); err != nil {
-		log.Warn("警告: 启动时清除缓存失败 | 错误: %v", err)
+	ticker := time.NewTicker(noticeSyncSchedulerTick)
+	dryRun := getNoticeSyncDryRun()
+	dryRunTimes := make(map[uint]time.Time) // 试运行不保存同步记录，上次试运行时间只保存在内存
+	checkInterval := getUpstreamCheckInterval()
+	var lastUpstreamCheck time.Time
+	retention := getSyncRunRetention()
+	var lastPurge time.Time
+	log.Info("调度器已启动 | 实例: %s | 试运行: %v", instanceID, dryRun)
+
+	leader := newSchedulerLeader(s.redis, noticeSyncLeaderKey, "通知同步")
+	leader.onElected = func(ctx context.Context) {
+		// 成为主实例时清除缓存，避免使用其他实例留下的建筑缓存
+		if err := s.clearAllCaches(ctx); err != nil {
+			log.Warn("警告: 成为主实例时清除缓存失败 | 错误: %v", err)
+		}
 	}
 
 	go func() {
@@ -1176,25 +1143,50 @@ func (s *NoticeSyncService) StartSyncScheduler(ctx context.Context) {
 			"email":   "admin@example.com",
 		}
 
+		// 试运行模式不竞选主实例，各实例只记录计划的变更
+		runScheduled := func(name string) {
+			if dryRun {
+				log.Debug("正在试运行%s...", name)
+				s.runDryRunSync(dryRunTimes)
+				return
+			}
+			if leader.IsLeader(ctx) {
+				log.Debug("正在运行%s...", name)
+				s.runSync(adminClaims)
+
+				// 主实例定时比对来源建筑，更新建筑的来源缺失标记
+				if checkInterval > 0 && time.Since(lastUpstreamCheck) >= checkInterval {
+					lastUpstreamCheck = time.Now()
+					if _, err := s.RefreshUpstreamMissing(); err != nil {
+						log.Error("比对来源建筑失败 | 错误: %v", err)
+					}
+				}
+
+				// 主实例定时清理过期的同步记录，避免按建筑取上次同步时间的查询随历史增长变慢
+				if retention > 0 && time.Since(lastPurge) >= syncRunPurgeInterval {
+					lastPurge = time.Now()
+					purged, err := s.syncRunService.Purge(lastPurge.Add(-retention))
+					if err != nil {
+						log.Error("清理同步记录失败 | 错误: %v", err)
+					} else if purged > 0 {
+						log.Info("清理过期同步记录 | 数量: %d | 保留天数: %d", purged, int(retention.Hours()/24))
+					}
+				}
+			}
+		}
+
 		// 立即执行一次同步
-		log.Info("正在运行初始同步...")
-		s.runSync(adminClaims)
-		nextSync := time.Now().Add(syncInterval)
+		runScheduled("初始同步")
 
 		for {
 			select {
 			case <-ctx.Done():
 				ticker.Stop()
-				countdownTicker.Stop()
+				leader.Release()
 				log.Info("调度器已停止")
 				return
-			case <-countdownTicker.C:
-				remaining := time.Until(nextSync).Round(time.Minute)
-				log.Info("下次同步在 %d 分钟后", int(remaining.Minutes()))
 			case <-ticker.C:
-				log.Info("正在运行计划同步...")
-				s.runSync(adminClaims)
-				nextSync = time.Now().Add(syncInterval)
+				runScheduled("计划同步")
 			}
 		}
 	}()
@@ -1202,15 +1194,24 @@ func (s *NoticeSyncService) StartSyncScheduler(ctx context.Context) {
 
 // 11. runSync
 func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
-	// Get all buildings
-	var buildings []base_models.Building
-	if err := s.db.Find(&buildings).Error; err != nil {
+	// 获取到期的建筑
+	lastSynced, err := s.lastSyncTimes()
+	if err != nil {
+		log.Error("获取上次同步时间失败 | 错误: %v", err)
+		return
+	}
+	buildings, err := s.dueBuildings(time.Now(), lastSynced)
+	if err != nil {
 		log.Error("获取建筑物失败 | 错误: %v", err)
 		return
 	}
 
 	buildingCount := len(buildings)
-	log.Info("找到 %d 个建筑物进行同步", buildingCount)
+	if buildingCount == 0 {
+		log.Debug("没有到期需要同步的建筑物")
+		return
+	}
+	log.Info("找到 %d 个到期的建筑物进行同步", buildingCount)
 
 	// Get number of CPU cores
 	cpuCores := runtime.NumCPU()
@@ -1220,6 +1221,12 @@ func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
 
 	log.Info("系统有 %d 个CPU核心，使用 %d 个并发建筑工人", cpuCores, maxBuildingWorkers)
 
+	// 记录本次定时同步
+	run, err := s.syncRunService.StartRun(field.SyncRunTriggerScheduled, "", buildingCount)
+	if err != nil {
+		log.Warn("创建同步记录失败 | 错误: %v", err)
+	}
+
 	// Create a wait group to wait for all goroutines to finish
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, maxBuildingWorkers)
@@ -1228,6 +1235,8 @@ func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
 	resultChan := make(chan struct {
 		buildingID uint
 		name       string
+		building   base_models.Building
+		startedAt  time.Time
 		result     gin.H
 		err        error
 	}, buildingCount)
@@ -1243,17 +1252,22 @@ func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
 			defer func() { <-semaphore }()
 
 			log.Info("开始同步建筑物 %d (%s)", b.ID, b.Name)
+			startedAt := time.Now()
 			result, err := s.SyncBuildingNotices(b.ID, adminClaims)
 
 			// Send result to channel
 			resultChan <- struct {
 				buildingID uint
 				name       string
+				building   base_models.Building
+				startedAt  time.Time
 				result     gin.H
 				err        error
 			}{
 				buildingID: b.ID,
 				name:       b.Name,
+				building:   b,
+				startedAt:  startedAt,
 				result:     result,
 				err:        err,
 			}
@@ -1268,6 +1282,16 @@ func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
 
 	// Process results as they come in
 	for res := range resultChan {
+		if run != nil {
+			if err := s.syncRunService.RecordBuilding(run.ID, res.building, res.result, res.err, res.startedAt); err != nil {
+				log.Warn("保存建筑同步结果失败 | 建筑ID: %d | 错误: %v", res.buildingID, err)
+			}
+		}
+
+		if errors.Is(res.err, ErrNoticeSyncInProgress) {
+			log.Info("建筑正在由其他同步处理，本次跳过 | 建筑ID: %d | 名称: %s", res.buildingID, res.name)
+			continue
+		}
 		if res.err != nil {
 			log.Error("建筑通知同步失败 | 建筑ID: %d | 名称: %s | 错误: %v",
 				res.buildingID, res.name, res.err)
@@ -1313,14 +1337,35 @@ func (s *NoticeSyncService) runSync(adminClaims jwt.MapClaims) {
 			}
 		}
 	}
+
+	if run != nil {
+		if finished, err := s.syncRunService.FinishRun(run.ID); err != nil {
+			log.Warn("结束同步记录失败 | 同步ID: %d | 错误: %v", run.ID, err)
+		} else {
+			log.Info("同步记录已保存 | 同步ID: %d | 状态: %s | 成功: %d | 失败: %d | 耗时: %dms",
+				finished.ID, finished.Status, finished.SuccessCount, finished.FailedCount, finished.DurationMs)
+		}
+	}
+
+	// 熔断中的上游主机
+	for _, breaker := range getNoticeSourceClient().OpenBreakers() {
+		log.Warn("上游熔断中 | 主机: %s | 状态: %s | 连续失败: %d | 错误: %s",
+			breaker.Host, breaker.State, breaker.ConsecutiveFailures, breaker.LastError)
+	}
 }
 
-// processNotice handles the processing of a single notice
-func (s *NoticeSyncService) processNotice(buildingID uint, oldNotice OldSystemNotice, fileContent []byte, claims jwt.MapClaims) error {
-	fileSize := len(fileContent)
+// storeNoticeFile 保存通知文件，相同内容（MD5）的文件只上传一次
+func (s *NoticeSyncService) storeNoticeFile(fileContent []byte, claims jwt.MapClaims) (*base_models.File, error) {
 	md5Hash := md5.Sum(fileContent)
 	md5Str := hex.EncodeToString(md5Hash[:])
-	mimeType := http.DetectContentType(fileContent)
+
+	var existingFile base_models.File
+	err := s.db.Where("md5 = ?", md5Str).First(&existingFile).Error
+	if err == nil {
+		return &existingFile, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to get file: %v", err)
+	}
 
 	// Get uploader info from claims
 	var uploaderID uint
@@ -1343,188 +1388,185 @@ func (s *NoticeSyncService) processNotice(buildingID uint, oldNotice OldSystemNo
 		uploaderEmail = "admin@example.com"
 	}
 
-	// Check if file exists
-	var existingFile base_models.File
-	var shouldUpload bool = true
-	var fileForNotice *base_models.File
-
-	err := s.db.Where("md5 = ?", md5Str).First(&existingFile).Error
-	if err == nil {
-		// File exists, check if it's associated with any notice
-		var existingNotice base_models.Notice
-		err := s.db.Where("file_id = ?", existingFile.ID).First(&existingNotice).Error
-
-		if err == nil {
-			// Check if notice is bound to current building
-			var count int64
-			err = s.db.Table("notice_buildings").
-				Where("notice_id = ? AND building_id = ?", existingNotice.ID, buildingID).
-				Count(&count).Error
-
-			if err == nil && count > 0 {
-				return nil
-			}
-
-			// Check if notice is bound to other buildings
-			err = s.db.Table("notice_buildings").
-				Where("notice_id = ?", existingNotice.ID).
-				Count(&count).Error
-
-			if err == nil {
-				if count > 0 {
-					shouldUpload = false
-					fileForNotice = &existingFile
-				} else {
-					if err := s.db.Delete(&existingNotice).Error; err != nil {
-						return fmt.Errorf("failed to delete old notice: %v", err)
-					}
-					shouldUpload = false
-					fileForNotice = &existingFile
-				}
-			}
-		} else {
-			shouldUpload = false
-			fileForNotice = &existingFile
-		}
-	} else if err == gorm.ErrRecordNotFound {
-		shouldUpload = true
-
-		// Generate unique file name using UUID
-		uuid := uuid.New().String()
-		fileName := uuid + ".pdf"
-		// Use consistent directory structure with regular uploads
-		dir := "iboard/pdf/"
-		objectKey := dir + fileName
-
-		// Get OSS configuration from environment variables
-		host := os.Getenv("HOST")
-		if host == "" {
-			host = "http://idreamsky.oss-cn-beijing.aliyuncs.com"
-		}
-
-		// Create file record with OSS path
-		fileForNotice = &base_models.File{
-			Path:         host + "/" + objectKey, // Store the complete OSS URL
-			Size:         int64(fileSize),
-			MimeType:     mimeType,
-			Oss:          "aliyun",
-			UploaderType: uploaderType,
-			UploaderID:   uploaderID,
-			Uploader:     uploaderEmail,
-			Md5:          md5Str,
-		}
-
-		if shouldUpload {
-			// Get upload parameters from service
-			uploadParams, err := s.uploadService.GetUploadParamsSync(objectKey)
-			if err != nil {
-				return fmt.Errorf("failed to get upload params: %v", err)
-			}
-
-			// Upload file to OSS
-			var b bytes.Buffer
-			w := multipart.NewWriter(&b)
+	// Generate unique file name using UUID
+	fileName := uuid.New().String() + ".pdf"
+	// Use consistent directory structure with regular uploads
+	objectKey := "iboard/pdf/" + fileName
 
-			formFields := []struct {
-				key      string
-				paramKey string
-			}{
-				{key: "key", paramKey: "dir"},
-				{key: "policy", paramKey: "policy"},
-				{key: "OSSAccessKeyId", paramKey: "accessid"},
-				{key: "success_action_status", paramKey: ""},
-				{key: "callback", paramKey: "callback"},
-				{key: "signature", paramKey: "signature"},
-			}
-
-			for _, field := range formFields {
-				var fieldValue string
-				switch field.key {
-				case "key":
-					fieldValue = objectKey
-				case "success_action_status":
-					fieldValue = "200"
-				default:
-					if field.paramKey != "" {
-						if val, ok := uploadParams[field.paramKey]; ok {
-							fieldValue = fmt.Sprintf("%v", val)
-						} else {
-							continue
-						}
-					}
-				}
-				if err := w.WriteField(field.key, fieldValue); err != nil {
-					return fmt.Errorf("failed to write form field %s: %v", field.key, err)
-				}
-			}
-
-			fw, err := w.CreateFormFile("file", objectKey)
-			if err != nil {
-				return fmt.Errorf("failed to create form file: %v", err)
-			}
-			if _, err = io.Copy(fw, bytes.NewReader(fileContent)); err != nil {
-				return fmt.Errorf("failed to copy file content: %v", err)
-			}
-			w.Close()
-
-			uploadReq, err := http.NewRequest("POST", uploadParams["host"].(string), &b)
-			if err != nil {
-				return fmt.Errorf("failed to create upload request: %v", err)
-			}
-			uploadReq.Header.Set("Content-Type", w.FormDataContentType())
+	// Get OSS configuration from environment variables
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = "http://idreamsky.oss-cn-beijing.aliyuncs.com"
+	}
 
-			client := &http.Client{}
-			uploadResp, err := client.Do(uploadReq)
-			if err != nil {
-				return fmt.Errorf("failed to upload file: %v", err)
-			}
-			defer uploadResp.Body.Close()
+	if _, err := s.uploadService.UploadContentSync(objectKey, fileContent); err != nil {
+		return nil, err
+	}
 
-			if uploadResp.StatusCode != http.StatusOK {
-				respBody, _ := io.ReadAll(uploadResp.Body)
-				return fmt.Errorf("upload failed with status %d: %s", uploadResp.StatusCode, string(respBody))
-			}
-		}
+	// Create file record with OSS path
+	file := &base_models.File{
+		Path:         host + "/" + objectKey, // Store the complete OSS URL
+		Size:         int64(len(fileContent)),
+		MimeType:     http.DetectContentType(fileContent),
+		Oss:          "aliyun",
+		UploaderType: uploaderType,
+		UploaderID:   uploaderID,
+		Uploader:     uploaderEmail,
+		Md5:          md5Str,
+	}
+	if err := s.fileService.Create(file); err != nil {
+		return nil, fmt.Errorf("failed to create file record: %v", err)
+	}
+	return file, nil
+}
 
-		// Create file record
-		if err := s.fileService.Create(fileForNotice); err != nil {
-			return fmt.Errorf("failed to create file record: %v", err)
-		}
+// findOrCreateSourceNotice 复用使用同一文件的iSmart通知，没有时创建
+func (s *NoticeSyncService) findOrCreateSourceNotice(sourceNotice SourceNotice, file *base_models.File, building *base_models.Building) (*base_models.Notice, error) {
+	existing, err := s.findIsmartNoticeByFile(file.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
 	}
 
 	// Create notice - 确保只创建 iSmart 通知
 	notice := &base_models.Notice{
-		Title:          oldNotice.MessTitle,
-		Description:    oldNotice.MessTitle,
-		Type:           s.mapNoticeType(oldNotice.MessType),
+		Title:          sourceNotice.Title,
+		Description:    sourceNotice.Title,
+		Type:           s.buildingNoticeType(building, sourceNotice.Type),
 		Status:         field.Status("active"),
 		StartTime:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)),
-		EndTime:        time.Date(2100, 2, 1, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		EndTime:        buildingNoticeEndTime(building),
 		IsPublic:       true,
 		IsIsmartNotice: true, // 明确标识为 iSmart 通知
-		FileID:         &fileForNotice.ID,
+		FileID:         &file.ID,
 		FileType:       field.FileTypePdf,
 		ReferenceID:    nil, // Will be set if needed in API calls
 	}
 
-	log.Debug("创建iSmart通知 | 旧系统ID: %d | 标题: %s | 建筑ID: %d",
-		oldNotice.ID, oldNotice.MessTitle, buildingID)
+	log.Debug("创建iSmart通知 | 来源: %s | 来源ID: %d | 标题: %s",
+		sourceNotice.Source, sourceNotice.ID, sourceNotice.Title)
 
-	// Create notice and bind to building in a transaction
+	if err := s.db.Create(notice).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notice: %v", err)
+	}
+	return notice, nil
+}
+
+// findIsmartNoticeByFile 获取使用该文件的iSmart通知，没有时返回 nil
+func (s *NoticeSyncService) findIsmartNoticeByFile(fileID uint) (*base_models.Notice, error) {
+	var notice base_models.Notice
+	err := s.db.Where("file_id = ? AND is_ismart_notice = ?", fileID, true).First(&notice).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get notice: %v", err)
+	}
+	return &notice, nil
+}
+
+// noticeShared 通知是否绑定了其他建筑，或被其他来源通知对应
+func (s *NoticeSyncService) noticeShared(noticeID uint, buildingID uint, mappingID uint) (bool, error) {
+	var buildingCount int64
+	if err := s.db.Table("notice_buildings").Where("notice_id = ? AND building_id <> ?", noticeID, buildingID).
+		Count(&buildingCount).Error; err != nil {
+		return false, fmt.Errorf("failed to check notice bindings: %v", err)
+	}
+	if buildingCount > 0 {
+		return true, nil
+	}
+	var mappingCount int64
+	if err := s.db.Model(&base_models.NoticeSourceMapping{}).Where("notice_id = ? AND id <> ?", noticeID, mappingID).
+		Count(&mappingCount).Error; err != nil {
+		return false, fmt.Errorf("failed to check notice source mappings: %v", err)
+	}
+	return mappingCount > 0, nil
+}
+
+// copySourceNotice 共用的通知文件变化时，复用使用新文件的iSmart通知，没有时复制原通知并使用新文件，保留本地修改
+func (s *NoticeSyncService) copySourceNotice(notice *base_models.Notice, file *base_models.File) (*base_models.Notice, error) {
+	existing, err := s.findIsmartNoticeByFile(file.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+	return s.cloneNotice(notice, &file.ID)
+}
+
+// cloneNotice 复制iSmart通知并使用指定文件，保留本地修改，不复制建筑绑定
+func (s *NoticeSyncService) cloneNotice(notice *base_models.Notice, fileID *uint) (*base_models.Notice, error) {
+	copied := &base_models.Notice{
+		Title:          notice.Title,
+		Description:    notice.Description,
+		Type:           notice.Type,
+		IsPublic:       notice.IsPublic,
+		IsIsmartNotice: true,
+		LocalOverrides: notice.LocalOverrides,
+		Priority:       notice.Priority,
+		Status:         notice.Status,
+		StartTime:      notice.StartTime,
+		EndTime:        notice.EndTime,
+		FileID:         fileID,
+		FileType:       notice.FileType,
+	}
+	if err := s.db.Create(copied).Error; err != nil {
+		return nil, fmt.Errorf("failed to create notice: %v", err)
+	}
+	return copied, nil
+}
+
+// replaceNoticeFile 替换通知文件，旧文件不再被引用时删除文件记录；mappingID 为当前来源通知的对应关系，不计入引用
+func (s *NoticeSyncService) replaceNoticeFile(notice *base_models.Notice, file *base_models.File, mappingID uint) error {
+	oldFileID := notice.FileID
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(notice).Error; err != nil {
-			return fmt.Errorf("failed to create notice: %v", err)
+		if err := tx.Model(&base_models.Notice{}).Where("id = ?", notice.ID).
+			Update("file_id", file.ID).Error; err != nil {
+			return fmt.Errorf("failed to update notice file: %v", err)
 		}
+		notice.FileID = &file.ID
 
-		if err := tx.Exec("INSERT INTO notice_buildings (notice_id, building_id) VALUES (?, ?)",
-			notice.ID, buildingID).Error; err != nil {
-			return fmt.Errorf("failed to bind notice to building: %v", err)
+		if oldFileID == nil || *oldFileID == file.ID {
+			return nil
 		}
-
-		return nil
+		return deleteUnreferencedFile(tx, *oldFileID, mappingID)
 	})
 }
 
+// deleteUnreferencedFile 文件没有被通知、广告、打印任务或其他来源通知引用时删除文件记录
+// 通知文件按 MD5 复用，可能与其他模块上传的文件相同
+func deleteUnreferencedFile(tx *gorm.DB, fileID uint, mappingID uint) error {
+	references := []struct {
+		model interface{}
+		query string
+		args  []interface{}
+	}{
+		{&base_models.Notice{}, "file_id = ?", []interface{}{fileID}},
+		{&base_models.Advertisement{}, "file_id = ?", []interface{}{fileID}},
+		{&base_models.PrintJob{}, "file_id = ?", []interface{}{fileID}},
+		{&base_models.PrintDispatch{}, "file_id = ?", []interface{}{fileID}},
+		{&base_models.NoticeSourceMapping{}, "file_id = ? AND id <> ?", []interface{}{fileID, mappingID}},
+	}
+	for _, ref := range references {
+		var count int64
+		if err := tx.Model(ref.model).Where(ref.query, ref.args...).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check file references: %v", err)
+		}
+		if count > 0 {
+			log.Info("文件ID %d 仍被引用，保留文件", fileID)
+			return nil
+		}
+	}
+
+	if err := tx.Delete(&base_models.File{}, fileID).Error; err != nil {
+		return fmt.Errorf("failed to delete old file: %v", err)
+	}
+	log.Info("删除文件ID | 文件ID: %d", fileID)
+	return nil
+}
+
 // 9. mapNoticeType
 func (s *NoticeSyncService) mapNoticeType(oldType string) field.NoticeType {
 	switch oldType {
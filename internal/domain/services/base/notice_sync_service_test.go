@@ -0,0 +1,515 @@
+package base_services
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+)
+
+// stubNoticeSource 按建筑返回固定通知列表、按文件地址返回固定内容的通知来源
+type stubNoticeSource struct {
+	notices map[string][]SourceNotice // 建筑 IsmartID -> 通知
+	files   map[string]string         // 文件地址 -> 内容
+}
+
+func (s *stubNoticeSource) Name() field.NoticeSource { return field.NoticeSourceIsmart }
+
+func (s *stubNoticeSource) ListBuildings(_ context.Context) ([]SourceBuilding, error) {
+	return nil, nil
+}
+
+func (s *stubNoticeSource) ListNotices(_ context.Context, building *base_models.Building) ([]SourceNotice, error) {
+	return s.notices[building.IsmartID], nil
+}
+
+func (s *stubNoticeSource) FetchFile(_ context.Context, notice SourceNotice, _ FileValidators) (*FetchedFile, error) {
+	content, ok := s.files[notice.FileURL]
+	if !ok {
+		return nil, fmt.Errorf("file %s not found", notice.FileURL)
+	}
+	return &FetchedFile{Content: []byte(content)}, nil
+}
+
+func contentMD5(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// noticeSyncFixture 两个建筑、内存数据库与 Redis 的同步测试环境
+type noticeSyncFixture struct {
+	t         *testing.T
+	db        *gorm.DB
+	service   *NoticeSyncService
+	source    *stubNoticeSource
+	redis     *miniredis.Miniredis
+	buildings []base_models.Building
+}
+
+func newNoticeSyncFixture(t *testing.T) *noticeSyncFixture {
+	t.Helper()
+	db := newTestDB(t, &base_models.Building{}, &base_models.Notice{}, &base_models.NoticeSourceMapping{},
+		&base_models.File{}, &base_models.Device{}, &base_models.Advertisement{},
+		&base_models.PrintJob{}, &base_models.PrintDispatch{})
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	sources := datatypes.JSON(`["ismart"]`)
+	buildings := []base_models.Building{
+		{Name: "A", IsmartID: "a", NoticeSources: sources},
+		{Name: "B", IsmartID: "b", NoticeSources: sources},
+	}
+	if err := db.Create(&buildings).Error; err != nil {
+		t.Fatalf("create buildings: %v", err)
+	}
+
+	source := &stubNoticeSource{notices: map[string][]SourceNotice{}, files: map[string]string{}}
+	service := &NoticeSyncService{
+		db:             db,
+		redis:          client,
+		sources:        map[field.NoticeSource]NoticeSource{field.NoticeSourceIsmart: source},
+		syncRunService: NewSyncRunService(db),
+	}
+	return &noticeSyncFixture{t: t, db: db, service: service, source: source, redis: mr, buildings: buildings}
+}
+
+// file 创建内容对应的文件记录，同步时按 MD5 复用，不会上传
+func (f *noticeSyncFixture) file(content string) base_models.File {
+	f.t.Helper()
+	file := base_models.File{Path: "http://oss/" + content, Oss: "aliyun", Md5: contentMD5(content)}
+	if err := f.db.Create(&file).Error; err != nil {
+		f.t.Fatalf("create file: %v", err)
+	}
+	return file
+}
+
+// notice 创建使用该文件的iSmart通知并绑定到建筑
+func (f *noticeSyncFixture) notice(title string, file base_models.File, buildingIDs ...uint) base_models.Notice {
+	f.t.Helper()
+	notice := base_models.Notice{Title: title, Description: title, Type: field.NoticeTypeNormal,
+		IsIsmartNotice: true, FileID: &file.ID, Status: "active"}
+	if err := f.db.Create(&notice).Error; err != nil {
+		f.t.Fatalf("create notice: %v", err)
+	}
+	for _, buildingID := range buildingIDs {
+		f.db.Exec("INSERT INTO notice_buildings (notice_id, building_id) VALUES (?, ?)", notice.ID, buildingID)
+	}
+	return notice
+}
+
+// mapping 记录来源通知与本地通知的对应关系，视为上次已同步
+func (f *noticeSyncFixture) mapping(externalID int, url string, notice base_models.Notice, content string, title string) {
+	f.t.Helper()
+	mapping := base_models.NoticeSourceMapping{Source: field.NoticeSourceIsmart, ExternalID: externalID,
+		NoticeID: notice.ID, FileID: notice.FileID, FileURL: url, Md5: contentMD5(content), Title: title, Type: "common"}
+	if err := f.db.Create(&mapping).Error; err != nil {
+		f.t.Fatalf("create mapping: %v", err)
+	}
+}
+
+func (f *noticeSyncFixture) list(building base_models.Building, notices ...SourceNotice) {
+	f.source.notices[building.IsmartID] = notices
+}
+
+func (f *noticeSyncFixture) sync(building base_models.Building, opts noticeSyncOptions) map[string]interface{} {
+	f.t.Helper()
+	result, err := f.service.syncBuildingNotices(building.ID, jwt.MapClaims{}, opts)
+	if err != nil {
+		f.t.Fatalf("sync building %d: %v", building.ID, err)
+	}
+	return result
+}
+
+// boundNotices 建筑绑定的通知ID
+func (f *noticeSyncFixture) boundNotices(building base_models.Building) []uint {
+	f.t.Helper()
+	var ids []uint
+	f.db.Table("notice_buildings").Where("building_id = ?", building.ID).Pluck("notice_id", &ids)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (f *noticeSyncFixture) loadNotice(id uint) (base_models.Notice, bool) {
+	var notice base_models.Notice
+	err := f.db.First(&notice, id).Error
+	return notice, err == nil
+}
+
+func (f *noticeSyncFixture) mappingFor(externalID int) base_models.NoticeSourceMapping {
+	f.t.Helper()
+	var mapping base_models.NoticeSourceMapping
+	if err := f.db.Where("source = ? AND external_id = ?", field.NoticeSourceIsmart, externalID).First(&mapping).Error; err != nil {
+		f.t.Fatalf("load mapping %d: %v", externalID, err)
+	}
+	return mapping
+}
+
+func (f *noticeSyncFixture) fileExists(id uint) bool {
+	var count int64
+	f.db.Model(&base_models.File{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
+
+func sourceNotice(id int, title string, url string) SourceNotice {
+	return SourceNotice{Source: field.NoticeSourceIsmart, ID: id, Title: title, Type: "common", FileURL: url}
+}
+
+func TestSyncSharedNoticeFileChangeRebindsOnlyCurrentBuilding(t *testing.T) {
+	tests := []struct {
+		name string
+		// 原通知是否绑定到建筑 B
+		boundToB bool
+		// 建筑 B 的来源通知ID，0 表示 B 没有来源通知
+		bExternalID int
+		// 期望原通知保持不变并继续绑定到 B
+		wantCopy bool
+	}{
+		{"只被当前建筑使用时替换文件", false, 0, false},
+		{"其他建筑的其他来源通知共用时复制", true, 102, true},
+		{"其他建筑的同一来源通知共用时复制", true, 101, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newNoticeSyncFixture(t)
+			a, b := f.buildings[0], f.buildings[1]
+			oldFile := f.file("old")
+			newFile := f.file("new")
+			bindings := []uint{a.ID}
+			if tt.boundToB {
+				bindings = append(bindings, b.ID)
+			}
+			original := f.notice("通知", oldFile, bindings...)
+			f.mapping(101, "u1", original, "old", "通知")
+			if tt.bExternalID == 102 {
+				f.mapping(102, "u2", original, "old", "通知")
+			}
+
+			f.source.files["u1-v2"] = "new"
+			f.source.files["u2"] = "old"
+			f.list(a, sourceNotice(101, "通知", "u1-v2"))
+			if tt.bExternalID == 102 {
+				f.list(b, sourceNotice(102, "通知", "u2"))
+			} else if tt.bExternalID == 101 {
+				f.list(b, sourceNotice(101, "通知", "u1-v2"))
+			}
+
+			f.sync(a, noticeSyncOptions{})
+
+			bound := f.boundNotices(a)
+			if len(bound) != 1 {
+				t.Fatalf("building A bound to %v, want one notice", bound)
+			}
+			current, _ := f.loadNotice(bound[0])
+			if current.FileID == nil || *current.FileID != newFile.ID {
+				t.Errorf("building A notice file = %v, want %d", current.FileID, newFile.ID)
+			}
+			if got := f.mappingFor(101); got.NoticeID != current.ID {
+				t.Errorf("mapping 101 -> notice %d, want %d", got.NoticeID, current.ID)
+			}
+
+			stored, ok := f.loadNotice(original.ID)
+			if !tt.wantCopy {
+				if current.ID != original.ID {
+					t.Errorf("unshared notice should be updated in place, got new notice %d", current.ID)
+				}
+				if f.fileExists(oldFile.ID) {
+					t.Error("unreferenced old file should be deleted")
+				}
+				return
+			}
+
+			if current.ID == original.ID {
+				t.Fatal("shared notice must not be updated in place")
+			}
+			if !ok || stored.FileID == nil || *stored.FileID != oldFile.ID {
+				t.Errorf("shared notice file changed: %+v", stored.FileID)
+			}
+			if got := f.boundNotices(b); len(got) != 1 || got[0] != original.ID {
+				t.Errorf("building B bound to %v, want [%d]", got, original.ID)
+			}
+			if !f.fileExists(oldFile.ID) {
+				t.Error("old file still used by building B was deleted")
+			}
+			if tt.bExternalID == 102 {
+				if got := f.mappingFor(102); got.NoticeID != original.ID {
+					t.Errorf("mapping 102 -> notice %d, want %d", got.NoticeID, original.ID)
+				}
+			}
+
+			// B 同步后也使用最新内容，原通知与旧文件不再被引用时删除
+			if tt.bExternalID == 101 {
+				f.sync(b, noticeSyncOptions{})
+				if got := f.boundNotices(b); len(got) != 1 || got[0] != current.ID {
+					t.Errorf("building B bound to %v after its sync, want [%d]", got, current.ID)
+				}
+				if _, ok := f.loadNotice(original.ID); ok {
+					t.Error("unbound original notice should be deleted")
+				}
+				if f.fileExists(oldFile.ID) {
+					t.Error("unreferenced old file should be deleted")
+				}
+			}
+		})
+	}
+}
+
+func TestSyncSharedNoticeCopyKeepsLocalEdits(t *testing.T) {
+	f := newNoticeSyncFixture(t)
+	a, b := f.buildings[0], f.buildings[1]
+	oldFile := f.file("old")
+	newFile := f.file("new")
+	original := f.notice("通知", oldFile, a.ID, b.ID)
+	f.db.Model(&original).Updates(map[string]interface{}{
+		"title": "本地标题", "description": "本地标题", "priority": 80,
+		"local_overrides": noticeOverridesJSON(map[field.NoticeSyncField]bool{field.NoticeSyncFieldTitle: true}),
+	})
+	f.mapping(101, "u1", original, "old", "通知")
+	f.mapping(102, "u2", original, "old", "通知")
+	f.source.files["u1-v2"] = "new"
+	f.list(a, sourceNotice(101, "通知", "u1-v2"))
+
+	result := f.sync(a, noticeSyncOptions{})
+
+	bound := f.boundNotices(a)
+	if len(bound) != 1 || bound[0] == original.ID {
+		t.Fatalf("building A bound to %v, want a copy of notice %d", bound, original.ID)
+	}
+	copied, _ := f.loadNotice(bound[0])
+	if copied.Title != "本地标题" || copied.Priority != 80 || !noticeOverrides(&copied)[field.NoticeSyncFieldTitle] {
+		t.Errorf("copy lost local edits: title %q priority %d overrides %s", copied.Title, copied.Priority, copied.LocalOverrides)
+	}
+	if copied.FileID == nil || *copied.FileID != newFile.ID {
+		t.Errorf("copy file = %v, want %d", copied.FileID, newFile.ID)
+	}
+	// 原通知有本地修改，但已改绑到副本，不应报告为来源已删除
+	for _, c := range result["conflicts"].([]NoticeSyncConflict) {
+		if c.NoticeID == original.ID && c.Field == "" {
+			t.Errorf("replaced notice reported as removed upstream: %+v", c)
+		}
+	}
+}
+
+func TestSyncMetadataConflictReportedUntilResolved(t *testing.T) {
+	tests := []struct {
+		name string
+		opts noticeSyncOptions
+	}{
+		{"定时同步", noticeSyncOptions{}},
+		{"手动同步", noticeSyncOptions{full: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newNoticeSyncFixture(t)
+			a := f.buildings[0]
+			file := f.file("content")
+			notice := f.notice("通知", file, a.ID)
+			f.db.Model(&notice).Updates(map[string]interface{}{
+				"title": "本地标题", "description": "本地标题",
+				"local_overrides": noticeOverridesJSON(map[field.NoticeSyncField]bool{field.NoticeSyncFieldTitle: true}),
+			})
+			f.mapping(101, "u1", notice, "content", "通知")
+			f.source.files["u1"] = "content"
+			f.list(a, sourceNotice(101, "来源新标题", "u1"))
+
+			for round := 1; round <= 3; round++ {
+				result := f.sync(a, tt.opts)
+				conflicts := result["conflicts"].([]NoticeSyncConflict)
+				if len(conflicts) != 1 || conflicts[0].Field != field.NoticeSyncFieldTitle || conflicts[0].UpstreamValue != "来源新标题" {
+					t.Fatalf("round %d conflicts = %+v, want one title conflict", round, conflicts)
+				}
+				if got := f.mappingFor(101); got.Title != "通知" {
+					t.Fatalf("round %d mapping title = %q, want snapshot kept while conflicting", round, got.Title)
+				}
+			}
+			if stored, _ := f.loadNotice(notice.ID); stored.Title != "本地标题" {
+				t.Errorf("local title overwritten: %q", stored.Title)
+			}
+
+			// 来源改回与本地一致后冲突消失，对应关系更新
+			f.list(a, sourceNotice(101, "本地标题", "u1"))
+			result := f.sync(a, tt.opts)
+			if conflicts := result["conflicts"].([]NoticeSyncConflict); len(conflicts) != 0 {
+				t.Errorf("conflicts = %+v, want none once upstream matches", conflicts)
+			}
+			if got := f.mappingFor(101); got.Title != "本地标题" {
+				t.Errorf("mapping title = %q, want advanced", got.Title)
+			}
+		})
+	}
+}
+
+func TestSyncSharedNoticeMetadataChangeUsesCopy(t *testing.T) {
+	tests := []struct {
+		name   string
+		shared bool
+	}{
+		{"只被当前建筑使用时直接更新", false},
+		{"被其他建筑共用时更新副本", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newNoticeSyncFixture(t)
+			a, b := f.buildings[0], f.buildings[1]
+			file := f.file("content")
+			bindings := []uint{a.ID}
+			if tt.shared {
+				bindings = append(bindings, b.ID)
+			}
+			original := f.notice("通知", file, bindings...)
+			f.mapping(101, "u1", original, "content", "通知")
+			if tt.shared {
+				f.mapping(102, "u2", original, "content", "通知")
+			}
+			f.list(a, sourceNotice(101, "新标题", "u1"))
+
+			f.sync(a, noticeSyncOptions{})
+
+			bound := f.boundNotices(a)
+			if len(bound) != 1 {
+				t.Fatalf("building A bound to %v, want one notice", bound)
+			}
+			current, _ := f.loadNotice(bound[0])
+			if current.Title != "新标题" {
+				t.Errorf("building A notice title = %q, want 新标题", current.Title)
+			}
+			if got := f.mappingFor(101); got.NoticeID != current.ID {
+				t.Errorf("mapping 101 -> notice %d, want %d", got.NoticeID, current.ID)
+			}
+
+			stored, _ := f.loadNotice(original.ID)
+			if !tt.shared {
+				if current.ID != original.ID {
+					t.Errorf("unshared notice should be updated in place, got new notice %d", current.ID)
+				}
+				return
+			}
+			if current.ID == original.ID {
+				t.Fatal("shared notice must not be updated in place")
+			}
+			if current.FileID == nil || *current.FileID != file.ID {
+				t.Errorf("copy file = %v, want %d", current.FileID, file.ID)
+			}
+			if stored.Title != "通知" {
+				t.Errorf("shared notice title = %q, want unchanged", stored.Title)
+			}
+			if got := f.boundNotices(b); len(got) != 1 || got[0] != original.ID {
+				t.Errorf("building B bound to %v, want [%d]", got, original.ID)
+			}
+			if got := f.mappingFor(102); got.NoticeID != original.ID {
+				t.Errorf("mapping 102 -> notice %d, want %d", got.NoticeID, original.ID)
+			}
+		})
+	}
+}
+
+// countWrites 统计之后通过 db 执行的写入语句
+func countWrites(db *gorm.DB) *int {
+	writes := 0
+	count := func(tx *gorm.DB) { writes++ }
+	db.Callback().Create().Before("gorm:create").Register("test:count_create", count)
+	db.Callback().Update().Before("gorm:update").Register("test:count_update", count)
+	db.Callback().Delete().Before("gorm:delete").Register("test:count_delete", count)
+	db.Callback().Raw().Before("gorm:raw").Register("test:count_raw", count)
+	return &writes
+}
+
+func TestDryRunMakesNoWrites(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(f *noticeSyncFixture) []gin.H
+	}{
+		{"手动试运行", func(f *noticeSyncFixture) []gin.H {
+			result, err := f.service.ManualSyncBuildingNotices(f.buildings[0].ID, jwt.MapClaims{}, true)
+			if err != nil {
+				f.t.Fatalf("dry run: %v", err)
+			}
+			return []gin.H{result}
+		}},
+		{"定时同步试运行", func(f *noticeSyncFixture) []gin.H {
+			results, err := f.service.DryRunScheduledSync()
+			if err != nil {
+				f.t.Fatalf("dry run: %v", err)
+			}
+			return results
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newNoticeSyncFixture(t)
+			a, b := f.buildings[0], f.buildings[1]
+
+			// 文件变化：新内容没有文件记录，实际同步需要上传
+			changed := f.notice("文件变化", f.file("old"), a.ID)
+			f.mapping(101, "u1", changed, "old", "文件变化")
+			f.source.files["u1-v2"] = "new"
+			// 标题变化且与建筑 B 共用：实际同步会创建副本
+			shared := f.notice("共用", f.file("shared"), a.ID, b.ID)
+			f.mapping(102, "u2", shared, "shared", "共用")
+			f.mapping(202, "u2", shared, "shared", "共用")
+			f.source.files["u2"] = "shared"
+			// 来源已删除：实际同步会解绑并删除
+			removed := f.notice("已删除", f.file("removed"), a.ID)
+			f.mapping(103, "u3", removed, "removed", "已删除")
+			// 新通知：实际同步会上传并创建
+			f.source.files["u4"] = "fresh"
+			f.list(a, sourceNotice(101, "文件变化", "u1-v2"), sourceNotice(102, "共用新标题", "u2"),
+				sourceNotice(104, "新通知", "u4"))
+
+			for _, key := range []string{
+				fmt.Sprintf("building_notice_ids:%d", a.ID),
+				fmt.Sprintf("building:%d", a.ID),
+				fmt.Sprintf("%s:%d", syncedNoticeMD5Prefix, a.ID),
+				fmt.Sprintf("%s:%d", syncedNoticeIDsPrefix, a.ID),
+			} {
+				f.redis.Set(key, "cached")
+			}
+			redisBefore := f.redis.Dump()
+			writes := countWrites(f.db)
+
+			results := tt.run(f)
+
+			if *writes != 0 {
+				t.Errorf("dry run executed %d write statements", *writes)
+			}
+			if redisAfter := f.redis.Dump(); redisAfter != redisBefore {
+				t.Errorf("dry run changed redis:\nbefore:\n%s\nafter:\n%s", redisBefore, redisAfter)
+			}
+
+			// 计划中包含所有会写入的操作，确认试运行确实走到了这些分支
+			var planA []NoticeSyncPlanItem
+			for _, result := range results {
+				if result["buildingId"] == a.ID {
+					planA = result["plan"].([]NoticeSyncPlanItem)
+				}
+			}
+			actions := map[field.NoticeSyncAction]int{}
+			for _, item := range planA {
+				actions[item.Action]++
+			}
+			want := map[field.NoticeSyncAction]int{
+				field.NoticeSyncActionCreate: 1,
+				field.NoticeSyncActionUpdate: 2,
+				field.NoticeSyncActionUnbind: 1,
+			}
+			for action, count := range want {
+				if actions[action] != count {
+					t.Errorf("plan %s = %d, want %d (plan %+v)", action, actions[action], count, planA)
+				}
+			}
+		})
+	}
+}
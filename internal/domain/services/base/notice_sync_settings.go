@@ -0,0 +1,168 @@
+package base_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/datatypes"
+)
+
+// noticeSyncSchedulerTick 调度器检查到期建筑的间隔，建筑同步间隔以分钟为单位
+const noticeSyncSchedulerTick = time.Minute
+
+// noticeSyncZone 通知时间与免同步时段使用的时区
+var noticeSyncZone = time.FixedZone("CST", 8*3600)
+
+// defaultNoticeEndTime 同步创建的通知未配置结束时间时使用
+var defaultNoticeEndTime = time.Date(2100, 2, 1, 0, 0, 0, 0, noticeSyncZone)
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseQuietHours 校验免同步时段，开始与结束需同时设置或同时为空，开始晚于结束时表示跨零点
+func ParseQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	if start == "" || end == "" {
+		return fmt.Errorf("quiet hours require both start and end")
+	}
+	startMinute, err := parseClock(start)
+	if err != nil {
+		return err
+	}
+	endMinute, err := parseClock(end)
+	if err != nil {
+		return err
+	}
+	if startMinute == endMinute {
+		return fmt.Errorf("quiet hours start and end must differ")
+	}
+	return nil
+}
+
+// ParseNoticeTypeMapping 校验来源通知类型到通知类型的映射并转换为 JSON，空映射表示使用默认映射
+func ParseNoticeTypeMapping(mapping map[string]string) (datatypes.JSON, error) {
+	if len(mapping) == 0 {
+		return nil, nil
+	}
+	normalized := make(map[string]field.NoticeType, len(mapping))
+	for sourceType, noticeType := range mapping {
+		sourceType = strings.TrimSpace(sourceType)
+		if sourceType == "" {
+			return nil, fmt.Errorf("notice type mapping has an empty source type")
+		}
+		if !field.IsValidNoticeType(noticeType) {
+			return nil, fmt.Errorf("invalid notice type %q for source type %q", noticeType, sourceType)
+		}
+		normalized[sourceType] = field.NoticeType(noticeType)
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// noticeSyncEnabled 建筑是否参与定时同步，未设置时为参与
+func noticeSyncEnabled(building *base_models.Building) bool {
+	return building.NoticeSyncEnabled == nil || *building.NoticeSyncEnabled
+}
+
+// buildingSyncInterval 建筑的定时同步间隔
+func buildingSyncInterval(building *base_models.Building) time.Duration {
+	if building.NoticeSyncInterval > 0 {
+		return time.Duration(building.NoticeSyncInterval) * time.Minute
+	}
+	return getNoticeSyncInterval()
+}
+
+// inQuietHours 当前是否处于建筑的免同步时段，配置无效时视为未设置
+func inQuietHours(building *base_models.Building, now time.Time) bool {
+	if ParseQuietHours(building.NoticeSyncQuietStart, building.NoticeSyncQuietEnd) != nil ||
+		building.NoticeSyncQuietStart == "" {
+		return false
+	}
+	start, _ := parseClock(building.NoticeSyncQuietStart)
+	end, _ := parseClock(building.NoticeSyncQuietEnd)
+
+	local := now.In(noticeSyncZone)
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// buildingNoticeEndTime 建筑同步创建的通知的结束时间
+func buildingNoticeEndTime(building *base_models.Building) time.Time {
+	if building.NoticeSyncEndTime != nil {
+		return *building.NoticeSyncEndTime
+	}
+	return defaultNoticeEndTime
+}
+
+// buildingNoticeType 按建筑的类型映射转换来源通知类型，未覆盖的类型使用默认映射
+func (s *NoticeSyncService) buildingNoticeType(building *base_models.Building, sourceType string) field.NoticeType {
+	if len(building.NoticeTypeMapping) > 0 {
+		var mapping map[string]field.NoticeType
+		if err := json.Unmarshal(building.NoticeTypeMapping, &mapping); err == nil {
+			if noticeType, ok := mapping[sourceType]; ok && field.IsValidNoticeType(string(noticeType)) {
+				return noticeType
+			}
+		}
+	}
+	return s.mapNoticeType(sourceType)
+}
+
+// lastSyncTimes 各建筑上次定时或手动同步的开始时间，取自同步记录，切换主实例后仍按原节奏同步
+func (s *NoticeSyncService) lastSyncTimes() (map[uint]time.Time, error) {
+	var lastRuns []struct {
+		BuildingID uint
+		LastAt     time.Time
+	}
+	if err := s.db.Model(&base_models.SyncRunBuilding{}).
+		Select("building_id, MAX(started_at) AS last_at").
+		Where("status <> ?", field.SyncRunStatusSkipped).
+		Group("building_id").
+		Scan(&lastRuns).Error; err != nil {
+		return nil, fmt.Errorf("failed to get last sync times: %v", err)
+	}
+	lastSynced := make(map[uint]time.Time, len(lastRuns))
+	for _, run := range lastRuns {
+		lastSynced[run.BuildingID] = run.LastAt
+	}
+	return lastSynced, nil
+}
+
+// dueBuildings 获取本次应定时同步的建筑：已启用、不在免同步时段、距上次同步已达到同步间隔
+func (s *NoticeSyncService) dueBuildings(now time.Time, lastSynced map[uint]time.Time) ([]base_models.Building, error) {
+	var buildings []base_models.Building
+	if err := s.db.Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get buildings: %v", err)
+	}
+
+	var due []base_models.Building
+	for _, building := range buildings {
+		if !noticeSyncEnabled(&building) || inQuietHours(&building, now) {
+			continue
+		}
+		// 留半个检查间隔的余量，避免同步开始时间的误差使建筑推迟一个检查间隔
+		if last, ok := lastSynced[building.ID]; ok &&
+			now.Sub(last)+noticeSyncSchedulerTick/2 < buildingSyncInterval(&building) {
+			continue
+		}
+		due = append(due, building)
+	}
+	return due, nil
+}
@@ -0,0 +1,666 @@
+package base_services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/httpclient"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 单次下发的最大打印份数
+const maxPrintDispatchCopies = 20
+
+// getPrintDispatchDefaultTTL returns the default print dispatch TTL from environment variables
+func getPrintDispatchDefaultTTL() time.Duration {
+	ttl := os.Getenv("PRINT_DISPATCH_DEFAULT_TTL")
+	if ttl == "" {
+		return time.Hour
+	}
+
+	seconds, err := strconv.Atoi(ttl)
+	if err != nil || seconds <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// getPrintDispatchPushPath returns the OrangePi bridge print path from environment variables
+func getPrintDispatchPushPath() string {
+	path := os.Getenv("PRINT_DISPATCH_PUSH_PATH")
+	if path == "" {
+		return "/print"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// getPrintDispatchPushTimeout returns the OrangePi push timeout from environment variables
+func getPrintDispatchPushTimeout() time.Duration {
+	timeout := os.Getenv("PRINT_DISPATCH_PUSH_TIMEOUT")
+	if timeout == "" {
+		return 10 * time.Second
+	}
+
+	seconds, err := strconv.Atoi(timeout)
+	if err != nil || seconds <= 0 {
+		return 10 * time.Second
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// getPrintDispatchAllowedNetworks returns the networks the OrangePi push address must belong to
+func getPrintDispatchAllowedNetworks() []*net.IPNet {
+	cidrs := os.Getenv("PRINT_DISPATCH_ALLOWED_CIDRS")
+	if cidrs == "" {
+		cidrs = "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16" // default to private IPv4 ranges
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(cidrs, ",") {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			log.Warn("忽略无效的打印推送网段 | 网段: %s | 错误: %v", cidr, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// checkPushAddress 香橙派地址由设备上报，只允许推送到配置的内网网段，避免服务端被引导访问任意主机
+func checkPushAddress(ip string, port int) error {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("orange pi address %s is not an IP address", ip)
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid orange pi port: %d", port)
+	}
+	for _, network := range getPrintDispatchAllowedNetworks() {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("orange pi address %s is not in the allowed networks", ip)
+}
+
+// printDispatchClient 推送打印任务的客户端：打印请求不是幂等的，不重试也不跟随重定向，只保留按香橙派熔断
+// 与同步通知的共享客户端分开，熔断状态互不影响
+var printDispatchClient = httpclient.New(httpclient.Config{
+	MaxRetries:       0,
+	FailureThreshold: 5,
+	OpenDuration:     time.Minute,
+	DisableRedirects: true,
+})
+
+// errPushUnconfirmed 请求可能已到达香橙派但没有得到确认（超时、连接中断、5xx），不能重发
+var errPushUnconfirmed = errors.New("push not confirmed by orange pi")
+
+// PrintDispatchRequest 管理端发起打印的参数
+type PrintDispatchRequest struct {
+	PrinterID    uint
+	NoticeID     *uint // 与 FileID 二选一，打印通知的 PDF
+	FileID       *uint
+	Copies       int
+	DeliveryMode field.PrintDeliveryMode
+	TTL          time.Duration // 为 0 时使用默认值
+	IssuedBy     string
+}
+
+// PrintDispatchPayload 推送给香橙派的打印任务（字段命名与打印机上报接口保持一致）
+type PrintDispatchPayload struct {
+	JobID     string `json:"job_id"` // 完成后通过打印回调的 print_jobs[].job_id 上报
+	Printer   string `json:"printer"`
+	PrinterIP string `json:"printer_ip"`
+	FileURL   string `json:"file_url"`
+	FileName  string `json:"file_name"`
+	FileMd5   string `json:"file_md5"`
+	Copies    int    `json:"copies"`
+	NoticeID  *uint  `json:"notice_id,omitempty"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// InterfacePrintDispatchService 管理端下发打印任务服务接口
+type InterfacePrintDispatchService interface {
+	// 下发打印任务，buildingIDs 不为 nil 时只允许下发到这些建筑的打印机（建筑管理员）
+	Dispatch(req PrintDispatchRequest, buildingIDs []uint) (*models.PrintDispatch, error)
+	// 设备拉取待打印任务，拉取后状态变为 delivered
+	FetchPending(deviceID uint) ([]models.PrintDispatch, error)
+	// 重新推送到香橙派
+	Push(id uint) (*models.PrintDispatch, error)
+	Cancel(id uint, buildingIDs []uint) (*models.PrintDispatch, error)
+	Get(query map[string]interface{}, paginate map[string]interface{}, buildingIDs []uint) ([]models.PrintDispatch, models.PaginationResult, error)
+	GetByID(id uint, buildingIDs []uint) (*models.PrintDispatch, error)
+	// 可下发的打印机，buildingIDs 为 nil 时返回所有打印机
+	GetPrinters(buildingIDs []uint, buildingID uint) ([]models.Printer, error)
+	// 将超时未完成的任务标记为 expired
+	ExpireStale() (int64, error)
+}
+
+// PrintDispatchService 管理端下发打印任务服务实现
+type PrintDispatchService struct {
+	db *gorm.DB
+}
+
+// NewPrintDispatchService 创建管理端下发打印任务服务
+func NewPrintDispatchService(db *gorm.DB) InterfacePrintDispatchService {
+	return &PrintDispatchService{db: db}
+}
+
+func containsBuilding(buildingIDs []uint, buildingID uint) bool {
+	if buildingIDs == nil {
+		return true
+	}
+	for _, id := range buildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PrintDispatchService) Dispatch(req PrintDispatchRequest, buildingIDs []uint) (*models.PrintDispatch, error) {
+	if req.DeliveryMode == "" {
+		req.DeliveryMode = field.PrintDeliveryPull
+	}
+	if !field.IsValidPrintDeliveryMode(string(req.DeliveryMode)) {
+		return nil, fmt.Errorf("invalid delivery mode: %s", req.DeliveryMode)
+	}
+	if req.Copies <= 0 {
+		req.Copies = 1
+	}
+	if req.Copies > maxPrintDispatchCopies {
+		return nil, fmt.Errorf("too many copies, at most %d", maxPrintDispatchCopies)
+	}
+	if req.TTL <= 0 {
+		req.TTL = getPrintDispatchDefaultTTL()
+	}
+
+	var printer models.Printer
+	if err := s.db.First(&printer, req.PrinterID).Error; err != nil {
+		return nil, fmt.Errorf("printer not found: %v", err)
+	}
+	if printer.DeviceID == nil {
+		return nil, errors.New("printer is not bound to a device")
+	}
+	if printer.Name == nil || *printer.Name == "" {
+		return nil, errors.New("printer has no CUPS name")
+	}
+
+	var device models.Device
+	if err := s.db.First(&device, *printer.DeviceID).Error; err != nil {
+		return nil, fmt.Errorf("device not found: %v", err)
+	}
+	if !containsBuilding(buildingIDs, device.BuildingID) {
+		return nil, errors.New("printer does not belong to your buildings")
+	}
+
+	dispatch := models.PrintDispatch{
+		JobID:        uuid.New().String(),
+		DeviceID:     device.ID,
+		BuildingID:   device.BuildingID,
+		PrinterID:    printer.ID,
+		PrinterName:  *printer.Name,
+		Copies:       req.Copies,
+		DeliveryMode: req.DeliveryMode,
+		Status:       field.PrintDispatchQueued,
+		ExpiresAt:    time.Now().Add(req.TTL),
+		IssuedBy:     req.IssuedBy,
+	}
+	if printer.IPAddress != nil {
+		dispatch.PrinterIP = *printer.IPAddress
+	}
+
+	// 打印通知时需要通知对该建筑可见
+	fileID := req.FileID
+	if req.NoticeID != nil {
+		var notice models.Notice
+		if err := s.db.Select("id", "title", "file_id", "file_type", "is_public").First(&notice, *req.NoticeID).Error; err != nil {
+			return nil, fmt.Errorf("notice not found: %v", err)
+		}
+		if !notice.IsPublic {
+			var count int64
+			if err := s.db.Table("notice_buildings").
+				Where("notice_id = ? AND building_id = ?", notice.ID, device.BuildingID).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, errors.New("notice is not published to the printer's building")
+			}
+		}
+		if notice.FileID == nil {
+			return nil, errors.New("notice has no file")
+		}
+		if notice.FileType != "" && notice.FileType != field.FileTypePdf {
+			return nil, fmt.Errorf("notice file type %s is not printable", notice.FileType)
+		}
+		dispatch.NoticeID = &notice.ID
+		dispatch.FileName = notice.Title
+		fileID = notice.FileID
+	}
+	if fileID == nil {
+		return nil, errors.New("noticeId or fileId is required")
+	}
+
+	var file models.File
+	if err := s.db.First(&file, *fileID).Error; err != nil {
+		return nil, fmt.Errorf("file not found: %v", err)
+	}
+	if file.MimeType != "" && !strings.Contains(file.MimeType, "pdf") {
+		return nil, fmt.Errorf("file type %s is not printable", file.MimeType)
+	}
+	dispatch.FileID = file.ID
+	dispatch.FileURL = file.Path
+	dispatch.FileMd5 = file.Md5
+	if dispatch.FileName == "" {
+		dispatch.FileName = file.Path[strings.LastIndex(file.Path, "/")+1:]
+	}
+
+	// 没有记录香橙派地址或地址不在允许的网段时只能由设备拉取
+	if dispatch.DeliveryMode == field.PrintDeliveryPush {
+		if device.OrangePi.IP == nil || *device.OrangePi.IP == "" || device.OrangePi.Port == nil {
+			log.Warn("香橙派地址未知，改为设备拉取 | 设备ID: %d", device.ID)
+			dispatch.DeliveryMode = field.PrintDeliveryPull
+		} else if err := checkPushAddress(*device.OrangePi.IP, *device.OrangePi.Port); err != nil {
+			log.Warn("香橙派地址不允许推送，改为设备拉取 | 设备ID: %d | 错误: %v", device.ID, err)
+			dispatch.DeliveryMode = field.PrintDeliveryPull
+		}
+	}
+
+	if err := s.db.Create(&dispatch).Error; err != nil {
+		return nil, fmt.Errorf("failed to create print dispatch: %v", err)
+	}
+
+	log.Info("下发打印任务 | 任务ID: %s | 设备ID: %d | 打印机: %s | 文件: %s | 份数: %d | 方式: %s",
+		dispatch.JobID, dispatch.DeviceID, dispatch.PrinterName, dispatch.FileName, dispatch.Copies, dispatch.DeliveryMode)
+
+	if dispatch.DeliveryMode == field.PrintDeliveryPush {
+		go func(id uint) {
+			if _, err := s.Push(id); err != nil && !errors.Is(err, errPushUnconfirmed) {
+				log.Warn("推送打印任务失败，等待设备拉取 | 下发ID: %d | 错误: %v", id, err)
+			}
+		}(dispatch.ID)
+	} else {
+		s.notifyDevice(dispatch)
+	}
+
+	return &dispatch, nil
+}
+
+// notifyDevice 通知在线设备立即拉取
+func (s *PrintDispatchService) notifyDevice(dispatch models.PrintDispatch) {
+	PublishDeviceEvent(field.DeviceEventPrintJobQueued, nil, []uint{dispatch.DeviceID}, map[string]interface{}{
+		"jobId": dispatch.JobID,
+	})
+}
+
+func (s *PrintDispatchService) Push(id uint) (*models.PrintDispatch, error) {
+	var dispatch models.PrintDispatch
+	if err := s.db.First(&dispatch, id).Error; err != nil {
+		return nil, fmt.Errorf("print dispatch not found: %v", err)
+	}
+	if dispatch.Status != field.PrintDispatchQueued {
+		return nil, fmt.Errorf("print dispatch already %s", dispatch.Status)
+	}
+	if time.Now().After(dispatch.ExpiresAt) {
+		if _, err := s.ExpireStale(); err != nil {
+			log.Warn("标记过期打印任务失败 | 错误: %v", err)
+		}
+		return nil, errors.New("print dispatch expired")
+	}
+
+	var device models.Device
+	if err := s.db.First(&device, dispatch.DeviceID).Error; err != nil {
+		return nil, fmt.Errorf("device not found: %v", err)
+	}
+	if device.OrangePi.IP == nil || *device.OrangePi.IP == "" || device.OrangePi.Port == nil {
+		return nil, errors.New("orange pi address unknown")
+	}
+	ip, port := *device.OrangePi.IP, *device.OrangePi.Port
+	if err := checkPushAddress(ip, port); err != nil {
+		log.Warn("拒绝推送打印任务 | 任务ID: %s | 设备ID: %d | 错误: %v", dispatch.JobID, device.ID, err)
+		// 记为一次推送失败，之后设备可以拉取
+		s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", dispatch.ID, field.PrintDispatchQueued).
+			Updates(map[string]interface{}{
+				"push_attempts": gorm.Expr("push_attempts + 1"),
+				"last_error":    truncateString(err.Error(), 500),
+			})
+		s.notifyDevice(dispatch)
+		return nil, err
+	}
+
+	// 先将任务标记为推送中，并发推送同一任务时只有一个能发送；推送中的任务设备不会拉取
+	claim := s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", dispatch.ID, field.PrintDispatchQueued).
+		Update("status", field.PrintDispatchPushing)
+	if claim.Error != nil {
+		return nil, fmt.Errorf("failed to claim print dispatch: %v", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil, errors.New("print dispatch is no longer queued")
+	}
+
+	pushErr := s.push(dispatch, ip, port)
+
+	now := time.Now()
+	if errors.Is(pushErr, errPushUnconfirmed) {
+		// 香橙派可能已收到任务，保持推送中，不再推送也不允许设备拉取，等待打印回调或过期
+		s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", dispatch.ID, field.PrintDispatchPushing).
+			Updates(map[string]interface{}{
+				"push_attempts": gorm.Expr("push_attempts + 1"),
+				"last_error":    truncateString(pushErr.Error(), 500),
+			})
+		log.Warn("推送打印任务结果未知，等待设备回调 | 任务ID: %s | 错误: %v", dispatch.JobID, pushErr)
+		return nil, pushErr
+	}
+	if pushErr != nil {
+		// 香橙派确定没有收到任务，恢复排队，由设备拉取
+		s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", dispatch.ID, field.PrintDispatchPushing).
+			Updates(map[string]interface{}{
+				"status":        field.PrintDispatchQueued,
+				"push_attempts": gorm.Expr("push_attempts + 1"),
+				"last_error":    truncateString(pushErr.Error(), 500),
+			})
+		s.notifyDevice(dispatch)
+		return nil, pushErr
+	}
+
+	// 推送期间设备可能已上报结果，只更新仍在推送中的任务
+	s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", dispatch.ID, field.PrintDispatchPushing).
+		Updates(map[string]interface{}{
+			"status":        field.PrintDispatchDelivered,
+			"delivered_at":  now,
+			"push_attempts": gorm.Expr("push_attempts + 1"),
+			"last_error":    "",
+		})
+
+	log.Info("推送打印任务成功 | 任务ID: %s | 地址: %s:%d", dispatch.JobID, ip, port)
+	return s.GetByID(dispatch.ID, nil)
+}
+
+// push 将任务发送到香橙派打印桥接服务，只发送一次
+// 请求确定未到达（无法连接、熔断中）或被拒绝（4xx）时返回普通错误；可能已到达但未确认时返回 errPushUnconfirmed
+func (s *PrintDispatchService) push(dispatch models.PrintDispatch, ip string, port int) error {
+	body, err := json.Marshal(NewPrintDispatchPayload(dispatch))
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(ip, strconv.Itoa(port)), getPrintDispatchPushPath())
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 桥接服务按该键去重，同一任务即使重复到达也只打印一次
+	req.Header.Set("Idempotency-Key", dispatch.JobID)
+
+	resp, err := printDispatchClient.DoTimeout(req, getPrintDispatchPushTimeout())
+	if err != nil {
+		var opErr *net.OpError
+		if errors.Is(err, httpclient.ErrCircuitOpen) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+			return fmt.Errorf("failed to push to %s: %v", url, err)
+		}
+		return fmt.Errorf("%w: %s: %v", errPushUnconfirmed, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%w: orange pi returned %d: %s", errPushUnconfirmed, resp.StatusCode, strings.TrimSpace(string(respBody)))
+		}
+		return fmt.Errorf("orange pi returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// NewPrintDispatchPayload 转换为推送/拉取时下发给香橙派的任务内容
+func NewPrintDispatchPayload(dispatch models.PrintDispatch) PrintDispatchPayload {
+	return PrintDispatchPayload{
+		JobID:     dispatch.JobID,
+		Printer:   dispatch.PrinterName,
+		PrinterIP: dispatch.PrinterIP,
+		FileURL:   dispatch.FileURL,
+		FileName:  dispatch.FileName,
+		FileMd5:   dispatch.FileMd5,
+		Copies:    dispatch.Copies,
+		NoticeID:  dispatch.NoticeID,
+		ExpiresAt: dispatch.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+func truncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	return value[:max]
+}
+
+func (s *PrintDispatchService) FetchPending(deviceID uint) ([]models.PrintDispatch, error) {
+	var dispatches []models.PrintDispatch
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PrintDispatch{}).
+			Where("device_id = ? AND status IN ? AND expires_at < ?", deviceID,
+				[]field.PrintDispatchStatus{field.PrintDispatchQueued, field.PrintDispatchPushing, field.PrintDispatchDelivered}, now).
+			Updates(map[string]interface{}{"status": field.PrintDispatchExpired, "completed_at": now}).Error; err != nil {
+			return err
+		}
+
+		// 推送方式的任务在推送失败后才允许拉取，避免重复打印
+		if err := tx.Where("device_id = ? AND status = ? AND (delivery_mode = ? OR push_attempts > 0)",
+			deviceID, field.PrintDispatchQueued, field.PrintDeliveryPull).
+			Order("created_at ASC").Find(&dispatches).Error; err != nil {
+			return err
+		}
+
+		if len(dispatches) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(dispatches))
+		for i := range dispatches {
+			ids = append(ids, dispatches[i].ID)
+			dispatches[i].Status = field.PrintDispatchDelivered
+			dispatches[i].DeliveredAt = &now
+		}
+
+		return tx.Model(&models.PrintDispatch{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": field.PrintDispatchDelivered, "delivered_at": now}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending print dispatches: %v", err)
+	}
+
+	if len(dispatches) > 0 {
+		log.Info("设备拉取打印任务 | 设备ID: %d | 任务数量: %d", deviceID, len(dispatches))
+	}
+	return dispatches, nil
+}
+
+func (s *PrintDispatchService) Cancel(id uint, buildingIDs []uint) (*models.PrintDispatch, error) {
+	dispatch, err := s.GetByID(id, buildingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已送达的任务可能已经在打印，只能取消排队中的任务
+	result := s.db.Model(&models.PrintDispatch{}).Where("id = ? AND status = ?", id, field.PrintDispatchQueued).
+		Updates(map[string]interface{}{"status": field.PrintDispatchCanceled, "completed_at": time.Now()})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel print dispatch: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("print dispatch already %s", dispatch.Status)
+	}
+
+	log.Info("取消打印任务 | 任务ID: %s", dispatch.JobID)
+	return s.GetByID(id, buildingIDs)
+}
+
+func (s *PrintDispatchService) Get(query map[string]interface{}, paginate map[string]interface{}, buildingIDs []uint) ([]models.PrintDispatch, models.PaginationResult, error) {
+	if _, err := s.ExpireStale(); err != nil {
+		log.Warn("标记过期打印任务失败 | 错误: %v", err)
+	}
+
+	var dispatches []models.PrintDispatch
+	var total int64
+	db := s.db.Model(&models.PrintDispatch{})
+
+	if buildingIDs != nil {
+		db = db.Where("building_id IN ?", buildingIDs)
+	}
+	if deviceID, ok := query["deviceId"].(uint); ok && deviceID != 0 {
+		db = db.Where("device_id = ?", deviceID)
+	}
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	if printerID, ok := query["printerId"].(uint); ok && printerID != 0 {
+		db = db.Where("printer_id = ?", printerID)
+	}
+	if noticeID, ok := query["noticeId"].(uint); ok && noticeID != 0 {
+		db = db.Where("notice_id = ?", noticeID)
+	}
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("created_at DESC")
+	} else {
+		db = db.Order("created_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&dispatches).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return dispatches, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *PrintDispatchService) GetByID(id uint, buildingIDs []uint) (*models.PrintDispatch, error) {
+	var dispatch models.PrintDispatch
+	if err := s.db.First(&dispatch, id).Error; err != nil {
+		return nil, err
+	}
+	if !containsBuilding(buildingIDs, dispatch.BuildingID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &dispatch, nil
+}
+
+func (s *PrintDispatchService) GetPrinters(buildingIDs []uint, buildingID uint) ([]models.Printer, error) {
+	var printers []models.Printer
+	db := s.db.Model(&models.Printer{}).
+		Joins("JOIN devices ON devices.id = printers.device_id")
+
+	if buildingIDs != nil {
+		db = db.Where("devices.building_id IN ?", buildingIDs)
+	}
+	if buildingID != 0 {
+		db = db.Where("devices.building_id = ?", buildingID)
+	}
+
+	if err := db.Order("printers.device_id ASC, printers.id ASC").Find(&printers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load printers: %v", err)
+	}
+	FillPrinterMarkers(printers)
+	return printers, nil
+}
+
+func (s *PrintDispatchService) ExpireStale() (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&models.PrintDispatch{}).
+		Where("status IN ? AND expires_at < ?",
+			[]field.PrintDispatchStatus{field.PrintDispatchQueued, field.PrintDispatchPushing, field.PrintDispatchDelivered}, now).
+		Updates(map[string]interface{}{"status": field.PrintDispatchExpired, "completed_at": now})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// loadPrintDispatches 获取设备上报的 job_id 对应的下发任务
+// 已过期的任务仍然接收结果，以记录实际打印情况
+func loadPrintDispatches(db *gorm.DB, deviceID uint, jobIDs []string) (map[string]models.PrintDispatch, error) {
+	dispatches := make(map[string]models.PrintDispatch)
+	if len(jobIDs) == 0 {
+		return dispatches, nil
+	}
+
+	var rows []models.PrintDispatch
+	if err := db.Where("device_id = ? AND job_id IN ? AND status IN ?", deviceID, jobIDs,
+		[]field.PrintDispatchStatus{field.PrintDispatchQueued, field.PrintDispatchPushing, field.PrintDispatchDelivered, field.PrintDispatchExpired}).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		dispatches[row.JobID] = row
+	}
+	return dispatches, nil
+}
+
+// completePrintDispatches 根据设备上报的打印结果完成对应的下发任务
+func completePrintDispatches(db *gorm.DB, dispatches map[string]models.PrintDispatch, jobs []models.PrintJob) {
+	now := time.Now()
+	for _, job := range jobs {
+		if job.ClientJobID == nil {
+			continue
+		}
+		dispatch, ok := dispatches[*job.ClientJobID]
+		if !ok {
+			continue
+		}
+
+		status := field.PrintDispatchSucceeded
+		if job.Status == field.PrintJobStatusFailed {
+			status = field.PrintDispatchFailed
+		}
+		updates := map[string]interface{}{
+			"status":       status,
+			"completed_at": now,
+			"print_job_id": job.ID,
+			"last_error":   truncateString(job.ErrorReason, 500),
+		}
+		if dispatch.DeliveredAt == nil {
+			updates["delivered_at"] = now
+		}
+		if err := db.Model(&models.PrintDispatch{}).Where("id = ?", dispatch.ID).Updates(updates).Error; err != nil {
+			log.Warn("更新打印任务状态失败 | 任务ID: %s | 错误: %v", dispatch.JobID, err)
+			continue
+		}
+		log.Info("打印任务完成 | 任务ID: %s | 设备ID: %d | 状态: %s", dispatch.JobID, dispatch.DeviceID, status)
+	}
+}
@@ -0,0 +1,494 @@
+package base_services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+// 单次最多上报的打印任务数
+const maxPrintJobsPerReport = 200
+
+// PrintJobReport 设备上报的单个打印任务（字段命名与打印机上报接口保持一致）
+type PrintJobReport struct {
+	JobID       *string    `json:"job_id"`     // 设备端任务ID，重复上报时忽略
+	PrinterID   *uint      `json:"printer_id"` // 与 printer_ip 二选一
+	PrinterIP   *string    `json:"printer_ip"`
+	NoticeID    *uint      `json:"notice_id"` // 打印的通知
+	FileID      *uint      `json:"file_id"`   // 打印的文件，传 notice_id 时可省略
+	FileName    string     `json:"file_name"`
+	Pages       int        `json:"pages"`
+	Copies      int        `json:"copies"`
+	Success     *bool      `json:"success"`
+	ErrorReason string     `json:"error_reason"`
+	PrintedAt   *time.Time `json:"printed_at"` // 默认为上报时间
+}
+
+// PrintErrorCount 失败原因统计
+type PrintErrorCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// PrintStatsItem 按建筑、设备或打印机分组的打印量
+type PrintStatsItem struct {
+	TargetID    uint              `json:"targetId"`
+	Name        string            `json:"name"`
+	Jobs        int64             `json:"jobs"`
+	Succeeded   int64             `json:"succeeded"`
+	Failed      int64             `json:"failed"`
+	Pages       int64             `json:"pages"`       // 成功打印的总页数（页数 × 份数）
+	FailureRate *float64          `json:"failureRate"` // 失败率（%）
+	TopErrors   []PrintErrorCount `json:"topErrors,omitempty"`
+}
+
+// PrintStatsReport 打印量统计
+type PrintStatsReport struct {
+	GroupBy     field.PrintStatsGroup `json:"groupBy"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Jobs        int64                 `json:"jobs"`
+	Succeeded   int64                 `json:"succeeded"`
+	Failed      int64                 `json:"failed"`
+	Pages       int64                 `json:"pages"`
+	FailureRate *float64              `json:"failureRate"`
+	Items       []PrintStatsItem      `json:"items"`
+}
+
+// InterfacePrintJobService 打印任务服务接口
+type InterfacePrintJobService interface {
+	// 记录设备上报的打印任务，返回新增数量与重复数量
+	Record(deviceID uint, reports []PrintJobReport) (int, int, error)
+	Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.PrintJob, models.PaginationResult, error)
+	GetByID(id uint) (*models.PrintJob, error)
+	// 按建筑、设备或打印机统计打印量，buildingID 为 0 时统计所有建筑
+	GetStats(groupBy field.PrintStatsGroup, from, to time.Time, buildingID uint) (*PrintStatsReport, error)
+	// 失败率报表：任务数不少于 minJobs 且失败率不低于 minFailureRate(%) 的分组，按失败率倒序
+	GetFailureReport(groupBy field.PrintStatsGroup, from, to time.Time, buildingID uint, minJobs int64, minFailureRate float64) (*PrintStatsReport, error)
+}
+
+// PrintJobService 打印任务服务实现
+type PrintJobService struct {
+	db *gorm.DB
+}
+
+// NewPrintJobService 创建打印任务服务
+func NewPrintJobService(db *gorm.DB) InterfacePrintJobService {
+	return &PrintJobService{db: db}
+}
+
+func (s *PrintJobService) Record(deviceID uint, reports []PrintJobReport) (int, int, error) {
+	if len(reports) == 0 {
+		return 0, 0, errors.New("no print jobs reported")
+	}
+	if len(reports) > maxPrintJobsPerReport {
+		return 0, 0, fmt.Errorf("too many print jobs, at most %d per report", maxPrintJobsPerReport)
+	}
+
+	var device models.Device
+	if err := s.db.Select("id", "building_id").First(&device, deviceID).Error; err != nil {
+		return 0, 0, fmt.Errorf("device not found: %v", err)
+	}
+
+	var printers []models.Printer
+	if err := s.db.Where("device_id = ?", deviceID).Find(&printers).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to load printers: %v", err)
+	}
+	printersByID := make(map[uint]models.Printer, len(printers))
+	printersByIP := make(map[string]models.Printer, len(printers))
+	for _, printer := range printers {
+		printersByID[printer.ID] = printer
+		if printer.IPAddress != nil {
+			printersByIP[*printer.IPAddress] = printer
+		}
+	}
+
+	// 打印通知时补全文件信息
+	noticeIDs := make([]uint, 0)
+	jobIDs := make([]string, 0)
+	for i, report := range reports {
+		if report.Success == nil {
+			return 0, 0, fmt.Errorf("print job %d: success is required", i)
+		}
+		if report.NoticeID != nil {
+			noticeIDs = append(noticeIDs, *report.NoticeID)
+		}
+		if report.JobID != nil && *report.JobID != "" {
+			jobIDs = append(jobIDs, *report.JobID)
+		}
+	}
+	notices := make(map[uint]models.Notice)
+	if len(noticeIDs) > 0 {
+		var rows []models.Notice
+		if err := s.db.Select("id", "title", "file_id").Where("id IN ?", noticeIDs).Find(&rows).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to load notices: %v", err)
+		}
+		for _, notice := range rows {
+			notices[notice.ID] = notice
+		}
+	}
+
+	existing := make(map[string]bool)
+	if len(jobIDs) > 0 {
+		var recorded []string
+		if err := s.db.Model(&models.PrintJob{}).Where("device_id = ? AND client_job_id IN ?", deviceID, jobIDs).
+			Pluck("client_job_id", &recorded).Error; err != nil {
+			return 0, 0, fmt.Errorf("failed to check reported jobs: %v", err)
+		}
+		for _, id := range recorded {
+			existing[id] = true
+		}
+	}
+
+	// 管理端下发的任务只需上报 job_id 与结果，其余信息从下发记录补全
+	dispatches, err := loadPrintDispatches(s.db, deviceID, jobIDs)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load print dispatches: %v", err)
+	}
+
+	now := time.Now()
+	jobs := make([]models.PrintJob, 0, len(reports))
+	duplicates := 0
+	for _, report := range reports {
+		if report.JobID != nil && *report.JobID != "" {
+			if existing[*report.JobID] {
+				duplicates++
+				continue
+			}
+			existing[*report.JobID] = true
+		} else {
+			report.JobID = nil
+		}
+
+		if report.JobID != nil {
+			if dispatch, ok := dispatches[*report.JobID]; ok {
+				if report.PrinterID == nil && report.PrinterIP == nil {
+					report.PrinterID = &dispatch.PrinterID
+				}
+				if report.NoticeID == nil && report.FileID == nil {
+					report.NoticeID = dispatch.NoticeID
+					report.FileID = &dispatch.FileID
+				}
+				if report.FileName == "" {
+					report.FileName = dispatch.FileName
+				}
+				if report.Copies <= 0 {
+					report.Copies = dispatch.Copies
+				}
+			}
+		}
+
+		job := models.PrintJob{
+			DeviceID:    deviceID,
+			BuildingID:  device.BuildingID,
+			ClientJobID: report.JobID,
+			NoticeID:    report.NoticeID,
+			FileID:      report.FileID,
+			FileName:    report.FileName,
+			Pages:       report.Pages,
+			Copies:      report.Copies,
+			Status:      field.PrintJobStatusSucceeded,
+			PrintedAt:   now,
+		}
+		if !*report.Success {
+			job.Status = field.PrintJobStatusFailed
+			job.ErrorReason = report.ErrorReason
+		}
+		if job.Pages <= 0 {
+			job.Pages = 1
+		}
+		if job.Copies <= 0 {
+			job.Copies = 1
+		}
+		// 设备时钟可能不准，未来时间按上报时间处理
+		if report.PrintedAt != nil && !report.PrintedAt.IsZero() && report.PrintedAt.Before(now) {
+			job.PrintedAt = *report.PrintedAt
+		}
+
+		var printer models.Printer
+		var found bool
+		if report.PrinterID != nil {
+			printer, found = printersByID[*report.PrinterID]
+		}
+		if !found && report.PrinterIP != nil {
+			printer, found = printersByIP[*report.PrinterIP]
+			job.PrinterIP = *report.PrinterIP
+		}
+		if found {
+			job.PrinterID = &printer.ID
+			if printer.IPAddress != nil {
+				job.PrinterIP = *printer.IPAddress
+			}
+		}
+
+		if report.NoticeID != nil {
+			if notice, ok := notices[*report.NoticeID]; ok {
+				if job.FileID == nil {
+					job.FileID = notice.FileID
+				}
+				if job.FileName == "" {
+					job.FileName = notice.Title
+				}
+			} else {
+				job.NoticeID = nil
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	if len(jobs) == 0 {
+		return 0, duplicates, nil
+	}
+	if err := s.db.Create(&jobs).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to record print jobs: %v", err)
+	}
+
+	completePrintDispatches(s.db, dispatches, jobs)
+
+	log.Info("记录打印任务 | 设备ID: %d | 新增: %d | 重复: %d", deviceID, len(jobs), duplicates)
+	return len(jobs), duplicates, nil
+}
+
+func (s *PrintJobService) Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.PrintJob, models.PaginationResult, error) {
+	var jobs []models.PrintJob
+	var total int64
+	db := s.db.Model(&models.PrintJob{})
+
+	for key, column := range map[string]string{
+		"deviceId":   "device_id",
+		"buildingId": "building_id",
+		"printerId":  "printer_id",
+		"noticeId":   "notice_id",
+	} {
+		if id, ok := query[key].(uint); ok && id != 0 {
+			db = db.Where(column+" = ?", id)
+		}
+	}
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if from, ok := query["from"].(time.Time); ok && !from.IsZero() {
+		db = db.Where("printed_at >= ?", from)
+	}
+	if to, ok := query["to"].(time.Time); ok && !to.IsZero() {
+		db = db.Where("printed_at <= ?", to)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("printed_at DESC, id DESC")
+	} else {
+		db = db.Order("printed_at ASC, id ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&jobs).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return jobs, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *PrintJobService) GetByID(id uint) (*models.PrintJob, error) {
+	var job models.PrintJob
+	if err := s.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// printStatsColumn 分组对应的列
+func printStatsColumn(groupBy field.PrintStatsGroup) (string, error) {
+	switch groupBy {
+	case field.PrintStatsByBuilding:
+		return "building_id", nil
+	case field.PrintStatsByDevice:
+		return "device_id", nil
+	case field.PrintStatsByPrinter:
+		return "printer_id", nil
+	}
+	return "", fmt.Errorf("invalid groupBy: %s", groupBy)
+}
+
+func (s *PrintJobService) scopedJobs(from, to time.Time, buildingID uint) *gorm.DB {
+	db := s.db.Model(&models.PrintJob{}).Where("printed_at >= ? AND printed_at <= ?", from, to)
+	if buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	return db
+}
+
+func (s *PrintJobService) GetStats(groupBy field.PrintStatsGroup, from, to time.Time, buildingID uint) (*PrintStatsReport, error) {
+	column, err := printStatsColumn(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		TargetID  uint
+		Jobs      int64
+		Succeeded int64
+		Failed    int64
+		Pages     int64
+	}
+	if err := s.scopedJobs(from, to, buildingID).
+		Select(column+" AS target_id, COUNT(*) AS jobs, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS succeeded, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS failed, "+
+			"SUM(CASE WHEN status = ? THEN pages * copies ELSE 0 END) AS pages",
+			field.PrintJobStatusSucceeded, field.PrintJobStatusFailed, field.PrintJobStatusSucceeded).
+		Where(column + " IS NOT NULL").
+		Group(column).
+		Order("pages DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate print jobs: %v", err)
+	}
+
+	report := &PrintStatsReport{
+		GroupBy: groupBy,
+		From:    from,
+		To:      to,
+		Items:   []PrintStatsItem{},
+	}
+	if len(rows) == 0 {
+		return report, nil
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.TargetID)
+	}
+	names, err := s.targetNames(groupBy, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		report.Items = append(report.Items, PrintStatsItem{
+			TargetID:    row.TargetID,
+			Name:        names[row.TargetID],
+			Jobs:        row.Jobs,
+			Succeeded:   row.Succeeded,
+			Failed:      row.Failed,
+			Pages:       row.Pages,
+			FailureRate: printFailureRate(row.Failed, row.Jobs),
+		})
+		report.Jobs += row.Jobs
+		report.Succeeded += row.Succeeded
+		report.Failed += row.Failed
+		report.Pages += row.Pages
+	}
+	report.FailureRate = printFailureRate(report.Failed, report.Jobs)
+
+	return report, nil
+}
+
+func (s *PrintJobService) GetFailureReport(groupBy field.PrintStatsGroup, from, to time.Time, buildingID uint, minJobs int64, minFailureRate float64) (*PrintStatsReport, error) {
+	report, err := s.GetStats(groupBy, from, to, buildingID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]PrintStatsItem, 0)
+	targetIDs := make([]uint, 0)
+	for _, item := range report.Items {
+		if item.Jobs < minJobs || item.Failed == 0 || *item.FailureRate < minFailureRate {
+			continue
+		}
+		items = append(items, item)
+		targetIDs = append(targetIDs, item.TargetID)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if *items[i].FailureRate != *items[j].FailureRate {
+			return *items[i].FailureRate > *items[j].FailureRate
+		}
+		return items[i].Failed > items[j].Failed
+	})
+
+	if len(targetIDs) > 0 {
+		column, _ := printStatsColumn(groupBy)
+		var reasons []struct {
+			TargetID uint
+			Reason   string
+			Count    int64
+		}
+		if err := s.scopedJobs(from, to, buildingID).
+			Select(column+" AS target_id, error_reason AS reason, COUNT(*) AS count").
+			Where("status = ? AND "+column+" IN ?", field.PrintJobStatusFailed, targetIDs).
+			Group(column + ", error_reason").
+			Order("count DESC").
+			Scan(&reasons).Error; err != nil {
+			return nil, fmt.Errorf("failed to aggregate failure reasons: %v", err)
+		}
+
+		// 每个分组保留最常见的 3 个原因
+		topErrors := make(map[uint][]PrintErrorCount)
+		for _, reason := range reasons {
+			if len(topErrors[reason.TargetID]) < 3 {
+				topErrors[reason.TargetID] = append(topErrors[reason.TargetID], PrintErrorCount{Reason: reason.Reason, Count: reason.Count})
+			}
+		}
+		for i := range items {
+			items[i].TopErrors = topErrors[items[i].TargetID]
+		}
+	}
+
+	report.Items = items
+	return report, nil
+}
+
+func printFailureRate(failed, jobs int64) *float64 {
+	if jobs <= 0 {
+		return nil
+	}
+	rate := float64(failed) * 100 / float64(jobs)
+	return &rate
+}
+
+// targetNames 查询分组对象的显示名称
+func (s *PrintJobService) targetNames(groupBy field.PrintStatsGroup, ids []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(ids))
+	switch groupBy {
+	case field.PrintStatsByBuilding:
+		var buildings []models.Building
+		if err := s.db.Select("id", "name").Where("id IN ?", ids).Find(&buildings).Error; err != nil {
+			return nil, fmt.Errorf("failed to load buildings: %v", err)
+		}
+		for _, building := range buildings {
+			names[building.ID] = building.Name
+		}
+	case field.PrintStatsByDevice:
+		var devices []models.Device
+		if err := s.db.Select("id", "device_id").Where("id IN ?", ids).Find(&devices).Error; err != nil {
+			return nil, fmt.Errorf("failed to load devices: %v", err)
+		}
+		for _, device := range devices {
+			names[device.ID] = device.DeviceID
+		}
+	case field.PrintStatsByPrinter:
+		var printers []models.Printer
+		if err := s.db.Where("id IN ?", ids).Find(&printers).Error; err != nil {
+			return nil, fmt.Errorf("failed to load printers: %v", err)
+		}
+		for _, printer := range printers {
+			names[printer.ID] = printerDisplayName(printer)
+		}
+	}
+	return names, nil
+}
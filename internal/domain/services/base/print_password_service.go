@@ -0,0 +1,691 @@
+package base_services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	redis "github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"golang.org/x/crypto/pbkdf2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// 多实例部署时只有持有该租约的实例执行密码轮换
+	printPasswordRotationLeaderKey = "print_password:rotation:leader"
+	// 多实例同时启动时只有取得该租约的实例迁移旧密码
+	printPasswordMigrationLockKey = "print_password:migration:lock"
+	// 设备表中旧打印密码字段的默认值
+	legacyDefaultPrintPassword = "1090119"
+	printPasswordIterations    = 10000
+)
+
+// getPrintPasswordMaxFailures returns the failed attempts allowed before a device is locked
+func getPrintPasswordMaxFailures() int64 {
+	value := os.Getenv("PRINT_PASSWORD_MAX_FAILURES")
+	if value == "" {
+		return 5
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 5
+	}
+
+	return n
+}
+
+// getPrintPasswordLockDuration returns how long a device stays locked after too many failures
+func getPrintPasswordLockDuration() time.Duration {
+	value := os.Getenv("PRINT_PASSWORD_LOCK_MINUTES")
+	if value == "" {
+		return 15 * time.Minute
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 15 * time.Minute
+	}
+
+	return time.Duration(n) * time.Minute
+}
+
+// getPrintPasswordLength returns the length of generated print passwords
+func getPrintPasswordLength() int {
+	value := os.Getenv("PRINT_PASSWORD_LENGTH")
+	if value == "" {
+		return 6
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 4 || n > 16 {
+		return 6
+	}
+
+	return n
+}
+
+// PrintPasswordVerifyResult 服务端校验结果
+type PrintPasswordVerifyResult struct {
+	Valid             bool       `json:"valid"`
+	Version           int        `json:"version"`
+	RemainingAttempts int64      `json:"remainingAttempts"`
+	LockedUntil       *time.Time `json:"lockedUntil,omitempty"`
+}
+
+// ErrPrintPasswordLocked 失败次数过多，设备暂时被锁定
+var ErrPrintPasswordLocked = errors.New("too many failed attempts, try again later")
+
+// ErrPrintPasswordConflict 同一密码同时被修改或轮换，本次修改未保存
+var ErrPrintPasswordConflict = errors.New("print password was changed by another request, try again")
+
+// InterfacePrintPasswordService 打印密码服务接口
+type InterfacePrintPasswordService interface {
+	// 管理员维护密码，password 为空时随机生成；返回明文，仅此一次
+	Get(scope string, targetID *uint) ([]models.PrintPassword, error)
+	Set(scope field.PrintPasswordScope, targetID uint, password string, rotationDays *int, updatedBy string) (*models.PrintPassword, string, error)
+	Rotate(id uint, updatedBy string) (*models.PrintPassword, string, error)
+	Delete(ids []uint) error
+	// 设备校验，只在服务端校验，不向设备下发哈希
+	Verify(deviceID uint, password string, clientIP string) (*PrintPasswordVerifyResult, error)
+	// 校验记录
+	GetAttempts(query map[string]interface{}, paginate map[string]interface{}) ([]models.PrintPasswordAttempt, models.PaginationResult, error)
+	// 将设置配置与设备表中的明文密码迁移为哈希并清空旧字段，不符合规则的按原值迁移并标记为需要重新设置
+	MigrateLegacyPasswords() error
+	// 轮换到期的密码
+	RotateDue() (int, error)
+	StartRotationScheduler(ctx context.Context)
+}
+
+// PrintPasswordService 打印密码服务实现
+type PrintPasswordService struct {
+	db           *gorm.DB
+	emailService IEmailService
+}
+
+// NewPrintPasswordService 创建打印密码服务，emailService 为空时轮换后不发送邮件
+func NewPrintPasswordService(db *gorm.DB, emailService IEmailService) InterfacePrintPasswordService {
+	return &PrintPasswordService{db: db, emailService: emailService}
+}
+
+// ValidatePrintPassword 打印密码为 4-16 位数字
+func ValidatePrintPassword(password string) error {
+	if len(password) < 4 || len(password) > 16 {
+		return errors.New("print password must be 4-16 digits")
+	}
+	for _, r := range password {
+		if r < '0' || r > '9' {
+			return errors.New("print password must be 4-16 digits")
+		}
+	}
+	return nil
+}
+
+func generatePrintPassword() (string, error) {
+	length := getPrintPasswordLength()
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
+
+func hashPrintPassword(password string, salt []byte, iterations int) string {
+	return hex.EncodeToString(pbkdf2.Key([]byte(password), salt, iterations, sha256.Size, sha256.New))
+}
+
+// applyPrintPassword 为记录设置新的盐和哈希
+func applyPrintPassword(record *models.PrintPassword, password string, now time.Time) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	record.Salt = hex.EncodeToString(salt)
+	record.Iterations = printPasswordIterations
+	record.Hash = hashPrintPassword(password, salt, printPasswordIterations)
+	record.RotatedAt = now
+	record.NextRotationAt = nil
+	if record.RotationDays > 0 {
+		next := now.AddDate(0, 0, record.RotationDays)
+		record.NextRotationAt = &next
+	}
+	return nil
+}
+
+func (s *PrintPasswordService) checkTarget(scope field.PrintPasswordScope, targetID uint) error {
+	switch scope {
+	case field.PrintPasswordScopeBuilding:
+		if err := s.db.Select("id").First(&models.Building{}, targetID).Error; err != nil {
+			return fmt.Errorf("building not found: %v", err)
+		}
+	case field.PrintPasswordScopeDevice:
+		if err := s.db.Select("id").First(&models.Device{}, targetID).Error; err != nil {
+			return fmt.Errorf("device not found: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *PrintPasswordService) Get(scope string, targetID *uint) ([]models.PrintPassword, error) {
+	var records []models.PrintPassword
+	db := s.db.Model(&models.PrintPassword{})
+	if scope != "" {
+		db = db.Where("scope = ?", scope)
+	}
+	if targetID != nil {
+		db = db.Where("target_id = ?", *targetID)
+	}
+	if err := db.Order("scope ASC, target_id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *PrintPasswordService) Set(scope field.PrintPasswordScope, targetID uint, password string, rotationDays *int, updatedBy string) (*models.PrintPassword, string, error) {
+	if !field.IsValidPrintPasswordScope(string(scope)) {
+		return nil, "", fmt.Errorf("invalid print password scope: %s", scope)
+	}
+	if scope == field.PrintPasswordScopeGlobal {
+		targetID = 0
+	} else if targetID == 0 {
+		return nil, "", errors.New("targetId is required for non-global scope")
+	}
+	if rotationDays != nil && *rotationDays < 0 {
+		return nil, "", errors.New("rotationDays must not be negative")
+	}
+	if password == "" {
+		generated, err := generatePrintPassword()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate print password: %v", err)
+		}
+		password = generated
+	} else if err := ValidatePrintPassword(password); err != nil {
+		return nil, "", err
+	}
+	if err := s.checkTarget(scope, targetID); err != nil {
+		return nil, "", err
+	}
+
+	record, err := s.save(scope, targetID, password, rotationDays, updatedBy, false)
+	if err != nil {
+		return nil, "", err
+	}
+	return record, password, nil
+}
+
+// save 保存密码的哈希，已有记录时只在版本未被其他请求修改时更新，版本加 1
+// needsReset 为 true 时保存不符合规则的旧密码，之后需要管理员重新设置
+func (s *PrintPasswordService) save(scope field.PrintPasswordScope, targetID uint, password string, rotationDays *int, updatedBy string, needsReset bool) (*models.PrintPassword, error) {
+	var record models.PrintPassword
+	err := s.db.Where("scope = ? AND target_id = ?", scope, targetID).First(&record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	exists := record.ID != 0
+	version := record.Version
+	if !exists {
+		record.Scope = scope
+		record.TargetID = targetID
+	}
+	if rotationDays != nil {
+		record.RotationDays = *rotationDays
+	}
+	if err := applyPrintPassword(&record, password, time.Now()); err != nil {
+		return nil, err
+	}
+	record.UpdatedBy = updatedBy
+	record.NeedsReset = needsReset
+	record.Version = version + 1
+
+	if !exists {
+		if err := s.db.Create(&record).Error; err != nil {
+			return nil, fmt.Errorf("failed to save print password: %v", err)
+		}
+	} else {
+		result := s.db.Model(&models.PrintPassword{}).Where("id = ? AND version = ?", record.ID, version).
+			Updates(map[string]interface{}{
+				"salt":             record.Salt,
+				"hash":             record.Hash,
+				"iterations":       record.Iterations,
+				"version":          record.Version,
+				"rotation_days":    record.RotationDays,
+				"rotated_at":       record.RotatedAt,
+				"next_rotation_at": record.NextRotationAt,
+				"updated_by":       record.UpdatedBy,
+				"needs_reset":      record.NeedsReset,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to save print password: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrPrintPasswordConflict
+		}
+	}
+
+	log.Info("设置打印密码 | 层级: %s | 目标ID: %d | 版本: %d | 操作人: %s", scope, targetID, record.Version, updatedBy)
+	s.notifyDevices(record)
+	return &record, nil
+}
+
+func (s *PrintPasswordService) Rotate(id uint, updatedBy string) (*models.PrintPassword, string, error) {
+	var record models.PrintPassword
+	if err := s.db.First(&record, id).Error; err != nil {
+		return nil, "", fmt.Errorf("print password not found: %v", err)
+	}
+	return s.Set(record.Scope, record.TargetID, "", nil, updatedBy)
+}
+
+func (s *PrintPasswordService) Delete(ids []uint) error {
+	var records []models.PrintPassword
+	if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Scope == field.PrintPasswordScopeGlobal {
+			return errors.New("global print password cannot be deleted, rotate it instead")
+		}
+	}
+	if err := s.db.Delete(&models.PrintPassword{}, ids).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		s.notifyDevices(record)
+	}
+	return nil
+}
+
+// resolve 按 device -> building -> global 查找设备生效的密码
+func (s *PrintPasswordService) resolve(device models.Device) (*models.PrintPassword, error) {
+	var records []models.PrintPassword
+	conditions := s.db.Where("scope = ?", field.PrintPasswordScopeGlobal).
+		Or("scope = ? AND target_id = ?", field.PrintPasswordScopeDevice, device.ID)
+	if device.BuildingID != 0 {
+		conditions = conditions.Or("scope = ? AND target_id = ?", field.PrintPasswordScopeBuilding, device.BuildingID)
+	}
+	if err := s.db.Where(conditions).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load print passwords: %v", err)
+	}
+
+	byScope := make(map[field.PrintPasswordScope]models.PrintPassword, len(records))
+	for _, record := range records {
+		byScope[record.Scope] = record
+	}
+	for _, scope := range []field.PrintPasswordScope{field.PrintPasswordScopeDevice, field.PrintPasswordScopeBuilding, field.PrintPasswordScopeGlobal} {
+		if record, ok := byScope[scope]; ok {
+			return &record, nil
+		}
+	}
+	return nil, errors.New("print password not configured")
+}
+
+func (s *PrintPasswordService) loadDevice(deviceID uint) (models.Device, error) {
+	var device models.Device
+	if err := s.db.Select("id", "building_id").First(&device, deviceID).Error; err != nil {
+		return device, fmt.Errorf("device not found: %v", err)
+	}
+	return device, nil
+}
+
+func (s *PrintPasswordService) Verify(deviceID uint, password string, clientIP string) (*PrintPasswordVerifyResult, error) {
+	device, err := s.loadDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 统计最近一次成功之后、锁定窗口内的失败次数
+	now := time.Now()
+	since := now.Add(-getPrintPasswordLockDuration())
+	var lastSuccess models.PrintPasswordAttempt
+	if err := s.db.Where("device_id = ? AND success = ? AND attempted_at >= ?", deviceID, true, since).
+		Order("attempted_at DESC").Limit(1).Find(&lastSuccess).Error; err != nil {
+		return nil, err
+	}
+	if lastSuccess.ID != 0 {
+		since = lastSuccess.AttemptedAt
+	}
+	var failures []models.PrintPasswordAttempt
+	if err := s.db.Select("id", "attempted_at").
+		Where("device_id = ? AND success = ? AND attempted_at > ?", deviceID, false, since).
+		Order("attempted_at ASC").Find(&failures).Error; err != nil {
+		return nil, err
+	}
+
+	maxFailures := getPrintPasswordMaxFailures()
+	if int64(len(failures)) >= maxFailures {
+		lockedUntil := failures[len(failures)-int(maxFailures)].AttemptedAt.Add(getPrintPasswordLockDuration())
+		log.Warn("打印密码已锁定 | 设备ID: %d | 解锁时间: %s", deviceID, lockedUntil.Format("2006-01-02 15:04:05"))
+		return &PrintPasswordVerifyResult{LockedUntil: &lockedUntil}, ErrPrintPasswordLocked
+	}
+
+	record, err := s.resolve(device)
+	if err != nil {
+		return nil, err
+	}
+
+	salt, err := hex.DecodeString(record.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid print password salt: %v", err)
+	}
+	valid := subtle.ConstantTimeCompare([]byte(hashPrintPassword(password, salt, record.Iterations)), []byte(record.Hash)) == 1
+
+	attempt := models.PrintPasswordAttempt{
+		DeviceID:        deviceID,
+		BuildingID:      device.BuildingID,
+		PrintPasswordID: &record.ID,
+		Version:         record.Version,
+		Success:         valid,
+		ClientIP:        clientIP,
+		AttemptedAt:     now,
+	}
+	if err := s.db.Create(&attempt).Error; err != nil {
+		log.Warn("记录打印密码校验失败 | 设备ID: %d | 错误: %v", deviceID, err)
+	}
+
+	result := &PrintPasswordVerifyResult{Valid: valid, Version: record.Version, RemainingAttempts: maxFailures}
+	if !valid {
+		result.RemainingAttempts = maxFailures - int64(len(failures)) - 1
+		log.Warn("打印密码错误 | 设备ID: %d | 剩余次数: %d", deviceID, result.RemainingAttempts)
+	}
+	return result, nil
+}
+
+func (s *PrintPasswordService) GetAttempts(query map[string]interface{}, paginate map[string]interface{}) ([]models.PrintPasswordAttempt, models.PaginationResult, error) {
+	var attempts []models.PrintPasswordAttempt
+	var total int64
+	db := s.db.Model(&models.PrintPasswordAttempt{})
+
+	if deviceID, ok := query["deviceId"].(uint); ok && deviceID != 0 {
+		db = db.Where("device_id = ?", deviceID)
+	}
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("building_id = ?", buildingID)
+	}
+	if success, ok := query["success"].(*bool); ok && success != nil {
+		db = db.Where("success = ?", *success)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("attempted_at DESC")
+	} else {
+		db = db.Order("attempted_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&attempts).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return attempts, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *PrintPasswordService) MigrateLegacyPasswords() error {
+	// 多实例同时启动时只由一个实例迁移
+	if redis.REDIS_CONN != nil {
+		lease, err := acquireLease(context.Background(), redis.REDIS_CONN, printPasswordMigrationLockKey, getSchedulerLeaseTTL())
+		if err != nil {
+			return err
+		}
+		if lease == nil {
+			log.Info("其他实例正在迁移打印密码，跳过 | 持有者: %s", leaseHolder(context.Background(), redis.REDIS_CONN, printPasswordMigrationLockKey))
+			return nil
+		}
+		defer lease.Release()
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PrintPassword{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if err := s.migrateSettingsProfiles(); err != nil {
+		return err
+	}
+
+	// 首次启用时以旧默认值作为全局密码，保证现有设备不受影响
+	var globals int64
+	s.db.Model(&models.PrintPassword{}).Where("scope = ?", field.PrintPasswordScopeGlobal).Count(&globals)
+	if count == 0 && globals == 0 {
+		if _, _, err := s.Set(field.PrintPasswordScopeGlobal, 0, legacyDefaultPrintPassword, nil, "system"); err != nil {
+			return err
+		}
+		log.Warn("已使用旧默认值初始化全局打印密码，请尽快修改或开启自动轮换")
+	}
+
+	return s.migrateDevicePasswords()
+}
+
+// migrateLegacyPassword 迁移一个旧密码，不符合规则时按原值保存并标记为需要重新设置，保证设备仍使用原密码
+func (s *PrintPasswordService) migrateLegacyPassword(scope field.PrintPasswordScope, targetID uint, password string) error {
+	if !field.IsValidPrintPasswordScope(string(scope)) {
+		return fmt.Errorf("invalid print password scope: %s", scope)
+	}
+	if scope == field.PrintPasswordScopeGlobal {
+		targetID = 0
+	}
+	if err := ValidatePrintPassword(password); err != nil {
+		log.Warn("旧打印密码不符合规则，已按原值迁移并标记为需要重新设置 | 层级: %s | 目标ID: %d", scope, targetID)
+		_, err := s.save(scope, targetID, password, nil, "system", true)
+		return err
+	}
+	_, _, err := s.Set(scope, targetID, password, nil, "system")
+	return err
+}
+
+// migrateDevicePasswords 将设备表中与默认值不同的旧密码迁移为设备级密码并清空旧字段，已有设备级密码的只清空
+func (s *PrintPasswordService) migrateDevicePasswords() error {
+	var devices []models.Device
+	if err := s.db.Select("id", "print_pass_word").Where("print_pass_word <> ''").Find(&devices).Error; err != nil {
+		return fmt.Errorf("failed to load legacy print passwords: %v", err)
+	}
+	migrated := 0
+	for _, device := range devices {
+		password := device.Settings.PrintPassWord
+		if password != legacyDefaultPrintPassword && !s.hasPrintPassword(field.PrintPasswordScopeDevice, device.ID) {
+			if err := s.migrateLegacyPassword(field.PrintPasswordScopeDevice, device.ID, password); err != nil {
+				return err
+			}
+			migrated++
+		}
+		if err := s.db.Model(&models.Device{}).Where("id = ?", device.ID).Update("print_pass_word", "").Error; err != nil {
+			return fmt.Errorf("failed to clear legacy print passwords: %v", err)
+		}
+	}
+
+	if len(devices) > 0 {
+		log.Info("迁移设备打印密码 | 设备数量: %d | 迁移为设备级密码: %d", len(devices), migrated)
+	}
+	return nil
+}
+
+// migrateSettingsProfiles 将设置配置中的 printPassWord 迁移为对应层级的打印密码并从配置中移除
+// 分组配置的密码迁移为分组内各设备的设备级密码，已有设备级密码的设备不变
+func (s *PrintPasswordService) migrateSettingsProfiles() error {
+	var profiles []models.DeviceSettingsProfile
+	if err := s.db.Where("JSON_CONTAINS_PATH(settings, 'one', '$.printPassWord')").Find(&profiles).Error; err != nil {
+		return fmt.Errorf("failed to load settings profiles: %v", err)
+	}
+
+	for _, profile := range profiles {
+		var settings map[string]interface{}
+		if err := json.Unmarshal(profile.Settings, &settings); err != nil {
+			continue
+		}
+		password, _ := settings["printPassWord"].(string)
+
+		if password != "" {
+			var err error
+			if profile.Scope == field.SettingsScopeGroup {
+				err = s.migrateGroupPassword(profile.TargetID, password)
+			} else {
+				err = s.migrateLegacyPassword(field.PrintPasswordScope(profile.Scope), profile.TargetID, password)
+			}
+			if err != nil {
+				// 迁移失败时保留配置中的旧值，下次启动重试
+				log.Warn("迁移配置中的打印密码失败，已保留旧值 | 配置ID: %d | 错误: %v", profile.ID, err)
+				continue
+			}
+		}
+		delete(settings, "printPassWord")
+
+		b, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+		if err := s.db.Model(&profile).Update("settings", datatypes.JSON(b)).Error; err != nil {
+			return fmt.Errorf("failed to update settings profile: %v", err)
+		}
+	}
+	return nil
+}
+
+// migrateGroupPassword 打印密码没有分组层级，分组配置中的密码迁移为分组内设备的设备级密码
+func (s *PrintPasswordService) migrateGroupPassword(groupID uint, password string) error {
+	var deviceIDs []uint
+	if err := s.db.Model(&models.Device{}).Where("device_group_id = ?", groupID).Pluck("id", &deviceIDs).Error; err != nil {
+		return fmt.Errorf("failed to load group devices: %v", err)
+	}
+	for _, deviceID := range deviceIDs {
+		if s.hasPrintPassword(field.PrintPasswordScopeDevice, deviceID) {
+			continue
+		}
+		if err := s.migrateLegacyPassword(field.PrintPasswordScopeDevice, deviceID, password); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasPrintPassword 该层级与目标是否已配置打印密码
+func (s *PrintPasswordService) hasPrintPassword(scope field.PrintPasswordScope, targetID uint) bool {
+	var count int64
+	s.db.Model(&models.PrintPassword{}).Where("scope = ? AND target_id = ?", scope, targetID).Count(&count)
+	return count > 0
+}
+
+func (s *PrintPasswordService) RotateDue() (int, error) {
+	var records []models.PrintPassword
+	if err := s.db.Where("rotation_days > 0 AND next_rotation_at <= ?", time.Now()).Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due print passwords: %v", err)
+	}
+
+	rotated := 0
+	for _, record := range records {
+		updated, password, err := s.Set(record.Scope, record.TargetID, "", nil, "system")
+		if err != nil {
+			log.Error("轮换打印密码失败 | 密码ID: %d | 错误: %v", record.ID, err)
+			continue
+		}
+		rotated++
+		s.notifyAdmins(*updated, password)
+	}
+	return rotated, nil
+}
+
+func (s *PrintPasswordService) StartRotationScheduler(ctx context.Context) {
+	interval := time.Hour
+	ticker := time.NewTicker(interval)
+	leader := newSchedulerLeader(redis.REDIS_CONN, printPasswordRotationLeaderKey, "打印密码轮换")
+	log.Info("打印密码轮换检测已启动 | 间隔: %s", interval)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				leader.Release()
+				log.Info("打印密码轮换检测已停止")
+				return
+			case <-ticker.C:
+				if !leader.IsLeader(ctx) {
+					continue
+				}
+				rotated, err := s.RotateDue()
+				if err != nil {
+					log.Error("打印密码轮换失败 | 错误: %v", err)
+					continue
+				}
+				if rotated > 0 {
+					log.Info("打印密码轮换完成 | 数量: %d", rotated)
+				}
+			}
+		}
+	}()
+}
+
+// notifyDevices 密码变化后通知受影响的设备刷新校验信息
+func (s *PrintPasswordService) notifyDevices(record models.PrintPassword) {
+	data := map[string]interface{}{"printPasswordVersion": record.Version}
+	switch record.Scope {
+	case field.PrintPasswordScopeGlobal:
+		BroadcastDeviceEvent(field.DeviceEventSettingsChanged, data)
+	case field.PrintPasswordScopeBuilding:
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, []uint{record.TargetID}, nil, data)
+	case field.PrintPasswordScopeDevice:
+		PublishDeviceEvent(field.DeviceEventSettingsChanged, nil, []uint{record.TargetID}, data)
+	}
+}
+
+// notifyAdmins 自动轮换后将新密码发送给相关管理员
+func (s *PrintPasswordService) notifyAdmins(record models.PrintPassword, password string) {
+	if s.emailService == nil {
+		log.Warn("邮件服务未初始化，跳过打印密码通知 | 密码ID: %d", record.ID)
+		return
+	}
+
+	var recipients []string
+	var err error
+	target := "全局"
+	switch record.Scope {
+	case field.PrintPasswordScopeGlobal:
+		err = s.db.Model(&models.SuperAdmin{}).Pluck("email", &recipients).Error
+	case field.PrintPasswordScopeBuilding:
+		var building models.Building
+		s.db.Select("id", "name").First(&building, record.TargetID)
+		target = "建筑 " + building.Name
+		recipients, err = buildingAdminEmails(s.db, record.TargetID)
+	case field.PrintPasswordScopeDevice:
+		var device models.Device
+		s.db.Select("id", "device_id", "building_id").First(&device, record.TargetID)
+		target = "设备 " + device.DeviceID
+		recipients, err = buildingAdminEmails(s.db, device.BuildingID)
+	}
+	if err != nil || len(recipients) == 0 {
+		log.Warn("打印密码通知无收件人 | 密码ID: %d | 错误: %v", record.ID, err)
+		return
+	}
+
+	subject := fmt.Sprintf("[iBoard] %s打印密码已更新", target)
+	body := fmt.Sprintf("<p>%s的打印密码已自动轮换为: <b>%s</b></p><p>版本: %d</p><p>下次轮换: %s</p>",
+		html.EscapeString(target), password, record.Version, record.NextRotationAt.Format("2006-01-02 15:04"))
+	if err := s.emailService.SendEmail(recipients, subject, body); err != nil {
+		log.Warn("发送打印密码通知失败 | 密码ID: %d | 错误: %v", record.ID, err)
+	}
+}
@@ -0,0 +1,195 @@
+package base_services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+func newTestPrintPasswordService(t *testing.T) (*PrintPasswordService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t, &models.Building{}, &models.Device{}, &models.PrintPassword{}, &models.PrintPasswordAttempt{})
+	buildings := []models.Building{{Name: "A", IsmartID: "a"}, {Name: "B", IsmartID: "b"}}
+	if err := db.Create(&buildings).Error; err != nil {
+		t.Fatalf("create buildings: %v", err)
+	}
+	devices := []models.Device{
+		{DeviceID: "d1", BuildingID: buildings[0].ID},
+		{DeviceID: "d2", BuildingID: buildings[0].ID},
+		{DeviceID: "d3", BuildingID: buildings[1].ID},
+	}
+	if err := db.Create(&devices).Error; err != nil {
+		t.Fatalf("create devices: %v", err)
+	}
+	return &PrintPasswordService{db: db}, db
+}
+
+func verifyPrintPassword(t *testing.T, s *PrintPasswordService, deviceID uint, password string) bool {
+	t.Helper()
+	result, err := s.Verify(deviceID, password, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("verify device %d: %v", deviceID, err)
+	}
+	return result.Valid
+}
+
+func TestPrintPasswordResolveFallback(t *testing.T) {
+	s, _ := newTestPrintPasswordService(t)
+	mustSet := func(scope field.PrintPasswordScope, targetID uint, password string) {
+		if _, _, err := s.Set(scope, targetID, password, nil, "admin@example.com"); err != nil {
+			t.Fatalf("set %s/%d: %v", scope, targetID, err)
+		}
+	}
+	mustSet(field.PrintPasswordScopeGlobal, 0, "1111")
+	mustSet(field.PrintPasswordScopeBuilding, 1, "2222")
+	mustSet(field.PrintPasswordScopeDevice, 1, "3333")
+
+	tests := []struct {
+		name     string
+		deviceID uint
+		password string
+		valid    bool
+	}{
+		{"设备级密码优先", 1, "3333", true},
+		{"有设备级密码时建筑密码无效", 1, "2222", false},
+		{"有设备级密码时全局密码无效", 1, "1111", false},
+		{"无设备级密码时使用建筑密码", 2, "2222", true},
+		{"有建筑密码时全局密码无效", 2, "1111", false},
+		{"无建筑与设备级密码时使用全局密码", 3, "1111", true},
+		{"其他建筑的密码无效", 3, "2222", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyPrintPassword(t, s, tt.deviceID, tt.password); got != tt.valid {
+				t.Errorf("device %d password %s: valid = %v, want %v", tt.deviceID, tt.password, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMigrateDevicePasswordsKeepsLegacyValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		legacy     string
+		existing   string // 迁移前已有的设备级密码
+		accept     string
+		reject     string
+		needsReset bool
+	}{
+		{"符合规则的旧密码", "5678", "", "5678", legacyDefaultPrintPassword, false},
+		{"不符合规则的旧密码按原值保留", "12a", "", "12a", legacyDefaultPrintPassword, true},
+		{"过长的旧密码按原值保留", "12345678901234567", "", "12345678901234567", legacyDefaultPrintPassword, true},
+		{"已有设备级密码时不覆盖", "12a", "4321", "4321", "12a", false},
+		{"旧默认值使用全局密码", legacyDefaultPrintPassword, "", legacyDefaultPrintPassword, "12a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestPrintPasswordService(t)
+			if _, _, err := s.Set(field.PrintPasswordScopeGlobal, 0, legacyDefaultPrintPassword, nil, "system"); err != nil {
+				t.Fatalf("set global: %v", err)
+			}
+			if tt.existing != "" {
+				if _, _, err := s.Set(field.PrintPasswordScopeDevice, 1, tt.existing, nil, "admin@example.com"); err != nil {
+					t.Fatalf("set device: %v", err)
+				}
+			}
+			db.Model(&models.Device{}).Where("id = ?", 1).Update("print_pass_word", tt.legacy)
+
+			if err := s.migrateDevicePasswords(); err != nil {
+				t.Fatalf("migrate: %v", err)
+			}
+
+			if !verifyPrintPassword(t, s, 1, tt.accept) {
+				t.Errorf("password %s should be accepted", tt.accept)
+			}
+			if verifyPrintPassword(t, s, 1, tt.reject) {
+				t.Errorf("password %s should be rejected", tt.reject)
+			}
+
+			var device models.Device
+			db.First(&device, 1)
+			if device.Settings.PrintPassWord != "" {
+				t.Errorf("legacy column = %q, want cleared", device.Settings.PrintPassWord)
+			}
+			var record models.PrintPassword
+			if err := db.Where("scope = ? AND target_id = ?", field.PrintPasswordScopeDevice, 1).First(&record).Error; err == nil {
+				if record.NeedsReset != tt.needsReset {
+					t.Errorf("needsReset = %v, want %v", record.NeedsReset, tt.needsReset)
+				}
+			} else if tt.legacy != legacyDefaultPrintPassword {
+				t.Errorf("device password not migrated: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrateGroupPasswordToDevices(t *testing.T) {
+	s, db := newTestPrintPasswordService(t)
+	groupID := uint(7)
+	db.Model(&models.Device{}).Where("id IN ?", []uint{1, 2}).Update("device_group_id", groupID)
+	if _, _, err := s.Set(field.PrintPasswordScopeGlobal, 0, "1111", nil, "system"); err != nil {
+		t.Fatalf("set global: %v", err)
+	}
+	if _, _, err := s.Set(field.PrintPasswordScopeDevice, 2, "4321", nil, "admin@example.com"); err != nil {
+		t.Fatalf("set device: %v", err)
+	}
+
+	if err := s.migrateGroupPassword(groupID, "99"); err != nil {
+		t.Fatalf("migrate group: %v", err)
+	}
+
+	if !verifyPrintPassword(t, s, 1, "99") {
+		t.Error("group member without device password should use the group password")
+	}
+	if !verifyPrintPassword(t, s, 2, "4321") {
+		t.Error("existing device password should be kept")
+	}
+	if !verifyPrintPassword(t, s, 3, "1111") {
+		t.Error("device outside the group should keep the global password")
+	}
+}
+
+func TestSetPrintPasswordVersion(t *testing.T) {
+	s, db := newTestPrintPasswordService(t)
+
+	record, _, err := s.Set(field.PrintPasswordScopeBuilding, 1, "2222", nil, "admin@example.com")
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if record.Version != 1 {
+		t.Fatalf("version = %d, want 1", record.Version)
+	}
+	record, _, err = s.Set(field.PrintPasswordScopeBuilding, 1, "3333", nil, "admin@example.com")
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if record.Version != 2 {
+		t.Fatalf("version = %d, want 2", record.Version)
+	}
+
+	// 在读取记录之后、写入之前模拟另一个请求完成了修改
+	raced := false
+	db.Callback().Update().Before("gorm:update").Register("test:concurrent_set", func(tx *gorm.DB) {
+		if raced {
+			return
+		}
+		raced = true
+		tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+			Exec("UPDATE print_passwords SET version = version + 1 WHERE id = ?", record.ID)
+	})
+
+	if _, _, err := s.Set(field.PrintPasswordScopeBuilding, 1, "4444", nil, "other@example.com"); !errors.Is(err, ErrPrintPasswordConflict) {
+		t.Fatalf("err = %v, want ErrPrintPasswordConflict", err)
+	}
+	var stored models.PrintPassword
+	db.First(&stored, record.ID)
+	if stored.Version != 3 || stored.UpdatedBy != "admin@example.com" {
+		t.Errorf("stored version %d by %s, want the concurrent write (3) to be kept", stored.Version, stored.UpdatedBy)
+	}
+	if verifyPrintPassword(t, s, 1, "4444") {
+		t.Error("conflicting password must not be saved")
+	}
+}
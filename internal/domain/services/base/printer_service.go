@@ -58,6 +58,7 @@ func (s *PrinterService) Get(query map[string]interface{}, paginate map[string]i
 	if err := db.Limit(pageSize).Offset(offset).Find(&printers).Error; err != nil {
 		return nil, models.PaginationResult{}, err
 	}
+	FillPrinterMarkers(printers)
 
 	return printers, models.PaginationResult{
 		Total:    int(total),
@@ -95,6 +96,7 @@ func (s *PrinterService) GetByID(id uint) (*models.Printer, error) {
 	if err := s.db.First(&printer, id).Error; err != nil {
 		return nil, err
 	}
+	printer.Markers = ParsePrinterMarkers(printer)
 	return &printer, nil
 }
 
@@ -104,6 +106,7 @@ func (s *PrinterService) GetByDeviceID(deviceID uint) ([]models.Printer, error)
 	if err := s.db.Where("device_id = ?", deviceID).Find(&printers).Error; err != nil {
 		return nil, err
 	}
+	FillPrinterMarkers(printers)
 	return printers, nil
 }
 
@@ -128,6 +131,12 @@ func (s *PrinterService) BatchUpdateOrCreate(deviceID uint, printers []models.Pr
 				if err := tx.Create(&printer).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterSupplyLevels(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
+				if err := recordPrinterStatusChange(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
 			} else {
 				// 更新现有打印机
 				updates := map[string]interface{}{}
@@ -155,10 +164,28 @@ func (s *PrinterService) BatchUpdateOrCreate(deviceID uint, printers []models.Pr
 				if printer.MarkerLevels != nil {
 					updates["marker_levels"] = printer.MarkerLevels
 				}
+				if printer.MarkerNames != nil {
+					updates["marker_names"] = printer.MarkerNames
+				}
+				if printer.MarkerColors != nil {
+					updates["marker_colors"] = printer.MarkerColors
+				}
 
+				previousLevels := existing.MarkerLevels
+				previousStatus := existing.Status
 				if err := tx.Model(&existing).Updates(updates).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterSupplyLevels(tx, existing.ID, previousLevels, printer); err != nil {
+					return err
+				}
+				// 按名称匹配到的打印机沿用原有 IP
+				if printer.IPAddress == nil {
+					printer.IPAddress = existing.IPAddress
+				}
+				if err := recordPrinterStatusChange(tx, existing.ID, previousStatus, printer); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
@@ -193,9 +220,11 @@ func (s *PrinterService) SyncPrinters(deviceID uint, printers []models.Printer)
 
 		// 3. 找出要删除的打印机（数据库中有，但新列表中没有）
 		var toDelete []uint
+		var removed []models.Printer
 		for ip, existing := range existingMap {
 			if _, found := newMap[ip]; !found {
 				toDelete = append(toDelete, existing.ID)
+				removed = append(removed, existing)
 			}
 		}
 
@@ -204,6 +233,11 @@ func (s *PrinterService) SyncPrinters(deviceID uint, printers []models.Printer)
 			if err := tx.Delete(&models.Printer{}, toDelete).Error; err != nil {
 				return err
 			}
+			for _, printer := range removed {
+				if err := recordPrinterRemoved(tx, printer); err != nil {
+					return err
+				}
+			}
 		}
 
 		// 5. 添加或更新打印机
@@ -238,15 +272,35 @@ func (s *PrinterService) SyncPrinters(deviceID uint, printers []models.Printer)
 				if printer.MarkerLevels != nil {
 					updates["marker_levels"] = printer.MarkerLevels
 				}
+				if printer.MarkerNames != nil {
+					updates["marker_names"] = printer.MarkerNames
+				}
+				if printer.MarkerColors != nil {
+					updates["marker_colors"] = printer.MarkerColors
+				}
 
+				previousLevels := existing.MarkerLevels
+				previousStatus := existing.Status
 				if err := tx.Model(&existing).Updates(updates).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterSupplyLevels(tx, existing.ID, previousLevels, printer); err != nil {
+					return err
+				}
+				if err := recordPrinterStatusChange(tx, existing.ID, previousStatus, printer); err != nil {
+					return err
+				}
 			} else {
 				// 创建新打印机
 				if err := tx.Create(&printer).Error; err != nil {
 					return err
 				}
+				if err := recordPrinterSupplyLevels(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
+				if err := recordPrinterStatusChange(tx, printer.ID, nil, printer); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -0,0 +1,372 @@
+package base_services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+// getPrinterFlapWindow returns the flapping detection window from environment variables
+func getPrinterFlapWindow() time.Duration {
+	minutes := os.Getenv("PRINTER_FLAP_WINDOW_MINUTES")
+	if minutes == "" {
+		return time.Hour
+	}
+
+	n, err := strconv.Atoi(minutes)
+	if err != nil || n <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration(n) * time.Minute
+}
+
+// getPrinterFlapThreshold returns the number of status changes within the window that counts as flapping
+func getPrinterFlapThreshold() int64 {
+	threshold := os.Getenv("PRINTER_FLAP_THRESHOLD")
+	if threshold == "" {
+		return 4
+	}
+
+	n, err := strconv.ParseInt(threshold, 10, 64)
+	if err != nil || n <= 0 {
+		return 4
+	}
+
+	return n
+}
+
+// PrinterTimeline 单台打印机的状态时间线
+type PrinterTimeline struct {
+	PrinterID      uint                        `json:"printerId"`
+	DeviceID       uint                        `json:"deviceId"`
+	IPAddress      string                      `json:"ipAddress"`
+	From           time.Time                   `json:"from"`
+	To             time.Time                   `json:"to"`
+	Changes        int                         `json:"changes"`        // 时间范围内的状态变化次数
+	OfflineCount   int                         `json:"offlineCount"`   // 离线次数
+	OfflineSeconds int64                       `json:"offlineSeconds"` // 离线（含 removed）累计时长
+	OfflineReasons map[string]int              `json:"offlineReasons"` // 离线原因及次数
+	Flapping       bool                        `json:"flapping"`       // 最近检测窗口内是否频繁抖动
+	Events         []models.PrinterStatusEvent `json:"events"`
+}
+
+// PrinterFlappingItem 频繁抖动的打印机
+type PrinterFlappingItem struct {
+	PrinterID     *uint     `json:"printerId,omitempty"` // 打印机已被移除时为空
+	PrinterName   string    `json:"printerName"`
+	IPAddress     string    `json:"ipAddress"`
+	CurrentStatus string    `json:"currentStatus"`
+	DeviceID      uint      `json:"deviceId"`
+	DeviceCode    string    `json:"deviceCode"`
+	BuildingID    uint      `json:"buildingId"`
+	BuildingName  string    `json:"buildingName"`
+	Changes       int64     `json:"changes"`
+	OfflineCount  int64     `json:"offlineCount"`
+	LastChangeAt  time.Time `json:"lastChangeAt"`
+}
+
+// InterfacePrinterStatusService 打印机状态历史服务接口
+type InterfacePrinterStatusService interface {
+	// 单台打印机的状态时间线
+	GetTimeline(printerID uint, from, to time.Time) (*PrinterTimeline, error)
+	// window 内状态变化次数不少于 threshold 的打印机，参数为 0 时使用默认值
+	GetFlapping(window time.Duration, threshold int64, buildingID uint) ([]PrinterFlappingItem, error)
+}
+
+// PrinterStatusService 打印机状态历史服务实现
+type PrinterStatusService struct {
+	db *gorm.DB
+}
+
+// NewPrinterStatusService 创建打印机状态历史服务
+func NewPrinterStatusService(db *gorm.DB) InterfacePrinterStatusService {
+	return &PrinterStatusService{db: db}
+}
+
+// recordPrinterStatusChange 打印机 Status 与上次不同时写入状态变化记录
+// previous 为空表示打印机记录是新建的，此时以该设备同一打印机最近一条状态记录作为上次状态
+func recordPrinterStatusChange(tx *gorm.DB, printerID uint, previous *string, printer models.Printer) error {
+	if printer.Status == nil || *printer.Status == "" || printer.DeviceID == nil || printer.IPAddress == nil {
+		return nil
+	}
+	if previous == nil {
+		last, err := lastPrinterStatus(tx, printer)
+		if err != nil {
+			return err
+		}
+		previous = last
+	}
+	if previous != nil && *previous == *printer.Status {
+		return nil
+	}
+
+	event := models.PrinterStatusEvent{
+		PrinterID:   &printerID,
+		DeviceID:    *printer.DeviceID,
+		IPAddress:   *printer.IPAddress,
+		PrinterName: printerEventName(printer),
+		Status:      field.PrinterStatus(*printer.Status),
+		OccurredAt:  time.Now(),
+	}
+	if previous != nil {
+		event.PreviousStatus = field.PrinterStatus(*previous)
+	}
+	if printer.State != nil {
+		event.State = *printer.State
+	}
+	if printer.Reason != nil {
+		event.Reason = *printer.Reason
+	}
+	return createPrinterStatusEvent(tx, &event)
+}
+
+// recordPrinterRemoved 打印机从上报列表中消失时记录 removed
+func recordPrinterRemoved(tx *gorm.DB, printer models.Printer) error {
+	if printer.DeviceID == nil || printer.IPAddress == nil {
+		return nil
+	}
+
+	event := models.PrinterStatusEvent{
+		PrinterID:   &printer.ID,
+		DeviceID:    *printer.DeviceID,
+		IPAddress:   *printer.IPAddress,
+		PrinterName: printerEventName(printer),
+		Status:      field.PrinterStatusRemoved,
+		OccurredAt:  time.Now(),
+	}
+	if printer.Status != nil {
+		event.PreviousStatus = field.PrinterStatus(*printer.Status)
+	}
+	return createPrinterStatusEvent(tx, &event)
+}
+
+// lastPrinterStatus 该设备同一打印机（IP 或名称相同）最近一条状态记录的状态，没有记录时返回 nil
+// 打印机被删除后重新上报会新建记录，沿用历史状态才能把重建后的第一次变化计入抖动统计
+func lastPrinterStatus(tx *gorm.DB, printer models.Printer) (*string, error) {
+	query := tx.Where("device_id = ?", *printer.DeviceID)
+	if name := printerEventName(printer); name != "" {
+		query = query.Where("ip_address = ? OR printer_name = ?", *printer.IPAddress, name)
+	} else {
+		query = query.Where("ip_address = ?", *printer.IPAddress)
+	}
+
+	var last models.PrinterStatusEvent
+	if err := query.Order("occurred_at DESC, id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, fmt.Errorf("failed to load last printer status: %v", err)
+	}
+	if last.ID == 0 {
+		return nil, nil
+	}
+	status := string(last.Status)
+	return &status, nil
+}
+
+func printerEventName(printer models.Printer) string {
+	if printer.Name == nil {
+		return ""
+	}
+	return *printer.Name
+}
+
+func createPrinterStatusEvent(tx *gorm.DB, event *models.PrinterStatusEvent) error {
+	var buildingIDs []uint
+	if err := tx.Model(&models.Device{}).Where("id = ?", event.DeviceID).Pluck("building_id", &buildingIDs).Error; err != nil {
+		return err
+	}
+	if len(buildingIDs) > 0 {
+		event.BuildingID = buildingIDs[0]
+	}
+
+	if err := tx.Create(event).Error; err != nil {
+		return err
+	}
+
+	log.Debug("打印机状态变化 | 设备ID: %d | 打印机IP: %s | %s -> %s", event.DeviceID, event.IPAddress, event.PreviousStatus, event.Status)
+	return nil
+}
+
+func (s *PrinterStatusService) GetTimeline(printerID uint, from, to time.Time) (*PrinterTimeline, error) {
+	var printer models.Printer
+	if err := s.db.First(&printer, printerID).Error; err != nil {
+		return nil, fmt.Errorf("printer not found: %v", err)
+	}
+	if printer.DeviceID == nil || printer.IPAddress == nil {
+		return nil, fmt.Errorf("printer has no device or ip address")
+	}
+
+	timeline := &PrinterTimeline{
+		PrinterID:      printer.ID,
+		DeviceID:       *printer.DeviceID,
+		IPAddress:      *printer.IPAddress,
+		From:           from,
+		To:             to,
+		OfflineReasons: map[string]int{},
+		Events:         []models.PrinterStatusEvent{},
+	}
+	target := s.db.Where("device_id = ? AND ip_address = ?", *printer.DeviceID, *printer.IPAddress)
+
+	if err := target.Session(&gorm.Session{}).Where("occurred_at >= ? AND occurred_at <= ?", from, to).
+		Order("occurred_at ASC, id ASC").Find(&timeline.Events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load printer status events: %v", err)
+	}
+
+	// 时间范围开始时的状态
+	var before models.PrinterStatusEvent
+	current := field.PrinterStatus("")
+	if err := target.Session(&gorm.Session{}).Where("occurred_at < ?", from).
+		Order("occurred_at DESC, id DESC").Limit(1).Find(&before).Error; err != nil {
+		return nil, fmt.Errorf("failed to load printer status events: %v", err)
+	}
+	if before.ID != 0 {
+		current = before.Status
+	}
+
+	cursor := from
+	for _, event := range timeline.Events {
+		if current == field.PrinterStatusOffline || current == field.PrinterStatusRemoved {
+			timeline.OfflineSeconds += int64(event.OccurredAt.Sub(cursor).Seconds())
+		}
+		if event.PreviousStatus != "" {
+			timeline.Changes++
+		}
+		if event.Status == field.PrinterStatusOffline {
+			timeline.OfflineCount++
+			reason := event.Reason
+			if reason == "" {
+				reason = "unknown"
+			}
+			timeline.OfflineReasons[reason]++
+		}
+		current = event.Status
+		cursor = event.OccurredAt
+	}
+	end := to
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	if (current == field.PrinterStatusOffline || current == field.PrinterStatusRemoved) && end.After(cursor) {
+		timeline.OfflineSeconds += int64(end.Sub(cursor).Seconds())
+	}
+
+	var recent int64
+	if err := target.Session(&gorm.Session{}).Model(&models.PrinterStatusEvent{}).
+		Where("occurred_at >= ? AND previous_status <> ''", time.Now().Add(-getPrinterFlapWindow())).
+		Count(&recent).Error; err != nil {
+		return nil, fmt.Errorf("failed to count printer status changes: %v", err)
+	}
+	timeline.Flapping = recent >= getPrinterFlapThreshold()
+
+	return timeline, nil
+}
+
+func (s *PrinterStatusService) GetFlapping(window time.Duration, threshold int64, buildingID uint) ([]PrinterFlappingItem, error) {
+	return findFlappingPrinters(s.db, window, threshold, buildingID)
+}
+
+// findFlappingPrinters 统计 window 内状态变化次数不少于 threshold 的打印机（按设备与 IP 分组）
+func findFlappingPrinters(db *gorm.DB, window time.Duration, threshold int64, buildingID uint) ([]PrinterFlappingItem, error) {
+	if window <= 0 {
+		window = getPrinterFlapWindow()
+	}
+	if threshold <= 0 {
+		threshold = getPrinterFlapThreshold()
+	}
+
+	var rows []struct {
+		DeviceID     uint
+		BuildingID   uint
+		IPAddress    string
+		Changes      int64
+		OfflineCount int64
+		LastChangeAt time.Time
+	}
+	query := db.Model(&models.PrinterStatusEvent{}).
+		Select("device_id, MAX(building_id) AS building_id, ip_address, COUNT(*) AS changes, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS offline_count, MAX(occurred_at) AS last_change_at",
+			field.PrinterStatusOffline).
+		Where("occurred_at >= ? AND previous_status <> ''", time.Now().Add(-window))
+	if buildingID != 0 {
+		query = query.Where("building_id = ?", buildingID)
+	}
+	if err := query.Group("device_id, ip_address").
+		Having("COUNT(*) >= ?", threshold).
+		Order("changes DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count printer status changes: %v", err)
+	}
+
+	items := make([]PrinterFlappingItem, 0, len(rows))
+	if len(rows) == 0 {
+		return items, nil
+	}
+
+	deviceIDs := make([]uint, 0, len(rows))
+	buildingIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		deviceIDs = append(deviceIDs, row.DeviceID)
+		buildingIDs = append(buildingIDs, row.BuildingID)
+	}
+
+	var printers []models.Printer
+	if err := db.Where("device_id IN ?", deviceIDs).Find(&printers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load printers: %v", err)
+	}
+	printerByTarget := make(map[string]models.Printer, len(printers))
+	for _, printer := range printers {
+		if printer.DeviceID != nil && printer.IPAddress != nil {
+			printerByTarget[fmt.Sprintf("%d:%s", *printer.DeviceID, *printer.IPAddress)] = printer
+		}
+	}
+
+	var devices []models.Device
+	if err := db.Select("id", "device_id").Where("id IN ?", deviceIDs).Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to load devices: %v", err)
+	}
+	deviceCodes := make(map[uint]string, len(devices))
+	for _, device := range devices {
+		deviceCodes[device.ID] = device.DeviceID
+	}
+
+	var buildings []models.Building
+	if err := db.Select("id", "name").Where("id IN ?", buildingIDs).Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load buildings: %v", err)
+	}
+	buildingNames := make(map[uint]string, len(buildings))
+	for _, building := range buildings {
+		buildingNames[building.ID] = building.Name
+	}
+
+	for _, row := range rows {
+		item := PrinterFlappingItem{
+			PrinterName:   row.IPAddress,
+			IPAddress:     row.IPAddress,
+			CurrentStatus: string(field.PrinterStatusRemoved),
+			DeviceID:      row.DeviceID,
+			DeviceCode:    deviceCodes[row.DeviceID],
+			BuildingID:    row.BuildingID,
+			BuildingName:  buildingNames[row.BuildingID],
+			Changes:       row.Changes,
+			OfflineCount:  row.OfflineCount,
+			LastChangeAt:  row.LastChangeAt,
+		}
+		if printer, ok := printerByTarget[fmt.Sprintf("%d:%s", row.DeviceID, row.IPAddress)]; ok {
+			printerID := printer.ID
+			item.PrinterID = &printerID
+			item.PrinterName = printerDisplayName(printer)
+			item.CurrentStatus = ""
+			if printer.Status != nil {
+				item.CurrentStatus = *printer.Status
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
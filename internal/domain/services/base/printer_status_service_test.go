@@ -0,0 +1,117 @@
+package base_services
+
+import (
+	"testing"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"gorm.io/gorm"
+)
+
+func TestPrinterStatusAfterRecreate(t *testing.T) {
+	strPtr := func(v string) *string { return &v }
+	report := func(ip, name, status string) []models.Printer {
+		return []models.Printer{{IPAddress: strPtr(ip), Name: strPtr(name), Status: strPtr(status)}}
+	}
+
+	tests := []struct {
+		name    string
+		reports [][]models.Printer
+		after   func(db *gorm.DB) // 第一次上报后执行
+		want    []string          // 各条记录的 "previousStatus->status"
+		changes int64
+	}{
+		{
+			name:    "首次上报不计入变化",
+			reports: [][]models.Printer{report("10.0.0.1", "hp", "online")},
+			want:    []string{"->online"},
+		},
+		{
+			name: "消失后以相同 IP 重新上报沿用 removed",
+			reports: [][]models.Printer{
+				report("10.0.0.1", "hp", "online"),
+				{},
+				report("10.0.0.1", "hp", "online"),
+			},
+			want:    []string{"->online", "online->removed", "removed->online"},
+			changes: 2,
+		},
+		{
+			name: "消失后以相同名称、新 IP 重新上报",
+			reports: [][]models.Printer{
+				report("10.0.0.1", "hp", "offline"),
+				{},
+				report("10.0.0.2", "hp", "online"),
+			},
+			want:    []string{"->offline", "offline->removed", "removed->online"},
+			changes: 2,
+		},
+		{
+			name: "其他打印机的历史不影响新打印机",
+			reports: [][]models.Printer{
+				report("10.0.0.1", "hp", "online"),
+				report("10.0.0.2", "canon", "offline"),
+				report("10.0.0.1", "hp", "online"),
+			},
+			want:    []string{"->online", "online->removed", "->offline", "offline->removed", "removed->online"},
+			changes: 3,
+		},
+		{
+			name: "直接删除后重建且状态未变不写入",
+			reports: [][]models.Printer{
+				report("10.0.0.1", "hp", "online"),
+				report("10.0.0.1", "hp", "online"),
+			},
+			after: func(db *gorm.DB) {
+				db.Where("1 = 1").Delete(&models.Printer{})
+			},
+			want: []string{"->online"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.Device{}, &models.Printer{}, &models.PrinterStatusEvent{}, &models.PrinterSupplySample{})
+			device := models.Device{DeviceID: "d1", BuildingID: 1}
+			if err := db.Create(&device).Error; err != nil {
+				t.Fatalf("create device: %v", err)
+			}
+			s := NewPrinterService(db)
+			for i, printers := range tt.reports {
+				if err := s.SyncPrinters(device.ID, printers); err != nil {
+					t.Fatalf("sync %d: %v", i, err)
+				}
+				if i == 0 && tt.after != nil {
+					tt.after(db)
+				}
+			}
+
+			var events []models.PrinterStatusEvent
+			db.Order("id ASC").Find(&events)
+			var got []string
+			for _, event := range events {
+				got = append(got, string(event.PreviousStatus)+"->"+string(event.Status))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("events = %v, want %v", got, tt.want)
+				}
+			}
+
+			var changes int64
+			db.Model(&models.PrinterStatusEvent{}).Where("previous_status <> ''").Count(&changes)
+			if changes != tt.changes {
+				t.Errorf("changes = %d, want %d", changes, tt.changes)
+			}
+			if tt.changes > 0 {
+				var last models.PrinterStatusEvent
+				db.Last(&last)
+				if last.PreviousStatus != field.PrinterStatusRemoved {
+					t.Errorf("re-created printer previous status = %q, want removed", last.PreviousStatus)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package base_services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"gorm.io/gorm"
+)
+
+// getPrinterSupplyWindow returns the window used to compute consumption rate
+func getPrinterSupplyWindow() time.Duration {
+	days := os.Getenv("PRINTER_SUPPLY_WINDOW_DAYS")
+	if days == "" {
+		return 30 * 24 * time.Hour
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n <= 0 {
+		return 30 * 24 * time.Hour
+	}
+
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// getPrinterSupplyRetention returns how long supply history is kept
+func getPrinterSupplyRetention() time.Duration {
+	days := os.Getenv("PRINTER_SUPPLY_RETENTION_DAYS")
+	if days == "" {
+		return 180 * 24 * time.Hour
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n <= 0 {
+		return 180 * 24 * time.Hour
+	}
+
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// 按墨盒名称推断颜色
+var markerNameColors = []struct {
+	keyword string
+	color   string
+}{
+	{"black", "#000000"},
+	{"cyan", "#00FFFF"},
+	{"magenta", "#FF00FF"},
+	{"yellow", "#FFFF00"},
+	{"黑", "#000000"},
+	{"青", "#00FFFF"},
+	{"品红", "#FF00FF"},
+	{"黄", "#FFFF00"},
+}
+
+func splitMarkerField(value *string) []string {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil
+	}
+	parts := strings.Split(*value, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// ParsePrinterMarkers 将 marker_levels/marker_names/marker_colors 解析为墨盒列表，
+// 负数墨量（CUPS 中表示未知）解析为 null
+func ParsePrinterMarkers(printer models.Printer) []models.PrinterMarker {
+	levels := splitMarkerField(printer.MarkerLevels)
+	if len(levels) == 0 {
+		return nil
+	}
+	names := splitMarkerField(printer.MarkerNames)
+	colors := splitMarkerField(printer.MarkerColors)
+
+	markers := make([]models.PrinterMarker, 0, len(levels))
+	for i, raw := range levels {
+		marker := models.PrinterMarker{
+			Index: i,
+			Name:  fmt.Sprintf("墨盒%d", i+1),
+		}
+		if i < len(names) && names[i] != "" {
+			marker.Name = names[i]
+		}
+		if i < len(colors) && colors[i] != "" {
+			marker.Color = colors[i]
+		} else {
+			lower := strings.ToLower(marker.Name)
+			for _, c := range markerNameColors {
+				if strings.Contains(lower, c.keyword) {
+					marker.Color = c.color
+					break
+				}
+			}
+		}
+		if level, err := strconv.Atoi(raw); err == nil && level >= 0 {
+			marker.Level = &level
+		}
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
+// FillPrinterMarkers 为打印机列表填充解析后的墨盒信息
+func FillPrinterMarkers(printers []models.Printer) {
+	for i := range printers {
+		printers[i].Markers = ParsePrinterMarkers(printers[i])
+	}
+}
+
+// recordPrinterSupplyLevels 墨量与上次不同时写入历史
+func recordPrinterSupplyLevels(tx *gorm.DB, printerID uint, previous *string, printer models.Printer) error {
+	if printer.MarkerLevels == nil {
+		return nil
+	}
+	if previous != nil && strings.ReplaceAll(*previous, " ", "") == strings.ReplaceAll(*printer.MarkerLevels, " ", "") {
+		return nil
+	}
+
+	now := time.Now()
+	samples := make([]models.PrinterSupplySample, 0)
+	for _, marker := range ParsePrinterMarkers(printer) {
+		if marker.Level == nil {
+			continue
+		}
+		samples = append(samples, models.PrinterSupplySample{
+			PrinterID:   printerID,
+			MarkerIndex: marker.Index,
+			Name:        marker.Name,
+			Color:       marker.Color,
+			Level:       *marker.Level,
+			RecordedAt:  now,
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	if err := tx.Create(&samples).Error; err != nil {
+		return err
+	}
+	return tx.Where("printer_id = ? AND recorded_at < ?", printerID, now.Add(-getPrinterSupplyRetention())).
+		Delete(&models.PrinterSupplySample{}).Error
+}
+
+// forecastMarkers 根据最近一次换墨盒之后的历史计算每日消耗与剩余天数
+func forecastMarkers(markers []models.PrinterMarker, samples []models.PrinterSupplySample) {
+	byMarker := make(map[int][]models.PrinterSupplySample)
+	for _, sample := range samples {
+		byMarker[sample.MarkerIndex] = append(byMarker[sample.MarkerIndex], sample)
+	}
+
+	for i := range markers {
+		history := byMarker[markers[i].Index]
+		// 墨量上升视为更换墨盒，只使用之后的记录
+		start := 0
+		for j := 1; j < len(history); j++ {
+			if history[j].Level > history[j-1].Level {
+				start = j
+			}
+		}
+		history = history[start:]
+		if len(history) < 2 {
+			continue
+		}
+
+		first, last := history[0], history[len(history)-1]
+		days := last.RecordedAt.Sub(first.RecordedAt).Hours() / 24
+		if days < 1.0/24 || first.Level <= last.Level {
+			continue
+		}
+
+		usage := float64(first.Level-last.Level) / days
+		markers[i].DailyUsage = &usage
+		if markers[i].Level != nil {
+			remaining := float64(*markers[i].Level) / usage
+			markers[i].DaysUntilEmpty = &remaining
+		}
+	}
+}
+
+// PrinterSupplyReportItem 需要补充耗材的打印机
+type PrinterSupplyReportItem struct {
+	PrinterID    uint                   `json:"printerId"`
+	PrinterName  string                 `json:"printerName"`
+	IPAddress    *string                `json:"ipAddress,omitempty"`
+	Status       *string                `json:"status,omitempty"`
+	DeviceID     uint                   `json:"deviceId"`
+	DeviceCode   string                 `json:"deviceCode"`
+	BuildingID   uint                   `json:"buildingId"`
+	BuildingName string                 `json:"buildingName"`
+	Markers      []models.PrinterMarker `json:"markers"`
+	LowMarkers   []string               `json:"lowMarkers"` // 需要补充的墨盒名称
+}
+
+// InterfacePrinterSupplyService 打印机耗材服务接口
+type InterfacePrinterSupplyService interface {
+	// 单台打印机的墨盒信息（含预测）与历史
+	GetPrinterSupplies(printerID uint, from, to time.Time) ([]models.PrinterMarker, []models.PrinterSupplySample, error)
+	// 所有建筑中墨量低于 thresholdPercent 或预计 withinDays 天内耗尽的打印机
+	GetSuppliesReport(thresholdPercent float64, withinDays float64, buildingID uint) ([]PrinterSupplyReportItem, error)
+}
+
+// PrinterSupplyService 打印机耗材服务实现
+type PrinterSupplyService struct {
+	db *gorm.DB
+}
+
+// NewPrinterSupplyService 创建打印机耗材服务
+func NewPrinterSupplyService(db *gorm.DB) InterfacePrinterSupplyService {
+	return &PrinterSupplyService{db: db}
+}
+
+func (s *PrinterSupplyService) GetPrinterSupplies(printerID uint, from, to time.Time) ([]models.PrinterMarker, []models.PrinterSupplySample, error) {
+	var printer models.Printer
+	if err := s.db.First(&printer, printerID).Error; err != nil {
+		return nil, nil, fmt.Errorf("printer not found: %v", err)
+	}
+
+	var recent []models.PrinterSupplySample
+	if err := s.db.Where("printer_id = ? AND recorded_at >= ?", printerID, time.Now().Add(-getPrinterSupplyWindow())).
+		Order("recorded_at ASC, id ASC").Find(&recent).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load supply history: %v", err)
+	}
+	markers := ParsePrinterMarkers(printer)
+	forecastMarkers(markers, recent)
+
+	var history []models.PrinterSupplySample
+	if err := s.db.Where("printer_id = ? AND recorded_at >= ? AND recorded_at <= ?", printerID, from, to).
+		Order("recorded_at ASC, id ASC").Find(&history).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load supply history: %v", err)
+	}
+
+	return markers, history, nil
+}
+
+func (s *PrinterSupplyService) GetSuppliesReport(thresholdPercent float64, withinDays float64, buildingID uint) ([]PrinterSupplyReportItem, error) {
+	type printerRow struct {
+		models.Printer
+		DeviceCode   string
+		BuildingID   uint
+		BuildingName string
+	}
+	var rows []printerRow
+	db := s.db.Model(&models.Printer{}).
+		Select("printers.*, devices.device_id AS device_code, devices.building_id AS building_id, buildings.name AS building_name").
+		Joins("JOIN devices ON devices.id = printers.device_id").
+		Joins("LEFT JOIN buildings ON buildings.id = devices.building_id").
+		Where("printers.marker_levels IS NOT NULL AND printers.marker_levels <> ''")
+	if buildingID != 0 {
+		db = db.Where("devices.building_id = ?", buildingID)
+	}
+	if err := db.Order("devices.building_id ASC, printers.id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load printers: %v", err)
+	}
+	if len(rows) == 0 {
+		return []PrinterSupplyReportItem{}, nil
+	}
+
+	printerIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		printerIDs = append(printerIDs, row.ID)
+	}
+	var samples []models.PrinterSupplySample
+	if err := s.db.Where("printer_id IN ? AND recorded_at >= ?", printerIDs, time.Now().Add(-getPrinterSupplyWindow())).
+		Order("recorded_at ASC, id ASC").Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to load supply history: %v", err)
+	}
+	samplesByPrinter := make(map[uint][]models.PrinterSupplySample)
+	for _, sample := range samples {
+		samplesByPrinter[sample.PrinterID] = append(samplesByPrinter[sample.PrinterID], sample)
+	}
+
+	items := make([]PrinterSupplyReportItem, 0)
+	for _, row := range rows {
+		markers := ParsePrinterMarkers(row.Printer)
+		forecastMarkers(markers, samplesByPrinter[row.ID])
+
+		var low []string
+		for _, marker := range markers {
+			lowLevel := marker.Level != nil && float64(*marker.Level) <= thresholdPercent
+			runningOut := marker.DaysUntilEmpty != nil && *marker.DaysUntilEmpty <= withinDays
+			if lowLevel || runningOut {
+				low = append(low, marker.Name)
+			}
+		}
+		if len(low) == 0 {
+			continue
+		}
+
+		items = append(items, PrinterSupplyReportItem{
+			PrinterID:    row.ID,
+			PrinterName:  printerDisplayName(row.Printer),
+			IPAddress:    row.IPAddress,
+			Status:       row.Status,
+			DeviceID:     *row.DeviceID,
+			DeviceCode:   row.DeviceCode,
+			BuildingID:   row.BuildingID,
+			BuildingName: row.BuildingName,
+			Markers:      markers,
+			LowMarkers:   low,
+		})
+	}
+
+	log.Debug("耗材报表 | 打印机总数: %d | 需补充: %d", len(rows), len(items))
+	return items, nil
+}
@@ -0,0 +1,193 @@
+package base_services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// 只有持有者才能续期、释放租约
+var (
+	leaseRenewScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+		end
+		return 0`)
+	leaseReleaseScript = redis.NewScript(`
+		if redis.call("GET", KEYS[1]) == ARGV[1] then
+			return redis.call("DEL", KEYS[1])
+		end
+		return 0`)
+)
+
+// instanceID 当前服务进程的标识，作为租约的持有者
+var instanceID = func() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8])
+}()
+
+// getSchedulerLeaseTTL returns the leader lease ttl of background schedulers, renewed while the holder is alive
+func getSchedulerLeaseTTL() time.Duration {
+	ttl := os.Getenv("SCHEDULER_LEASE_TTL")
+	if ttl == "" {
+		return 60 * time.Second // default to 60 seconds if not set
+	}
+
+	ttlInt, err := strconv.Atoi(ttl)
+	if err != nil || ttlInt < 3 {
+		return 60 * time.Second // default to 60 seconds if invalid value
+	}
+
+	return time.Duration(ttlInt) * time.Second
+}
+
+// redisLease 基于 Redis 的可续期租约，持有期间每 ttl/3 自动续期，进程退出后最多 ttl 过期
+type redisLease struct {
+	client *redis.Client
+	key    string
+	token  string
+	ttl    time.Duration
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+	lost   bool
+}
+
+// acquireLease 尝试获取租约，已被其他持有者占用时返回 nil, nil
+func acquireLease(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (*redisLease, error) {
+	token := instanceID + "-" + uuid.New().String()[:8]
+	ok, err := client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lease %s: %v", key, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	lease := &redisLease{
+		client: client,
+		key:    key,
+		token:  token,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go lease.renew()
+	return lease, nil
+}
+
+// leaseHolder 获取租约当前的持有者
+func leaseHolder(ctx context.Context, client *redis.Client, key string) string {
+	holder, err := client.Get(ctx, key).Result()
+	if err != nil {
+		return ""
+	}
+	return holder
+}
+
+func (l *redisLease) renew() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewed, err := leaseRenewScript.Run(context.Background(), l.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+			if err != nil {
+				// Redis 暂时不可用时继续尝试，租约过期前恢复即可保持
+				log.Warn("租约续期失败 | 键: %s | 错误: %v", l.key, err)
+				continue
+			}
+			if renewed == 0 {
+				log.Warn("租约已失效 | 键: %s", l.key)
+				l.mu.Lock()
+				l.lost = true
+				l.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Lost 租约是否已过期或被其他持有者取得
+func (l *redisLease) Lost() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// Release 停止续期并释放租约
+func (l *redisLease) Release() {
+	l.once.Do(func() {
+		close(l.stop)
+		<-l.done
+		if _, err := leaseReleaseScript.Run(context.Background(), l.client, []string{l.key}, l.token).Result(); err != nil && err != redis.Nil {
+			log.Warn("释放租约失败 | 键: %s | 错误: %v", l.key, err)
+		}
+	})
+}
+
+// schedulerLeader 后台定时任务的主实例身份，多实例部署时只有持有租约的实例执行任务
+// 只在调度器自身的协程中使用；失去租约后在下一次检查时重新竞选
+type schedulerLeader struct {
+	client *redis.Client
+	key    string
+	name   string
+	lease  *redisLease
+
+	onElected func(ctx context.Context) // 成为主实例时调用，可为空
+}
+
+func newSchedulerLeader(client *redis.Client, key, name string) *schedulerLeader {
+	return &schedulerLeader{client: client, key: key, name: name}
+}
+
+// IsLeader 当前实例是否为主实例，不是时尝试竞选
+func (l *schedulerLeader) IsLeader(ctx context.Context) bool {
+	if l.lease != nil && !l.lease.Lost() {
+		return true
+	}
+	if l.lease != nil {
+		l.lease.Release()
+		l.lease = nil
+		log.Warn("失去%s主实例身份 | 实例: %s", l.name, instanceID)
+	}
+
+	lease, err := acquireLease(ctx, l.client, l.key, getSchedulerLeaseTTL())
+	if err != nil {
+		log.Warn("竞选%s主实例失败 | 错误: %v", l.name, err)
+		return false
+	}
+	if lease == nil {
+		log.Debug("非主实例，跳过%s | 主实例: %s", l.name, leaseHolder(ctx, l.client, l.key))
+		return false
+	}
+	l.lease = lease
+	log.Info("成为%s主实例 | 实例: %s", l.name, instanceID)
+	if l.onElected != nil {
+		l.onElected(ctx)
+	}
+	return true
+}
+
+// Release 释放主实例租约，其他实例可立即接替
+func (l *schedulerLeader) Release() {
+	if l.lease != nil {
+		l.lease.Release()
+		l.lease = nil
+	}
+}
@@ -0,0 +1,72 @@
+package base_services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestSchedulerLeaderElection(t *testing.T) {
+	t.Setenv("SCHEDULER_LEASE_TTL", "3")
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	const key = "test:scheduler:leader"
+
+	elected := map[string]int{}
+	newLeader := func(name string) *schedulerLeader {
+		leader := newSchedulerLeader(client, key, name)
+		leader.onElected = func(context.Context) { elected[name]++ }
+		return leader
+	}
+	a, b := newLeader("a"), newLeader("b")
+	defer a.Release()
+	defer b.Release()
+
+	steps := []struct {
+		name    string
+		before  func()
+		leader  *schedulerLeader
+		want    bool
+		elected map[string]int
+	}{
+		{"首个实例成为主实例", nil, a, true, map[string]int{"a": 1}},
+		{"其他实例不能成为主实例", nil, b, false, map[string]int{"a": 1}},
+		{"主实例持续持有，不重复触发", nil, a, true, map[string]int{"a": 1}},
+		{"租约被其他持有者取得后失去主实例身份", func() {
+			mr.Set(key, "other")
+			waitFor(t, func() bool { return a.lease.Lost() })
+		}, a, false, map[string]int{"a": 1}},
+		{"租约过期后其他实例接替", func() { mr.Del(key) }, b, true, map[string]int{"a": 1, "b": 1}},
+		{"主实例释放后原实例重新当选", func() { b.Release() }, a, true, map[string]int{"a": 2, "b": 1}},
+	}
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		if got := step.leader.IsLeader(ctx); got != step.want {
+			t.Fatalf("%s: IsLeader = %v, want %v", step.name, got, step.want)
+		}
+		for name, count := range step.elected {
+			if elected[name] != count {
+				t.Fatalf("%s: %s elected %d times, want %d", step.name, name, elected[name], count)
+			}
+		}
+	}
+}
+
+// waitFor 等待条件成立，最多 5 秒
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,466 @@
+package base_services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/httpclient"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 同步失败通知详情的最大保存条数
+const maxSyncRunFailedNotices = 200
+
+// 同步冲突详情的最大保存条数
+const maxSyncRunConflicts = 200
+
+// getSyncFailureThreshold 连续失败多少次时标记建筑
+func getSyncFailureThreshold() int {
+	threshold := os.Getenv("NOTICE_SYNC_FAILURE_THRESHOLD")
+	if threshold == "" {
+		return 3 // default to 3 if not set
+	}
+
+	thresholdInt, err := strconv.Atoi(threshold)
+	if err != nil || thresholdInt <= 0 {
+		return 3 // default to 3 if invalid value
+	}
+
+	return thresholdInt
+}
+
+// 清理同步记录时每批删除的条数
+const syncRunPurgeBatchSize = 1000
+
+// 主实例清理过期同步记录的间隔
+const syncRunPurgeInterval = time.Hour
+
+// getSyncRunRetention 同步记录保留天数，0 时不清理
+func getSyncRunRetention() time.Duration {
+	days := os.Getenv("NOTICE_SYNC_RUN_RETENTION_DAYS")
+	if days == "" {
+		return 30 * 24 * time.Hour // default to 30 days if not set
+	}
+
+	daysInt, err := strconv.Atoi(days)
+	if err != nil || daysInt < 0 {
+		return 30 * 24 * time.Hour // default to 30 days if invalid value
+	}
+
+	return time.Duration(daysInt) * 24 * time.Hour
+}
+
+// FailingSyncBuilding 最近连续同步失败的建筑
+type FailingSyncBuilding struct {
+	BuildingID   uint                     `json:"buildingId"`
+	BuildingName string                   `json:"buildingName"`
+	IsmartID     string                   `json:"ismartId"`
+	Failures     int                      `json:"failures"` // 参与判断的最近同步次数，均为失败
+	LastError    string                   `json:"lastError"`
+	LastRunAt    time.Time                `json:"lastRunAt"`
+	Recent       []models.SyncRunBuilding `json:"recent"`
+}
+
+// NoticeSyncStatus 通知同步当前状态
+type NoticeSyncStatus struct {
+	Breakers    []httpclient.BreakerState `json:"breakers"`    // 最近一次结束的同步保存的通知来源上游主机熔断器状态
+	BreakersAt  *time.Time                `json:"breakersAt"`  // 熔断器状态的记录时间，没有结束的同步时为空
+	OpenCount   int                       `json:"openCount"`   // 熔断中（含待试探）的上游主机数
+	LatestRun   *models.SyncRun           `json:"latestRun"`   // 最近一次同步
+	FailedCount int                       `json:"failedCount"` // 最近一次同步中失败的建筑数
+}
+
+type InterfaceSyncRunService interface {
+	StartRun(trigger field.SyncRunTrigger, triggeredBy string, buildingCount int) (*models.SyncRun, error)
+	RecordBuilding(runID uint, building models.Building, result gin.H, syncErr error, startedAt time.Time) error
+	FinishRun(runID uint) (*models.SyncRun, error)
+	Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.SyncRun, models.PaginationResult, error)
+	GetByID(id uint) (*models.SyncRun, error)
+	GetBuildingHistory(buildingID uint, paginate map[string]interface{}) ([]models.SyncRunBuilding, models.PaginationResult, error)
+	GetFailingBuildings(n int) ([]FailingSyncBuilding, error)
+	GetStatus() (*NoticeSyncStatus, error)
+	// 删除 before 之前开始的同步记录及其建筑结果，返回删除的同步记录数
+	Purge(before time.Time) (int64, error)
+}
+
+type SyncRunService struct {
+	db *gorm.DB
+}
+
+func NewSyncRunService(db *gorm.DB) InterfaceSyncRunService {
+	return &SyncRunService{db: db}
+}
+
+// StartRun 创建一条进行中的同步记录
+func (s *SyncRunService) StartRun(trigger field.SyncRunTrigger, triggeredBy string, buildingCount int) (*models.SyncRun, error) {
+	run := &models.SyncRun{
+		Trigger:       trigger,
+		Status:        field.SyncRunStatusRunning,
+		TriggeredBy:   triggeredBy,
+		BuildingCount: buildingCount,
+		StartedAt:     time.Now(),
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to create sync run: %v", err)
+	}
+	return run, nil
+}
+
+// RecordBuilding 保存单个建筑的同步结果，result 为 SyncBuildingNotices 的返回值
+func (s *SyncRunService) RecordBuilding(runID uint, building models.Building, result gin.H, syncErr error, startedAt time.Time) error {
+	record := &models.SyncRunBuilding{
+		RunID:        runID,
+		BuildingID:   building.ID,
+		BuildingName: building.Name,
+		Status:       field.SyncRunStatusSuccess,
+		StartedAt:    startedAt,
+		DurationMs:   time.Since(startedAt).Milliseconds(),
+	}
+
+	if errors.Is(syncErr, ErrNoticeSyncInProgress) {
+		record.Status = field.SyncRunStatusSkipped
+		record.Error = syncErr.Error()
+	} else if syncErr != nil {
+		record.Status = field.SyncRunStatusFailed
+		record.Error = truncateString(syncErr.Error(), 1000)
+	} else {
+		record.TotalProcessed = resultInt(result, "totalProcessed")
+		record.SuccessCount = resultInt(result, "successCount")
+		record.HasSyncedCount = resultInt(result, "hasSyncedCount")
+		record.DeleteCount = resultInt(result, "deleteCount")
+
+		failedNotices, _ := result["failedNotices"].([]string)
+		record.FailedCount = len(failedNotices)
+		if len(failedNotices) > 0 {
+			record.Status = field.SyncRunStatusPartial
+			if len(failedNotices) > maxSyncRunFailedNotices {
+				failedNotices = failedNotices[:maxSyncRunFailedNotices]
+			}
+			data, err := json.Marshal(failedNotices)
+			if err != nil {
+				return fmt.Errorf("failed to marshal failed notices: %v", err)
+			}
+			record.FailedNotices = data
+		}
+
+		conflicts, _ := result["conflicts"].([]NoticeSyncConflict)
+		record.ConflictCount = len(conflicts)
+		if len(conflicts) > 0 {
+			if len(conflicts) > maxSyncRunConflicts {
+				conflicts = conflicts[:maxSyncRunConflicts]
+			}
+			data, err := json.Marshal(conflicts)
+			if err != nil {
+				return fmt.Errorf("failed to marshal sync conflicts: %v", err)
+			}
+			record.Conflicts = data
+		}
+	}
+
+	if err := s.db.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to create sync run building: %v", err)
+	}
+	return nil
+}
+
+// FinishRun 汇总建筑结果并结束同步记录
+func (s *SyncRunService) FinishRun(runID uint) (*models.SyncRun, error) {
+	var run models.SyncRun
+	if err := s.db.First(&run, runID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sync run: %v", err)
+	}
+
+	var stats []struct {
+		Status field.SyncRunStatus
+		Count  int
+	}
+	if err := s.db.Model(&models.SyncRunBuilding{}).
+		Select("status, COUNT(*) AS count").
+		Where("run_id = ?", runID).
+		Group("status").
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to count sync run buildings: %v", err)
+	}
+
+	successCount, failedCount, partialCount := 0, 0, 0
+	for _, stat := range stats {
+		switch stat.Status {
+		case field.SyncRunStatusFailed:
+			failedCount += stat.Count
+		case field.SyncRunStatusPartial:
+			partialCount += stat.Count
+			successCount += stat.Count
+		case field.SyncRunStatusSkipped:
+			// 由其他同步处理，不计入成功或失败
+		default:
+			successCount += stat.Count
+		}
+	}
+
+	status := field.SyncRunStatusSuccess
+	switch {
+	case failedCount > 0 && successCount == 0:
+		status = field.SyncRunStatusFailed
+	case failedCount > 0 || partialCount > 0:
+		status = field.SyncRunStatusPartial
+	}
+
+	now := time.Now()
+	run.Status = status
+	run.SuccessCount = successCount
+	run.FailedCount = failedCount
+	run.FinishedAt = &now
+	run.DurationMs = now.Sub(run.StartedAt).Milliseconds()
+
+	breakers, err := json.Marshal(getNoticeSourceClient().BreakerStates())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal breaker states: %v", err)
+	}
+	run.Breakers = breakers
+
+	updates := map[string]interface{}{
+		"status":        run.Status,
+		"success_count": run.SuccessCount,
+		"failed_count":  run.FailedCount,
+		"finished_at":   run.FinishedAt,
+		"duration_ms":   run.DurationMs,
+		"breakers":      run.Breakers,
+	}
+	if err := s.db.Model(&models.SyncRun{}).Where("id = ?", runID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to finish sync run: %v", err)
+	}
+	return &run, nil
+}
+
+func (s *SyncRunService) Get(query map[string]interface{}, paginate map[string]interface{}) ([]models.SyncRun, models.PaginationResult, error) {
+	var runs []models.SyncRun
+	var total int64
+	db := s.db.Model(&models.SyncRun{})
+
+	if trigger, ok := query["trigger"].(string); ok && trigger != "" {
+		db = db.Where("trigger_type = ?", trigger)
+	}
+	if status, ok := query["status"].(string); ok && status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if buildingID, ok := query["buildingId"].(uint); ok && buildingID != 0 {
+		db = db.Where("id IN (?)", s.db.Model(&models.SyncRunBuilding{}).Select("run_id").Where("building_id = ?", buildingID))
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if desc, ok := paginate["desc"].(bool); ok && desc {
+		db = db.Order("started_at DESC")
+	} else {
+		db = db.Order("started_at ASC")
+	}
+
+	if err := db.Limit(pageSize).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return runs, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+func (s *SyncRunService) GetByID(id uint) (*models.SyncRun, error) {
+	var run models.SyncRun
+	if err := s.db.Preload("Buildings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("status ASC, building_id ASC")
+	}).First(&run, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("sync run not found")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetBuildingHistory 获取建筑的同步历史，最新的在前
+func (s *SyncRunService) GetBuildingHistory(buildingID uint, paginate map[string]interface{}) ([]models.SyncRunBuilding, models.PaginationResult, error) {
+	var records []models.SyncRunBuilding
+	var total int64
+	db := s.db.Model(&models.SyncRunBuilding{}).Where("building_id = ?", buildingID)
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	pageSize := paginate["pageSize"].(int)
+	pageNum := paginate["pageNum"].(int)
+	offset := (pageNum - 1) * pageSize
+
+	if err := db.Order("started_at DESC, id DESC").Limit(pageSize).Offset(offset).Find(&records).Error; err != nil {
+		return nil, models.PaginationResult{}, err
+	}
+
+	return records, models.PaginationResult{
+		Total:    int(total),
+		PageSize: pageSize,
+		PageNum:  pageNum,
+	}, nil
+}
+
+// GetFailingBuildings 获取最近 n 次同步均失败的建筑，n 为 0 时使用 NOTICE_SYNC_FAILURE_THRESHOLD
+func (s *SyncRunService) GetFailingBuildings(n int) ([]FailingSyncBuilding, error) {
+	if n <= 0 {
+		n = getSyncFailureThreshold()
+	}
+
+	// 先取最近一次同步失败的建筑，再逐个检查最近 n 次；不使用窗口函数，兼容 MySQL 5.7
+	var buildingIDs []uint
+	if err := s.db.Raw(`
+		SELECT b.building_id FROM sync_run_buildings b
+		JOIN (
+			SELECT building_id, MAX(id) AS id
+			FROM sync_run_buildings
+			WHERE status <> ?
+			GROUP BY building_id
+		) latest ON latest.id = b.id
+		WHERE b.status = ?`,
+		field.SyncRunStatusSkipped, field.SyncRunStatusFailed).Scan(&buildingIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get failing buildings: %v", err)
+	}
+
+	result := []FailingSyncBuilding{}
+	if len(buildingIDs) == 0 {
+		return result, nil
+	}
+
+	var buildings []models.Building
+	if err := s.db.Where("id IN ?", buildingIDs).Find(&buildings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get buildings: %v", err)
+	}
+
+	for _, building := range buildings {
+		var recent []models.SyncRunBuilding
+		if err := s.db.Where("building_id = ? AND status <> ?", building.ID, field.SyncRunStatusSkipped).
+			Order("started_at DESC, id DESC").
+			Limit(n).
+			Find(&recent).Error; err != nil {
+			return nil, fmt.Errorf("failed to get building sync history: %v", err)
+		}
+		if len(recent) < n || !allSyncRunsFailed(recent) {
+			continue
+		}
+
+		result = append(result, FailingSyncBuilding{
+			BuildingID:   building.ID,
+			BuildingName: building.Name,
+			IsmartID:     building.IsmartID,
+			Failures:     len(recent),
+			LastError:    recent[0].Error,
+			LastRunAt:    recent[0].StartedAt,
+			Recent:       recent,
+		})
+	}
+	return result, nil
+}
+
+// allSyncRunsFailed 同步结果是否均为失败
+func allSyncRunsFailed(records []models.SyncRunBuilding) bool {
+	for _, record := range records {
+		if record.Status != field.SyncRunStatusFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// GetStatus 获取上游熔断器的实时状态与最近一次同步
+// GetStatus 熔断器状态取自最近一次结束的同步，由执行同步的实例（主实例）保存，与处理请求的实例无关
+func (s *SyncRunService) GetStatus() (*NoticeSyncStatus, error) {
+	status := &NoticeSyncStatus{Breakers: []httpclient.BreakerState{}}
+
+	var latest models.SyncRun
+	err := s.db.Order("started_at DESC, id DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get latest sync run: %v", err)
+	}
+	status.LatestRun = &latest
+	status.FailedCount = latest.FailedCount
+
+	finished := &latest
+	if latest.FinishedAt == nil {
+		var previous models.SyncRun
+		err := s.db.Where("finished_at IS NOT NULL").Order("started_at DESC, id DESC").First(&previous).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return status, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get latest finished sync run: %v", err)
+		}
+		finished = &previous
+	}
+	if len(finished.Breakers) > 0 {
+		if err := json.Unmarshal(finished.Breakers, &status.Breakers); err != nil {
+			return nil, fmt.Errorf("failed to parse breaker states: %v", err)
+		}
+	}
+	status.BreakersAt = finished.FinishedAt
+	for _, breaker := range status.Breakers {
+		if breaker.State != httpclient.StateClosed {
+			status.OpenCount++
+		}
+	}
+	return status, nil
+}
+
+// Purge 分批删除过期的同步记录，避免一次删除过多行长时间锁表
+func (s *SyncRunService) Purge(before time.Time) (int64, error) {
+	var purged int64
+	for {
+		var runIDs []uint
+		if err := s.db.Model(&models.SyncRun{}).
+			Where("started_at < ?", before).
+			Order("id ASC").Limit(syncRunPurgeBatchSize).
+			Pluck("id", &runIDs).Error; err != nil {
+			return purged, fmt.Errorf("failed to get expired sync runs: %v", err)
+		}
+		if len(runIDs) == 0 {
+			return purged, nil
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("run_id IN ?", runIDs).Delete(&models.SyncRunBuilding{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", runIDs).Delete(&models.SyncRun{}).Error
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge sync runs: %v", err)
+		}
+		purged += int64(len(runIDs))
+		if len(runIDs) < syncRunPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// resultInt 读取同步结果中的计数
+func resultInt(result gin.H, key string) int {
+	switch v := result[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
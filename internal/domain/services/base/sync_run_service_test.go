@@ -0,0 +1,68 @@
+package base_services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/httpclient"
+)
+
+func TestGetStatusUsesBreakersOfLatestFinishedRun(t *testing.T) {
+	breakers := func(states ...httpclient.BreakerState) []byte {
+		data, _ := json.Marshal(states)
+		return data
+	}
+	open := httpclient.BreakerState{Host: "ismart.example.com", State: httpclient.StateOpen, ConsecutiveFailures: 5}
+	closed := httpclient.BreakerState{Host: "files.example.com", State: httpclient.StateClosed}
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name      string
+		runs      []models.SyncRun
+		wantHosts int
+		wantOpen  int
+		wantAt    bool
+	}{
+		{"没有同步记录", nil, 0, 0, false},
+		{"最近一次同步已结束", []models.SyncRun{
+			{Status: field.SyncRunStatusSuccess, StartedAt: start, Breakers: breakers(closed)},
+			{Status: field.SyncRunStatusFailed, StartedAt: start.Add(time.Hour), Breakers: breakers(open, closed)},
+		}, 2, 1, true},
+		{"最近一次同步进行中时使用上一次结束的同步", []models.SyncRun{
+			{Status: field.SyncRunStatusFailed, StartedAt: start, Breakers: breakers(open)},
+			{Status: field.SyncRunStatusRunning, StartedAt: start.Add(time.Hour)},
+		}, 1, 1, true},
+		{"只有进行中的同步", []models.SyncRun{
+			{Status: field.SyncRunStatusRunning, StartedAt: start},
+		}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.SyncRun{}, &models.SyncRunBuilding{})
+			for i := range tt.runs {
+				run := tt.runs[i]
+				if run.Status != field.SyncRunStatusRunning {
+					finishedAt := run.StartedAt.Add(time.Minute)
+					run.FinishedAt = &finishedAt
+				}
+				if err := db.Create(&run).Error; err != nil {
+					t.Fatalf("create run: %v", err)
+				}
+			}
+
+			status, err := NewSyncRunService(db).GetStatus()
+			if err != nil {
+				t.Fatalf("get status: %v", err)
+			}
+			if len(status.Breakers) != tt.wantHosts || status.OpenCount != tt.wantOpen {
+				t.Errorf("breakers = %+v open = %d, want %d hosts and %d open", status.Breakers, status.OpenCount, tt.wantHosts, tt.wantOpen)
+			}
+			if (status.BreakersAt != nil) != tt.wantAt {
+				t.Errorf("breakersAt = %v, want set %v", status.BreakersAt, tt.wantAt)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package base_services
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/hmac"
 	"crypto/rsa"
@@ -11,8 +12,9 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
-
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -35,6 +37,25 @@ type IUploadService interface {
 	SaveUploaderInfo(id uint, uploaderType string, email string) error
 	GetLatestUploaderInfo() (uint, string, string, error)
 	VerifyCallback(pubKeyURL, authorization, md5, date string, body []byte) error
+	// 服务端直接上传内容到 OSS，返回文件完整访问路径
+	UploadContentSync(objectKey string, content []byte) (string, error)
+	// 删除 OSS 对象，对象不存在时视为成功
+	DeleteObject(objectKey string) error
+}
+
+// getOSSRequestTimeout returns the timeout of server side OSS requests from environment variables
+func getOSSRequestTimeout() time.Duration {
+	timeout := os.Getenv("OSS_REQUEST_TIMEOUT")
+	if timeout == "" {
+		return 60 * time.Second
+	}
+
+	n, err := strconv.Atoi(timeout)
+	if err != nil || n <= 0 {
+		return 60 * time.Second
+	}
+
+	return time.Duration(n) * time.Second
 }
 
 type UploadService struct {
@@ -618,3 +639,128 @@ func (s *UploadService) GetUploadParamsNoCallback(uploadDir string) (map[string]
 
 	return result, nil
 }
+
+// UploadContentSync 使用同步上传策略在服务端直接上传内容到 OSS
+func (s *UploadService) UploadContentSync(objectKey string, content []byte) (string, error) {
+	uploadParams, err := s.GetUploadParamsSync(objectKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get upload params: %v", err)
+	}
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+
+	formFields := []struct {
+		key      string
+		paramKey string
+	}{
+		{key: "key", paramKey: "dir"},
+		{key: "policy", paramKey: "policy"},
+		{key: "OSSAccessKeyId", paramKey: "accessid"},
+		{key: "success_action_status", paramKey: ""},
+		{key: "callback", paramKey: "callback"},
+		{key: "signature", paramKey: "signature"},
+	}
+
+	for _, field := range formFields {
+		var fieldValue string
+		switch field.key {
+		case "key":
+			fieldValue = objectKey
+		case "success_action_status":
+			fieldValue = "200"
+		default:
+			if field.paramKey != "" {
+				if val, ok := uploadParams[field.paramKey]; ok {
+					fieldValue = fmt.Sprintf("%v", val)
+				} else {
+					continue
+				}
+			}
+		}
+		if err := w.WriteField(field.key, fieldValue); err != nil {
+			return "", fmt.Errorf("failed to write form field %s: %v", field.key, err)
+		}
+	}
+
+	fw, err := w.CreateFormFile("file", objectKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %v", err)
+	}
+	if _, err = io.Copy(fw, bytes.NewReader(content)); err != nil {
+		return "", fmt.Errorf("failed to copy file content: %v", err)
+	}
+	w.Close()
+
+	uploadReq, err := http.NewRequest("POST", uploadParams["host"].(string), &b)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %v", err)
+	}
+	uploadReq.Header.Set("Content-Type", w.FormDataContentType())
+
+	client := &http.Client{Timeout: getOSSRequestTimeout()}
+	uploadResp, err := client.Do(uploadReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	defer uploadResp.Body.Close()
+
+	if uploadResp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(uploadResp.Body)
+		return "", fmt.Errorf("upload failed with status %d: %s", uploadResp.StatusCode, string(respBody))
+	}
+
+	return getOSSHost() + "/" + objectKey, nil
+}
+
+func getOSSHost() string {
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = "http://idreamsky.oss-cn-beijing.aliyuncs.com"
+	}
+	return host
+}
+
+// DeleteObject 使用 AccessKey 签名（OSS V1 签名）删除对象，bucket 取 HOST 的第一段域名
+func (s *UploadService) DeleteObject(objectKey string) error {
+	host := getOSSHost()
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("invalid oss host %s: %v", host, err)
+	}
+	bucket := strings.SplitN(hostURL.Hostname(), ".", 2)[0]
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	h := hmac.New(sha1.New, []byte(os.Getenv("ACCESS_KEY_SECRET")))
+	io.WriteString(h, "DELETE\n\n\n"+date+"\n/"+bucket+"/"+objectKey)
+	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	deleteReq, err := http.NewRequest(http.MethodDelete, strings.TrimRight(host, "/")+"/"+objectKey, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %v", err)
+	}
+	deleteReq.Header.Set("Date", date)
+	deleteReq.Header.Set("Authorization", "OSS "+os.Getenv("ACCESS_KEY_ID")+":"+signature)
+
+	client := &http.Client{Timeout: getOSSRequestTimeout()}
+	deleteResp, err := client.Do(deleteReq)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	defer deleteResp.Body.Close()
+
+	if deleteResp.StatusCode != http.StatusNoContent && deleteResp.StatusCode != http.StatusOK && deleteResp.StatusCode != http.StatusNotFound {
+		respBody, _ := io.ReadAll(deleteResp.Body)
+		return fmt.Errorf("delete failed with status %d: %s", deleteResp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// ossObjectKey 从文件访问路径中取出 OSS 对象 key
+func ossObjectKey(path string) string {
+	parsed, err := url.Parse(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(parsed.Path, "/")
+}
@@ -0,0 +1,72 @@
+package base_services
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"删除成功", http.StatusNoContent, false},
+		{"对象不存在视为成功", http.StatusNotFound, false},
+		{"签名错误返回错误", http.StatusForbidden, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			t.Setenv("HOST", server.URL)
+			t.Setenv("ACCESS_KEY_ID", "id")
+			t.Setenv("ACCESS_KEY_SECRET", "secret")
+
+			err := (&UploadService{}).DeleteObject("iboard/screenshot/1/a.png")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Method != http.MethodDelete || got.URL.Path != "/iboard/screenshot/1/a.png" {
+				t.Fatalf("request = %s %s", got.Method, got.URL.Path)
+			}
+			// httptest 的主机为 127.0.0.1，bucket 取第一段 "127"
+			h := hmac.New(sha1.New, []byte("secret"))
+			h.Write([]byte("DELETE\n\n\n" + got.Header.Get("Date") + "\n/127/iboard/screenshot/1/a.png"))
+			want := "OSS id:" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+			if auth := got.Header.Get("Authorization"); auth != want {
+				t.Errorf("authorization = %s, want %s", auth, want)
+			}
+		})
+	}
+}
+
+func TestDeleteObjectTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	t.Setenv("HOST", server.URL)
+	t.Setenv("OSS_REQUEST_TIMEOUT", "1")
+
+	start := time.Now()
+	err := (&UploadService{}).DeleteObject("a.png")
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("request took %v, want it to stop after OSS_REQUEST_TIMEOUT", elapsed)
+	}
+}
@@ -1,12 +1,162 @@
 package base_services
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/apk"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"gorm.io/gorm"
 )
 
+// VersionSignatureAlgorithm 版本元数据签名算法
+const VersionSignatureAlgorithm = "ed25519"
+
+// getApkInspectTimeout returns the APK download timeout from environment variables
+func getApkInspectTimeout() time.Duration {
+	timeout := os.Getenv("APK_INSPECT_TIMEOUT")
+	if timeout == "" {
+		return 5 * time.Minute
+	}
+
+	seconds, err := strconv.Atoi(timeout)
+	if err != nil || seconds <= 0 {
+		return 5 * time.Minute
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// getApkMaxSize returns the maximum APK size in bytes from environment variables
+func getApkMaxSize() int64 {
+	size := os.Getenv("APK_MAX_SIZE_MB")
+	if size == "" {
+		return 500 << 20
+	}
+
+	mb, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || mb <= 0 {
+		return 500 << 20
+	}
+
+	return mb << 20
+}
+
+// getAppSigningKey 从环境变量读取 Ed25519 私钥（base64 的 32 字节种子或 64 字节私钥），未配置时返回 nil
+func getAppSigningKey() ed25519.PrivateKey {
+	encoded := os.Getenv("APP_SIGNING_PRIVATE_KEY")
+	if encoded == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Warn("APP_SIGNING_PRIVATE_KEY 不是有效的 base64 | 错误: %v", err)
+		return nil
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key)
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key)
+	}
+	log.Warn("APP_SIGNING_PRIVATE_KEY 长度无效 | 长度: %d", len(key))
+	return nil
+}
+
+// AppSigningPublicKey 返回 base64 编码的签名公钥，未配置私钥时返回 false
+func AppSigningPublicKey() (string, bool) {
+	key := getAppSigningKey()
+	if key == nil {
+		return "", false
+	}
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), true
+}
+
+// VersionSignaturePayload 签名内容，字段按行拼接，设备按相同格式验证
+func VersionSignaturePayload(version *models.Version) string {
+	return strings.Join([]string{
+		"iboard-apk-v1",
+		version.VersionNumber,
+		strconv.Itoa(version.VersionCode),
+		version.PackageName,
+		version.DownloadUrl,
+		strings.ToLower(version.Sha256),
+		strconv.FormatInt(version.FileSize, 10),
+		strconv.Itoa(version.MinSdkVersion),
+	}, "\n")
+}
+
+// signVersion 对版本元数据签名，未配置私钥、缺少 SHA-256 或元数据未经校验时返回空
+func signVersion(version *models.Version) string {
+	key := getAppSigningKey()
+	if key == nil || version.Sha256 == "" || version.MetadataUnverified {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(VersionSignaturePayload(version))))
+}
+
+// apkArtifact 下载 APK 后得到的元数据
+type apkArtifact struct {
+	Sha256   string
+	Size     int64
+	Manifest *apk.Manifest
+}
+
+// inspectApk 下载 APK，计算 SHA-256 与大小并读取清单
+func inspectApk(url string) (*apkArtifact, error) {
+	client := &http.Client{Timeout: getApkInspectTimeout()}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download apk: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download apk: status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "iboard-apk-*.apk")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	maxSize := getApkMaxSize()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download apk: %v", err)
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("apk exceeds %d MB", maxSize>>20)
+	}
+
+	manifest, err := apk.ReadManifest(tmp, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apkArtifact{
+		Sha256:   hex.EncodeToString(hash.Sum(nil)),
+		Size:     size,
+		Manifest: manifest,
+	}, nil
+}
+
 // InterfaceVersionService 版本服务接口
 type InterfaceVersionService interface {
 	// 创建版本
@@ -21,8 +171,10 @@ type InterfaceVersionService interface {
 	Update(version *models.Version) (*models.Version, error)
 	// 删除版本
 	Delete(id uint) error
-	// 获取活跃版本列表
-	GetActiveVersions() ([]*models.Version, error)
+	// 重新下载 APK 计算元数据并签名
+	Inspect(id uint) (*models.Version, error)
+	// 获取活跃版本列表，channel 不为空时只返回该渠道可见的版本
+	GetActiveVersions(channel field.ReleaseChannel) ([]*models.Version, error)
 }
 
 // VersionService 版本服务实现
@@ -47,10 +199,23 @@ func (s *VersionService) Create(version *models.Version) (*models.Version, error
 		return nil, errors.New("version number already exists")
 	}
 
+	if version.Channel == "" {
+		version.Channel = field.ReleaseChannelStable
+	}
+	if !field.IsValidReleaseChannel(string(version.Channel)) {
+		return nil, fmt.Errorf("invalid release channel: %s", version.Channel)
+	}
+
+	if err := s.prepareArtifact(version, nil); err != nil {
+		return nil, err
+	}
+	version.Signature = signVersion(version)
+
 	if err := s.db.Create(version).Error; err != nil {
 		return nil, err
 	}
 
+	s.notifyChannelDevices(version)
 	return version, nil
 }
 
@@ -106,11 +271,44 @@ func (s *VersionService) Update(version *models.Version) (*models.Version, error
 		return nil, errors.New("version number already exists")
 	}
 
+	if version.Channel != "" && !field.IsValidReleaseChannel(string(version.Channel)) {
+		return nil, fmt.Errorf("invalid release channel: %s", version.Channel)
+	}
+
+	existing, err := s.GetByID(version.ID)
+	if err != nil {
+		return nil, errors.New("version not found")
+	}
+	if err := s.prepareArtifact(version, existing); err != nil {
+		return nil, err
+	}
+	version.Signature = ""
+
 	if err := s.db.Model(&models.Version{}).Where("id = ?", version.ID).Updates(version).Error; err != nil {
 		return nil, err
 	}
+	// Updates 忽略零值，校验状态单独更新
+	if err := s.db.Model(&models.Version{}).Where("id = ?", version.ID).
+		Update("metadata_unverified", version.MetadataUnverified).Error; err != nil {
+		return nil, err
+	}
 
-	return s.GetByID(version.ID)
+	updated, err := s.GetByID(version.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 元数据任一字段变化都需要重新签名
+	if signature := signVersion(updated); signature != updated.Signature {
+		if err := s.db.Model(&models.Version{}).Where("id = ?", updated.ID).Update("signature", signature).Error; err != nil {
+			return nil, err
+		}
+		updated.Signature = signature
+	}
+	if version.Channel != "" || version.Status != "" {
+		s.notifyChannelDevices(updated)
+	}
+	return updated, nil
 }
 
 // Delete 删除版本
@@ -121,13 +319,135 @@ func (s *VersionService) Delete(id uint) error {
 		return errors.New("cannot delete version that is currently in use")
 	}
 
+	// 检查是否有进行中的灰度发布
+	var rollouts int64
+	if err := s.db.Model(&models.AppRollout{}).
+		Where("version_id = ? AND status IN ?", id, []field.AppRolloutStatus{field.AppRolloutActive, field.AppRolloutPaused}).
+		Count(&rollouts).Error; err != nil {
+		return err
+	}
+	if rollouts > 0 {
+		return errors.New("cannot delete version that has an ongoing rollout")
+	}
+
 	return s.db.Delete(&models.Version{}, id).Error
 }
 
+// prepareArtifact 根据上传的文件或下载地址填充 APK 元数据；修改时下载地址未变则沿用已有元数据
+func (s *VersionService) prepareArtifact(version *models.Version, existing *models.Version) error {
+	if version.FileID != nil {
+		if *version.FileID == 0 {
+			version.FileID = nil
+		} else {
+			var file models.File
+			if err := s.db.First(&file, *version.FileID).Error; err != nil {
+				return errors.New("apk file not found")
+			}
+			if !strings.HasSuffix(strings.ToLower(file.Path), ".apk") {
+				return errors.New("file is not an APK")
+			}
+			version.DownloadUrl = file.Path
+		}
+	}
+
+	if existing != nil && (version.DownloadUrl == "" || version.DownloadUrl == existing.DownloadUrl) && version.Sha256 == "" {
+		// 元数据只能来自 APK，忽略直接提交的值
+		version.MetadataUnverified = existing.MetadataUnverified
+		version.FileSize = 0
+		version.PackageName = ""
+		version.VersionCode = 0
+		version.MinSdkVersion = 0
+		return nil
+	}
+	if version.DownloadUrl == "" {
+		return errors.New("downloadUrl or fileId is required")
+	}
+
+	declared := strings.ToLower(version.Sha256)
+	artifact, err := inspectApk(version.DownloadUrl)
+	if err != nil {
+		// 无法下载时可使用管理员提供的元数据，标记为未校验且不签名，待 Inspect 成功后签名
+		if declared != "" && version.FileSize > 0 {
+			log.Warn("读取 APK 失败，使用提交的元数据，未签名 | 地址: %s | 错误: %v", version.DownloadUrl, err)
+			version.Sha256 = declared
+			version.MetadataUnverified = true
+			return nil
+		}
+		return fmt.Errorf("failed to inspect apk: %v", err)
+	}
+	if declared != "" && declared != artifact.Sha256 {
+		return fmt.Errorf("sha256 mismatch: declared %s, actual %s", declared, artifact.Sha256)
+	}
+
+	version.Sha256 = artifact.Sha256
+	version.MetadataUnverified = false
+	version.FileSize = artifact.Size
+	version.PackageName = artifact.Manifest.Package
+	version.VersionCode = artifact.Manifest.VersionCode
+	version.MinSdkVersion = artifact.Manifest.MinSdkVersion
+	if artifact.Manifest.VersionName != "" && artifact.Manifest.VersionName != version.VersionNumber {
+		log.Warn("APK 版本名与版本号不一致 | 版本号: %s | APK: %s", version.VersionNumber, artifact.Manifest.VersionName)
+	}
+
+	log.Info("读取 APK 元数据 | 版本号: %s | 大小: %d | SHA-256: %s | minSdk: %d",
+		version.VersionNumber, version.FileSize, version.Sha256, version.MinSdkVersion)
+	return nil
+}
+
+// Inspect 重新下载 APK 计算元数据并签名，用于补全已有版本
+func (s *VersionService) Inspect(id uint) (*models.Version, error) {
+	version, err := s.GetByID(id)
+	if err != nil {
+		return nil, errors.New("version not found")
+	}
+
+	version.Sha256 = ""
+	if err := s.prepareArtifact(version, nil); err != nil {
+		return nil, err
+	}
+	version.Signature = signVersion(version)
+
+	if err := s.db.Model(&models.Version{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sha256":              version.Sha256,
+		"file_size":           version.FileSize,
+		"package_name":        version.PackageName,
+		"version_code":        version.VersionCode,
+		"min_sdk_version":     version.MinSdkVersion,
+		"signature":           version.Signature,
+		"metadata_unverified": version.MetadataUnverified,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update version metadata: %v", err)
+	}
+	return s.GetByID(id)
+}
+
+// notifyChannelDevices beta、pilot 渠道的活跃版本发布后通知订阅该渠道的设备
+func (s *VersionService) notifyChannelDevices(version *models.Version) {
+	if version.Status != "active" || version.Channel == "" || version.Channel == field.ReleaseChannelStable {
+		return
+	}
+
+	deviceIDs, err := channelDeviceIDs(s.db, subscribedReleaseChannels(version.Channel))
+	if err != nil {
+		log.Warn("获取渠道设备失败 | 渠道: %s | 错误: %v", version.Channel, err)
+		return
+	}
+	log.Info("渠道版本发布 | 版本: %s | 渠道: %s | 设备数: %d", version.VersionNumber, version.Channel, len(deviceIDs))
+	PublishDeviceEvent(field.DeviceEventAppUpdateAvailable, nil, deviceIDs, map[string]interface{}{
+		"versionId":     version.ID,
+		"versionNumber": version.VersionNumber,
+		"channel":       version.Channel,
+	})
+}
+
 // GetActiveVersions 获取活跃版本列表
-func (s *VersionService) GetActiveVersions() ([]*models.Version, error) {
+func (s *VersionService) GetActiveVersions(channel field.ReleaseChannel) ([]*models.Version, error) {
 	var versions []*models.Version
-	if err := s.db.Where("status = ?", "active").Order("created_at DESC").Find(&versions).Error; err != nil {
+	db := s.db.Where("status = ?", "active")
+	if channel != "" {
+		db = db.Where("channel IN ?", visibleReleaseChannels(channel))
+	}
+	if err := db.Order("created_at DESC").Find(&versions).Error; err != nil {
 		return nil, err
 	}
 	return versions, nil
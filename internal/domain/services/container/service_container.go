@@ -11,6 +11,7 @@ import (
 	relationship_service "github.com/The-Healthist/iboard_http_service/internal/domain/services/relationship"
 	redis "github.com/The-Healthist/iboard_http_service/internal/infrastructure/redis"
 	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils"
 	"gorm.io/gorm"
 )
 
@@ -19,20 +20,37 @@ type ServiceContainer struct {
 	db *gorm.DB
 
 	// Base Services
-	advertisementService base_services.InterfaceAdvertisementService
-	buildingService      base_services.InterfaceBuildingService
-	buildingAdminService base_services.InterfaceBuildingAdminService
-	noticeService        base_services.InterfaceNoticeService
-	fileService          base_services.InterfaceFileService
-	jwtService           base_services.IJWTService
-	emailService         base_services.IEmailService
-	superAdminService    base_services.InterfaceSuperAdminService
-	uploadService        base_services.IUploadService
-	deviceService        base_services.InterfaceDeviceService
-	noticeSyncService    base_services.InterfaceNoticeSyncService
-	appService           base_services.InterfaceAppService
-	versionService       base_services.InterfaceVersionService
-	printerService       base_services.InterfacePrinterService
+	advertisementService    base_services.InterfaceAdvertisementService
+	buildingService         base_services.InterfaceBuildingService
+	buildingAdminService    base_services.InterfaceBuildingAdminService
+	noticeService           base_services.InterfaceNoticeService
+	fileService             base_services.InterfaceFileService
+	jwtService              base_services.IJWTService
+	emailService            base_services.IEmailService
+	superAdminService       base_services.InterfaceSuperAdminService
+	uploadService           base_services.IUploadService
+	deviceService           base_services.InterfaceDeviceService
+	noticeSyncService       base_services.InterfaceNoticeSyncService
+	syncRunService          base_services.InterfaceSyncRunService
+	appService              base_services.InterfaceAppService
+	appRolloutService       base_services.InterfaceAppRolloutService
+	appReleaseService       base_services.InterfaceAppReleaseService
+	appFleetService         base_services.InterfaceAppFleetService
+	versionService          base_services.InterfaceVersionService
+	printerService          base_services.InterfacePrinterService
+	deviceEventService      base_services.InterfaceDeviceEventService
+	deviceGroupService      base_services.InterfaceDeviceGroupService
+	deviceSettingsService   base_services.InterfaceDeviceSettingsService
+	deviceCommandService    base_services.InterfaceDeviceCommandService
+	deviceScreenshotService base_services.InterfaceDeviceScreenshotService
+	deviceTelemetryService  base_services.InterfaceDeviceTelemetryService
+	deviceStatusService     base_services.InterfaceDeviceStatusService
+	alertService            base_services.InterfaceAlertService
+	printerSupplyService    base_services.InterfacePrinterSupplyService
+	printJobService         base_services.InterfacePrintJobService
+	printDispatchService    base_services.InterfacePrintDispatchService
+	printerStatusService    base_services.InterfacePrinterStatusService
+	printPasswordService    base_services.InterfacePrintPasswordService
 
 	// Building Admin Services
 	buildingAdminAdvertisementService building_admin_services.InterfaceBuildingAdminAdvertisementService
@@ -118,12 +136,19 @@ func (c *ServiceContainer) initializeServices() {
 	c.buildingAdminService = base_services.NewBuildingAdminService(c.db)
 	c.superAdminService = base_services.NewSuperAdminService(c.db)
 	c.deviceService = base_services.NewDeviceService(c.db)
+	// Email service, SMTP client is initialized in main
+	if utils.EmailClient != nil {
+		c.emailService = base_services.NewEmailService(utils.EmailClient)
+	} else {
+		log.Warn("SMTP未配置，邮件服务不可用")
+	}
 
 	// Use global Redis connection
 	c.uploadService = base_services.NewUploadService(c.db, redis.REDIS_CONN)
 
 	// Initialize Notice Sync Service
 	log.Debug("初始化通知同步服务...")
+	c.syncRunService = base_services.NewSyncRunService(c.db)
 	c.noticeSyncService = base_services.NewNoticeSyncService(
 		c.db,
 		redis.REDIS_CONN,
@@ -133,10 +158,33 @@ func (c *ServiceContainer) initializeServices() {
 	)
 	// App service
 	c.appService = base_services.NewAppService(c.db)
+	c.appRolloutService = base_services.NewAppRolloutService(c.db)
+	c.appReleaseService = base_services.NewAppReleaseService(c.db)
+	c.appFleetService = base_services.NewAppFleetService(c.db)
 	// Version service
 	c.versionService = base_services.NewVersionService(c.db)
 	// Printer service
 	c.printerService = base_services.NewPrinterService(c.db)
+	c.printerSupplyService = base_services.NewPrinterSupplyService(c.db)
+	c.printJobService = base_services.NewPrintJobService(c.db)
+	c.printDispatchService = base_services.NewPrintDispatchService(c.db)
+	c.printerStatusService = base_services.NewPrinterStatusService(c.db)
+	// Device event service (SSE push, fan-out via Redis pub/sub)
+	c.deviceEventService = base_services.NewDeviceEventService()
+	// Device group & settings profile services
+	c.deviceGroupService = base_services.NewDeviceGroupService(c.db)
+	c.deviceSettingsService = base_services.NewDeviceSettingsService(c.db)
+	// Device remote command service
+	c.deviceCommandService = base_services.NewDeviceCommandService(c.db)
+	// Device screenshot service
+	c.deviceScreenshotService = base_services.NewDeviceScreenshotService(c.db, c.uploadService)
+	// Device telemetry & health rule service
+	c.deviceTelemetryService = base_services.NewDeviceTelemetryService(c.db)
+	// Device online/offline history service
+	c.deviceStatusService = base_services.NewDeviceStatusService(c.db)
+	// Alert rules engine
+	c.alertService = base_services.NewAlertService(c.db, c.emailService)
+	c.printPasswordService = base_services.NewPrintPasswordService(c.db, c.emailService)
 
 	// Initialize Relationship Services
 	log.Debug("初始化关系服务...")
@@ -197,12 +245,46 @@ func (c *ServiceContainer) GetService(name string) interface{} {
 		service = c.deviceService
 	case "noticeSync":
 		service = c.noticeSyncService
+	case "syncRun":
+		service = c.syncRunService
 	case "app":
 		service = c.appService
+	case "appRollout":
+		service = c.appRolloutService
+	case "appRelease":
+		service = c.appReleaseService
+	case "appFleet":
+		service = c.appFleetService
 	case "version":
 		service = c.versionService
 	case "printer":
 		service = c.printerService
+	case "printerSupply":
+		service = c.printerSupplyService
+	case "printJob":
+		service = c.printJobService
+	case "printDispatch":
+		service = c.printDispatchService
+	case "printerStatus":
+		service = c.printerStatusService
+	case "printPassword":
+		service = c.printPasswordService
+	case "deviceEvent":
+		service = c.deviceEventService
+	case "deviceGroup":
+		service = c.deviceGroupService
+	case "deviceSettings":
+		service = c.deviceSettingsService
+	case "deviceCommand":
+		service = c.deviceCommandService
+	case "deviceScreenshot":
+		service = c.deviceScreenshotService
+	case "deviceTelemetry":
+		service = c.deviceTelemetryService
+	case "deviceStatus":
+		service = c.deviceStatusService
+	case "alert":
+		service = c.alertService
 
 	// Building admin services
 	case "buildingAdminAdvertisement":
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
 	"github.com/The-Healthist/iboard_http_service/pkg/log"
 	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"gorm.io/datatypes"
@@ -33,7 +34,7 @@ func NewAdvertisementBuildingService(db *gorm.DB) InterfaceAdvertisementBuilding
 
 func (s *AdvertisementBuildingService) BindBuildings(advertisementID uint, buildingIDs []uint) error {
 	log.Info("绑定建筑到广告 | 广告ID: %d | 建筑数量: %d", advertisementID, len(buildingIDs))
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		// check if the advertisement exists
 		exists, err := s.AdvertisementExists(advertisementID)
 		if err != nil {
@@ -112,12 +113,17 @@ func (s *AdvertisementBuildingService) BindBuildings(advertisementID uint, build
 
 		log.Info("成功绑定建筑到广告 | 广告ID: %d | 新绑定建筑数量: %d", advertisementID, len(newBuildingIDs))
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	base_services.PublishDeviceEvent(field.DeviceEventCarouselChanged, buildingIDs, nil, map[string]interface{}{"advertisementId": advertisementID})
+	return nil
 }
 
 func (s *AdvertisementBuildingService) UnbindBuildings(advertisementID uint, buildingIDs []uint) error {
 	log.Info("解绑建筑与广告 | 广告ID: %d | 建筑数量: %d", advertisementID, len(buildingIDs))
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		// 获取广告信息以确定其 display 类型
 		var advertisement base_models.Advertisement
 		if err := tx.First(&advertisement, advertisementID).Error; err != nil {
@@ -142,7 +148,12 @@ func (s *AdvertisementBuildingService) UnbindBuildings(advertisementID uint, bui
 
 		log.Info("成功解绑建筑与广告 | 广告ID: %d | 解绑建筑数量: %d", advertisementID, len(buildingIDs))
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	base_services.PublishDeviceEvent(field.DeviceEventCarouselChanged, buildingIDs, nil, map[string]interface{}{"advertisementId": advertisementID})
+	return nil
 }
 
 func (s *AdvertisementBuildingService) GetBuildingsByAdvertisementID(advertisementID uint) ([]base_models.Building, error) {
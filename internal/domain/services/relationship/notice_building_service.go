@@ -6,7 +6,9 @@ import (
 	"fmt"
 
 	base_models "github.com/The-Healthist/iboard_http_service/internal/domain/models"
+	base_services "github.com/The-Healthist/iboard_http_service/internal/domain/services/base"
 	"github.com/The-Healthist/iboard_http_service/pkg/log"
+	"github.com/The-Healthist/iboard_http_service/pkg/utils/field"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -32,7 +34,7 @@ func NewNoticeBuildingService(db *gorm.DB) InterfaceNoticeBuildingService {
 
 func (s *NoticeBuildingService) BindBuildings(noticeID uint, buildingIDs []uint) error {
 	log.Info("绑定通知到建筑 | 通知ID: %d | 建筑IDs: %v", noticeID, buildingIDs)
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		var notice base_models.Notice
 		if err := tx.Preload("Buildings").First(&notice, noticeID).Error; err != nil {
 			log.Warn("通知不存在 | 通知ID: %d | 错误: %v", noticeID, err)
@@ -98,12 +100,17 @@ func (s *NoticeBuildingService) BindBuildings(noticeID uint, buildingIDs []uint)
 
 		log.Info("成功绑定通知到建筑 | 通知ID: %d | 新绑定建筑数量: %d", noticeID, len(newBuildingIDs))
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	base_services.PublishDeviceEvent(field.DeviceEventNoticesChanged, buildingIDs, nil, map[string]interface{}{"noticeId": noticeID})
+	return nil
 }
 
 func (s *NoticeBuildingService) UnbindBuildings(noticeID uint, buildingIDs []uint) error {
 	log.Info("解绑通知与建筑 | 通知ID: %d | 建筑数量: %d", noticeID, len(buildingIDs))
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
 		// 同步更新相关 device 的轮播列表（在删除关系之前）
 		if err := s.syncDeviceNoticeCarouselLists(tx, noticeID, buildingIDs, false); err != nil {
 			log.Error("同步设备通知轮播列表失败 | 通知ID: %d | 错误: %v", noticeID, err)
@@ -121,7 +128,12 @@ func (s *NoticeBuildingService) UnbindBuildings(noticeID uint, buildingIDs []uin
 
 		log.Info("成功解绑通知与建筑 | 通知ID: %d | 解绑建筑数量: %d", noticeID, len(buildingIDs))
 		return nil
-	})
+	}); err != nil {
+		return err
+	}
+
+	base_services.PublishDeviceEvent(field.DeviceEventNoticesChanged, buildingIDs, nil, map[string]interface{}{"noticeId": noticeID})
+	return nil
 }
 
 func (s *NoticeBuildingService) GetBuildingsByNoticeID(noticeID uint) ([]base_models.Building, error) {
@@ -86,8 +86,28 @@ func InitDB(host, user, password, port, dbname string) *gorm.DB {
 		&models.Advertisement{},
 		&models.Notice{},
 		&models.File{},
-		&models.Printer{}, // 打印机表
-		&models.Device{},  // 包含 JSON 列：top/full/notices carousel lists
+		&models.Printer{},               // 打印机表
+		&models.Device{},                // 包含 JSON 列：top/full/notices carousel lists
+		&models.DeviceGroup{},           // 设备分组表
+		&models.DeviceSettingsProfile{}, // 设备设置配置表
+		&models.DeviceCommand{},         // 设备远程命令表
+		&models.DeviceScreenshot{},      // 设备截图表
+		&models.DeviceTelemetrySample{}, // 设备遥测历史表
+		&models.DeviceHealthRule{},      // 设备健康规则表
+		&models.DeviceStatusEvent{},     // 设备上下线记录表
+		&models.AlertRule{},             // 告警规则表
+		&models.Alert{},                 // 告警记录表
+		&models.PrinterSupplySample{},   // 打印机墨量历史表
+		&models.PrintJob{},              // 打印任务记录
+		&models.PrinterStatusEvent{},    // 打印机状态变化记录
+		&models.PrintPassword{},         // 打印密码
+		&models.PrintPasswordAttempt{},  // 打印密码校验记录
+		&models.PrintDispatch{},         // 管理端下发的打印任务
+		&models.AppRollout{},            // 应用灰度发布
+		&models.AppUpdateAttempt{},      // 应用更新结果
+		&models.SyncRun{},               // 通知同步记录
+		&models.SyncRunBuilding{},       // 通知同步建筑结果
+		&models.NoticeSourceMapping{},   // 来源通知与本地通知的对应关系
 	); err != nil {
 		log.Error("迁移其他表失败: %v", err)
 		return nil
@@ -136,6 +156,26 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.File{},
 		&models.Printer{}, // 打印机表
 		&models.Device{},
+		&models.DeviceGroup{},           // 设备分组表
+		&models.DeviceSettingsProfile{}, // 设备设置配置表
+		&models.DeviceCommand{},         // 设备远程命令表
+		&models.DeviceScreenshot{},      // 设备截图表
+		&models.DeviceTelemetrySample{}, // 设备遥测历史表
+		&models.DeviceHealthRule{},      // 设备健康规则表
+		&models.DeviceStatusEvent{},     // 设备上下线记录表
+		&models.AlertRule{},             // 告警规则表
+		&models.Alert{},                 // 告警记录表
+		&models.PrinterSupplySample{},   // 打印机墨量历史表
+		&models.PrintJob{},              // 打印任务记录
+		&models.PrinterStatusEvent{},    // 打印机状态变化记录
+		&models.PrintPassword{},         // 打印密码
+		&models.PrintPasswordAttempt{},  // 打印密码校验记录
+		&models.PrintDispatch{},         // 管理端下发的打印任务
+		&models.AppRollout{},            // 应用灰度发布
+		&models.AppUpdateAttempt{},      // 应用更新结果
+		&models.SyncRun{},               // 通知同步记录
+		&models.SyncRunBuilding{},       // 通知同步建筑结果
+		&models.NoticeSourceMapping{},   // 来源通知与本地通知的对应关系
 	)
 
 	if err != nil {
@@ -0,0 +1,255 @@
+// Package apk 从 APK 中读取 AndroidManifest.xml（二进制 XML）的版本与 SDK 信息
+package apk
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Manifest APK 清单中与升级相关的字段
+type Manifest struct {
+	Package          string `json:"package"`
+	VersionCode      int    `json:"versionCode"`
+	VersionName      string `json:"versionName"`
+	MinSdkVersion    int    `json:"minSdkVersion"`
+	TargetSdkVersion int    `json:"targetSdkVersion"`
+}
+
+// 二进制 XML 块类型
+const (
+	chunkStringPool   = 0x0001
+	chunkXML          = 0x0003
+	chunkResourceMap  = 0x0180
+	chunkStartElement = 0x0102
+)
+
+// android 属性的资源 ID，混淆后的 APK 属性名可能为空，按资源 ID 匹配
+const (
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrMinSdkVersion    = 0x0101020c
+	attrTargetSdkVersion = 0x01010270
+)
+
+// 属性值类型
+const (
+	typeString = 0x03
+	typeIntDec = 0x10
+	typeIntHex = 0x11
+)
+
+// ReadManifest 读取 APK 文件中的清单信息
+func ReadManifest(r io.ReaderAt, size int64) (*Manifest, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apk: %v", err)
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "AndroidManifest.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open manifest: %v", err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, 16<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %v", err)
+		}
+		return ParseManifest(data)
+	}
+	return nil, errors.New("AndroidManifest.xml not found in apk")
+}
+
+// ParseManifest 解析二进制 AndroidManifest.xml
+func ParseManifest(data []byte) (*Manifest, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXML {
+		return nil, errors.New("manifest is not binary xml")
+	}
+
+	var (
+		strings  []string
+		resIDs   []uint32
+		manifest Manifest
+	)
+
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if chunkSize < 8 || offset+chunkSize > len(data) {
+			return nil, errors.New("corrupted manifest chunk")
+		}
+		chunk := data[offset : offset+chunkSize]
+
+		switch chunkType {
+		case chunkStringPool:
+			pool, err := parseStringPool(chunk)
+			if err != nil {
+				return nil, err
+			}
+			strings = pool
+		case chunkResourceMap:
+			headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+			for i := headerSize; i+4 <= len(chunk); i += 4 {
+				resIDs = append(resIDs, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case chunkStartElement:
+			if err := parseStartElement(chunk, strings, resIDs, &manifest); err != nil {
+				return nil, err
+			}
+		}
+		offset += chunkSize
+	}
+	return &manifest, nil
+}
+
+func parseStringPool(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, errors.New("corrupted string pool")
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	isUTF8 := binary.LittleEndian.Uint32(chunk[16:])&0x100 != 0
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if headerSize+count*4 > len(chunk) || stringsStart > len(chunk) {
+		return nil, errors.New("corrupted string pool")
+	}
+
+	result := make([]string, count)
+	for i := 0; i < count; i++ {
+		pos := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+		if pos >= len(chunk) {
+			continue
+		}
+		if isUTF8 {
+			result[i] = readUTF8(chunk[pos:])
+		} else {
+			result[i] = readUTF16(chunk[pos:])
+		}
+	}
+	return result, nil
+}
+
+// readUTF8 字符数与字节数各占 1-2 字节，之后为内容
+func readUTF8(b []byte) string {
+	_, n := utf8Length(b)
+	if n >= len(b) {
+		return ""
+	}
+	length, m := utf8Length(b[n:])
+	start := n + m
+	if start+length > len(b) {
+		return ""
+	}
+	return string(b[start : start+length])
+}
+
+func utf8Length(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	if b[0]&0x80 != 0 && len(b) > 1 {
+		return int(b[0]&0x7f)<<8 | int(b[1]), 2
+	}
+	return int(b[0]), 1
+}
+
+// readUTF16 长度占 1-2 个 uint16，之后为 UTF-16LE 内容
+func readUTF16(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+	length := int(binary.LittleEndian.Uint16(b))
+	pos := 2
+	if length&0x8000 != 0 && len(b) >= 4 {
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(b[2:]))
+		pos = 4
+	}
+	if pos+length*2 > len(b) {
+		return ""
+	}
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[pos+i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func parseStartElement(chunk []byte, strings []string, resIDs []uint32, manifest *Manifest) error {
+	if len(chunk) < 36 {
+		return errors.New("corrupted start element")
+	}
+	name := lookup(strings, binary.LittleEndian.Uint32(chunk[20:]))
+	if name != "manifest" && name != "uses-sdk" {
+		return nil
+	}
+
+	attrStart := 16 + int(binary.LittleEndian.Uint16(chunk[24:]))
+	attrSize := int(binary.LittleEndian.Uint16(chunk[26:]))
+	attrCount := int(binary.LittleEndian.Uint16(chunk[28:]))
+	if attrSize < 20 || attrStart+attrSize*attrCount > len(chunk) {
+		return errors.New("corrupted start element")
+	}
+
+	for i := 0; i < attrCount; i++ {
+		attr := chunk[attrStart+i*attrSize:]
+		nameIndex := binary.LittleEndian.Uint32(attr[4:])
+		rawValue := binary.LittleEndian.Uint32(attr[8:])
+		dataType := attr[15]
+		value := binary.LittleEndian.Uint32(attr[16:])
+
+		var resID uint32
+		if int(nameIndex) < len(resIDs) {
+			resID = resIDs[nameIndex]
+		}
+		attrName := lookup(strings, nameIndex)
+
+		stringValue := func() string {
+			if dataType == typeString {
+				return lookup(strings, value)
+			}
+			if s := lookup(strings, rawValue); s != "" {
+				return s
+			}
+			return strconv.Itoa(int(value))
+		}
+		intValue := func() int {
+			if dataType == typeIntDec || dataType == typeIntHex {
+				return int(value)
+			}
+			// 预览版 SDK 以代号字符串表示，无法转换时为 0
+			n, _ := strconv.Atoi(stringValue())
+			return n
+		}
+
+		switch {
+		case name == "manifest" && attrName == "package":
+			manifest.Package = stringValue()
+		case name == "manifest" && (resID == attrVersionCode || attrName == "versionCode"):
+			manifest.VersionCode = intValue()
+		case name == "manifest" && (resID == attrVersionName || attrName == "versionName"):
+			manifest.VersionName = stringValue()
+		case name == "uses-sdk" && (resID == attrMinSdkVersion || attrName == "minSdkVersion"):
+			manifest.MinSdkVersion = intValue()
+		case name == "uses-sdk" && (resID == attrTargetSdkVersion || attrName == "targetSdkVersion"):
+			manifest.TargetSdkVersion = intValue()
+		}
+	}
+	return nil
+}
+
+func lookup(strings []string, index uint32) string {
+	if index == 0xffffffff || int(index) >= len(strings) {
+		return ""
+	}
+	return strings[index]
+}
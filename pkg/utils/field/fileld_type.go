@@ -58,6 +58,253 @@ type FileUploaderType string
 const (
 	UploaderTypeBuildingAdmin FileUploaderType = "buildingAdmin"
 	UploaderTypeSuperAdmin    FileUploaderType = "superAdmin"
+	UploaderTypeDevice        FileUploaderType = "device"
+)
+
+// device push event type.
+type DeviceEventType string
+
+const (
+	DeviceEventNoticesChanged     DeviceEventType = "notices_changed"
+	DeviceEventCarouselChanged    DeviceEventType = "carousel_changed"
+	DeviceEventSettingsChanged    DeviceEventType = "settings_changed"
+	DeviceEventAppUpdateAvailable DeviceEventType = "app_update_available"
+	DeviceEventCommandQueued      DeviceEventType = "command_queued"
+	DeviceEventPrintJobQueued     DeviceEventType = "print_job_queued"
+)
+
+// device settings profile scope.
+type SettingsScope string
+
+const (
+	SettingsScopeGlobal   SettingsScope = "global"
+	SettingsScopeBuilding SettingsScope = "building"
+	SettingsScopeGroup    SettingsScope = "group"
+	SettingsScopeDevice   SettingsScope = "device"
+)
+
+// device remote command.
+type DeviceCommandType string
+
+const (
+	DeviceCommandReboot     DeviceCommandType = "reboot"
+	DeviceCommandRefresh    DeviceCommandType = "refresh"
+	DeviceCommandScreenshot DeviceCommandType = "screenshot"
+	DeviceCommandClearCache DeviceCommandType = "clear_cache"
+)
+
+// device remote command status.
+type DeviceCommandStatus string
+
+const (
+	CommandStatusQueued    DeviceCommandStatus = "queued"
+	CommandStatusDelivered DeviceCommandStatus = "delivered"
+	CommandStatusSucceeded DeviceCommandStatus = "succeeded"
+	CommandStatusFailed    DeviceCommandStatus = "failed"
+	CommandStatusExpired   DeviceCommandStatus = "expired"
+)
+
+// device command target.
+type CommandTarget string
+
+const (
+	CommandTargetDevice   CommandTarget = "device"
+	CommandTargetGroup    CommandTarget = "group"
+	CommandTargetBuilding CommandTarget = "building"
+)
+
+// device telemetry metric used by health rules.
+type TelemetryMetric string
+
+const (
+	TelemetryMetricTemperature        TelemetryMetric = "temperature"
+	TelemetryMetricStorageFreeMB      TelemetryMetric = "storageFreeMB"
+	TelemetryMetricStorageFreePercent TelemetryMetric = "storageFreePercent"
+	TelemetryMetricMemoryFreeMB       TelemetryMetric = "memoryFreeMB"
+	TelemetryMetricMemoryFreePercent  TelemetryMetric = "memoryFreePercent"
+	TelemetryMetricUptimeSeconds      TelemetryMetric = "uptimeSeconds"
+	TelemetryMetricScreenOn           TelemetryMetric = "screenOn" // 1 亮屏, 0 熄屏
+)
+
+// health rule comparison operator.
+type RuleOperator string
+
+const (
+	RuleOperatorGT  RuleOperator = "gt"
+	RuleOperatorGTE RuleOperator = "gte"
+	RuleOperatorLT  RuleOperator = "lt"
+	RuleOperatorLTE RuleOperator = "lte"
+	RuleOperatorEQ  RuleOperator = "eq"
+)
+
+// device health derived from telemetry.
+type DeviceHealth string
+
+const (
+	DeviceHealthUnknown   DeviceHealth = "unknown"
+	DeviceHealthHealthy   DeviceHealth = "healthy"
+	DeviceHealthUnhealthy DeviceHealth = "unhealthy"
+)
+
+// device online status transition.
+type DeviceOnlineStatus string
+
+const (
+	DeviceStatusOnline  DeviceOnlineStatus = "online"
+	DeviceStatusOffline DeviceOnlineStatus = "offline"
+)
+
+// alert rule type.
+type AlertRuleType string
+
+const (
+	AlertRuleDeviceOffline   AlertRuleType = "device_offline"   // 设备离线超过 threshold 分钟
+	AlertRuleOrangePiOffline AlertRuleType = "orangepi_offline" // 香橙派状态为 offline
+	AlertRulePrinterOffline  AlertRuleType = "printer_offline"  // 打印机状态为 offline
+	AlertRuleInkLow          AlertRuleType = "ink_low"          // 任一墨盒低于 threshold%
+	AlertRulePrinterFlapping AlertRuleType = "printer_flapping" // 检测窗口内状态变化次数达到 threshold
+)
+
+// alert status.
+type AlertStatus string
+
+const (
+	AlertStatusOpen     AlertStatus = "open"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// printer network status.
+type PrinterStatus string
+
+const (
+	PrinterStatusOnline  PrinterStatus = "online"
+	PrinterStatusOffline PrinterStatus = "offline"
+	PrinterStatusRemoved PrinterStatus = "removed" // 从设备上报的打印机列表中消失
+)
+
+// print job result.
+type PrintJobStatus string
+
+const (
+	PrintJobStatusSucceeded PrintJobStatus = "succeeded"
+	PrintJobStatusFailed    PrintJobStatus = "failed"
+)
+
+// print statistics grouping.
+type PrintStatsGroup string
+
+const (
+	PrintStatsByBuilding PrintStatsGroup = "building"
+	PrintStatsByDevice   PrintStatsGroup = "device"
+	PrintStatsByPrinter  PrintStatsGroup = "printer"
+)
+
+// print password scope.
+type PrintPasswordScope string
+
+const (
+	PrintPasswordScopeGlobal   PrintPasswordScope = "global"
+	PrintPasswordScopeBuilding PrintPasswordScope = "building"
+	PrintPasswordScopeDevice   PrintPasswordScope = "device"
+)
+
+// server-initiated print job status.
+type PrintDispatchStatus string
+
+const (
+	PrintDispatchQueued    PrintDispatchStatus = "queued"
+	PrintDispatchPushing   PrintDispatchStatus = "pushing" // 服务端正在推送到香橙派
+	PrintDispatchDelivered PrintDispatchStatus = "delivered"
+	PrintDispatchSucceeded PrintDispatchStatus = "succeeded"
+	PrintDispatchFailed    PrintDispatchStatus = "failed"
+	PrintDispatchExpired   PrintDispatchStatus = "expired"
+	PrintDispatchCanceled  PrintDispatchStatus = "canceled"
+)
+
+// server-initiated print job delivery mode.
+type PrintDeliveryMode string
+
+const (
+	PrintDeliveryPull PrintDeliveryMode = "pull" // 设备收到事件后拉取
+	PrintDeliveryPush PrintDeliveryMode = "push" // 服务端直接推送到香橙派
+)
+
+// app rollout status.
+type AppRolloutStatus string
+
+const (
+	AppRolloutActive     AppRolloutStatus = "active"
+	AppRolloutPaused     AppRolloutStatus = "paused"
+	AppRolloutCompleted  AppRolloutStatus = "completed"   // 已全量发布为默认版本
+	AppRolloutRolledBack AppRolloutStatus = "rolled_back" // 已回滚，灰度设备退回默认版本
+	AppRolloutCanceled   AppRolloutStatus = "canceled"
+)
+
+// app rollout action when failures exceed the limit.
+type AppRolloutFailureAction string
+
+const (
+	AppRolloutFailurePause    AppRolloutFailureAction = "pause"
+	AppRolloutFailureRollback AppRolloutFailureAction = "rollback"
+)
+
+// app release channel, a channel also receives the versions of the channels before it.
+type ReleaseChannel string
+
+const (
+	ReleaseChannelStable ReleaseChannel = "stable"
+	ReleaseChannelBeta   ReleaseChannel = "beta"
+	ReleaseChannelPilot  ReleaseChannel = "pilot"
+)
+
+// notice source, the upstream system that feeds notices to a building.
+type NoticeSource string
+
+const (
+	NoticeSourceIsmart NoticeSource = "ismart"
+	NoticeSourceLocal  NoticeSource = "local"
+)
+
+// notice sync run trigger.
+type SyncRunTrigger string
+
+const (
+	SyncRunTriggerScheduled SyncRunTrigger = "scheduled"
+	SyncRunTriggerManual    SyncRunTrigger = "manual"
+)
+
+// notice sync run status, partial means some notices or buildings failed.
+type SyncRunStatus string
+
+const (
+	SyncRunStatusRunning SyncRunStatus = "running"
+	SyncRunStatusSuccess SyncRunStatus = "success"
+	SyncRunStatusPartial SyncRunStatus = "partial"
+	SyncRunStatusFailed  SyncRunStatus = "failed"
+	SyncRunStatusSkipped SyncRunStatus = "skipped" // building sync was held by another run
+)
+
+// planned action of a notice sync dry run.
+type NoticeSyncAction string
+
+const (
+	NoticeSyncActionCreate NoticeSyncAction = "create" // create a notice and bind it
+	NoticeSyncActionUpdate NoticeSyncAction = "update" // replace file or title of a bound notice
+	NoticeSyncActionBind   NoticeSyncAction = "bind"   // bind an existing notice to the building
+	NoticeSyncActionUnbind NoticeSyncAction = "unbind" // unbind a notice no longer upstream
+	NoticeSyncActionKeep   NoticeSyncAction = "keep"   // nothing changes
+	NoticeSyncActionFailed NoticeSyncAction = "failed" // upstream file request failed
+)
+
+// notice field that sync may write, kept as-is once overridden locally.
+type NoticeSyncField string
+
+const (
+	NoticeSyncFieldTitle    NoticeSyncField = "title" // title and description
+	NoticeSyncFieldType     NoticeSyncField = "type"
+	NoticeSyncFieldEndTime  NoticeSyncField = "endTime"
+	NoticeSyncFieldPriority NoticeSyncField = "priority"
+	NoticeSyncFieldFile     NoticeSyncField = "file"
 )
 
 // validate method.
@@ -101,3 +348,135 @@ func IsValidStatus(s string) bool {
 	}
 	return false
 }
+
+func IsValidDeviceEventType(t string) bool {
+	switch DeviceEventType(t) {
+	case DeviceEventNoticesChanged, DeviceEventCarouselChanged, DeviceEventSettingsChanged, DeviceEventAppUpdateAvailable,
+		DeviceEventCommandQueued, DeviceEventPrintJobQueued:
+		return true
+	}
+	return false
+}
+
+func IsValidSettingsScope(s string) bool {
+	switch SettingsScope(s) {
+	case SettingsScopeGlobal, SettingsScopeBuilding, SettingsScopeGroup, SettingsScopeDevice:
+		return true
+	}
+	return false
+}
+
+func IsValidDeviceCommandType(t string) bool {
+	switch DeviceCommandType(t) {
+	case DeviceCommandReboot, DeviceCommandRefresh, DeviceCommandScreenshot, DeviceCommandClearCache:
+		return true
+	}
+	return false
+}
+
+func IsValidCommandTarget(t string) bool {
+	switch CommandTarget(t) {
+	case CommandTargetDevice, CommandTargetGroup, CommandTargetBuilding:
+		return true
+	}
+	return false
+}
+
+func IsValidTelemetryMetric(m string) bool {
+	switch TelemetryMetric(m) {
+	case TelemetryMetricTemperature, TelemetryMetricStorageFreeMB, TelemetryMetricStorageFreePercent,
+		TelemetryMetricMemoryFreeMB, TelemetryMetricMemoryFreePercent, TelemetryMetricUptimeSeconds,
+		TelemetryMetricScreenOn:
+		return true
+	}
+	return false
+}
+
+func IsValidRuleOperator(o string) bool {
+	switch RuleOperator(o) {
+	case RuleOperatorGT, RuleOperatorGTE, RuleOperatorLT, RuleOperatorLTE, RuleOperatorEQ:
+		return true
+	}
+	return false
+}
+
+func IsValidAlertRuleType(t string) bool {
+	switch AlertRuleType(t) {
+	case AlertRuleDeviceOffline, AlertRuleOrangePiOffline, AlertRulePrinterOffline, AlertRuleInkLow,
+		AlertRulePrinterFlapping:
+		return true
+	}
+	return false
+}
+
+func IsValidPrintStatsGroup(g string) bool {
+	switch PrintStatsGroup(g) {
+	case PrintStatsByBuilding, PrintStatsByDevice, PrintStatsByPrinter:
+		return true
+	}
+	return false
+}
+
+func IsValidPrintPasswordScope(s string) bool {
+	switch PrintPasswordScope(s) {
+	case PrintPasswordScopeGlobal, PrintPasswordScopeBuilding, PrintPasswordScopeDevice:
+		return true
+	}
+	return false
+}
+
+func IsValidPrintDeliveryMode(mode string) bool {
+	switch PrintDeliveryMode(mode) {
+	case PrintDeliveryPull, PrintDeliveryPush:
+		return true
+	}
+	return false
+}
+
+func IsValidAppRolloutFailureAction(action string) bool {
+	switch AppRolloutFailureAction(action) {
+	case AppRolloutFailurePause, AppRolloutFailureRollback:
+		return true
+	}
+	return false
+}
+
+func IsValidReleaseChannel(channel string) bool {
+	switch ReleaseChannel(channel) {
+	case ReleaseChannelStable, ReleaseChannelBeta, ReleaseChannelPilot:
+		return true
+	}
+	return false
+}
+
+func IsValidNoticeSource(source string) bool {
+	switch NoticeSource(source) {
+	case NoticeSourceIsmart, NoticeSourceLocal:
+		return true
+	}
+	return false
+}
+
+func IsValidSyncRunTrigger(trigger string) bool {
+	switch SyncRunTrigger(trigger) {
+	case SyncRunTriggerScheduled, SyncRunTriggerManual:
+		return true
+	}
+	return false
+}
+
+func IsValidSyncRunStatus(status string) bool {
+	switch SyncRunStatus(status) {
+	case SyncRunStatusRunning, SyncRunStatusSuccess, SyncRunStatusPartial, SyncRunStatusFailed, SyncRunStatusSkipped:
+		return true
+	}
+	return false
+}
+
+func IsValidNoticeSyncField(f string) bool {
+	switch NoticeSyncField(f) {
+	case NoticeSyncFieldTitle, NoticeSyncFieldType, NoticeSyncFieldEndTime, NoticeSyncFieldPriority, NoticeSyncFieldFile:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	StateClosed   = "closed"    // 正常
+	StateOpen     = "open"      // 熔断中，拒绝请求
+	StateHalfOpen = "half_open" // 熔断到期，允许一次试探请求
+)
+
+// BreakerState 上游主机的熔断器状态
+type BreakerState struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	RetryAt             *time.Time `json:"retryAt,omitempty"` // 熔断到期时间
+	LastError           string     `json:"lastError,omitempty"`
+	LastFailureAt       *time.Time `json:"lastFailureAt,omitempty"`
+}
+
+type breaker struct {
+	mu                  sync.Mutex
+	host                string
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+	lastError           string
+	lastFailureAt       time.Time
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{host: host, state: StateClosed}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// allow 是否允许发出请求，熔断到期后只放行一次试探请求
+func (b *breaker) allow(config Config) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < config.OpenDuration {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.trialInFlight = true
+		return true
+	case StateHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	}
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.consecutiveFailures = 0
+	b.trialInFlight = false
+}
+
+func (b *breaker) failure(config Config, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.lastError = err.Error()
+	b.lastFailureAt = time.Now()
+	b.trialInFlight = false
+
+	// 试探失败或连续失败达到阈值时熔断，阈值为 0 时不熔断
+	if b.state == StateHalfOpen || (config.FailureThreshold > 0 && b.consecutiveFailures >= config.FailureThreshold) {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *breaker) snapshot(config Config) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := BreakerState{
+		Host:                b.host,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		LastError:           b.lastError,
+	}
+	if b.state == StateOpen && time.Since(b.openedAt) >= config.OpenDuration {
+		state.State = StateHalfOpen
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(config.OpenDuration)
+		state.OpenedAt = &openedAt
+		state.RetryAt = &retryAt
+	}
+	if !b.lastFailureAt.IsZero() {
+		lastFailureAt := b.lastFailureAt
+		state.LastFailureAt = &lastFailureAt
+	}
+	return state
+}
+
+// BreakerStates 返回已请求过的所有上游主机的熔断器状态，按主机排序
+func (c *Client) BreakerStates() []BreakerState {
+	c.mu.Lock()
+	breakers := make([]*breaker, 0, len(c.breakers))
+	for _, b := range c.breakers {
+		breakers = append(breakers, b)
+	}
+	c.mu.Unlock()
+
+	states := make([]BreakerState, 0, len(breakers))
+	for _, b := range breakers {
+		states = append(states, b.snapshot(c.config))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
+
+// OpenBreakers 返回熔断中的上游主机
+func (c *Client) OpenBreakers() []BreakerState {
+	var open []BreakerState
+	for _, state := range c.BreakerStates() {
+		if state.State != StateClosed {
+			open = append(open, state)
+		}
+	}
+	return open
+}
@@ -0,0 +1,209 @@
+// Package httpclient 对外部系统的 HTTP 调用：单次请求超时、带抖动的指数退避重试、按上游主机熔断
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 上游主机熔断中，请求未发出
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Config 客户端配置
+type Config struct {
+	Timeout          time.Duration // 单次请求（含读取响应）超时
+	MaxRetries       int           // 失败后的最大重试次数
+	RetryBaseDelay   time.Duration // 首次重试的基础等待时间，之后每次翻倍
+	RetryMaxDelay    time.Duration // 单次重试等待时间上限
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenDuration     time.Duration // 熔断持续时间，之后允许一次试探请求
+
+	DisableRedirects bool // 不跟随重定向，直接返回 3xx 响应
+}
+
+// Client 可在多个外部集成间共享的 HTTP 客户端
+type Client struct {
+	config   Config
+	client   *http.Client
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New 创建客户端
+func New(config Config) *Client {
+	client := &http.Client{}
+	if config.DisableRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return &Client{
+		config:   config,
+		client:   client,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+var (
+	defaultClient *Client
+	defaultOnce   sync.Once
+)
+
+// Default 返回共享客户端，首次使用时读取环境变量配置
+func Default() *Client {
+	defaultOnce.Do(func() {
+		defaultClient = New(EnvConfig())
+	})
+	return defaultClient
+}
+
+// EnvConfig 从 OUTBOUND_* 环境变量读取配置，需要独立熔断状态的集成用它创建自己的客户端
+func EnvConfig() Config {
+	return Config{
+		Timeout:          time.Duration(getEnvInt("OUTBOUND_HTTP_TIMEOUT", 60)) * time.Second,
+		MaxRetries:       getEnvInt("OUTBOUND_HTTP_MAX_RETRIES", 3),
+		RetryBaseDelay:   time.Duration(getEnvInt("OUTBOUND_HTTP_RETRY_BASE_MS", 500)) * time.Millisecond,
+		RetryMaxDelay:    time.Duration(getEnvInt("OUTBOUND_HTTP_RETRY_MAX_MS", 10000)) * time.Millisecond,
+		FailureThreshold: getEnvInt("OUTBOUND_BREAKER_FAILURES", 5),
+		OpenDuration:     time.Duration(getEnvInt("OUTBOUND_BREAKER_OPEN_SECONDS", 60)) * time.Second,
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Do 使用默认超时发送请求
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.DoTimeout(req, c.config.Timeout)
+}
+
+// DoTimeout 发送请求，timeout 为每次尝试的超时
+// 网络错误、5xx 与 429 会重试，其余状态码直接返回；重试的请求体通过 req.GetBody 重新获取
+func (c *Client) DoTimeout(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	host := req.URL.Host
+	var lastErr error
+
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if req.Body != nil && req.GetBody == nil {
+				break // 请求体无法重放，不再重试
+			}
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(c.backoff(attempt)):
+			}
+		}
+
+		b := c.breaker(host)
+		if !b.allow(c.config) {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w for %s: %v", ErrCircuitOpen, host, lastErr)
+			}
+			return nil, fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+		}
+
+		resp, err := c.attempt(req, timeout)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			b.success()
+			return resp, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		b.failure(c.config, lastErr)
+
+		if req.Context().Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+
+		// 最后一次尝试的响应原样返回，由调用方处理状态码
+		if err == nil {
+			if attempt == c.config.MaxRetries {
+				return resp, nil
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+	}
+
+	return nil, lastErr
+}
+
+// attempt 发送一次请求，超时覆盖到响应体读取完成
+func (c *Client) attempt(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(req.Context())
+	}
+
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+
+	resp, err := c.client.Do(attemptReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff 第 attempt 次重试前的等待时间，在指数退避值的 50%-100% 间随机
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.config.RetryBaseDelay << (attempt - 1)
+	if delay <= 0 || (c.config.RetryMaxDelay > 0 && delay > c.config.RetryMaxDelay) {
+		delay = c.config.RetryMaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}